@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderAPIKey carries the API key of a request.
+	HeaderAPIKey = "X-API-Key"
+
+	apiKeyPrefix = "dsk_"
+)
+
+// GenerateAPIKey returns a new random API key and its hash. Only the hash
+// is meant to be stored; the key is shown once to the operator.
+func GenerateAPIKey() (key, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("generate api key: %w", err)
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of key. API keys carry
+// 256 bits of entropy, so a fast unsalted hash is sufficient.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyAuthenticator authenticates requests carrying an X-API-Key header
+// against the keys stored in the repository.
+type APIKeyAuthenticator struct {
+	Repository repository.RepositoryInterface
+}
+
+// NewAPIKeyAuthenticator returns an authenticator backed by repo.
+func NewAPIKeyAuthenticator(repo repository.RepositoryInterface) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{Repository: repo}
+}
+
+func (a *APIKeyAuthenticator) Authenticate(c echo.Context) (Identity, error) {
+	key := c.Request().Header.Get(HeaderAPIKey)
+	if key == "" {
+		return Identity{}, ErrNoCredentials
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return Identity{}, ErrInvalidCredentials
+	}
+
+	apiKey, err := a.Repository.FindAPIKey(c.Request().Context(), &repository.FilterAPIKey{KeyHash: HashAPIKey(key)})
+	if errors.Is(err, repository.ErrNotFound) {
+		return Identity{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return Identity{}, err
+	}
+
+	identity := Identity{
+		Subject:        "apikey:" + apiKey.ID,
+		OrganizationID: apiKey.OrganizationID,
+	}
+	for _, s := range apiKey.Scopes {
+		if scope, ok := ParseScope(s); ok {
+			identity.Scopes = append(identity.Scopes, scope)
+		}
+	}
+	return identity, nil
+}
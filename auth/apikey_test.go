@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, hash, err := GenerateAPIKey()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+	assert.Len(t, hash, 64)
+	assert.Equal(t, HashAPIKey(key), hash)
+
+	other, _, err := GenerateAPIKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestAPIKeyAuthenticator_Authenticate(t *testing.T) {
+	key, hash, err := GenerateAPIKey()
+	require.NoError(t, err)
+
+	newContext := func(apiKey string) echo.Context {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if apiKey != "" {
+			req.Header.Set(HeaderAPIKey, apiKey)
+		}
+		return echo.New().NewContext(req, httptest.NewRecorder())
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindAPIKey(gomock.Any(), &repository.FilterAPIKey{KeyHash: hash}).Return(repository.APIKey{
+			BaseModel:      repository.BaseModel{ID: "key-1"},
+			OrganizationID: "org-1",
+			Scopes:         []string{"read", "plan", "unknown"},
+		}, nil)
+
+		identity, err := NewAPIKeyAuthenticator(mockRepo).Authenticate(newContext(key))
+		assert.NoError(t, err)
+		assert.Equal(t, Identity{
+			Subject:        "apikey:key-1",
+			OrganizationID: "org-1",
+			Scopes:         []Scope{ScopeRead, ScopePlan},
+		}, identity)
+	})
+
+	t.Run("No header", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, err := NewAPIKeyAuthenticator(mockrepo.NewMockRepositoryInterface(ctrl)).Authenticate(newContext(""))
+		assert.ErrorIs(t, err, ErrNoCredentials)
+	})
+
+	t.Run("Malformed key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, err := NewAPIKeyAuthenticator(mockrepo.NewMockRepositoryInterface(ctrl)).Authenticate(newContext("secret"))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("Unknown or revoked key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindAPIKey(gomock.Any(), gomock.Any()).Return(repository.APIKey{}, &repository.Error{Kind: repository.ErrNotFound})
+
+		_, err := NewAPIKeyAuthenticator(mockRepo).Authenticate(newContext(key))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("Repository unavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindAPIKey(gomock.Any(), gomock.Any()).Return(repository.APIKey{}, &repository.Error{Kind: repository.ErrUnavailable})
+
+		_, err := NewAPIKeyAuthenticator(mockRepo).Authenticate(newContext(key))
+		assert.ErrorIs(t, err, repository.ErrUnavailable)
+	})
+}
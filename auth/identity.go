@@ -0,0 +1,75 @@
+// Package auth authenticates API callers and exposes who they are to the
+// handlers through the echo context.
+package auth
+
+import (
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// Scope is a permission granted to a caller.
+type Scope string
+
+const (
+	// ScopeRead allows reading estates and their stats.
+	ScopeRead Scope = "read"
+	// ScopeWrite allows creating estates and trees.
+	ScopeWrite Scope = "write"
+	// ScopePlan allows computing drone plans.
+	ScopePlan Scope = "plan"
+	// ScopeWebhooks allows managing webhook subscriptions and their
+	// deliveries.
+	ScopeWebhooks Scope = "webhooks"
+	// ScopeAudit allows reading the audit log.
+	ScopeAudit Scope = "audit"
+)
+
+// Scopes lists every scope known to the service.
+var Scopes = []Scope{ScopeRead, ScopeWrite, ScopePlan, ScopeWebhooks, ScopeAudit}
+
+// ParseScope returns the scope named s.
+func ParseScope(s string) (Scope, bool) {
+	for _, scope := range Scopes {
+		if string(scope) == s {
+			return scope, true
+		}
+	}
+	return "", false
+}
+
+// Identity describes an authenticated caller.
+type Identity struct {
+	// Subject identifies the caller, e.g. "apikey:<id>" or "jwt:<sub>".
+	Subject string
+	// OrganizationID is the organization owning the caller's estates.
+	OrganizationID string
+	// Roles are the roles asserted by the identity provider, if any.
+	Roles  []string
+	Scopes []Scope
+}
+
+// HasScope reports whether the identity was granted scope.
+func (i Identity) HasScope(scope Scope) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+const identityContextKey = "auth.identity"
+
+// SetIdentity stores the authenticated identity in the echo context, and
+// its subject as the actor of the repository writes of the request.
+func SetIdentity(c echo.Context, identity Identity) {
+	c.Set(identityContextKey, identity)
+	req := c.Request()
+	c.SetRequest(req.WithContext(repository.WithActor(req.Context(), identity.Subject)))
+}
+
+// IdentityFromContext returns the identity stored by the middleware.
+func IdentityFromContext(c echo.Context) (Identity, bool) {
+	identity, ok := c.Get(identityContextKey).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJWKS(t *testing.T) {
+	t.Run("Skips encryption keys", func(t *testing.T) {
+		keys, err := ParseJWKS([]byte(`{"keys": [
+			{"kid": "sig", "kty": "OKP", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+			{"kid": "enc", "kty": "RSA", "use": "enc", "n": "AQAB", "e": "AQAB"}
+		]}`))
+		assert.NoError(t, err)
+		assert.Len(t, keys, 1)
+		assert.Contains(t, keys, "sig")
+	})
+
+	testcases := []struct {
+		name string
+		data string
+	}{
+		{name: "malformed json", data: `{"keys":`},
+		{name: "empty set", data: `{"keys": []}`},
+		{name: "unsupported key type", data: `{"keys": [{"kid": "a", "kty": "oct", "k": "c2VjcmV0"}]}`},
+		{name: "unsupported curve", data: `{"keys": [{"kid": "a", "kty": "EC", "crv": "P-192", "x": "AQ", "y": "AQ"}]}`},
+		{name: "point not on curve", data: `{"keys": [{"kid": "a", "kty": "EC", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`},
+		{name: "missing modulus", data: `{"keys": [{"kid": "a", "kty": "RSA", "e": "AQAB"}]}`},
+		{name: "duplicate kid", data: `{"keys": [
+			{"kid": "a", "kty": "OKP", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+			{"kid": "a", "kty": "OKP", "crv": "Ed25519", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+		]}`},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseJWKS([]byte(tc.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadPublicKeyFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPath := filepath.Join(dir, "sso-2024.pem")
+	require.NoError(t, os.WriteFile(rsaPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey),
+	}), 0o600))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	ecPath := filepath.Join(dir, "sso-2025.pub")
+	require.NoError(t, os.WriteFile(ecPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	keys, err := LoadPublicKeyFiles(rsaPath, ecPath)
+	require.NoError(t, err)
+	assert.True(t, rsaKey.PublicKey.Equal(keys["sso-2024"]))
+	assert.True(t, ecKey.PublicKey.Equal(keys["sso-2025"]))
+
+	badPath := filepath.Join(dir, "bad.pem")
+	require.NoError(t, os.WriteFile(badPath, []byte("not a key"), 0o600))
+	_, err = LoadPublicKeyFiles(badPath)
+	assert.Error(t, err)
+
+	_, err = LoadPublicKeyFiles(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// DefaultOrganizationClaim is the claim holding the caller's organization.
+	DefaultOrganizationClaim = "org_id"
+	// DefaultRolesClaim is the claim holding the caller's roles.
+	DefaultRolesClaim = "roles"
+
+	bearerPrefix = "Bearer "
+)
+
+// signingMethods lists the accepted algorithms. Only asymmetric algorithms
+// are allowed so a public key can never be used as an HMAC secret.
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// JWTConfig configures bearer token verification.
+type JWTConfig struct {
+	// Issuer must match the "iss" claim.
+	Issuer string
+	// Audience must be contained in the "aud" claim.
+	Audience string
+	// Keys are the public keys trusted to sign tokens.
+	Keys KeySet
+	// OrganizationClaim names the claim mapped to Identity.OrganizationID.
+	// Nested claims are addressed with dots, e.g. "tenant.id". Defaults to
+	// DefaultOrganizationClaim.
+	OrganizationClaim string
+	// RolesClaim names the claim mapped to Identity.Roles. It may hold an
+	// array of strings or a space separated string. Defaults to
+	// DefaultRolesClaim.
+	RolesClaim string
+	// RoleScopes grants scopes to roles. Roles not listed here grant the
+	// scope of the same name, if any.
+	RoleScopes map[string][]Scope
+	// Leeway tolerates clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// JWTAuthenticator authenticates requests carrying a bearer token issued by
+// an external identity provider.
+type JWTAuthenticator struct {
+	config JWTConfig
+	parser *jwt.Parser
+}
+
+// NewJWTAuthenticator validates config and returns an authenticator using it.
+func NewJWTAuthenticator(config JWTConfig) (*JWTAuthenticator, error) {
+	if config.Issuer == "" {
+		return nil, errors.New("jwt: issuer is required")
+	}
+	if config.Audience == "" {
+		return nil, errors.New("jwt: audience is required")
+	}
+	if len(config.Keys) == 0 {
+		return nil, errors.New("jwt: at least one key is required")
+	}
+	if config.OrganizationClaim == "" {
+		config.OrganizationClaim = DefaultOrganizationClaim
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = DefaultRolesClaim
+	}
+
+	return &JWTAuthenticator{
+		config: config,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(signingMethods),
+			jwt.WithIssuer(config.Issuer),
+			jwt.WithAudience(config.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(config.Leeway),
+		),
+	}, nil
+}
+
+func (a *JWTAuthenticator) Authenticate(c echo.Context) (Identity, error) {
+	header := c.Request().Header.Get(echo.HeaderAuthorization)
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return Identity{}, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(header[len(bearerPrefix):], claims, a.keyFunc); err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	organizationID, _ := claimValue(claims, a.config.OrganizationClaim).(string)
+	if organizationID == "" {
+		return Identity{}, fmt.Errorf("%w: missing %s claim", ErrInvalidCredentials, a.config.OrganizationClaim)
+	}
+	subject, _ := claims.GetSubject()
+
+	identity := Identity{
+		Subject:        "jwt:" + subject,
+		OrganizationID: organizationID,
+		Roles:          claimStrings(claimValue(claims, a.config.RolesClaim)),
+	}
+	identity.Scopes = a.scopes(identity.Roles)
+	return identity, nil
+}
+
+// keyFunc selects the verification key by the token's kid header. Tokens
+// without a kid are checked against every trusted key.
+func (a *JWTAuthenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, ok := a.config.Keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		return key, nil
+	}
+
+	var keySet jwt.VerificationKeySet
+	for _, key := range a.config.Keys {
+		keySet.Keys = append(keySet.Keys, key)
+	}
+	return keySet, nil
+}
+
+func (a *JWTAuthenticator) scopes(roles []string) []Scope {
+	var scopes []Scope
+	seen := map[Scope]bool{}
+	for _, role := range roles {
+		granted, ok := a.config.RoleScopes[role]
+		if !ok {
+			if scope, ok := ParseScope(role); ok {
+				granted = []Scope{scope}
+			}
+		}
+		for _, scope := range granted {
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
+// ParseRoleScopes parses a role to scope mapping written as
+// "admin=read+write+plan,viewer=read".
+func ParseRoleScopes(s string) (map[string][]Scope, error) {
+	roleScopes := map[string][]Scope{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		role, scopes, ok := strings.Cut(entry, "=")
+		if !ok || role == "" {
+			return nil, fmt.Errorf("invalid role mapping %q", entry)
+		}
+		for _, name := range strings.Split(scopes, "+") {
+			scope, ok := ParseScope(strings.TrimSpace(name))
+			if !ok {
+				return nil, fmt.Errorf("role %s: unknown scope %q", role, name)
+			}
+			roleScopes[role] = append(roleScopes[role], scope)
+		}
+	}
+	return roleScopes, nil
+}
+
+func claimValue(claims jwt.MapClaims, path string) interface{} {
+	var value interface{} = map[string]interface{}(claims)
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
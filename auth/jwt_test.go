@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://sso.example.com"
+	testAudience = "drone-sawit"
+)
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// writeJWKS writes the public halves of keys as a JWKS file and returns
+// its path.
+func writeJWKS(t *testing.T, keys map[string]crypto.Signer) string {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, signer := range keys {
+		switch pub := signer.Public().(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kid: kid, Kty: "RSA", Use: "sig", N: encodeBigInt(pub.N), E: encodeBigInt(big.NewInt(int64(pub.E)))})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kid: kid, Kty: "EC", Crv: pub.Curve.Params().Name, X: encodeBigInt(pub.X), Y: encodeBigInt(pub.Y)})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kid: kid, Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub)})
+		}
+	}
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key crypto.Signer, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":    testIssuer,
+		"aud":    []string{testAudience},
+		"sub":    "user-1",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"org_id": "org-1",
+		"roles":  []string{"read", "plan"},
+	}
+}
+
+func withClaims(overrides jwt.MapClaims, deleted ...string) jwt.MapClaims {
+	claims := validClaims()
+	for k, v := range overrides {
+		claims[k] = v
+	}
+	for _, k := range deleted {
+		delete(claims, k)
+	}
+	return claims
+}
+
+func TestJWTAuthenticator_Authenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keys, err := LoadJWKSFile(writeJWKS(t, map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey, "ed": edKey}))
+	require.NoError(t, err)
+
+	authenticator, err := NewJWTAuthenticator(JWTConfig{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		Keys:     keys,
+		RoleScopes: map[string][]Scope{
+			"admin": Scopes,
+		},
+	})
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name     string
+		header   string
+		identity Identity
+		err      error
+	}{
+		{
+			name:   "RSA key selected by kid",
+			header: "Bearer " + signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims()),
+			identity: Identity{
+				Subject: "jwt:user-1", OrganizationID: "org-1",
+				Roles: []string{"read", "plan"}, Scopes: []Scope{ScopeRead, ScopePlan},
+			},
+		},
+		{
+			name:   "EC key without kid",
+			header: "Bearer " + signToken(t, jwt.SigningMethodES256, ecKey, "", validClaims()),
+			identity: Identity{
+				Subject: "jwt:user-1", OrganizationID: "org-1",
+				Roles: []string{"read", "plan"}, Scopes: []Scope{ScopeRead, ScopePlan},
+			},
+		},
+		{
+			name:   "Ed25519 key and mapped role",
+			header: "bearer " + signToken(t, jwt.SigningMethodEdDSA, edKey, "ed", withClaims(jwt.MapClaims{"roles": "admin unknown"})),
+			identity: Identity{
+				Subject: "jwt:user-1", OrganizationID: "org-1",
+				Roles: []string{"admin", "unknown"}, Scopes: Scopes,
+			},
+		},
+		{
+			name:   "No authorization header",
+			header: "",
+			err:    ErrNoCredentials,
+		},
+		{
+			name:   "Other authorization scheme",
+			header: "Basic dXNlcjpwYXNz",
+			err:    ErrNoCredentials,
+		},
+		{
+			name:   "Malformed token",
+			header: "Bearer not-a-token",
+			err:    ErrInvalidCredentials,
+		},
+		{
+			name:   "Wrong issuer",
+			header: "Bearer " + signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", withClaims(jwt.MapClaims{"iss": "https://evil.example.com"})),
+			err:    ErrInvalidCredentials,
+		},
+		{
+			name:   "Wrong audience",
+			header: "Bearer " + signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", withClaims(jwt.MapClaims{"aud": "other-service"})),
+			err:    ErrInvalidCredentials,
+		},
+		{
+			name:   "Expired",
+			header: "Bearer " + signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", withClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+			err:    ErrInvalidCredentials,
+		},
+		{
+			name:   "Without expiry",
+			header: "Bearer " + signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", withClaims(nil, "exp")),
+			err:    ErrInvalidCredentials,
+		},
+		{
+			name:   "Unknown kid",
+			header: "Bearer " + signToken(t, jwt.SigningMethodRS256, rsaKey, "rotated", validClaims()),
+			err:    ErrInvalidCredentials,
+		},
+		{
+			name:   "Signed by untrusted key",
+			header: "Bearer " + signToken(t, jwt.SigningMethodRS256, otherKey, "rsa", validClaims()),
+			err:    ErrInvalidCredentials,
+		},
+		{
+			name:   "Missing organization",
+			header: "Bearer " + signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", withClaims(nil, "org_id")),
+			err:    ErrInvalidCredentials,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(echo.HeaderAuthorization, tc.header)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			identity, err := authenticator.Authenticate(c)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.identity, identity)
+		})
+	}
+
+	t.Run("Unsigned token is rejected", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		_, err = authenticator.Authenticate(c)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestJWTAuthenticator_NestedClaims(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	authenticator, err := NewJWTAuthenticator(JWTConfig{
+		Issuer:            testIssuer,
+		Audience:          testAudience,
+		Keys:              KeySet{"ec": key.Public()},
+		OrganizationClaim: "tenant.id",
+		RolesClaim:        "realm_access.roles",
+	})
+	require.NoError(t, err)
+
+	claims := withClaims(jwt.MapClaims{
+		"tenant":       map[string]interface{}{"id": "org-2"},
+		"realm_access": map[string]interface{}{"roles": []string{"write"}},
+	}, "org_id", "roles")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+signToken(t, jwt.SigningMethodES384, key, "ec", claims))
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+
+	identity, err := authenticator.Authenticate(c)
+	assert.NoError(t, err)
+	assert.Equal(t, "org-2", identity.OrganizationID)
+	assert.Equal(t, []Scope{ScopeWrite}, identity.Scopes)
+}
+
+func TestNewJWTAuthenticator(t *testing.T) {
+	keys := KeySet{"k": ed25519.PublicKey(make([]byte, ed25519.PublicKeySize))}
+
+	_, err := NewJWTAuthenticator(JWTConfig{Audience: testAudience, Keys: keys})
+	assert.Error(t, err)
+	_, err = NewJWTAuthenticator(JWTConfig{Issuer: testIssuer, Keys: keys})
+	assert.Error(t, err)
+	_, err = NewJWTAuthenticator(JWTConfig{Issuer: testIssuer, Audience: testAudience})
+	assert.Error(t, err)
+	_, err = NewJWTAuthenticator(JWTConfig{Issuer: testIssuer, Audience: testAudience, Keys: keys})
+	assert.NoError(t, err)
+}
+
+func TestParseRoleScopes(t *testing.T) {
+	roleScopes, err := ParseRoleScopes("admin=read+write+plan, viewer=read")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]Scope{
+		"admin":  {ScopeRead, ScopeWrite, ScopePlan},
+		"viewer": {ScopeRead},
+	}, roleScopes)
+
+	_, err = ParseRoleScopes("admin")
+	assert.Error(t, err)
+	_, err = ParseRoleScopes("admin=delete")
+	assert.Error(t, err)
+}
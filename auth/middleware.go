@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator when the request
+	// carries no credentials it understands, so the next one is tried.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned when credentials were presented but
+	// could not be verified.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator resolves the identity of the caller of a request.
+type Authenticator interface {
+	Authenticate(c echo.Context) (Identity, error)
+}
+
+// Config configures the authentication middleware.
+type Config struct {
+	// Skipper defines a function to skip authentication, e.g. for
+	// operational endpoints.
+	Skipper middleware.Skipper
+	// Authenticators are tried in order until one recognises the
+	// credentials of the request.
+	Authenticators []Authenticator
+}
+
+// Middleware authenticates every request with the configured authenticators
+// and stores the resulting Identity in the echo context. Requests without
+// valid credentials are rejected with 401.
+func Middleware(config Config) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			for _, authenticator := range config.Authenticators {
+				identity, err := authenticator.Authenticate(c)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+				if errors.Is(err, ErrInvalidCredentials) {
+					return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+				}
+				if err != nil {
+					return err
+				}
+
+				SetIdentity(c, identity)
+				return next(c)
+			}
+
+			return echo.NewHTTPError(http.StatusUnauthorized, "missing credentials")
+		}
+	}
+}
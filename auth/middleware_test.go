@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type authenticatorFunc func(c echo.Context) (Identity, error)
+
+func (f authenticatorFunc) Authenticate(c echo.Context) (Identity, error) {
+	return f(c)
+}
+
+func TestMiddleware(t *testing.T) {
+	noCredentials := authenticatorFunc(func(c echo.Context) (Identity, error) {
+		return Identity{}, ErrNoCredentials
+	})
+	invalid := authenticatorFunc(func(c echo.Context) (Identity, error) {
+		return Identity{}, ErrInvalidCredentials
+	})
+	valid := authenticatorFunc(func(c echo.Context) (Identity, error) {
+		return Identity{Subject: "user-1", OrganizationID: "org-1", Scopes: []Scope{ScopeRead}}, nil
+	})
+
+	testcases := []struct {
+		name           string
+		authenticators []Authenticator
+		skip           bool
+		status         int
+		subject        string
+	}{
+		{name: "first match wins", authenticators: []Authenticator{noCredentials, valid}, status: http.StatusOK, subject: "user-1"},
+		{name: "invalid credentials stop the chain", authenticators: []Authenticator{invalid, valid}, status: http.StatusUnauthorized},
+		{name: "no credentials", authenticators: []Authenticator{noCredentials}, status: http.StatusUnauthorized},
+		{name: "skipped", authenticators: []Authenticator{invalid}, skip: true, status: http.StatusOK},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			var subject, actor string
+			next := func(c echo.Context) error {
+				if identity, ok := IdentityFromContext(c); ok {
+					subject = identity.Subject
+				}
+				actor = repository.ActorFromContext(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			}
+
+			err := Middleware(Config{
+				Skipper:        func(echo.Context) bool { return tc.skip },
+				Authenticators: tc.authenticators,
+			})(next)(c)
+
+			if tc.status == http.StatusOK {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, rec.Code)
+			} else {
+				httpErr, ok := err.(*echo.HTTPError)
+				assert.True(t, ok)
+				assert.Equal(t, tc.status, httpErr.Code)
+			}
+			assert.Equal(t, tc.subject, subject)
+			if tc.subject != "" {
+				assert.Equal(t, tc.subject, actor, "writes are audited as made by the caller")
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Package cache stores computed results, such as drone plans and estate
+// stats, so unchanged estates are not recomputed on every request.
+//
+// Keys embed the version of the estate they were computed from, so a write
+// to the estate makes its entries unreachable; nothing is invalidated
+// explicitly and stale entries simply age out.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Cache stores values by key. Implementations are safe for concurrent use.
+// A failing cache only costs a recomputation, so callers log errors and
+// carry on.
+type Cache interface {
+	// Get returns the value of key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key, replacing any previous value.
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// Key derives a fixed-length key from parts, e.g. the kind of result, the
+// estate, its version and the parameters it was computed with.
+func Key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Nop is a Cache that stores nothing.
+type Nop struct{}
+
+func (Nop) Get(context.Context, string) ([]byte, bool, error) { return nil, false, nil }
+
+func (Nop) Set(context.Context, string, []byte) error { return nil }
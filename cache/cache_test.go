@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, Key("plan", "estate-1", "3"), Key("plan", "estate-1", "3"))
+	assert.NotEqual(t, Key("plan", "estate-1", "3"), Key("plan", "estate-1", "4"))
+	assert.NotEqual(t, Key("ab", "c"), Key("a", "bc"), "parts are separated")
+	assert.Len(t, Key("plan"), 32)
+}
+
+// testCache checks the behaviour every implementation shares.
+func testCache(t *testing.T, c Cache) {
+	ctx := context.Background()
+
+	_, ok, err := c.Get(ctx, Key("missing"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, c.Set(ctx, Key("a"), []byte("one")))
+	value, ok, err := c.Get(ctx, Key("a"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("one"), value)
+
+	require.NoError(t, c.Set(ctx, Key("a"), []byte("two")))
+	value, _, err = c.Get(ctx, Key("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("two"), value)
+}
+
+func TestMemory(t *testing.T) {
+	testCache(t, NewMemory(0))
+}
+
+func TestMemory_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemory(2)
+
+	require.NoError(t, c.Set(ctx, "a", []byte("a")))
+	require.NoError(t, c.Set(ctx, "b", []byte("b")))
+	_, _, _ = c.Get(ctx, "a")
+	require.NoError(t, c.Set(ctx, "c", []byte("c")))
+
+	assert.Equal(t, 2, c.Len())
+	_, ok, _ := c.Get(ctx, "b")
+	assert.False(t, ok, "b was the least recently used")
+	_, ok, _ = c.Get(ctx, "a")
+	assert.True(t, ok)
+	_, ok, _ = c.Get(ctx, "c")
+	assert.True(t, ok)
+}
+
+func TestFile(t *testing.T) {
+	c, err := NewFile(filepath.Join(t.TempDir(), "cache"), time.Hour)
+	require.NoError(t, err)
+	testCache(t, c)
+
+	// Entries survive a new instance, e.g. after a restart.
+	reopened, err := NewFile(c.dir, time.Hour)
+	require.NoError(t, err)
+	value, ok, err := reopened.Get(context.Background(), Key("a"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("two"), value)
+}
+
+func TestFile_Expiry(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewFile(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "old", []byte("old")))
+	require.NoError(t, c.Set(ctx, "new", []byte("new")))
+	require.NoError(t, os.Chtimes(filepath.Join(c.dir, "old"), now.Add(-2*time.Hour), now.Add(-2*time.Hour)))
+
+	_, ok, err := c.Get(ctx, "old")
+	require.NoError(t, err)
+	assert.False(t, ok, "expired entries are ignored")
+
+	removed, err := c.Prune()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, err = os.Stat(filepath.Join(c.dir, "old"))
+	assert.True(t, os.IsNotExist(err))
+	_, ok, _ = c.Get(ctx, "new")
+	assert.True(t, ok)
+}
+
+func TestFile_InvalidKey(t *testing.T) {
+	c, err := NewFile(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "../escape", "a/b", ".hidden"} {
+		t.Run(fmt.Sprintf("%q", key), func(t *testing.T) {
+			assert.Error(t, c.Set(context.Background(), key, []byte("x")))
+			_, _, err := c.Get(context.Background(), key)
+			assert.Error(t, err)
+		})
+	}
+}
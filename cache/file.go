@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultTTL is how long a File cache keeps entries when created with a
+// zero TTL.
+const DefaultTTL = 24 * time.Hour
+
+// File is a cache storing one file per entry in a directory. It survives
+// restarts and can be shared by the replicas of a host through a common
+// volume. Entries older than the TTL are ignored and removed by Prune.
+type File struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewFile returns a cache storing its entries in dir, which is created if
+// needed.
+func NewFile(dir string, ttl time.Duration) (*File, error) {
+	if dir == "" {
+		return nil, errors.New("cache: directory is required")
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	return &File{dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+func (f *File) Get(_ context.Context, key string) ([]byte, bool, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, false, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("cache: %w", err)
+	}
+	if f.expired(info) {
+		return nil, false, nil
+	}
+
+	value, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Pruned since the stat.
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("cache: %w", err)
+	}
+	return value, true, nil
+}
+
+// Set writes value to a temporary file renamed into place, so readers never
+// see a partial entry.
+func (f *File) Set(_ context.Context, key string, value []byte) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(f.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(value); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
+
+// Prune removes the expired entries and returns how many were removed.
+func (f *File) Prune() (int, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return 0, fmt.Errorf("cache: %w", err)
+	}
+	removed := 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || !f.expired(info) {
+			continue
+		}
+		if err = os.Remove(filepath.Join(f.dir, entry.Name())); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (f *File) expired(info fs.FileInfo) bool {
+	return f.now().Sub(info.ModTime()) >= f.ttl
+}
+
+// path maps key to its file. Keys are usually made by Key, but any key
+// that is a plain file name is accepted.
+func (f *File) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || key[0] == '.' {
+		return "", fmt.Errorf("cache: invalid key %q", key)
+	}
+	return filepath.Join(f.dir, key), nil
+}
@@ -0,0 +1,302 @@
+// Package client is a Go client of the API. It wraps the client generated
+// from api.yml with authentication, idempotency keys on every POST, retries
+// of failed attempts and typed errors.
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultMaxAttempts is how many times a request is sent when
+	// Options.MaxAttempts is zero.
+	DefaultMaxAttempts = 4
+	// DefaultInitialBackoff is the delay before the first retry when
+	// Options.InitialBackoff is zero; it doubles on every retry.
+	DefaultInitialBackoff = 200 * time.Millisecond
+	// DefaultMaxBackoff caps the delay between retries when
+	// Options.MaxBackoff is zero. A Retry-After longer than that is not
+	// waited for: the request fails with ErrRateLimited instead.
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// APIVersion is the version of the API the client calls.
+const APIVersion = "v1"
+
+// HeaderIdempotencyKey is the header retries of a POST are matched by.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+type Options struct {
+	// BaseURL is the URL of the API without its version, e.g.
+	// "http://localhost:8080".
+	BaseURL string
+	// HTTPClient sends the requests. It defaults to http.DefaultClient.
+	HTTPClient generated.HttpRequestDoer
+	// APIKey is sent as X-API-Key.
+	APIKey string
+	// Token is sent as a bearer token, for JWT authentication.
+	Token string
+	// UserAgent is sent as User-Agent when set.
+	UserAgent string
+	// MaxAttempts is how many times a request is sent; 1 disables retries.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	api *generated.ClientWithResponses
+}
+
+func New(opts Options) (*Client, error) {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = DefaultInitialBackoff
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+
+	api, err := generated.NewClientWithResponses(strings.TrimSuffix(opts.BaseURL, "/")+"/"+APIVersion,
+		generated.WithHTTPClient(&retryDoer{
+			doer:           opts.HTTPClient,
+			maxAttempts:    opts.MaxAttempts,
+			initialBackoff: opts.InitialBackoff,
+			maxBackoff:     opts.MaxBackoff,
+		}),
+		generated.WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
+			if opts.APIKey != "" {
+				req.Header.Set("X-API-Key", opts.APIKey)
+			}
+			if opts.Token != "" {
+				req.Header.Set("Authorization", "Bearer "+opts.Token)
+			}
+			if opts.UserAgent != "" {
+				req.Header.Set("User-Agent", opts.UserAgent)
+			}
+			if req.Method == http.MethodPost && req.Header.Get(HeaderIdempotencyKey) == "" {
+				req.Header.Set(HeaderIdempotencyKey, idempotencyKey(ctx))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{api: api}, nil
+}
+
+// API returns the generated client the Client is built on, for requests it
+// has no method for. Its requests are authenticated and retried alike, but
+// its responses are not checked.
+func (c *Client) API() *generated.ClientWithResponses {
+	return c.api
+}
+
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey makes the POST sent with ctx use key as its
+// Idempotency-Key, e.g. to retry a request across restarts of the caller.
+// Without one, every call gets a new key, shared by its retries.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+func idempotencyKey(ctx context.Context) string {
+	if key, ok := ctx.Value(idempotencyKeyContextKey{}).(string); ok && key != "" {
+		return key
+	}
+	return uuid.NewString()
+}
+
+func (c *Client) CreateEstate(ctx context.Context, req generated.EstateRequest) (generated.EstateResponse, error) {
+	res, err := c.api.PostEstateWithResponse(ctx, req)
+	if err != nil {
+		return generated.EstateResponse{}, err
+	}
+	if res.JSON201 == nil {
+		return generated.EstateResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON201, nil
+}
+
+func (c *Client) ListEstates(ctx context.Context, params generated.GetEstateParams) (generated.EstateListResponse, error) {
+	res, err := c.api.GetEstateWithResponse(ctx, &params)
+	if err != nil {
+		return generated.EstateListResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.EstateListResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+func (c *Client) GetEstate(ctx context.Context, estateID string) (generated.EstateDetailResponse, error) {
+	res, err := c.api.GetEstateIdWithResponse(ctx, estateID)
+	if err != nil {
+		return generated.EstateDetailResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.EstateDetailResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+func (c *Client) CreateTree(ctx context.Context, estateID string, req generated.EstateTreeRequest) (generated.EstateTreeResponse, error) {
+	res, err := c.api.PostEstateIdTreeWithResponse(ctx, estateID, req)
+	if err != nil {
+		return generated.EstateTreeResponse{}, err
+	}
+	if res.JSON201 == nil {
+		return generated.EstateTreeResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON201, nil
+}
+
+// Batch runs req in one transaction. The failures of its operations are in
+// their results rather than the error.
+func (c *Client) Batch(ctx context.Context, req generated.BatchRequest) (generated.BatchResponse, error) {
+	res, err := c.api.PostBatchWithResponse(ctx, req)
+	if err != nil {
+		return generated.BatchResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.BatchResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+func (c *Client) GetStats(ctx context.Context, estateID string) (generated.EstateStatsResponse, error) {
+	res, err := c.api.GetEstateIdStatsWithResponse(ctx, estateID)
+	if err != nil {
+		return generated.EstateStatsResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.EstateStatsResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+// GetDronePlan computes the drone plan of an estate, stopping the drone
+// after maxDistance when set.
+func (c *Client) GetDronePlan(ctx context.Context, estateID string, maxDistance *int) (generated.EstateDronePlanResponse, error) {
+	res, err := c.api.GetEstateIdDronePlanWithResponse(ctx, estateID, &generated.GetEstateIdDronePlanParams{MaxDistance: maxDistance})
+	if err != nil {
+		return generated.EstateDronePlanResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.EstateDronePlanResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+func (c *Client) SubmitPlanJob(ctx context.Context, estateID string, req generated.PlanJobRequest) (generated.PlanJobResponse, error) {
+	res, err := c.api.PostEstateIdDronePlanJobsWithResponse(ctx, estateID, req)
+	if err != nil {
+		return generated.PlanJobResponse{}, err
+	}
+	if res.JSON202 == nil {
+		return generated.PlanJobResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON202, nil
+}
+
+func (c *Client) GetPlanJob(ctx context.Context, estateID, jobID string) (generated.PlanJobResponse, error) {
+	res, err := c.api.GetEstateIdDronePlanJobsJobIdWithResponse(ctx, estateID, jobID)
+	if err != nil {
+		return generated.PlanJobResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.PlanJobResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+func (c *Client) CancelPlanJob(ctx context.Context, estateID, jobID string) (generated.PlanJobResponse, error) {
+	res, err := c.api.PostEstateIdDronePlanJobsJobIdCancelWithResponse(ctx, estateID, jobID)
+	if err != nil {
+		return generated.PlanJobResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.PlanJobResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+func (c *Client) CreateWebhook(ctx context.Context, req generated.WebhookSubscriptionRequest) (generated.WebhookSubscriptionResponse, error) {
+	res, err := c.api.PostWebhooksWithResponse(ctx, req)
+	if err != nil {
+		return generated.WebhookSubscriptionResponse{}, err
+	}
+	if res.JSON201 == nil {
+		return generated.WebhookSubscriptionResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON201, nil
+}
+
+func (c *Client) ListWebhooks(ctx context.Context) (generated.WebhookSubscriptionListResponse, error) {
+	res, err := c.api.GetWebhooksWithResponse(ctx)
+	if err != nil {
+		return generated.WebhookSubscriptionListResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.WebhookSubscriptionListResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, id string) error {
+	res, err := c.api.DeleteWebhooksIdWithResponse(ctx, id)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode() != http.StatusNoContent {
+		return newError(res.HTTPResponse, res.Body)
+	}
+	return nil
+}
+
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id string, params generated.GetWebhooksIdDeliveriesParams) (generated.WebhookDeliveryListResponse, error) {
+	res, err := c.api.GetWebhooksIdDeliveriesWithResponse(ctx, id, &params)
+	if err != nil {
+		return generated.WebhookDeliveryListResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.WebhookDeliveryListResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+func (c *Client) ReplayWebhookDeliveries(ctx context.Context, id string, req generated.WebhookReplayRequest) (generated.WebhookReplayResponse, error) {
+	res, err := c.api.PostWebhooksIdReplayWithResponse(ctx, id, req)
+	if err != nil {
+		return generated.WebhookReplayResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.WebhookReplayResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+func (c *Client) ListAuditEntries(ctx context.Context, params generated.GetAuditParams) (generated.AuditLogResponse, error) {
+	res, err := c.api.GetAuditWithResponse(ctx, &params)
+	if err != nil {
+		return generated.AuditLogResponse{}, err
+	}
+	if res.JSON200 == nil {
+		return generated.AuditLogResponse{}, newError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts Options) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	opts.BaseURL = server.URL
+	opts.InitialBackoff = time.Millisecond
+	c, err := New(opts)
+	require.NoError(t, err)
+	return c
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestClient_Headers(t *testing.T) {
+	var keys []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key-1", r.Header.Get("X-API-Key"))
+		assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+		assert.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+		keys = append(keys, r.Header.Get(HeaderIdempotencyKey))
+		writeJSON(w, http.StatusCreated, generated.EstateResponse{Id: "estate-1"})
+	}, Options{APIKey: "key-1", Token: "token-1", UserAgent: "test-agent"})
+
+	ctx := context.Background()
+	estate, err := c.CreateEstate(ctx, generated.EstateRequest{Width: 5, Length: 5})
+	require.NoError(t, err)
+	assert.Equal(t, "estate-1", estate.Id)
+	_, err = c.CreateEstate(ctx, generated.EstateRequest{Width: 5, Length: 5})
+	require.NoError(t, err)
+	_, err = c.CreateEstate(WithIdempotencyKey(ctx, "create-1"), generated.EstateRequest{Width: 5, Length: 5})
+	require.NoError(t, err)
+
+	require.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.NotEqual(t, keys[0], keys[1], "every call gets a new key")
+	assert.Equal(t, "create-1", keys[2])
+}
+
+func TestClient_Retries(t *testing.T) {
+	t.Run("Unavailable", func(t *testing.T) {
+		var keys []string
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			var req generated.EstateTreeRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req), "the body is sent again")
+			keys = append(keys, r.Header.Get(HeaderIdempotencyKey))
+			switch len(keys) {
+			case 1:
+				writeJSON(w, http.StatusServiceUnavailable, generated.ErrorResponse{Code: generated.SERVICEUNAVAILABLE, Message: "service temporarily unavailable"})
+			case 2:
+				writeJSON(w, http.StatusConflict, generated.ErrorResponse{Code: generated.IDEMPOTENCYKEYINPROGRESS, Message: "in progress"})
+			default:
+				writeJSON(w, http.StatusCreated, generated.EstateTreeResponse{Id: "tree-1"})
+			}
+		}, Options{})
+
+		tree, err := c.CreateTree(context.Background(), "estate-1", generated.EstateTreeRequest{X: 1, Y: 1, Height: 5})
+		require.NoError(t, err)
+		assert.Equal(t, "tree-1", tree.Id)
+		require.Len(t, keys, 3)
+		assert.Equal(t, keys[0], keys[1], "retries reuse the idempotency key")
+		assert.Equal(t, keys[0], keys[2], "retries reuse the idempotency key")
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		var attempts int32
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			writeJSON(w, http.StatusBadGateway, nil)
+		}, Options{MaxAttempts: 3})
+
+		_, err := c.GetStats(context.Background(), "estate-1")
+		assert.ErrorIs(t, err, ErrServer)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("RetryAfterTooLong", func(t *testing.T) {
+		var attempts int32
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.Header().Set("Retry-After", "60")
+			writeJSON(w, http.StatusTooManyRequests, generated.ErrorResponse{Code: generated.RATELIMITED, Message: "rate limit exceeded"})
+		}, Options{MaxBackoff: time.Second})
+
+		_, err := c.GetDronePlan(context.Background(), "estate-1", nil)
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.Equal(t, time.Minute, apiErr.RetryAfter)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("NotRetried", func(t *testing.T) {
+		var attempts int32
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			writeJSON(w, http.StatusConflict, generated.ErrorResponse{Code: generated.PLOTOCCUPIED, Message: "plot already has tree"})
+		}, Options{})
+
+		_, err := c.CreateTree(context.Background(), "estate-1", generated.EstateTreeRequest{X: 1, Y: 1, Height: 5})
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("BodyTooLarge", func(t *testing.T) {
+		var attempts int32
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusTooManyRequests, generated.ErrorResponse{Code: generated.PAYLOADTOOLARGE, Message: "Request Entity Too Large"})
+		}, Options{})
+
+		_, err := c.CreateEstate(context.Background(), generated.EstateRequest{Width: 1, Length: 1})
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			cancel()
+			writeJSON(w, http.StatusServiceUnavailable, nil)
+		}, Options{InitialBackoff: time.Hour})
+
+		_, err := c.GetStats(ctx, "estate-1")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestClient_Errors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, generated.ErrorResponse{
+			Code:      generated.VALIDATIONFAILED,
+			Message:   "request does not match the API specification",
+			Details:   &[]generated.ErrorDetail{{Field: "height", Message: "must be at most 30"}},
+			RequestId: stringPtr("request-1"),
+		})
+	}, Options{})
+
+	_, err := c.CreateTree(context.Background(), "estate-1", generated.EstateTreeRequest{X: 1, Y: 1, Height: 50})
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, generated.VALIDATIONFAILED, apiErr.Code)
+	assert.Equal(t, []generated.ErrorDetail{{Field: "height", Message: "must be at most 30"}}, apiErr.Details)
+	assert.Equal(t, "request-1", apiErr.RequestID)
+	assert.True(t, errors.Is(err, ErrInvalidRequest))
+	assert.Equal(t, "400 VALIDATION_FAILED: request does not match the API specification; height must be at most 30", err.Error())
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
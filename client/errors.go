@@ -0,0 +1,113 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+)
+
+// Kinds of Error, matched with errors.Is.
+var (
+	ErrInvalidRequest = errors.New("client: invalid request")
+	ErrUnauthorized   = errors.New("client: unauthorized")
+	ErrForbidden      = errors.New("client: forbidden")
+	ErrNotFound       = errors.New("client: not found")
+	ErrConflict       = errors.New("client: conflict")
+	ErrRateLimited    = errors.New("client: rate limited")
+	ErrUnavailable    = errors.New("client: unavailable")
+	ErrServer         = errors.New("client: server error")
+)
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+	// Code, Message, Details and RequestID are decoded from the body; a
+	// body that is not an API error leaves Code empty.
+	Code      generated.ErrorCode
+	Message   string
+	Details   []generated.ErrorDetail
+	RequestID string
+	// RetryAfter is the Retry-After of a 429 or 503 response.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+	if e.Code != "" {
+		msg = fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	for _, detail := range e.Details {
+		msg += fmt.Sprintf("; %s %s", detail.Field, detail.Message)
+	}
+	return msg
+}
+
+// Unwrap returns the kind of the error, which depends on its status code.
+func (e *Error) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest, e.Code == generated.PAYLOADTOOLARGE,
+		e.StatusCode == http.StatusUnprocessableEntity:
+		return ErrInvalidRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusServiceUnavailable:
+		return ErrUnavailable
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// newError decodes the error response resp, whose body was read into body.
+func newError(resp *http.Response, body []byte) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+		RetryAfter: retryAfter(resp),
+	}
+	var apiErr generated.ErrorResponse
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Code != "" {
+		e.Code = apiErr.Code
+		e.Message = apiErr.Message
+		if apiErr.Details != nil {
+			e.Details = *apiErr.Details
+		}
+		if apiErr.RequestId != nil {
+			e.RequestID = *apiErr.RequestId
+		}
+	}
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Request-ID")
+	}
+	return e
+}
+
+// retryAfter parses the Retry-After of resp, in seconds or as a date.
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+)
+
+// retryDoer sends requests again after transport errors, 429 responses but
+// for a body too large, 502, 503 and 504 responses, and 409 responses to a
+// POST whose first attempt is still in progress. POSTs are only retried with an Idempotency-Key, which the
+// server uses to run them once.
+type retryDoer struct {
+	doer           generated.HttpRequestDoer
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func (d *retryDoer) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := d.doer.Do(req)
+		if attempt >= d.maxAttempts || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		wait := d.backoff(attempt)
+		if resp != nil {
+			if after := retryAfter(resp); after > d.maxBackoff {
+				return resp, nil
+			} else if after > 0 {
+				wait = after
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff is the delay before retrying after attempt: it doubles from the
+// initial backoff up to the max, and is jittered by up to half so that
+// clients failing together do not retry together.
+func (d *retryDoer) backoff(attempt int) time.Duration {
+	wait := d.initialBackoff
+	for i := 1; i < attempt && wait < d.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.maxBackoff {
+		wait = d.maxBackoff
+	}
+	if half := int64(wait / 2); half > 0 {
+		wait = time.Duration(half + rand.Int63n(half+1))
+	}
+	return wait
+}
+
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if req.Method == http.MethodPost && req.Header.Get(HeaderIdempotencyKey) == "" {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// The same body is too large however long the client waits.
+		return errorCode(resp) != generated.PAYLOADTOOLARGE
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		// An earlier attempt of the request is still running.
+		return errorCode(resp) == generated.IDEMPOTENCYKEYINPROGRESS
+	}
+	return false
+}
+
+// errorCode returns the code of the error response resp, empty when its body
+// is not an API error. The body is left readable.
+func errorCode(resp *http.Response) generated.ErrorCode {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var apiErr generated.ErrorResponse
+	if json.Unmarshal(body, &apiErr) != nil {
+		return ""
+	}
+	return apiErr.Code
+}
@@ -0,0 +1,255 @@
+// Command admin manages organizations and API keys directly in the database.
+//
+// Usage:
+//
+//	admin create-org -name <name> [-id <organization id>]
+//	admin list-orgs
+//	admin issue-key -org <organization id> -name <name> [-scopes read,write,plan]
+//	admin revoke-key -id <key id>
+//	admin list-keys [-org <organization id>] [-all]
+//	admin migrate [-status]
+//
+// The database is read from the DATABASE_URL environment variable.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/google/uuid"
+)
+
+const usage = `usage: admin <command> [flags]
+
+commands:
+  create-org  create an organization
+  list-orgs   list organizations
+  issue-key   issue a new API key for an organization
+  revoke-key  revoke an API key
+  list-keys   list API keys
+  migrate     apply pending database migrations
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "create-org":
+		err = createOrg(os.Args[2:])
+	case "list-orgs":
+		err = listOrgs(os.Args[2:])
+	case "issue-key":
+		err = issueKey(os.Args[2:])
+	case "revoke-key":
+		err = revokeKey(os.Args[2:])
+	case "list-keys":
+		err = listKeys(os.Args[2:])
+	case "migrate":
+		err = migrate(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func newRepository() *repository.Repository {
+	return repository.NewRepository(repository.NewRepositoryOptions{
+		Dsn: os.Getenv("DATABASE_URL"),
+	})
+}
+
+// adminContext returns the context of the writes of the tool, audited as
+// made by "admin:<os user>".
+func adminContext() context.Context {
+	actor := "admin"
+	if u, err := user.Current(); err == nil {
+		actor += ":" + u.Username
+	}
+	return repository.WithActor(context.Background(), actor)
+}
+
+func createOrg(args []string) error {
+	fs := flag.NewFlagSet("create-org", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the organization, e.g. the tenant ID used by the SSO; generated when empty")
+	name := fs.String("name", "", "name of the organization (required)")
+	_ = fs.Parse(args)
+
+	if *name == "" {
+		return errors.New("-name is required")
+	}
+	if *id == "" {
+		*id = uuid.NewString()
+	}
+
+	organization := repository.Organization{
+		BaseModel: repository.BaseModel{
+			ID: *id,
+		},
+		Name: *name,
+	}
+	if err := newRepository().CreateOrganization(adminContext(), &organization); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return fmt.Errorf("organization %s already exists", *id)
+		}
+		return err
+	}
+
+	fmt.Printf("created organization %s\n", organization.ID)
+	return nil
+}
+
+func listOrgs(args []string) error {
+	fs := flag.NewFlagSet("list-orgs", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	organizations, err := newRepository().FindAllOrganization(context.Background(), &repository.FilterOrganization{})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCREATED")
+	for _, organization := range organizations {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", organization.ID, organization.Name, organization.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	return w.Flush()
+}
+
+func issueKey(args []string) error {
+	fs := flag.NewFlagSet("issue-key", flag.ExitOnError)
+	org := fs.String("org", "", "organization owning the key (required)")
+	name := fs.String("name", "", "human readable name of the key (required)")
+	scopes := fs.String("scopes", "read,write,plan", "comma separated scopes")
+	_ = fs.Parse(args)
+
+	if *org == "" || *name == "" {
+		return errors.New("-org and -name are required")
+	}
+	var keyScopes []string
+	for _, s := range strings.Split(*scopes, ",") {
+		scope, ok := auth.ParseScope(strings.TrimSpace(s))
+		if !ok {
+			return fmt.Errorf("unknown scope %q", s)
+		}
+		keyScopes = append(keyScopes, string(scope))
+	}
+
+	key, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+	apiKey := repository.APIKey{
+		BaseModel: repository.BaseModel{
+			ID: uuid.NewString(),
+		},
+		OrganizationID: *org,
+		Name:           *name,
+		KeyHash:        hash,
+		Scopes:         keyScopes,
+	}
+	if err = newRepository().CreateAPIKey(adminContext(), &apiKey); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("organization %s does not exist, create it with create-org", *org)
+		}
+		return err
+	}
+
+	fmt.Printf("id:     %s\nscopes: %s\nkey:    %s\n\nStore the key now, it cannot be shown again.\n",
+		apiKey.ID, strings.Join(keyScopes, ","), key)
+	return nil
+}
+
+func revokeKey(args []string) error {
+	fs := flag.NewFlagSet("revoke-key", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the key to revoke (required)")
+	_ = fs.Parse(args)
+
+	if *id == "" {
+		return errors.New("-id is required")
+	}
+	if err := newRepository().RevokeAPIKey(adminContext(), *id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("no active key with id %s", *id)
+		}
+		return err
+	}
+
+	fmt.Printf("revoked %s\n", *id)
+	return nil
+}
+
+func listKeys(args []string) error {
+	fs := flag.NewFlagSet("list-keys", flag.ExitOnError)
+	org := fs.String("org", "", "only list keys of this organization")
+	all := fs.Bool("all", false, "include revoked keys")
+	_ = fs.Parse(args)
+
+	apiKeys, err := newRepository().FindAllAPIKey(context.Background(), &repository.FilterAPIKey{
+		OrganizationID: *org,
+		ShowRevoked:    *all,
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tORGANIZATION\tNAME\tSCOPES\tCREATED\tREVOKED")
+	for _, apiKey := range apiKeys {
+		revoked := "-"
+		if apiKey.RevokedAt != nil {
+			revoked = apiKey.RevokedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", apiKey.ID, apiKey.OrganizationID, apiKey.Name,
+			strings.Join(apiKey.Scopes, ","), apiKey.CreatedAt.Format("2006-01-02 15:04"), revoked)
+	}
+	return w.Flush()
+}
+
+func migrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	status := fs.Bool("status", false, "list pending migrations without applying them")
+	_ = fs.Parse(args)
+
+	repo := newRepository()
+	if *status {
+		pending, err := repo.PendingMigrations(context.Background())
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			fmt.Println("database is up to date")
+		}
+		for _, migration := range pending {
+			fmt.Printf("pending %04d_%s\n", migration.Version, migration.Name)
+		}
+		return nil
+	}
+
+	applied, err := repo.Migrate(context.Background())
+	for _, migration := range applied {
+		fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("database is up to date")
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"os/user"
+
+	"github.com/dimassantoso/drone-sawit/client"
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/generated"
+	pb "github.com/dimassantoso/drone-sawit/generated/dronesawitv1"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// errPlotOccupied is returned by backend.CreateTree for a plot that already
+// has a tree.
+var errPlotOccupied = errors.New("plot already has tree")
+
+// backend carries out the commands, over the API or on the database. Both
+// return what the REST API would.
+type backend interface {
+	CreateEstate(ctx context.Context, width, length int) (generated.EstateResponse, error)
+	ListEstates(ctx context.Context, after string, limit int) ([]generated.EstateDetailResponse, error)
+	GetEstate(ctx context.Context, estateID string) (generated.EstateDetailResponse, error)
+	CreateTree(ctx context.Context, estateID string, x, y, height int) (generated.EstateTreeResponse, error)
+	// CreateTrees plants up to TreesPerWrite trees, all of them or none.
+	CreateTrees(ctx context.Context, estateID string, trees []estates.TreeRecord) error
+	TreesPerWrite() int
+	Stats(ctx context.Context, estateID string) (generated.EstateStatsResponse, error)
+	Plan(ctx context.Context, estateID string, maxDistance *int) (generated.EstateDronePlanResponse, error)
+	// Waypoints calls visit with every waypoint of the drone plan.
+	Waypoints(ctx context.Context, estateID string, maxDistance *int, visit func(planner.Waypoint) error) error
+	Close() error
+}
+
+func newBackend(ctx context.Context, p profile) (backend, error) {
+	if p.Mode == modeDirect {
+		return newDirectBackend(ctx, p)
+	}
+	return newHTTPBackend(p)
+}
+
+// httpBackend calls the REST API, and the gRPC API for waypoints, which the
+// REST API does not serve.
+type httpBackend struct {
+	profile profile
+	client  *client.Client
+}
+
+func newHTTPBackend(p profile) (*httpBackend, error) {
+	c, err := client.New(client.Options{
+		BaseURL:   p.URL,
+		APIKey:    p.APIKey,
+		Token:     p.Token,
+		UserAgent: "dronesawit-cli",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &httpBackend{profile: p, client: c}, nil
+}
+
+func (b *httpBackend) CreateEstate(ctx context.Context, width, length int) (generated.EstateResponse, error) {
+	return b.client.CreateEstate(ctx, generated.EstateRequest{Width: width, Length: length})
+}
+
+func (b *httpBackend) ListEstates(ctx context.Context, after string, limit int) ([]generated.EstateDetailResponse, error) {
+	params := generated.GetEstateParams{Limit: &limit}
+	if after != "" {
+		params.After = &after
+	}
+	res, err := b.client.ListEstates(ctx, params)
+	return res.Estates, err
+}
+
+func (b *httpBackend) GetEstate(ctx context.Context, estateID string) (generated.EstateDetailResponse, error) {
+	return b.client.GetEstate(ctx, estateID)
+}
+
+func (b *httpBackend) CreateTree(ctx context.Context, estateID string, x, y, height int) (generated.EstateTreeResponse, error) {
+	tree, err := b.client.CreateTree(ctx, estateID, generated.EstateTreeRequest{X: x, Y: y, Height: height})
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) && apiErr.Code == generated.PLOTOCCUPIED {
+		return tree, errPlotOccupied
+	}
+	return tree, err
+}
+
+// CreateTrees plants the trees in one atomic batch, each with a
+// tree.created webhook event as the batch sends them.
+func (b *httpBackend) CreateTrees(ctx context.Context, estateID string, trees []estates.TreeRecord) error {
+	atomic := true
+	req := generated.BatchRequest{Atomic: &atomic, Operations: make([]generated.BatchOperation, len(trees))}
+	for i := range trees {
+		req.Operations[i] = generated.BatchOperation{
+			Op:       generated.CreateTree,
+			EstateId: &estateID,
+			X:        &trees[i].X,
+			Y:        &trees[i].Y,
+			Height:   &trees[i].Height,
+		}
+	}
+	res, err := b.client.Batch(ctx, req)
+	if err != nil {
+		return err
+	}
+	for i, result := range res.Results {
+		if result.Status != generated.BatchFailed || result.Error == nil {
+			continue
+		}
+		if result.Error.Code == generated.PLOTOCCUPIED {
+			return fmt.Errorf("tree %d: %w", i, errPlotOccupied)
+		}
+		return fmt.Errorf("tree %d: %s: %s", i, result.Error.Code, result.Error.Message)
+	}
+	return nil
+}
+
+// TreesPerWrite is the most operations of a batch.
+func (b *httpBackend) TreesPerWrite() int {
+	return estates.MaxBatchOperations
+}
+
+func (b *httpBackend) Stats(ctx context.Context, estateID string) (generated.EstateStatsResponse, error) {
+	return b.client.GetStats(ctx, estateID)
+}
+
+func (b *httpBackend) Plan(ctx context.Context, estateID string, maxDistance *int) (generated.EstateDronePlanResponse, error) {
+	return b.client.GetDronePlan(ctx, estateID, maxDistance)
+}
+
+func (b *httpBackend) Waypoints(ctx context.Context, estateID string, maxDistance *int, visit func(planner.Waypoint) error) error {
+	if b.profile.GRPCAddress == "" {
+		return errors.New("waypoints are served by the gRPC API: set grpc_address in the profile")
+	}
+	creds := insecure.NewCredentials()
+	if b.profile.GRPCTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(b.profile.GRPCAddress, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if b.profile.APIKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", b.profile.APIKey)
+	}
+	if b.profile.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+b.profile.Token)
+	}
+	req := &pb.GetDronePlanRequest{EstateId: estateID}
+	if maxDistance != nil {
+		limit := int64(*maxDistance)
+		req.MaxDistance = &limit
+	}
+	stream, err := pb.NewDroneSawitClient(conn).StreamWaypoints(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		waypoint, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = visit(planner.Waypoint{
+			X:        int(waypoint.GetX()),
+			Y:        int(waypoint.GetY()),
+			Altitude: int(waypoint.GetAltitude()),
+			Distance: int(waypoint.GetDistance()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (b *httpBackend) Close() error {
+	return nil
+}
+
+// directBackend works on the database of the server, through the same
+// estates service and planner, on behalf of the organization of the
+// profile. Its writes are audited as made by "cli:<os user>".
+type directBackend struct {
+	organizationID string
+	repository     *repository.Repository
+	estates        *estates.Service
+	planner        *planner.Planner
+}
+
+func newDirectBackend(ctx context.Context, p profile) (*directBackend, error) {
+	repo, err := repository.Open(ctx, repository.NewRepositoryOptions{Dsn: p.DatabaseURL})
+	if err != nil {
+		return nil, err
+	}
+	return &directBackend{
+		organizationID: p.OrganizationID,
+		repository:     repo,
+		estates:        estates.New(estates.Options{Repository: repo}),
+		planner:        planner.New(planner.Options{Repository: repo}),
+	}, nil
+}
+
+// writeContext returns the context of the writes of the tool.
+func writeContext(ctx context.Context) context.Context {
+	actor := "cli"
+	if u, err := user.Current(); err == nil {
+		actor += ":" + u.Username
+	}
+	return repository.WithActor(ctx, actor)
+}
+
+func (b *directBackend) CreateEstate(ctx context.Context, width, length int) (generated.EstateResponse, error) {
+	estate, err := b.estates.CreateEstate(writeContext(ctx), b.organizationID, width, length)
+	return generated.EstateResponse{Id: estate.ID}, err
+}
+
+func (b *directBackend) ListEstates(ctx context.Context, after string, limit int) ([]generated.EstateDetailResponse, error) {
+	estateList, err := b.repository.FindAllEstate(ctx, &repository.FilterEstate{
+		Filter:         repository.Filter{Limit: limit},
+		OrganizationID: b.organizationID,
+		After:          after,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := []generated.EstateDetailResponse{}
+	for _, estate := range estateList {
+		response = append(response, estateDetail(estate))
+	}
+	return response, nil
+}
+
+func (b *directBackend) GetEstate(ctx context.Context, estateID string) (generated.EstateDetailResponse, error) {
+	estate, err := b.findEstate(ctx, estateID)
+	return estateDetail(estate), err
+}
+
+func (b *directBackend) findEstate(ctx context.Context, estateID string) (repository.Estate, error) {
+	return b.repository.FindEstate(ctx, &repository.FilterEstate{ID: estateID, OrganizationID: b.organizationID})
+}
+
+func (b *directBackend) CreateTree(ctx context.Context, estateID string, x, y, height int) (generated.EstateTreeResponse, error) {
+	tree, err := b.estates.CreateTree(writeContext(ctx), b.organizationID, estateID, x, y, height)
+	if errors.Is(err, estates.ErrPlotOccupied) {
+		return generated.EstateTreeResponse{}, errPlotOccupied
+	}
+	return generated.EstateTreeResponse{Id: tree.ID}, err
+}
+
+// CreateTrees plants the trees through the bulk path of the repository, in
+// one transaction.
+func (b *directBackend) CreateTrees(ctx context.Context, estateID string, trees []estates.TreeRecord) error {
+	err := b.estates.CreateTrees(writeContext(ctx), b.organizationID, estateID, trees)
+	if errors.Is(err, estates.ErrPlotOccupied) {
+		return errPlotOccupied
+	}
+	return err
+}
+
+// TreesPerWrite bounds the transactions of the bulk path, which takes any
+// number of trees.
+func (b *directBackend) TreesPerWrite() int {
+	return directTreesPerWrite
+}
+
+func (b *directBackend) Stats(ctx context.Context, estateID string) (generated.EstateStatsResponse, error) {
+	estate, err := b.findEstate(ctx, estateID)
+	if err != nil {
+		return generated.EstateStatsResponse{}, err
+	}
+	stats, err := b.estates.Stats(ctx, estate)
+	return generated.EstateStatsResponse(stats), err
+}
+
+func (b *directBackend) Plan(ctx context.Context, estateID string, maxDistance *int) (generated.EstateDronePlanResponse, error) {
+	result, err := b.planner.Plan(ctx, b.organizationID, estateID, maxDistance)
+	return dronePlan(result), err
+}
+
+func (b *directBackend) Waypoints(ctx context.Context, estateID string, maxDistance *int, visit func(planner.Waypoint) error) error {
+	_, err := b.planner.Waypoints(ctx, b.organizationID, estateID, maxDistance, visit)
+	return err
+}
+
+func (b *directBackend) Close() error {
+	return b.repository.Close()
+}
+
+func estateDetail(estate repository.Estate) generated.EstateDetailResponse {
+	return generated.EstateDetailResponse{
+		Id:        estate.ID,
+		Width:     estate.Width,
+		Length:    estate.Length,
+		Version:   estate.Version,
+		CreatedAt: estate.CreatedAt,
+	}
+}
+
+func dronePlan(result planner.Result) generated.EstateDronePlanResponse {
+	plan := generated.EstateDronePlanResponse{Distance: result.Distance}
+	if result.Rest != nil {
+		plan.Rest = &struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		}{X: result.Rest.X, Y: result.Rest.Y}
+	}
+	return plan
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/dimassantoso/drone-sawit/planner"
+)
+
+func createEstate(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("create-estate", flag.ExitOnError)
+	width := fs.Int("width", 0, "width of the estate, in plots (required)")
+	length := fs.Int("length", 0, "length of the estate, in plots (required)")
+	_ = fs.Parse(args)
+
+	if *width == 0 || *length == 0 {
+		return errors.New("-width and -length are required")
+	}
+	estate, err := c.backend.CreateEstate(ctx, *width, *length)
+	if err != nil {
+		return err
+	}
+	return c.printer.print(estate, []string{"ID"}, [][]string{{estate.Id}})
+}
+
+func listEstates(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("list-estates", flag.ExitOnError)
+	after := fs.String("after", "", "list the estates created after this one")
+	limit := fs.Int("limit", 100, "maximum number of estates listed")
+	_ = fs.Parse(args)
+
+	estateList, err := c.backend.ListEstates(ctx, *after, *limit)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, estate := range estateList {
+		rows = append(rows, estateRow(estate))
+	}
+	return c.printer.print(estateList, estateHeader, rows)
+}
+
+func showEstate(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("show-estate", flag.ExitOnError)
+	id := fs.String("id", "", "estate (required)")
+	_ = fs.Parse(args)
+
+	if *id == "" {
+		return errors.New("-id is required")
+	}
+	estate, err := c.backend.GetEstate(ctx, *id)
+	if err != nil {
+		return err
+	}
+	return c.printer.print(estate, estateHeader, [][]string{estateRow(estate)})
+}
+
+var estateHeader = []string{"ID", "WIDTH", "LENGTH", "VERSION", "CREATED"}
+
+func estateRow(estate generated.EstateDetailResponse) []string {
+	return []string{
+		estate.Id,
+		strconv.Itoa(estate.Width),
+		strconv.Itoa(estate.Length),
+		strconv.FormatInt(estate.Version, 10),
+		estate.CreatedAt.Format("2006-01-02 15:04"),
+	}
+}
+
+func addTree(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("add-tree", flag.ExitOnError)
+	estateID := fs.String("estate", "", "estate (required)")
+	x := fs.Int("x", 0, "plot of the tree, from 1 to the width of the estate (required)")
+	y := fs.Int("y", 0, "plot of the tree, from 1 to the length of the estate (required)")
+	height := fs.Int("height", 0, "height of the tree, in meters (required)")
+	_ = fs.Parse(args)
+
+	if *estateID == "" {
+		return errors.New("-estate is required")
+	}
+	tree, err := c.backend.CreateTree(ctx, *estateID, *x, *y, *height)
+	if err != nil {
+		return err
+	}
+	return c.printer.print(tree, []string{"ID"}, [][]string{{tree.Id}})
+}
+
+// importResult is the output of import-trees.
+type importResult struct {
+	Created int `json:"created"`
+	Skipped int `json:"skipped"`
+}
+
+func importTrees(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("import-trees", flag.ExitOnError)
+	estateID := fs.String("estate", "", "estate (required)")
+	file := fs.String("file", "", "file of the trees, - for stdin (required)")
+	format := fs.String("format", "", "format of the file, csv or json; guessed from the extension when empty")
+	skipExisting := fs.Bool("skip-existing", false, "skip the trees on plots that already have one instead of failing")
+	_ = fs.Parse(args)
+
+	if *estateID == "" || *file == "" {
+		return errors.New("-estate and -file are required")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+	}
+
+	r := io.Reader(os.Stdin)
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	trees, err := estates.ReadTrees(r, *format)
+	if err != nil {
+		return err
+	}
+
+	var result importResult
+	for i, tree := range trees {
+		_, err = c.backend.CreateTree(ctx, *estateID, tree.X, tree.Y, tree.Height)
+		switch {
+		case errors.Is(err, errPlotOccupied) && *skipExisting:
+			result.Skipped++
+		case err != nil:
+			return fmt.Errorf("tree %d (%d, %d): %w (%d trees created)", i+1, tree.X, tree.Y, err, result.Created)
+		default:
+			result.Created++
+		}
+	}
+	return c.printer.print(result, []string{"CREATED", "SKIPPED"},
+		[][]string{{strconv.Itoa(result.Created), strconv.Itoa(result.Skipped)}})
+}
+
+func stats(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	estateID := fs.String("estate", "", "estate (required)")
+	_ = fs.Parse(args)
+
+	if *estateID == "" {
+		return errors.New("-estate is required")
+	}
+	s, err := c.backend.Stats(ctx, *estateID)
+	if err != nil {
+		return err
+	}
+	return c.printer.print(s, []string{"COUNT", "MIN", "MAX", "MEDIAN"}, [][]string{{
+		strconv.Itoa(s.Count),
+		strconv.Itoa(s.Min),
+		strconv.Itoa(s.Max),
+		strconv.FormatFloat(float64(s.Median), 'f', -1, 32),
+	}})
+}
+
+func plan(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	estateID := fs.String("estate", "", "estate (required)")
+	maxDistance := fs.Int("max-distance", 0, "battery range of the drone, in meters; unlimited when unset")
+	_ = fs.Parse(args)
+
+	if *estateID == "" {
+		return errors.New("-estate is required")
+	}
+	result, err := c.backend.Plan(ctx, *estateID, optionalInt(fs, "max-distance", *maxDistance))
+	if err != nil {
+		return err
+	}
+	rest := ""
+	if result.Rest != nil {
+		rest = fmt.Sprintf("%d,%d", result.Rest.X, result.Rest.Y)
+	}
+	return c.printer.print(result, []string{"DISTANCE", "REST"}, [][]string{{strconv.Itoa(result.Distance), rest}})
+}
+
+func exportPlan(ctx context.Context, c *cli, args []string) (err error) {
+	fs := flag.NewFlagSet("export-plan", flag.ExitOnError)
+	estateID := fs.String("estate", "", "estate (required)")
+	maxDistance := fs.Int("max-distance", 0, "battery range of the drone, in meters; unlimited when unset")
+	format := fs.String("format", "csv", "format of the waypoints, csv or json")
+	out := fs.String("out", "-", "file written, - for stdout")
+	_ = fs.Parse(args)
+
+	if *estateID == "" {
+		return errors.New("-estate is required")
+	}
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = f
+	}
+
+	var export waypointWriter
+	switch *format {
+	case "csv":
+		export = &csvWaypoints{w: csv.NewWriter(w)}
+	case "json":
+		export = &jsonWaypoints{w: w}
+	default:
+		return fmt.Errorf("unknown format %q, want csv or json", *format)
+	}
+	err = c.backend.Waypoints(ctx, *estateID, optionalInt(fs, "max-distance", *maxDistance), export.write)
+	if err != nil {
+		return err
+	}
+	return export.close()
+}
+
+// waypointWriter writes the waypoints of export-plan as they are computed.
+type waypointWriter interface {
+	write(waypoint planner.Waypoint) error
+	close() error
+}
+
+type csvWaypoints struct {
+	w       *csv.Writer
+	started bool
+}
+
+func (e *csvWaypoints) write(waypoint planner.Waypoint) error {
+	if !e.started {
+		e.started = true
+		if err := e.w.Write([]string{"x", "y", "altitude", "distance"}); err != nil {
+			return err
+		}
+	}
+	return e.w.Write([]string{
+		strconv.Itoa(waypoint.X),
+		strconv.Itoa(waypoint.Y),
+		strconv.Itoa(waypoint.Altitude),
+		strconv.Itoa(waypoint.Distance),
+	})
+}
+
+func (e *csvWaypoints) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonWaypoints writes an array of waypoints, one per line.
+type jsonWaypoints struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonWaypoints) write(waypoint planner.Waypoint) error {
+	data, err := json.Marshal(struct {
+		X        int `json:"x"`
+		Y        int `json:"y"`
+		Altitude int `json:"altitude"`
+		Distance int `json:"distance"`
+	}{waypoint.X, waypoint.Y, waypoint.Altitude, waypoint.Distance})
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "[\n"
+	}
+	e.count++
+	_, err = fmt.Fprintf(e.w, "%s  %s", separator, data)
+	return err
+}
+
+func (e *jsonWaypoints) close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// optionalInt returns value when the flag name was set.
+func optionalInt(fs *flag.FlagSet, name string, value int) *int {
+	if !flagSet(fs, name) {
+		return nil
+	}
+	return &value
+}
+
+// flagSet reports whether the flag name was set.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	var set bool
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
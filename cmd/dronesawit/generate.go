@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/plantation"
+)
+
+// directTreesPerWrite is the number of trees generate plants at once in the
+// direct mode.
+const directTreesPerWrite = 10000
+
+// generateResult is the output of generate.
+type generateResult struct {
+	EstateID string `json:"estate_id,omitempty"`
+	File     string `json:"file,omitempty"`
+	Trees    int    `json:"trees"`
+	Seed     int64  `json:"seed"`
+}
+
+func generate(ctx context.Context, c *cli, args []string) (err error) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	var opts plantation.Options
+	fs.IntVar(&opts.Width, "width", 0, "width of the estate, in plots (required)")
+	fs.IntVar(&opts.Length, "length", 0, "length of the estate, in plots (required)")
+	fs.Float64Var(&opts.Density, "density", plantation.DefaultDensity, "share of the plots with a tree, above 0 and up to 1")
+	fs.StringVar(&opts.Layout, "layout", plantation.LayoutGrid, "layout of the trees: grid, random or clustered")
+	fs.IntVar(&opts.RowSpacing, "row-spacing", 0, "distance between the rows of the grid layout, in plots; derived from the density when 0")
+	fs.IntVar(&opts.Clusters, "clusters", 0, "number of clusters of the clustered layout; one per 2500 plots when 0")
+	fs.Float64Var(&opts.ClusterRadius, "cluster-radius", 0, "spread of the clusters, in plots; derived from the density when 0")
+	fs.StringVar(&opts.Heights, "heights", plantation.HeightsUniform, "distribution of the heights: uniform or normal")
+	fs.IntVar(&opts.MinHeight, "min-height", 1, "height of the shortest trees")
+	fs.IntVar(&opts.MaxHeight, "max-height", estates.MaxTreeHeight, "height of the tallest trees")
+	fs.Int64Var(&opts.Seed, "seed", 0, "seed of the random choices; the same seed gives the same estate. Picked and printed when unset")
+	out := fs.String("out", "", "write an import file for import-trees instead of creating the estate, - for stdout")
+	format := fs.String("format", "", "format of the import file, csv or json; guessed from the extension of -out when empty")
+	_ = fs.Parse(args)
+
+	if opts.Width == 0 || opts.Length == 0 {
+		return errors.New("-width and -length are required")
+	}
+	if !flagSet(fs, "seed") {
+		opts.Seed = time.Now().UnixNano()
+	}
+	result := generateResult{Seed: opts.Seed}
+
+	if *out != "" {
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(*out), ".")
+		}
+		w := io.Writer(os.Stdout)
+		if *out != "-" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
+				}
+			}()
+			w = f
+			result.File = *out
+		}
+		file, err := estates.NewTreeWriter(w, *format)
+		if err != nil {
+			return err
+		}
+		err = plantation.Generate(opts, func(tree estates.TreeRecord) error {
+			result.Trees++
+			return file.Write(tree)
+		})
+		if err != nil {
+			return err
+		}
+		if err = file.Close(); err != nil {
+			return err
+		}
+		if *out == "-" {
+			fmt.Fprintf(os.Stderr, "generated %d trees with seed %d\n", result.Trees, result.Seed)
+			return nil
+		}
+		return c.printer.print(result, []string{"FILE", "TREES", "SEED"},
+			[][]string{{result.File, strconv.Itoa(result.Trees), strconv.FormatInt(result.Seed, 10)}})
+	}
+
+	estate, err := c.backend.CreateEstate(ctx, opts.Width, opts.Length)
+	if err != nil {
+		return err
+	}
+	result.EstateID = estate.Id
+	var batch []estates.TreeRecord
+	write := func() error {
+		if err := c.backend.CreateTrees(ctx, estate.Id, batch); err != nil {
+			return fmt.Errorf("estate %s: %w (%d trees planted)", estate.Id, err, result.Trees)
+		}
+		result.Trees += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	err = plantation.Generate(opts, func(tree estates.TreeRecord) error {
+		batch = append(batch, tree)
+		if len(batch) < c.backend.TreesPerWrite() {
+			return nil
+		}
+		return write()
+	})
+	if err == nil && len(batch) > 0 {
+		err = write()
+	}
+	if err != nil {
+		return err
+	}
+	return c.printer.print(result, []string{"ESTATE", "TREES", "SEED"},
+		[][]string{{result.EstateID, strconv.Itoa(result.Trees), strconv.FormatInt(result.Seed, 10)}})
+}
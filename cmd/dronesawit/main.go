@@ -0,0 +1,114 @@
+// Command dronesawit manages estates and their drone plans, through the API
+// or directly in the database.
+//
+// Usage:
+//
+//	dronesawit [-config <file>] [-profile <name>] [-o table|json] <command> [flags]
+//
+//	dronesawit create-estate -width <n> -length <n>
+//	dronesawit list-estates [-after <estate id>] [-limit <n>]
+//	dronesawit show-estate -id <estate id>
+//	dronesawit add-tree -estate <estate id> -x <n> -y <n> -height <n>
+//	dronesawit import-trees -estate <estate id> -file <trees.csv|trees.json|-> [-format csv|json] [-skip-existing]
+//	dronesawit stats -estate <estate id>
+//	dronesawit plan -estate <estate id> [-max-distance <n>]
+//	dronesawit export-plan -estate <estate id> [-max-distance <n>] [-format csv|json] [-out <file>]
+//	dronesawit generate -width <n> -length <n> [-layout grid|random|clustered] [-density <0-1>] [-seed <n>] [-out <file>] ...
+//
+// The API, credentials and output format are read from a profile of the
+// configuration file, see profile.go.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const usage = `usage: dronesawit [-config <file>] [-profile <name>] [-o table|json] <command> [flags]
+
+commands:
+  create-estate  create an estate
+  list-estates   list estates, oldest first
+  show-estate    show an estate
+  add-tree       plant a tree in an estate
+  import-trees   plant the trees of a CSV or JSON file in an estate
+  stats          show the stats of the trees of an estate
+  plan           compute the drone plan of an estate
+  export-plan    export the waypoints of the drone plan of an estate
+  generate       generate an estate with synthetic trees, or its import file
+`
+
+// cli is what the commands run with.
+type cli struct {
+	backend backend
+	printer *printer
+}
+
+var commands = map[string]func(ctx context.Context, c *cli, args []string) error{
+	"create-estate": createEstate,
+	"list-estates":  listEstates,
+	"show-estate":   showEstate,
+	"add-tree":      addTree,
+	"import-trees":  importTrees,
+	"stats":         stats,
+	"plan":          plan,
+	"export-plan":   exportPlan,
+	"generate":      generate,
+}
+
+func main() {
+	fs := flag.NewFlagSet("dronesawit", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage, "\nflags:\n")
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", envOr("DRONESAWIT_CONFIG", defaultProfilesPath()), "configuration file ($DRONESAWIT_CONFIG)")
+	profileName := fs.String("profile", os.Getenv("DRONESAWIT_PROFILE"), "profile of the configuration file, the current one when empty ($DRONESAWIT_PROFILE)")
+	output := fs.String("o", "", "output format, table or json; defaults to the output of the profile")
+	_ = fs.Parse(os.Args[1:])
+
+	command, ok := commands[fs.Arg(0)]
+	if !ok {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := run(*configPath, *profileName, *output, command, fs.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, profileName, output string, command func(context.Context, *cli, []string) error, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	p, err := loadProfile(configPath, profileName)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		output = p.Output
+	}
+	printer, err := newPrinter(output)
+	if err != nil {
+		return err
+	}
+	backend, err := newBackend(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	return command(ctx, &cli{backend: backend, printer: printer}, args)
+}
+
+func envOr(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		return value
+	}
+	return fallback
+}
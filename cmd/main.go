@@ -1,33 +1,508 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/cache"
+	"github.com/dimassantoso/drone-sawit/config"
+	"github.com/dimassantoso/drone-sawit/docs"
+	"github.com/dimassantoso/drone-sawit/events"
 	"github.com/dimassantoso/drone-sawit/generated"
+	pb "github.com/dimassantoso/drone-sawit/generated/dronesawitv1"
+	"github.com/dimassantoso/drone-sawit/grpcapi"
 	"github.com/dimassantoso/drone-sawit/handler"
+	"github.com/dimassantoso/drone-sawit/health"
+	"github.com/dimassantoso/drone-sawit/idempotency"
+	"github.com/dimassantoso/drone-sawit/jobs"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/metrics"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/ratelimit"
 	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/dimassantoso/drone-sawit/tracing"
+	"github.com/dimassantoso/drone-sawit/webhooks"
 
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
+	"github.com/lib/pq"
+	"google.golang.org/grpc"
 )
 
 func main() {
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags]\n\n", os.Args[0])
+		config.Usage(os.Stderr)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger, err := logging.New(os.Stderr, logging.Config{
+		Level:  cfg.Log.Level,
+		Format: cfg.Log.Format,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	e.Server.ReadHeaderTimeout = cfg.Server.ReadHeaderTimeout
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Server.WriteTimeout = cfg.Server.WriteTimeout
+	e.Server.IdleTimeout = cfg.Server.IdleTimeout
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter: cfg.Tracing.Exporter,
+		File:     cfg.Tracing.File,
+	})
+	if err != nil {
+		fatal(logger, "setup tracing", err)
+	}
+
+	m := metrics.New()
+	repo, err := newRepository(ctx, cfg.Database, m)
+	if err != nil {
+		fatal(logger, "connect to database", err)
+	}
+	m.RegisterDB(repo.Db)
+	if cfg.Database.Migrate {
+		if _, err = repo.Migrate(ctx); err != nil {
+			fatal(logger, "migrate database", err)
+		}
+	}
+	resultCache, err := newCache(ctx, cfg.Cache)
+	if err != nil {
+		fatal(logger, "create cache", err)
+	}
+	broker := events.New(events.Options{HeartbeatInterval: cfg.Events.HeartbeatInterval})
+	server := newServer(repo, m, resultCache, broker, cfg.Planner, cfg.Jobs)
+
+	swagger, err := generated.GetSwagger()
+	if err != nil {
+		fatal(logger, "load openapi spec", err)
+	}
+	apiDocs, err := docs.New(swagger)
+	if err != nil {
+		fatal(logger, "load api docs", err)
+	}
+
+	checker := health.New(health.Database(repo), health.Migrations(repo))
+	var routes handler.RouteOptions
+	if cfg.API.UnprefixedRoutes {
+		routes.Unprefixed = &handler.Deprecation{Date: cfg.API.DeprecatedAt, Sunset: cfg.API.Sunset}
+	}
+	if err = handler.RegisterRoutes(e, server, routes); err != nil {
+		fatal(logger, "register routes", err)
+	}
+	apiDocs.Register(e)
+	e.Use(logging.RequestID())
+	e.Use(tracing.Middleware(nil))
+	e.Use(m.Middleware(nil))
+	e.Use(logging.Middleware(logger, nil))
+	// The gRPC API is limited per IP address with the same buckets too.
+	var ipBuckets *ratelimit.Buckets
+	if cfg.RateLimit.Enabled {
+		ipBuckets = ratelimit.NewBuckets(0, nil)
+		e.Use(newIPRateLimiter(cfg.RateLimit, ipBuckets))
+	}
+	if cfg.Server.MaxBodyBytes > 0 {
+		e.Use(ratelimit.BodyLimit(ratelimit.BodyLimitConfig{
+			Skipper: func(c echo.Context) bool { return c.Request().Method != http.MethodPost },
+			Limit:   cfg.Server.MaxBodyBytes,
+		}))
+	}
+	authenticators, err := newAuthenticators(repo, cfg.Auth)
+	if err != nil {
+		fatal(logger, "configure authentication", err)
+	}
+	e.Use(auth.Middleware(auth.Config{
+		Skipper:        docs.IsDocs,
+		Authenticators: authenticators,
+	}))
+	// The gRPC API is limited with the same buckets, so a caller has one
+	// budget over both APIs.
+	var grpcRateLimit *grpcapi.RateLimit
+	if cfg.RateLimit.Enabled {
+		buckets := ratelimit.NewBuckets(0, nil)
+		e.Use(newRateLimiter(cfg.RateLimit, buckets))
+		grpcRateLimit = newGRPCRateLimit(cfg.RateLimit, buckets, ipBuckets)
+	}
+	e.Use(idempotency.Middleware(idempotency.Config{
+		Skipper:     func(c echo.Context) bool { return c.Request().Method != http.MethodPost },
+		Store:       repo,
+		TTL:         cfg.Idempotency.TTL,
+		LockTimeout: cfg.Idempotency.LockTimeout,
+	}))
+	if cfg.Idempotency.SweepInterval > 0 {
+		go sweepIdempotencyKeys(ctx, repo, cfg.Idempotency.SweepInterval)
+	}
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		if cfg.Jobs.Workers > 0 {
+			server.Jobs.Run(ctx)
+		}
+	}()
+	webhooksDone := make(chan struct{})
+	go func() {
+		defer close(webhooksDone)
+		if cfg.Webhooks.Workers > 0 {
+			newDispatcher(repo, cfg.Webhooks).Run(ctx)
+		}
+	}()
+
+	// The broker ends the event streams when ctx is done, so they do not
+	// hold up the shutdown.
+	eventsDone := make(chan struct{})
+	go func() {
+		defer close(eventsDone)
+		broker.Listen(ctx, newListener(logger, cfg.Database.URL))
+	}()
+
+	// The operational endpoints are served without authentication on their
+	// own listener, kept off the public API port.
+	admin := newAdminServer(m, checker)
+	serveErr := make(chan error, 3)
+	go func() {
+		logger.Info("server listening", slog.String("address", cfg.Server.Address))
+		serveErr <- e.Start(cfg.Server.Address)
+	}()
+	go func() {
+		logger.Info("admin server listening", slog.String("address", cfg.Server.AdminAddress))
+		serveErr <- admin.Start(cfg.Server.AdminAddress)
+	}()
+	// The gRPC API shares the planner and its concurrency limit, and the
+	// rate limits of callers, with the REST API.
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Address != "" {
+		listener, err := net.Listen("tcp", cfg.GRPC.Address)
+		if err != nil {
+			fatal(logger, "listen for grpc", err)
+		}
+		grpcServer = grpcapi.New(grpcapi.NewServer(grpcapi.Options{
+			Repository:     repo,
+			Planner:        server.Planner,
+			Estates:        server.Estates,
+			Authenticators: authenticators,
+			RateLimit:      grpcRateLimit,
+		}))
+		go func() {
+			logger.Info("grpc server listening", slog.String("address", cfg.GRPC.Address))
+			serveErr <- grpcServer.Serve(listener)
+		}()
+	}
+
+	select {
+	case err = <-serveErr:
+		fatal(logger, "server stopped", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Fail readiness first so load balancers stop sending requests, then
+	// let the in-flight ones finish.
+	logger.Info("shutting down",
+		slog.Duration("delay", cfg.Server.ShutdownDelay),
+		slog.Duration("timeout", cfg.Server.ShutdownTimeout),
+	)
+	checker.Drain()
+	time.Sleep(cfg.Server.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err = e.Shutdown(shutdownCtx); err != nil {
+		logger.Error("shutdown server", slog.Any("error", err))
+	}
+	if err = admin.Shutdown(shutdownCtx); err != nil {
+		logger.Error("shutdown admin server", slog.Any("error", err))
+	}
+	if grpcServer != nil {
+		stopGRPC(shutdownCtx, grpcServer)
+	}
+	// Interrupted jobs are left running in the database and are taken over
+	// once they go stale.
+	<-jobsDone
+	// Likewise, interrupted deliveries are retried once their lease expires.
+	<-webhooksDone
+	<-eventsDone
+	if err = shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("shutdown tracing", slog.Any("error", err))
+	}
+	if err = repo.Close(); err != nil {
+		logger.Error("close database", slog.Any("error", err))
+	}
+	logger.Info("server stopped")
+}
+
+// fatal logs err and exits.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
 
-	var server generated.ServerInterface = newServer()
+// stopGRPC lets the in-flight RPCs of server finish, and cancels those
+// still running when ctx is done.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		server.GracefulStop()
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+		<-stopped
+	}
+}
+
+// newAdminServer serves the metrics and health checks. Its requests are
+// left out of request metrics, traces and logs.
+func newAdminServer(m *metrics.Metrics, checker *health.Checker) *echo.Echo {
+	admin := echo.New()
+	admin.HideBanner = true
+	admin.HidePort = true
+	admin.HTTPErrorHandler = handler.HTTPErrorHandler
+	admin.GET("/metrics", echo.WrapHandler(m.Handler()))
+	admin.GET("/healthz", checker.Live)
+	admin.GET("/readyz", checker.Ready)
+	return admin
+}
+
+// newRepository connects to the database, retrying for the configured
+// connect timeout.
+func newRepository(ctx context.Context, cfg config.DatabaseConfig, m *metrics.Metrics) (*repository.Repository, error) {
+	return repository.Open(ctx, repository.NewRepositoryOptions{
+		Dsn:             cfg.URL,
+		Hooks:           []repository.QueryHook{tracing.QueryHook, m.QueryHook},
+		ConnectTimeout:  cfg.ConnectTimeout,
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+	})
+}
+
+// sweepIdempotencyKeys deletes expired idempotency keys every interval
+// until ctx is done.
+func sweepIdempotencyKeys(ctx context.Context, repo repository.RepositoryInterface, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// Failures are logged by the repository; the next tick retries.
+		if deleted, err := repo.DeleteExpiredIdempotencyKeys(ctx); err == nil && deleted > 0 {
+			slog.InfoContext(ctx, "expired idempotency keys deleted", slog.Int64("count", deleted))
+		}
+	}
+}
+
+// newAuthenticators builds the authenticators of the enabled modes.
+func newAuthenticators(repo repository.RepositoryInterface, cfg config.AuthConfig) ([]auth.Authenticator, error) {
+	var authenticators []auth.Authenticator
+	for _, mode := range cfg.Modes {
+		switch mode {
+		case config.AuthModeAPIKey:
+			authenticators = append(authenticators, auth.NewAPIKeyAuthenticator(repo))
+		case config.AuthModeJWT:
+			authenticator, err := newJWTAuthenticator(cfg.JWT)
+			if err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, authenticator)
+		default:
+			return nil, fmt.Errorf("unknown authentication mode %q", mode)
+		}
+	}
+	return authenticators, nil
+}
+
+// newJWTAuthenticator verifies bearer tokens with the keys of the JWKS file
+// or, without one, the PEM public key files.
+func newJWTAuthenticator(cfg config.JWTConfig) (*auth.JWTAuthenticator, error) {
+	jwtConfig := auth.JWTConfig{
+		Issuer:            cfg.Issuer,
+		Audience:          cfg.Audience,
+		OrganizationClaim: cfg.OrganizationClaim,
+		RolesClaim:        cfg.RolesClaim,
+		RoleScopes:        cfg.RoleScopes,
+		Leeway:            cfg.Leeway,
+	}
+
+	var err error
+	if cfg.JWKSFile != "" {
+		jwtConfig.Keys, err = auth.LoadJWKSFile(cfg.JWKSFile)
+	} else if len(cfg.PublicKeys) > 0 {
+		jwtConfig.Keys, err = auth.LoadPublicKeyFiles(cfg.PublicKeys...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewJWTAuthenticator(jwtConfig)
+}
 
-	generated.RegisterHandlers(e, server)
-	e.Use(middleware.Logger())
-	e.Logger.Fatal(e.Start(":8080"))
+// dronePlanRule names the rate limit budget of drone plans.
+const dronePlanRule = "drone-plan"
+
+// newRateLimiter limits requests per caller, with a separate budget for
+// drone plans.
+func newRateLimiter(cfg config.RateLimitConfig, buckets *ratelimit.Buckets) echo.MiddlewareFunc {
+	return ratelimit.Middleware(ratelimit.Config{
+		Default: ratelimit.Limit{Rate: cfg.RequestsPerSecond, Burst: cfg.Burst},
+		Rules: []ratelimit.Rule{{
+			Name:  dronePlanRule,
+			Match: isDronePlan,
+			Limit: ratelimit.Limit{Rate: cfg.PlanRequestsPerSecond, Burst: cfg.PlanBurst},
+		}},
+		Buckets: buckets,
+	})
+}
+
+// newGRPCRateLimit limits RPCs as newRateLimiter and newIPRateLimiter limit
+// requests, in the same buckets.
+func newGRPCRateLimit(cfg config.RateLimitConfig, buckets, ipBuckets *ratelimit.Buckets) *grpcapi.RateLimit {
+	return &grpcapi.RateLimit{
+		Buckets: buckets,
+		Default: ratelimit.Limit{Rate: cfg.RequestsPerSecond, Burst: cfg.Burst},
+		Rules: []grpcapi.RateLimitRule{{
+			Name:    dronePlanRule,
+			Methods: []string{pb.DroneSawit_GetDronePlan_FullMethodName, pb.DroneSawit_StreamWaypoints_FullMethodName},
+			Limit:   ratelimit.Limit{Rate: cfg.PlanRequestsPerSecond, Burst: cfg.PlanBurst},
+		}},
+		IP:        ratelimit.Limit{Rate: cfg.IPRequestsPerSecond, Burst: cfg.IPBurst},
+		IPBuckets: ipBuckets,
+	}
+}
+
+// newIPRateLimiter limits requests per IP address before they are
+// authenticated, so that invalid credentials cannot be tried, and looked up
+// in the database, at any rate. Its buckets are apart from those of
+// newRateLimiter, which keys anonymous requests by IP address too.
+func newIPRateLimiter(cfg config.RateLimitConfig, buckets *ratelimit.Buckets) echo.MiddlewareFunc {
+	return ratelimit.Middleware(ratelimit.Config{
+		Default: ratelimit.Limit{Rate: cfg.IPRequestsPerSecond, Burst: cfg.IPBurst},
+		KeyFunc: ratelimit.IPKey,
+		Buckets: buckets,
+	})
+}
+
+// isDronePlan reports whether c computes a drone plan, at once or by
+// submitting a job.
+func isDronePlan(c echo.Context) bool {
+	path := c.Path()
+	return strings.HasSuffix(path, "/drone-plan") ||
+		(c.Request().Method == http.MethodPost && strings.HasSuffix(path, "/drone-plan/jobs"))
 }
 
-func newServer() *handler.Server {
-	dbDsn := os.Getenv("DATABASE_URL")
-	var repo repository.RepositoryInterface = repository.NewRepository(repository.NewRepositoryOptions{
-		Dsn: dbDsn,
+// newCache creates the cache of drone plans and stats. The entries of a file
+// cache are pruned every TTL until ctx is done.
+func newCache(ctx context.Context, cfg config.CacheConfig) (cache.Cache, error) {
+	switch cfg.Backend {
+	case config.CacheBackendMemory:
+		return cache.NewMemory(cfg.MaxEntries), nil
+	case config.CacheBackendFile:
+		fileCache, err := cache.NewFile(cfg.Dir, cfg.TTL)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			ticker := time.NewTicker(cfg.TTL)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				if _, err := fileCache.Prune(); err != nil {
+					slog.WarnContext(ctx, "prune cache", slog.Any("error", err))
+				}
+			}
+		}()
+		return fileCache, nil
+	}
+	return cache.Nop{}, nil
+}
+
+func newServer(repo repository.RepositoryInterface, m *metrics.Metrics, resultCache cache.Cache, broker *events.Broker, cfg config.PlannerConfig, jobsCfg config.JobsConfig) *handler.Server {
+	maxConcurrent := cfg.MaxConcurrent
+	if maxConcurrent == 0 {
+		maxConcurrent = runtime.GOMAXPROCS(0)
+	}
+	plans := planner.New(planner.Options{
+		Repository:         repo,
+		Observer:           m,
+		Cache:              resultCache,
+		DefaultMaxDistance: cfg.DefaultMaxDistance,
+		MaxConcurrent:      maxConcurrent,
+		QueueTimeout:       cfg.QueueTimeout,
 	})
 	opts := handler.NewServerOptions{
 		Repository: repo,
+		Cache:      resultCache,
+		Planner:    plans,
+		Events:     broker,
+		// Jobs are computed outside the planner's concurrency limit: the
+		// worker pool bounds them instead.
+		Jobs: jobs.New(jobs.Options{
+			Repository:        repo,
+			Planner:           plans,
+			Workers:           jobsCfg.Workers,
+			PollInterval:      jobsCfg.PollInterval,
+			HeartbeatInterval: jobsCfg.HeartbeatInterval,
+			StaleAfter:        jobsCfg.StaleAfter,
+			MaxAttempts:       jobsCfg.MaxAttempts,
+		}),
 	}
 	return handler.NewServer(opts)
 }
+
+func newDispatcher(repo repository.RepositoryInterface, cfg config.WebhooksConfig) *webhooks.Dispatcher {
+	return webhooks.New(webhooks.Options{
+		Repository:     repo,
+		Workers:        cfg.Workers,
+		PollInterval:   cfg.PollInterval,
+		Timeout:        cfg.Timeout,
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: cfg.InitialBackoff,
+		MaxBackoff:     cfg.MaxBackoff,
+	})
+}
+
+// newListener returns a listener for the notifications of the database at
+// url. It reconnects on its own; failures are logged.
+func newListener(logger *slog.Logger, url string) *pq.Listener {
+	return pq.NewListener(url, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Warn("database listener", slog.Any("error", err))
+		}
+	})
+}
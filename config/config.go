@@ -0,0 +1,453 @@
+// Package config loads the configuration of the API server from a YAML
+// file, environment variables and command line flags.
+//
+// Sources are applied in order of increasing precedence: defaults, the
+// file given by -config or CONFIG_FILE, environment variables, then flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/tracing"
+)
+
+// Cache backends.
+const (
+	CacheBackendNone   = "none"
+	CacheBackendMemory = "memory"
+	CacheBackendFile   = "file"
+)
+
+// Authentication modes.
+const (
+	AuthModeAPIKey = "apikey"
+	AuthModeJWT    = "jwt"
+)
+
+// Config is the configuration of the API server.
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	API         APIConfig         `yaml:"api"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Auth        AuthConfig        `yaml:"auth"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Planner     PlannerConfig     `yaml:"planner"`
+	Jobs        JobsConfig        `yaml:"jobs"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Events      EventsConfig      `yaml:"events"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+}
+
+// ServerConfig configures the HTTP server.
+type ServerConfig struct {
+	// Address is the TCP address to listen on, e.g. ":8080".
+	Address string `yaml:"address"`
+	// AdminAddress is the TCP address of the operational endpoints,
+	// /metrics, /healthz and /readyz, e.g. ":8081". They are served without
+	// authentication, so it must not be reachable from outside.
+	AdminAddress      string        `yaml:"admin_address"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// MaxBodyBytes caps the body of POST requests. Zero means unlimited.
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+	// ShutdownDelay is the time between failing readiness and closing the
+	// listener.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+	// ShutdownTimeout is the time allowed to drain in-flight requests.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// APIConfig configures the versions of the REST API.
+type APIConfig struct {
+	// UnprefixedRoutes also serves the current version without its /v1
+	// prefix, as the API was served before it was versioned. Those routes
+	// are deprecated.
+	UnprefixedRoutes bool `yaml:"unprefixed_routes"`
+	// DeprecatedAt is when the unprefixed routes were deprecated, sent in
+	// their Deprecation header.
+	DeprecatedAt time.Time `yaml:"deprecated_at"`
+	// Sunset is when the unprefixed routes are to be removed, sent in their
+	// Sunset header. Zero leaves the header out.
+	Sunset time.Time `yaml:"sunset"`
+}
+
+// DatabaseConfig configures the Postgres connection pool.
+type DatabaseConfig struct {
+	URL             string        `yaml:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// ConnectTimeout is how long to retry the database at startup.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// Migrate applies pending migrations at startup.
+	Migrate bool `yaml:"migrate"`
+}
+
+// LogConfig configures logging.
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// TracingConfig configures tracing. The OTLP exporter is configured with
+// the standard OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	Exporter string `yaml:"exporter"`
+	File     string `yaml:"file"`
+}
+
+// AuthConfig configures authentication.
+type AuthConfig struct {
+	// Modes lists the enabled authenticators, AuthModeAPIKey and
+	// AuthModeJWT.
+	Modes []string  `yaml:"modes"`
+	JWT   JWTConfig `yaml:"jwt"`
+}
+
+// JWTConfig configures bearer token verification. Keys are read from
+// JWKSFile, or from PublicKeys when it is empty.
+type JWTConfig struct {
+	Issuer            string                  `yaml:"issuer"`
+	Audience          string                  `yaml:"audience"`
+	JWKSFile          string                  `yaml:"jwks_file"`
+	PublicKeys        []string                `yaml:"public_keys"`
+	OrganizationClaim string                  `yaml:"organization_claim"`
+	RolesClaim        string                  `yaml:"roles_claim"`
+	RoleScopes        map[string][]auth.Scope `yaml:"role_scopes"`
+	Leeway            time.Duration           `yaml:"leeway"`
+}
+
+// RateLimitConfig configures the request rate allowed per API key, or per
+// IP address for anonymous requests, in requests per second. A zero rate
+// disables the corresponding limit.
+type RateLimitConfig struct {
+	Enabled           bool    `yaml:"enabled"`
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+	// PlanRequestsPerSecond and PlanBurst apply to drone plan requests,
+	// which are budgeted separately.
+	PlanRequestsPerSecond float64 `yaml:"plan_requests_per_second"`
+	PlanBurst             int     `yaml:"plan_burst"`
+	// IPRequestsPerSecond and IPBurst limit every request per IP address
+	// before it is authenticated, so that floods of bad credentials do not
+	// reach the database.
+	IPRequestsPerSecond float64 `yaml:"ip_requests_per_second"`
+	IPBurst             int     `yaml:"ip_burst"`
+}
+
+// IdempotencyConfig configures the Idempotency-Key support of the create
+// endpoints.
+type IdempotencyConfig struct {
+	// TTL is how long responses are replayed.
+	TTL time.Duration `yaml:"ttl"`
+	// LockTimeout is how long a request may hold its key before a retry
+	// takes it over.
+	LockTimeout time.Duration `yaml:"lock_timeout"`
+	// SweepInterval is how often expired keys are deleted. Zero disables
+	// the sweep.
+	SweepInterval time.Duration `yaml:"sweep_interval"`
+}
+
+// CacheConfig configures the cache of drone plans and estate stats.
+type CacheConfig struct {
+	// Backend is CacheBackendNone, CacheBackendMemory or CacheBackendFile.
+	Backend string `yaml:"backend"`
+	// MaxEntries caps the memory cache.
+	MaxEntries int `yaml:"max_entries"`
+	// Dir holds the entries of the file cache.
+	Dir string `yaml:"dir"`
+	// TTL is how long the file cache keeps entries.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// PlannerConfig configures drone plans.
+type PlannerConfig struct {
+	// DefaultMaxDistance limits plans requested without max_distance. Zero
+	// means unlimited.
+	DefaultMaxDistance int `yaml:"default_max_distance"`
+	// MaxConcurrent caps the plans computed at once. Zero means one per
+	// CPU.
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// QueueTimeout is how long a plan waits for a free slot before the
+	// request is rejected.
+	QueueTimeout time.Duration `yaml:"queue_timeout"`
+}
+
+// JobsConfig configures the background drone plan jobs.
+type JobsConfig struct {
+	// Workers is the number of jobs this process computes at once. Zero
+	// only queues jobs, for replicas that leave the work to others.
+	Workers           int           `yaml:"workers"`
+	PollInterval      time.Duration `yaml:"poll_interval"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+	// StaleAfter is how long a running job may go without a heartbeat
+	// before another worker takes it over.
+	StaleAfter  time.Duration `yaml:"stale_after"`
+	MaxAttempts int           `yaml:"max_attempts"`
+}
+
+// WebhooksConfig configures the delivery of webhook events.
+type WebhooksConfig struct {
+	// Workers is the number of deliveries this process sends at once. Zero
+	// only records events, for replicas that leave the sending to others.
+	Workers      int           `yaml:"workers"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Timeout bounds a delivery, including reading the response.
+	Timeout     time.Duration `yaml:"timeout"`
+	MaxAttempts int           `yaml:"max_attempts"`
+	// InitialBackoff is the delay before the first retry; it doubles on
+	// every retry, up to MaxBackoff.
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// EventsConfig configures the event streams of estates.
+type EventsConfig struct {
+	// HeartbeatInterval is how often idle streams send a comment, which
+	// keeps proxies from closing them, and look for changes whose
+	// notification was lost.
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+}
+
+// GRPCConfig configures the gRPC server.
+type GRPCConfig struct {
+	// Address is the TCP address to listen on, e.g. ":9090". Empty disables
+	// the gRPC server.
+	Address string `yaml:"address"`
+}
+
+// Default returns the configuration used for anything not configured.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:           ":8080",
+			AdminAddress:      ":8081",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxBodyBytes:      1 << 20,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		API: APIConfig{
+			UnprefixedRoutes: true,
+			DeprecatedAt:     time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+			Sunset:           time.Date(2027, time.April, 18, 0, 0, 0, 0, time.UTC),
+		},
+		Database: DatabaseConfig{
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  time.Minute,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: logging.FormatJSON,
+		},
+		Tracing: TracingConfig{
+			Exporter: tracing.ExporterNone,
+		},
+		Auth: AuthConfig{
+			Modes: []string{AuthModeAPIKey},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:               true,
+			RequestsPerSecond:     10,
+			Burst:                 20,
+			PlanRequestsPerSecond: 0.5,
+			PlanBurst:             3,
+			IPRequestsPerSecond:   50,
+			IPBurst:               100,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:           24 * time.Hour,
+			LockTimeout:   time.Minute,
+			SweepInterval: time.Hour,
+		},
+		Cache: CacheConfig{
+			Backend:    CacheBackendMemory,
+			MaxEntries: 10_000,
+			TTL:        24 * time.Hour,
+		},
+		Planner: PlannerConfig{
+			QueueTimeout: time.Second,
+		},
+		Jobs: JobsConfig{
+			Workers:           2,
+			PollInterval:      time.Second,
+			HeartbeatInterval: 5 * time.Second,
+			StaleAfter:        time.Minute,
+			MaxAttempts:       3,
+		},
+		Webhooks: WebhooksConfig{
+			Workers:        2,
+			PollInterval:   time.Second,
+			Timeout:        10 * time.Second,
+			MaxAttempts:    8,
+			InitialBackoff: 10 * time.Second,
+			MaxBackoff:     time.Hour,
+		},
+		Events: EventsConfig{
+			HeartbeatInterval: 15 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Address: ":9090",
+		},
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	nonNegative := func(name string, d time.Duration) {
+		check(d >= 0, "%s must not be negative", name)
+	}
+
+	check(c.Server.Address != "", "server.address is required")
+	check(c.Server.AdminAddress != "", "server.admin_address is required")
+	check(c.Server.AdminAddress != c.Server.Address, "server.admin_address must differ from server.address")
+	nonNegative("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	nonNegative("server.read_timeout", c.Server.ReadTimeout)
+	nonNegative("server.write_timeout", c.Server.WriteTimeout)
+	nonNegative("server.idle_timeout", c.Server.IdleTimeout)
+	check(c.Server.MaxBodyBytes >= 0, "server.max_body_bytes must not be negative")
+	nonNegative("server.shutdown_delay", c.Server.ShutdownDelay)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+
+	if c.API.UnprefixedRoutes {
+		check(!c.API.DeprecatedAt.IsZero(), "api.deprecated_at is required with api.unprefixed_routes")
+		check(c.API.Sunset.IsZero() || c.API.Sunset.After(c.API.DeprecatedAt), "api.sunset must be after api.deprecated_at")
+	}
+
+	check(c.Database.URL != "", "database.url is required")
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	nonNegative("database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+	nonNegative("database.conn_max_idle_time", c.Database.ConnMaxIdleTime)
+	nonNegative("database.connect_timeout", c.Database.ConnectTimeout)
+
+	if _, err := logging.New(nil, logging.Config{Level: c.Log.Level, Format: c.Log.Format}); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch c.Tracing.Exporter {
+	case "", tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	case tracing.ExporterFile:
+		check(c.Tracing.File != "", "tracing.file is required with the file exporter")
+	default:
+		check(false, "tracing.exporter: unknown exporter %q", c.Tracing.Exporter)
+	}
+
+	check(len(c.Auth.Modes) > 0, "auth.modes must enable at least one mode")
+	for _, mode := range c.Auth.Modes {
+		switch mode {
+		case AuthModeAPIKey:
+		case AuthModeJWT:
+			errs = append(errs, c.Auth.JWT.validate()...)
+		default:
+			check(false, "auth.modes: unknown mode %q", mode)
+		}
+	}
+
+	if c.RateLimit.Enabled {
+		check(c.RateLimit.RequestsPerSecond >= 0, "rate_limit.requests_per_second must not be negative")
+		check(c.RateLimit.RequestsPerSecond == 0 || c.RateLimit.Burst > 0, "rate_limit.burst must be positive")
+		check(c.RateLimit.PlanRequestsPerSecond >= 0, "rate_limit.plan_requests_per_second must not be negative")
+		check(c.RateLimit.PlanRequestsPerSecond == 0 || c.RateLimit.PlanBurst > 0, "rate_limit.plan_burst must be positive")
+		check(c.RateLimit.IPRequestsPerSecond >= 0, "rate_limit.ip_requests_per_second must not be negative")
+		check(c.RateLimit.IPRequestsPerSecond == 0 || c.RateLimit.IPBurst > 0, "rate_limit.ip_burst must be positive")
+	}
+
+	check(c.Idempotency.TTL > 0, "idempotency.ttl must be positive")
+	check(c.Idempotency.LockTimeout > 0, "idempotency.lock_timeout must be positive")
+	nonNegative("idempotency.sweep_interval", c.Idempotency.SweepInterval)
+
+	switch c.Cache.Backend {
+	case CacheBackendNone:
+	case CacheBackendMemory:
+		check(c.Cache.MaxEntries > 0, "cache.max_entries must be positive")
+	case CacheBackendFile:
+		check(c.Cache.Dir != "", "cache.dir is required with the file backend")
+		check(c.Cache.TTL > 0, "cache.ttl must be positive")
+	default:
+		check(false, "cache.backend: unknown backend %q", c.Cache.Backend)
+	}
+
+	check(c.Planner.DefaultMaxDistance >= 0, "planner.default_max_distance must not be negative")
+	check(c.Planner.MaxConcurrent >= 0, "planner.max_concurrent must not be negative")
+	nonNegative("planner.queue_timeout", c.Planner.QueueTimeout)
+
+	check(c.Jobs.Workers >= 0, "jobs.workers must not be negative")
+	check(c.Jobs.PollInterval > 0, "jobs.poll_interval must be positive")
+	check(c.Jobs.HeartbeatInterval > 0, "jobs.heartbeat_interval must be positive")
+	check(c.Jobs.StaleAfter > c.Jobs.HeartbeatInterval, "jobs.stale_after must be longer than jobs.heartbeat_interval")
+	check(c.Jobs.MaxAttempts > 0, "jobs.max_attempts must be positive")
+
+	check(c.Webhooks.Workers >= 0, "webhooks.workers must not be negative")
+	check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval must be positive")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(c.Webhooks.InitialBackoff > 0, "webhooks.initial_backoff must be positive")
+	check(c.Webhooks.MaxBackoff >= c.Webhooks.InitialBackoff, "webhooks.max_backoff must not be shorter than webhooks.initial_backoff")
+
+	check(c.Events.HeartbeatInterval > 0, "events.heartbeat_interval must be positive")
+
+	check(c.GRPC.Address == "" || c.GRPC.Address != c.Server.Address, "grpc.address must differ from server.address")
+	check(c.GRPC.Address == "" || c.GRPC.Address != c.Server.AdminAddress, "grpc.address must differ from server.admin_address")
+	return errors.Join(errs...)
+}
+
+func (c *JWTConfig) validate() []error {
+	var errs []error
+	if c.Issuer == "" {
+		errs = append(errs, errors.New("auth.jwt.issuer is required with jwt authentication"))
+	}
+	if c.Audience == "" {
+		errs = append(errs, errors.New("auth.jwt.audience is required with jwt authentication"))
+	}
+	if c.JWKSFile == "" && len(c.PublicKeys) == 0 {
+		errs = append(errs, errors.New("auth.jwt.jwks_file or auth.jwt.public_keys is required with jwt authentication"))
+	}
+	for role, scopes := range c.RoleScopes {
+		for _, scope := range scopes {
+			if _, ok := auth.ParseScope(string(scope)); !ok {
+				errs = append(errs, fmt.Errorf("auth.jwt.role_scopes: role %s: unknown scope %q", role, scope))
+			}
+		}
+	}
+	if c.Leeway < 0 {
+		errs = append(errs, errors.New("auth.jwt.leeway must not be negative"))
+	}
+	return errs
+}
+
+// splitList splits a comma separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
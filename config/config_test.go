@@ -0,0 +1,216 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil, env(map[string]string{"DATABASE_URL": "postgres://db"}))
+	require.NoError(t, err)
+
+	want := Default()
+	want.Database.URL = "postgres://db"
+	assert.Equal(t, &want, cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, `
+server:
+  address: ":7000"
+  shutdown_timeout: 10s
+database:
+  url: postgres://file
+  max_open_conns: 50
+log:
+  level: debug
+auth:
+  modes: [apikey, jwt]
+  jwt:
+    issuer: https://issuer.example
+    audience: drone-sawit
+    jwks_file: /etc/jwks.json
+    role_scopes:
+      admin: [read, write, plan]
+planner:
+  default_max_distance: 500
+`)
+
+	cfg, err := Load(
+		[]string{"-config", path, "-listen-address", ":9000", "-log-level", "warn"},
+		env(map[string]string{
+			"LISTEN_ADDRESS":    ":8000",
+			"DB_MAX_OPEN_CONNS": "40",
+			"LOG_LEVEL":         "error",
+			"LOG_FORMAT":        "",
+		}),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9000", cfg.Server.Address, "flags win over env")
+	assert.Equal(t, "warn", cfg.Log.Level, "flags win over env")
+	assert.Equal(t, 40, cfg.Database.MaxOpenConns, "env wins over the file")
+	assert.Equal(t, "postgres://file", cfg.Database.URL, "the file wins over defaults")
+	assert.Equal(t, 10*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, "json", cfg.Log.Format, "empty variables are ignored")
+	assert.Equal(t, 5, cfg.Database.MaxIdleConns, "defaults are kept")
+	assert.Equal(t, []string{"apikey", "jwt"}, cfg.Auth.Modes)
+	assert.Equal(t, map[string][]auth.Scope{"admin": {auth.ScopeRead, auth.ScopeWrite, auth.ScopePlan}}, cfg.Auth.JWT.RoleScopes)
+	assert.Equal(t, 500, cfg.Planner.DefaultMaxDistance)
+}
+
+func TestLoad_ConfigFileFromEnv(t *testing.T) {
+	path := writeFile(t, "database:\n  url: postgres://file\n")
+
+	cfg, err := Load(nil, env(map[string]string{EnvConfigFile: path}))
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://file", cfg.Database.URL)
+}
+
+func TestLoad_EnvLists(t *testing.T) {
+	cfg, err := Load(nil, env(map[string]string{
+		"DATABASE_URL":    "postgres://db",
+		"AUTH_MODE":       "apikey, jwt",
+		"JWT_ISSUER":      "https://issuer.example",
+		"JWT_AUDIENCE":    "drone-sawit",
+		"JWT_PUBLIC_KEYS": "a.pem,b.pem",
+		"JWT_ROLE_SCOPES": "viewer=read",
+		"DB_MIGRATE":      "true",
+		"RATE_LIMIT_RPS":  "2.5",
+		"MAX_BODY_BYTES":  "4096",
+		"IDEMPOTENCY_TTL": "1h",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"apikey", "jwt"}, cfg.Auth.Modes)
+	assert.Equal(t, []string{"a.pem", "b.pem"}, cfg.Auth.JWT.PublicKeys)
+	assert.Equal(t, map[string][]auth.Scope{"viewer": {auth.ScopeRead}}, cfg.Auth.JWT.RoleScopes)
+	assert.True(t, cfg.Database.Migrate)
+	assert.Equal(t, 2.5, cfg.RateLimit.RequestsPerSecond)
+	assert.Equal(t, int64(4096), cfg.Server.MaxBodyBytes)
+	assert.Equal(t, time.Hour, cfg.Idempotency.TTL)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+		want []string
+	}{
+		{
+			name: "missing database url",
+			want: []string{"database.url is required"},
+		},
+		{
+			name: "malformed env",
+			env:  map[string]string{"DATABASE_URL": "postgres://db", "DB_MAX_OPEN_CONNS": "many"},
+			want: []string{`$DB_MAX_OPEN_CONNS: invalid integer "many"`},
+		},
+		{
+			name: "malformed flag",
+			args: []string{"-shutdown-timeout", "soon"},
+			env:  map[string]string{"DATABASE_URL": "postgres://db"},
+			want: []string{`flag -shutdown-timeout: invalid duration "soon"`},
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-port", "80"},
+			want: []string{"flag provided but not defined: -port"},
+		},
+		{
+			name: "unknown key in file",
+			file: "server:\n  adress: \":80\"\n",
+			want: []string{"field adress not found"},
+		},
+		{
+			name: "every invalid setting is reported",
+			env: map[string]string{
+				"API_SUNSET":                "2026-01-01T00:00:00Z",
+				"DATABASE_URL":              "postgres://db",
+				"DB_MAX_OPEN_CONNS":         "2",
+				"DB_MAX_IDLE_CONNS":         "5",
+				"LOG_LEVEL":                 "verbose",
+				"OTEL_TRACES_EXPORTER":      "file",
+				"AUTH_MODE":                 "jwt,ldap",
+				"CACHE_BACKEND":             "file",
+				"JOBS_STALE_AFTER":          "1s",
+				"WEBHOOKS_MAX_BACKOFF":      "1s",
+				"EVENTS_HEARTBEAT_INTERVAL": "0s",
+				"GRPC_ADDRESS":              ":8080",
+				"ADMIN_LISTEN_ADDRESS":      ":8080",
+			},
+			want: []string{
+				"api.sunset must be after api.deprecated_at",
+				"database.max_idle_conns (5) must not exceed database.max_open_conns (2)",
+				`invalid level "verbose"`,
+				"tracing.file is required",
+				"auth.jwt.issuer is required",
+				"auth.jwt.audience is required",
+				"auth.jwt.jwks_file or auth.jwt.public_keys is required",
+				`unknown mode "ldap"`,
+				"cache.dir is required with the file backend",
+				"jobs.stale_after must be longer than jobs.heartbeat_interval",
+				"webhooks.max_backoff must not be shorter than webhooks.initial_backoff",
+				"events.heartbeat_interval must be positive",
+				"grpc.address must differ from server.address",
+				"grpc.address must differ from server.admin_address",
+				"server.admin_address must differ from server.address",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file)}, args...)
+			}
+			_, err := Load(args, env(tt.env))
+			require.Error(t, err)
+			for _, want := range tt.want {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+}
+
+func TestLoad_Help(t *testing.T) {
+	_, err := Load([]string{"-h"}, env(nil))
+	assert.ErrorIs(t, err, flag.ErrHelp)
+}
+
+func TestLoad_Example(t *testing.T) {
+	cfg, err := Load([]string{"-config", "../config.example.yaml"}, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, Default().Server, cfg.Server)
+	assert.Equal(t, Default().API, cfg.API)
+	assert.Equal(t, Default().RateLimit, cfg.RateLimit)
+	assert.Equal(t, Default().Idempotency, cfg.Idempotency)
+	assert.Equal(t, Default().Cache, cfg.Cache)
+	assert.Equal(t, Default().Planner, cfg.Planner)
+	assert.Equal(t, Default().Jobs, cfg.Jobs)
+	assert.Equal(t, Default().Webhooks, cfg.Webhooks)
+	assert.Equal(t, Default().Events, cfg.Events)
+	assert.Equal(t, Default().GRPC, cfg.GRPC)
+}
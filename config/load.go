@@ -0,0 +1,291 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfigFile names the configuration file when -config is not given.
+const EnvConfigFile = "CONFIG_FILE"
+
+// binding ties a setting to its environment variable and flag.
+type binding struct {
+	env   string
+	flag  string
+	usage string
+	set   func(value string) error
+}
+
+// bindings lists every setting that can be overridden from the environment
+// or the command line. The variables predating this package keep their
+// names.
+func (c *Config) bindings() []binding {
+	return []binding{
+		{"LISTEN_ADDRESS", "listen-address", "TCP address to listen on", stringVar(&c.Server.Address)},
+		{"ADMIN_LISTEN_ADDRESS", "admin-listen-address", "TCP address of the metrics and health endpoints", stringVar(&c.Server.AdminAddress)},
+		{"SERVER_READ_HEADER_TIMEOUT", "read-header-timeout", "time allowed to read request headers", durationVar(&c.Server.ReadHeaderTimeout)},
+		{"SERVER_READ_TIMEOUT", "read-timeout", "time allowed to read a request", durationVar(&c.Server.ReadTimeout)},
+		{"SERVER_WRITE_TIMEOUT", "write-timeout", "time allowed to write a response", durationVar(&c.Server.WriteTimeout)},
+		{"SERVER_IDLE_TIMEOUT", "idle-timeout", "time keep-alive connections stay idle", durationVar(&c.Server.IdleTimeout)},
+		{"MAX_BODY_BYTES", "max-body-bytes", "maximum body size of POST requests, 0 for unlimited", int64Var(&c.Server.MaxBodyBytes)},
+		{"SHUTDOWN_DELAY", "shutdown-delay", "time between failing readiness and closing the listener", durationVar(&c.Server.ShutdownDelay)},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "time allowed to drain in-flight requests", durationVar(&c.Server.ShutdownTimeout)},
+
+		{"API_UNPREFIXED_ROUTES", "api-unprefixed-routes", "also serve the API without its /v1 prefix, as deprecated routes", boolVar(&c.API.UnprefixedRoutes)},
+		{"API_DEPRECATED_AT", "api-deprecated-at", "when the unprefixed routes were deprecated, in RFC 3339", timeVar(&c.API.DeprecatedAt)},
+		{"API_SUNSET", "api-sunset", "when the unprefixed routes are to be removed, in RFC 3339, empty for unknown", timeVar(&c.API.Sunset)},
+
+		{"DATABASE_URL", "database-url", "Postgres connection string", stringVar(&c.Database.URL)},
+		{"DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum open database connections, 0 for unlimited", intVar(&c.Database.MaxOpenConns)},
+		{"DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum idle database connections", intVar(&c.Database.MaxIdleConns)},
+		{"DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "maximum lifetime of a database connection", durationVar(&c.Database.ConnMaxLifetime)},
+		{"DB_CONN_MAX_IDLE_TIME", "db-conn-max-idle-time", "maximum idle time of a database connection", durationVar(&c.Database.ConnMaxIdleTime)},
+		{"DB_CONNECT_TIMEOUT", "db-connect-timeout", "how long to retry the database at startup", durationVar(&c.Database.ConnectTimeout)},
+		{"DB_MIGRATE", "db-migrate", "apply pending migrations at startup", boolVar(&c.Database.Migrate)},
+
+		{"LOG_LEVEL", "log-level", "debug, info, warn or error", stringVar(&c.Log.Level)},
+		{"LOG_FORMAT", "log-format", "json or text", stringVar(&c.Log.Format)},
+
+		{"OTEL_TRACES_EXPORTER", "traces-exporter", "none, stdout, file or otlp", stringVar(&c.Tracing.Exporter)},
+		{"OTEL_TRACES_FILE", "traces-file", "file spans are appended to with the file exporter", stringVar(&c.Tracing.File)},
+
+		{"AUTH_MODE", "auth-mode", "comma separated authentication modes: apikey, jwt", listVar(&c.Auth.Modes)},
+		{"JWT_ISSUER", "jwt-issuer", "expected iss claim", stringVar(&c.Auth.JWT.Issuer)},
+		{"JWT_AUDIENCE", "jwt-audience", "expected aud claim", stringVar(&c.Auth.JWT.Audience)},
+		{"JWT_JWKS_FILE", "jwt-jwks-file", "JSON Web Key Set verifying tokens", stringVar(&c.Auth.JWT.JWKSFile)},
+		{"JWT_PUBLIC_KEYS", "jwt-public-keys", "comma separated PEM public keys, used without a JWKS file", listVar(&c.Auth.JWT.PublicKeys)},
+		{"JWT_ORGANIZATION_CLAIM", "jwt-organization-claim", "claim holding the organization ID", stringVar(&c.Auth.JWT.OrganizationClaim)},
+		{"JWT_ROLES_CLAIM", "jwt-roles-claim", "claim holding the roles", stringVar(&c.Auth.JWT.RolesClaim)},
+		{"JWT_ROLE_SCOPES", "jwt-role-scopes", "role to scope mapping, e.g. admin=read+write+plan,viewer=read", roleScopesVar(&c.Auth.JWT.RoleScopes)},
+		{"JWT_LEEWAY", "jwt-leeway", "allowed clock skew", durationVar(&c.Auth.JWT.Leeway)},
+
+		{"RATE_LIMIT_ENABLED", "rate-limit-enabled", "limit the request rate per API key or IP", boolVar(&c.RateLimit.Enabled)},
+		{"RATE_LIMIT_RPS", "rate-limit-rps", "requests per second allowed per client, 0 for unlimited", floatVar(&c.RateLimit.RequestsPerSecond)},
+		{"RATE_LIMIT_BURST", "rate-limit-burst", "requests a client may burst above the rate", intVar(&c.RateLimit.Burst)},
+		{"PLAN_RATE_LIMIT_RPS", "plan-rate-limit-rps", "drone plan requests per second allowed per client, 0 for unlimited", floatVar(&c.RateLimit.PlanRequestsPerSecond)},
+		{"PLAN_RATE_LIMIT_BURST", "plan-rate-limit-burst", "drone plan requests a client may burst above the rate", intVar(&c.RateLimit.PlanBurst)},
+		{"IP_RATE_LIMIT_RPS", "ip-rate-limit-rps", "requests per second allowed per IP address before authentication, 0 for unlimited", floatVar(&c.RateLimit.IPRequestsPerSecond)},
+		{"IP_RATE_LIMIT_BURST", "ip-rate-limit-burst", "requests an IP address may burst above the rate", intVar(&c.RateLimit.IPBurst)},
+
+		{"IDEMPOTENCY_TTL", "idempotency-ttl", "how long responses to requests with an Idempotency-Key are replayed", durationVar(&c.Idempotency.TTL)},
+		{"IDEMPOTENCY_LOCK_TIMEOUT", "idempotency-lock-timeout", "how long a request may hold its Idempotency-Key", durationVar(&c.Idempotency.LockTimeout)},
+		{"IDEMPOTENCY_SWEEP_INTERVAL", "idempotency-sweep-interval", "how often expired Idempotency-Keys are deleted, 0 to disable", durationVar(&c.Idempotency.SweepInterval)},
+
+		{"CACHE_BACKEND", "cache-backend", "cache of drone plans and stats: none, memory or file", stringVar(&c.Cache.Backend)},
+		{"CACHE_MAX_ENTRIES", "cache-max-entries", "entries kept by the memory cache", intVar(&c.Cache.MaxEntries)},
+		{"CACHE_DIR", "cache-dir", "directory of the file cache", stringVar(&c.Cache.Dir)},
+		{"CACHE_TTL", "cache-ttl", "how long the file cache keeps entries", durationVar(&c.Cache.TTL)},
+
+		{"PLANNER_DEFAULT_MAX_DISTANCE", "planner-default-max-distance", "max distance of plans requested without one, 0 for unlimited", intVar(&c.Planner.DefaultMaxDistance)},
+		{"PLANNER_MAX_CONCURRENT", "planner-max-concurrent", "plans computed at once, 0 for one per CPU", intVar(&c.Planner.MaxConcurrent)},
+		{"PLANNER_QUEUE_TIMEOUT", "planner-queue-timeout", "how long a plan waits for a free slot", durationVar(&c.Planner.QueueTimeout)},
+
+		{"JOBS_WORKERS", "jobs-workers", "drone plan jobs computed at once, 0 to only queue them", intVar(&c.Jobs.Workers)},
+		{"JOBS_POLL_INTERVAL", "jobs-poll-interval", "how often idle workers look for drone plan jobs", durationVar(&c.Jobs.PollInterval)},
+		{"JOBS_HEARTBEAT_INTERVAL", "jobs-heartbeat-interval", "how often running jobs record their progress", durationVar(&c.Jobs.HeartbeatInterval)},
+		{"JOBS_STALE_AFTER", "jobs-stale-after", "how long a running job may go without a heartbeat before it is taken over", durationVar(&c.Jobs.StaleAfter)},
+		{"JOBS_MAX_ATTEMPTS", "jobs-max-attempts", "how many times a job is started before it fails", intVar(&c.Jobs.MaxAttempts)},
+		{"WEBHOOKS_WORKERS", "webhooks-workers", "webhook deliveries sent at once, 0 to only record events", intVar(&c.Webhooks.Workers)},
+		{"WEBHOOKS_POLL_INTERVAL", "webhooks-poll-interval", "how often idle workers look for due webhook deliveries", durationVar(&c.Webhooks.PollInterval)},
+		{"WEBHOOKS_TIMEOUT", "webhooks-timeout", "timeout of a webhook delivery", durationVar(&c.Webhooks.Timeout)},
+		{"WEBHOOKS_MAX_ATTEMPTS", "webhooks-max-attempts", "how many times a webhook delivery is sent before it is dead", intVar(&c.Webhooks.MaxAttempts)},
+		{"WEBHOOKS_INITIAL_BACKOFF", "webhooks-initial-backoff", "delay before the first retry of a webhook delivery", durationVar(&c.Webhooks.InitialBackoff)},
+		{"WEBHOOKS_MAX_BACKOFF", "webhooks-max-backoff", "longest delay between retries of a webhook delivery", durationVar(&c.Webhooks.MaxBackoff)},
+		{"EVENTS_HEARTBEAT_INTERVAL", "events-heartbeat-interval", "how often idle event streams send a heartbeat", durationVar(&c.Events.HeartbeatInterval)},
+
+		{"GRPC_ADDRESS", "grpc-address", "address of the gRPC server, empty to disable it", stringVar(&c.GRPC.Address)},
+	}
+}
+
+// Load builds the configuration from the defaults, the configuration file,
+// the environment read with lookupEnv and the command line arguments args,
+// then validates it.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	config := Default()
+	bindings := config.bindings()
+
+	// Flags are parsed first to find the configuration file, but applied
+	// last so they take precedence.
+	fs := flag.NewFlagSet("drone-sawit", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	path := fs.String("config", "", "YAML configuration file, defaults to $"+EnvConfigFile)
+	var flags []func() error
+	for _, b := range bindings {
+		b := b
+		fs.Func(b.flag, b.usage+" ($"+b.env+")", func(value string) error {
+			flags = append(flags, func() error {
+				if err := b.set(value); err != nil {
+					return fmt.Errorf("flag -%s: %w", b.flag, err)
+				}
+				return nil
+			})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("config: unexpected argument %q", fs.Arg(0))
+	}
+
+	if *path == "" {
+		*path, _ = lookupEnv(EnvConfigFile)
+	}
+	if *path != "" {
+		if err := config.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, b := range bindings {
+		// Empty variables count as unset, as docker compose often
+		// declares them.
+		value, ok := lookupEnv(b.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := b.set(value); err != nil {
+			return nil, fmt.Errorf("config: $%s: %w", b.env, err)
+		}
+	}
+
+	for _, apply := range flags {
+		if err := apply(); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("config: invalid configuration:\n%w", err)
+	}
+	return &config, nil
+}
+
+// loadFile overlays the YAML file at path. Unknown keys are rejected so a
+// typo does not silently fall back to a default.
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+// Usage writes the flags and their environment variables to w.
+func Usage(w io.Writer) {
+	config := Default()
+	fmt.Fprintf(w, "  -config string\n\tYAML configuration file ($%s)\n", EnvConfigFile)
+	for _, b := range config.bindings() {
+		fmt.Fprintf(w, "  -%s value\n\t%s ($%s)\n", b.flag, b.usage, b.env)
+	}
+}
+
+func stringVar(p *string) func(string) error {
+	return func(value string) error {
+		*p = value
+		return nil
+	}
+}
+
+func intVar(p *int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*p = n
+		return nil
+	}
+}
+
+func int64Var(p *int64) func(string) error {
+	return func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*p = n
+		return nil
+	}
+}
+
+func floatVar(p *float64) func(string) error {
+	return func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*p = f
+		return nil
+	}
+}
+
+func boolVar(p *bool) func(string) error {
+	return func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*p = b
+		return nil
+	}
+}
+
+func durationVar(p *time.Duration) func(string) error {
+	return func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*p = d
+		return nil
+	}
+}
+
+func timeVar(p *time.Time) func(string) error {
+	return func(value string) error {
+		if value == "" {
+			*p = time.Time{}
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid time %q", value)
+		}
+		*p = t
+		return nil
+	}
+}
+
+func listVar(p *[]string) func(string) error {
+	return func(value string) error {
+		*p = splitList(value)
+		return nil
+	}
+}
+
+func roleScopesVar(p *map[string][]auth.Scope) func(string) error {
+	return func(value string) error {
+		roleScopes, err := auth.ParseRoleScopes(value)
+		if err != nil {
+			return err
+		}
+		*p = roleScopes
+		return nil
+	}
+}
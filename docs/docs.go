@@ -0,0 +1,111 @@
+// Package docs serves the OpenAPI spec of the API, as JSON and YAML, and a
+// documentation UI reading it. The UI is bundled into the binary and loads
+// nothing from elsewhere.
+package docs
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// Paths of the endpoints.
+const (
+	PathJSON = "/openapi.json"
+	PathYAML = "/openapi.yaml"
+	PathUI   = "/docs"
+)
+
+//go:embed ui/index.html
+var ui embed.FS
+
+// Docs serves a spec, encoded once by New.
+type Docs struct {
+	json []byte
+	yaml []byte
+	ui   []byte
+}
+
+// New returns the docs of swagger, normally the spec embedded in the
+// generated package.
+func New(swagger *openapi3.T) (*Docs, error) {
+	data, err := swagger.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("encode openapi spec: %w", err)
+	}
+	yamlData, err := toYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("encode openapi spec: %w", err)
+	}
+	page, err := ui.ReadFile("ui/index.html")
+	if err != nil {
+		return nil, err
+	}
+	return &Docs{json: data, yaml: yamlData, ui: page}, nil
+}
+
+// toYAML converts a JSON document to block style YAML.
+func toYAML(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var blockStyle func(node *yaml.Node)
+	blockStyle = func(node *yaml.Node) {
+		node.Style &^= yaml.FlowStyle
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+			node.Style &^= yaml.DoubleQuotedStyle
+		}
+		for _, child := range node.Content {
+			blockStyle(child)
+		}
+	}
+	blockStyle(&doc)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Register adds the endpoints to e. They need no credentials.
+func (d *Docs) Register(e *echo.Echo) {
+	e.GET(PathJSON, d.JSON)
+	e.GET(PathYAML, d.YAML)
+	e.GET(PathUI, d.UI)
+}
+
+// IsDocs reports whether c is one of the endpoints.
+func IsDocs(c echo.Context) bool {
+	switch c.Path() {
+	case PathJSON, PathYAML, PathUI:
+		return true
+	}
+	return false
+}
+
+// JSON serves the spec as JSON.
+func (d *Docs) JSON(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, d.json)
+}
+
+// YAML serves the spec as YAML.
+func (d *Docs) YAML(c echo.Context) error {
+	return c.Blob(http.StatusOK, "application/yaml", d.yaml)
+}
+
+// UI serves the documentation page.
+func (d *Docs) UI(c echo.Context) error {
+	return c.HTMLBlob(http.StatusOK, d.ui)
+}
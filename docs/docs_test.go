@@ -0,0 +1,54 @@
+package docs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, path string) *httptest.ResponseRecorder {
+	swagger, err := generated.GetSwagger()
+	require.NoError(t, err)
+	d, err := New(swagger)
+	require.NoError(t, err)
+
+	e := echo.New()
+	d.Register(e)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec
+}
+
+func TestDocs_Spec(t *testing.T) {
+	swagger, err := generated.GetSwagger()
+	require.NoError(t, err)
+	want, err := swagger.MarshalJSON()
+	require.NoError(t, err)
+
+	rec := serve(t, PathJSON)
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.JSONEq(t, string(want), rec.Body.String())
+
+	rec = serve(t, PathYAML)
+	assert.Equal(t, "application/yaml", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Body.String(), "openapi: 3.0.0\n")
+	fromYAML, err := openapi3.NewLoader().LoadFromData(rec.Body.Bytes())
+	require.NoError(t, err)
+	got, err := fromYAML.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got), "the YAML holds the same spec")
+}
+
+func TestDocs_UI(t *testing.T) {
+	rec := serve(t, PathUI)
+	assert.Equal(t, echo.MIMETextHTMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Body.String(), `new URL("openapi.json", document.baseURI)`)
+	assert.NotRegexp(t, `(src|href)="https?://`, rec.Body.String(), "nothing is loaded from elsewhere")
+}
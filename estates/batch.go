@@ -0,0 +1,383 @@
+package estates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/google/uuid"
+)
+
+// MaxBatchOperations is the most operations a batch may hold. api.yml
+// states the same.
+const MaxBatchOperations = 1000
+
+// Statuses of the operations of a batch.
+const (
+	BatchSucceeded = "succeeded"
+	BatchFailed    = "failed"
+	// BatchAborted operations were not applied because another operation
+	// of their atomic batch failed, or, in a batch that is not atomic,
+	// because an operation they depend on failed when written.
+	BatchAborted = "aborted"
+)
+
+// BatchOperation is an operation of Batch. Op is one of the kinds of
+// repository.BatchOperation.
+type BatchOperation struct {
+	Op string
+	// Ref names the estate or tree created by the operation, for the
+	// operations after it to refer to with EstateRef or TreeRef. Only
+	// creations have one.
+	Ref string
+	// EstateID, or EstateRef, is the estate a tree is planted in.
+	EstateID  string
+	EstateRef string
+	// TreeID, or TreeRef, is the tree updated or deleted.
+	TreeID  string
+	TreeRef string
+	// Width and Length are the size of the estate created.
+	Width  int
+	Length int
+	// X, Y and Height are the plot and height of the tree planted, or of
+	// the tree updated, nil leaving those it had.
+	X      *int
+	Y      *int
+	Height *int
+}
+
+// BatchResult is the outcome of a BatchOperation.
+type BatchResult struct {
+	Status string
+	// ID is the estate or tree written by the operation, when it succeeded.
+	ID string
+	// Err is why the operation failed: a *FieldError, ErrOutOfBounds,
+	// ErrPlotOccupied, or a repository error such as repository.ErrNotFound
+	// for an unknown estate or tree.
+	Err error
+}
+
+// Batch runs ops in order in one transaction, through
+// repository.ApplyBatch. Before any is written, every operation is checked
+// against the size and height limits and the bounds of its estate, with
+// the estates and trees as the operations before it leave them. Plots are
+// not checked for other trees: a tree written on an occupied plot fails as
+// it is written, with ErrPlotOccupied.
+//
+// When atomic, either every operation is applied or none is: the first to
+// fail aborts the others. Otherwise the operations that fail are left out,
+// along with those referring to them, and the others are applied. An
+// operation failing as it is written aborts those checked against it, e.g.
+// the update of a tree whose move failed. The error is only set when the
+// batch as a whole could not run, e.g. when the database is unavailable.
+func (s *Service) Batch(ctx context.Context, organizationID string, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	if len(ops) > MaxBatchOperations {
+		return nil, &FieldError{Field: "operations", Message: fmt.Sprintf("must have at most %d items", MaxBatchOperations)}
+	}
+
+	b := &batch{
+		service:        s,
+		organizationID: organizationID,
+		refs:           make(map[string]batchRef),
+		writes:         make(map[string]int),
+		estates:        make(map[string]repository.Estate),
+		trees:          make(map[string]repository.EstateTree),
+		deleted:        make(map[string]bool),
+	}
+	results := make([]BatchResult, len(ops))
+	writes := make([]repository.BatchOperation, 0, len(ops))
+	// indexes maps the writes to their operations.
+	indexes := make([]int, 0, len(ops))
+	for i, op := range ops {
+		write, err := b.plan(ctx, op)
+		if err != nil {
+			if !isOperationError(err) {
+				return nil, err
+			}
+			results[i] = BatchResult{Status: BatchFailed, Err: err}
+			if atomic {
+				return abortBatch(results), nil
+			}
+			continue
+		}
+		b.link(&write, len(writes))
+		writes = append(writes, write)
+		indexes = append(indexes, i)
+	}
+	if len(writes) == 0 {
+		return results, nil
+	}
+
+	errs, err := s.repository.ApplyBatch(ctx, writes, atomic)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range indexes {
+		if j < len(errs) && errors.Is(errs[j], repository.ErrBatchDependencyFailed) {
+			results[i] = BatchResult{Status: BatchAborted}
+			continue
+		}
+		if j < len(errs) && errs[j] != nil {
+			results[i] = BatchResult{Status: BatchFailed, Err: batchWriteError(writes[j], errs[j])}
+			continue
+		}
+		results[i] = BatchResult{Status: BatchSucceeded, ID: batchWriteID(writes[j])}
+	}
+	if atomic && len(errs) > 0 && errs[len(errs)-1] != nil {
+		return abortBatch(results), nil
+	}
+	return results, nil
+}
+
+// abortBatch marks every result of an atomic batch but the failed one as
+// aborted.
+func abortBatch(results []BatchResult) []BatchResult {
+	for i := range results {
+		if results[i].Status != BatchFailed {
+			results[i] = BatchResult{Status: BatchAborted}
+		}
+	}
+	return results
+}
+
+// isOperationError reports whether err is the failure of an operation
+// rather than of the batch.
+func isOperationError(err error) bool {
+	var fieldErr *FieldError
+	return errors.As(err, &fieldErr) ||
+		errors.Is(err, ErrOutOfBounds) ||
+		errors.Is(err, ErrPlotOccupied) ||
+		errors.Is(err, repository.ErrNotFound)
+}
+
+func batchWriteID(write repository.BatchOperation) string {
+	if write.Kind == repository.BatchCreateEstate {
+		return write.Estate.ID
+	}
+	return write.Tree.ID
+}
+
+// batchWriteError reports a tree written on a plot another tree stands on,
+// e.g. one planted since the batch was checked, as ErrPlotOccupied.
+func batchWriteError(write repository.BatchOperation, err error) error {
+	if (write.Kind == repository.BatchCreateTree || write.Kind == repository.BatchUpdateTree) &&
+		errors.Is(err, repository.ErrConflict) {
+		return ErrPlotOccupied
+	}
+	return err
+}
+
+// batchRef is what the operation of a Ref created.
+type batchRef struct {
+	kind   string
+	id     string
+	failed bool
+}
+
+// batch is a batch being checked. It keeps the estates and trees the
+// operations read or wrote, as the operations so far leave them.
+type batch struct {
+	service        *Service
+	organizationID string
+	refs           map[string]batchRef
+	estates        map[string]repository.Estate
+	trees          map[string]repository.EstateTree
+	deleted        map[string]bool
+	// writes maps the estates and trees written to the index of their last
+	// write.
+	writes map[string]int
+}
+
+// link records write as the write at index and makes it depend on the
+// last write of what it was checked against: the estate a tree is planted
+// in, or the tree updated or deleted.
+func (b *batch) link(write *repository.BatchOperation, index int) {
+	var against string
+	switch write.Kind {
+	case repository.BatchCreateTree:
+		against = write.Tree.EstateID
+	case repository.BatchUpdateTree, repository.BatchDeleteTree:
+		against = write.Tree.ID
+	}
+	if i, ok := b.writes[against]; ok {
+		write.DependsOn = []int{i}
+	}
+	b.writes[batchWriteID(*write)] = index
+}
+
+// plan checks op and returns its write.
+func (b *batch) plan(ctx context.Context, op BatchOperation) (repository.BatchOperation, error) {
+	if op.Ref != "" {
+		if op.Op != repository.BatchCreateEstate && op.Op != repository.BatchCreateTree {
+			return repository.BatchOperation{}, &FieldError{Field: "ref", Message: "is only allowed on create_estate and create_tree"}
+		}
+		if _, ok := b.refs[op.Ref]; ok {
+			return repository.BatchOperation{}, &FieldError{Field: "ref", Message: "is used by an earlier operation"}
+		}
+	}
+
+	write, err := b.write(ctx, op)
+	if op.Ref != "" {
+		b.refs[op.Ref] = batchRef{kind: op.Op, id: batchWriteID(write), failed: err != nil}
+	}
+	return write, err
+}
+
+func (b *batch) write(ctx context.Context, op BatchOperation) (repository.BatchOperation, error) {
+	switch op.Op {
+	case repository.BatchCreateEstate:
+		if err := checkRange("width", op.Width, 1, MaxSize); err != nil {
+			return repository.BatchOperation{}, err
+		}
+		if err := checkRange("length", op.Length, 1, MaxSize); err != nil {
+			return repository.BatchOperation{}, err
+		}
+		estate := repository.Estate{
+			BaseModel: repository.BaseModel{
+				ID: uuid.NewString(),
+			},
+			OrganizationID: b.organizationID,
+			Width:          op.Width,
+			Length:         op.Length,
+		}
+		b.estates[estate.ID] = estate
+		return repository.BatchOperation{Kind: op.Op, Estate: estate}, nil
+
+	case repository.BatchCreateTree:
+		estateID, err := b.resolve(op.EstateID, op.EstateRef, "estate", repository.BatchCreateEstate)
+		if err != nil {
+			return repository.BatchOperation{}, err
+		}
+		tree := repository.EstateTree{
+			BaseModel: repository.BaseModel{
+				ID: uuid.NewString(),
+			},
+			OrganizationID: b.organizationID,
+			EstateID:       estateID,
+			X:              valueOf(op.X),
+			Y:              valueOf(op.Y),
+			Height:         valueOf(op.Height),
+		}
+		if err = b.checkTree(ctx, tree); err != nil {
+			return repository.BatchOperation{}, err
+		}
+		b.trees[tree.ID] = tree
+		return repository.BatchOperation{Kind: op.Op, Tree: tree}, nil
+
+	case repository.BatchUpdateTree:
+		treeID, err := b.resolve(op.TreeID, op.TreeRef, "tree", repository.BatchCreateTree)
+		if err != nil {
+			return repository.BatchOperation{}, err
+		}
+		if op.X == nil && op.Y == nil && op.Height == nil {
+			return repository.BatchOperation{}, &FieldError{Field: "height", Message: "or x or y is required"}
+		}
+		tree, err := b.tree(ctx, treeID)
+		if err != nil {
+			return repository.BatchOperation{}, err
+		}
+		if op.X != nil {
+			tree.X = *op.X
+		}
+		if op.Y != nil {
+			tree.Y = *op.Y
+		}
+		if op.Height != nil {
+			tree.Height = *op.Height
+		}
+		if err = b.checkTree(ctx, tree); err != nil {
+			return repository.BatchOperation{}, err
+		}
+		b.trees[tree.ID] = tree
+		return repository.BatchOperation{Kind: op.Op, Tree: tree}, nil
+
+	case repository.BatchDeleteTree:
+		treeID, err := b.resolve(op.TreeID, op.TreeRef, "tree", repository.BatchCreateTree)
+		if err != nil {
+			return repository.BatchOperation{}, err
+		}
+		tree, err := b.tree(ctx, treeID)
+		if err != nil {
+			return repository.BatchOperation{}, err
+		}
+		b.deleted[tree.ID] = true
+		return repository.BatchOperation{Kind: op.Op, Tree: tree}, nil
+	}
+	return repository.BatchOperation{}, &FieldError{Field: "op", Message: "must be create_estate, create_tree, update_tree or delete_tree"}
+}
+
+// resolve returns the ID of the estate or tree, entity, given by id or by
+// ref, the Ref of the earlier operation of kind that created it.
+func (b *batch) resolve(id, ref, entity, kind string) (string, error) {
+	idField, refField := entity+"_id", entity+"_ref"
+	switch {
+	case id != "" && ref != "":
+		return "", &FieldError{Field: refField, Message: "is not allowed with " + idField}
+	case id != "":
+		return id, nil
+	case ref == "":
+		return "", &FieldError{Field: idField, Message: "or " + refField + " is required"}
+	}
+
+	r, ok := b.refs[ref]
+	switch {
+	case !ok:
+		return "", &FieldError{Field: refField, Message: "does not name an earlier operation"}
+	case r.kind != kind:
+		return "", &FieldError{Field: refField, Message: "does not name a created " + entity}
+	case r.failed:
+		return "", &FieldError{Field: refField, Message: "names an operation that failed"}
+	}
+	return r.id, nil
+}
+
+// checkTree checks the plot and height of tree against the limits and its
+// estate.
+func (b *batch) checkTree(ctx context.Context, tree repository.EstateTree) error {
+	if err := checkTree("", tree.X, tree.Y, tree.Height); err != nil {
+		return err
+	}
+	estate, err := b.estate(ctx, tree.EstateID)
+	if err != nil {
+		return err
+	}
+	if estate.Length < tree.X || estate.Width < tree.Y {
+		return ErrOutOfBounds
+	}
+	return nil
+}
+
+func (b *batch) estate(ctx context.Context, id string) (repository.Estate, error) {
+	if estate, ok := b.estates[id]; ok {
+		return estate, nil
+	}
+	estate, err := b.service.repository.FindEstate(ctx, &repository.FilterEstate{ID: id, OrganizationID: b.organizationID})
+	if err != nil {
+		return repository.Estate{}, err
+	}
+	b.estates[id] = estate
+	return estate, nil
+}
+
+func (b *batch) tree(ctx context.Context, id string) (repository.EstateTree, error) {
+	if b.deleted[id] {
+		return repository.EstateTree{}, repository.ErrNotFound
+	}
+	if tree, ok := b.trees[id]; ok {
+		return tree, nil
+	}
+	tree, err := b.service.repository.FindEstateTree(ctx, &repository.FilterEstateTree{ID: id, OrganizationID: b.organizationID})
+	if err != nil {
+		return repository.EstateTree{}, err
+	}
+	b.trees[id] = tree
+	return tree, nil
+}
+
+func valueOf(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
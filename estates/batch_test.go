@@ -0,0 +1,271 @@
+package estates
+
+import (
+	"context"
+	"testing"
+
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestService_Batch(t *testing.T) {
+	ctx := context.Background()
+	conflict := &repository.Error{Op: "CreateEstateTree", Kind: repository.ErrConflict}
+
+	t.Run("References", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var writes []repository.BatchOperation
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().ApplyBatch(ctx, gomock.Any(), true).
+			DoAndReturn(func(_ context.Context, ops []repository.BatchOperation, _ bool) ([]error, error) {
+				writes = ops
+				return make([]error, len(ops)), nil
+			})
+
+		results, err := New(Options{Repository: mockRepo}).Batch(ctx, "org-1", []BatchOperation{
+			{Op: repository.BatchCreateEstate, Ref: "estate", Width: 5, Length: 10},
+			{Op: repository.BatchCreateTree, Ref: "tree", EstateRef: "estate", X: intPtr(10), Y: intPtr(5), Height: intPtr(3)},
+			{Op: repository.BatchUpdateTree, TreeRef: "tree", Height: intPtr(4)},
+			{Op: repository.BatchDeleteTree, TreeRef: "tree"},
+		}, true)
+		require.NoError(t, err)
+		require.Len(t, writes, 4)
+
+		estate := writes[0].Estate
+		assert.Equal(t, repository.Estate{BaseModel: repository.BaseModel{ID: estate.ID}, OrganizationID: "org-1", Width: 5, Length: 10}, estate)
+		tree := writes[1].Tree
+		assert.Equal(t, repository.EstateTree{BaseModel: repository.BaseModel{ID: tree.ID}, OrganizationID: "org-1", EstateID: estate.ID, X: 10, Y: 5, Height: 3}, tree)
+		assert.Equal(t, repository.BatchUpdateTree, writes[2].Kind)
+		assert.Equal(t, tree.ID, writes[2].Tree.ID)
+		assert.Equal(t, 4, writes[2].Tree.Height)
+		assert.Equal(t, repository.BatchDeleteTree, writes[3].Kind)
+		assert.Equal(t, tree.ID, writes[3].Tree.ID)
+
+		assert.Equal(t, []BatchResult{
+			{Status: BatchSucceeded, ID: estate.ID},
+			{Status: BatchSucceeded, ID: tree.ID},
+			{Status: BatchSucceeded, ID: tree.ID},
+			{Status: BatchSucceeded, ID: tree.ID},
+		}, results)
+	})
+
+	t.Run("Existing trees", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstateTree(ctx, &repository.FilterEstateTree{ID: "tree-1", OrganizationID: "org-1"}).
+			Return(repository.EstateTree{BaseModel: repository.BaseModel{ID: "tree-1"}, OrganizationID: "org-1", EstateID: "estate-1", X: 1, Y: 1, Height: 5}, nil)
+		mockRepo.EXPECT().FindEstateTree(ctx, &repository.FilterEstateTree{ID: "tree-2", OrganizationID: "org-1"}).
+			Return(repository.EstateTree{}, &repository.Error{Op: "FindEstateTree", Kind: repository.ErrNotFound})
+		mockRepo.EXPECT().FindEstate(ctx, &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).
+			Return(repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, Width: 2, Length: 3}, nil)
+		mockRepo.EXPECT().ApplyBatch(ctx, gomock.Len(1), false).Return([]error{nil}, nil)
+
+		results, err := New(Options{Repository: mockRepo}).Batch(ctx, "org-1", []BatchOperation{
+			{Op: repository.BatchUpdateTree, TreeID: "tree-1", X: intPtr(4)},
+			{Op: repository.BatchUpdateTree, TreeID: "tree-1", X: intPtr(3), Y: intPtr(2)},
+			{Op: repository.BatchDeleteTree, TreeID: "tree-2"},
+		}, false)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.Equal(t, BatchFailed, results[0].Status)
+		assert.ErrorIs(t, results[0].Err, ErrOutOfBounds)
+		assert.Equal(t, BatchResult{Status: BatchSucceeded, ID: "tree-1"}, results[1])
+		assert.Equal(t, BatchFailed, results[2].Status)
+		assert.ErrorIs(t, results[2].Err, repository.ErrNotFound)
+	})
+
+	t.Run("Failures are left out", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().ApplyBatch(ctx, gomock.Len(2), false).Return([]error{nil, conflict}, nil)
+
+		results, err := New(Options{Repository: mockRepo}).Batch(ctx, "org-1", []BatchOperation{
+			{Op: repository.BatchCreateEstate, Ref: "small", Width: 0, Length: 10},
+			{Op: repository.BatchCreateTree, EstateRef: "small", X: intPtr(1), Y: intPtr(1), Height: intPtr(1)},
+			{Op: repository.BatchCreateEstate, Ref: "estate", Width: 5, Length: 5},
+			{Op: repository.BatchCreateTree, EstateRef: "estate", X: intPtr(1), Y: intPtr(1), Height: intPtr(1)},
+		}, false)
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+
+		var fieldErr *FieldError
+		require.ErrorAs(t, results[0].Err, &fieldErr)
+		assert.Equal(t, "width", fieldErr.Field)
+		require.ErrorAs(t, results[1].Err, &fieldErr)
+		assert.Equal(t, &FieldError{Field: "estate_ref", Message: "names an operation that failed"}, fieldErr)
+		assert.Equal(t, BatchSucceeded, results[2].Status)
+		assert.NotEmpty(t, results[2].ID)
+		assert.Equal(t, BatchResult{Status: BatchFailed, Err: ErrPlotOccupied}, results[3])
+	})
+
+	t.Run("Failed writes abort what was checked against them", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstateTree(ctx, &repository.FilterEstateTree{ID: "tree-1", OrganizationID: "org-1"}).
+			Return(repository.EstateTree{BaseModel: repository.BaseModel{ID: "tree-1"}, OrganizationID: "org-1", EstateID: "estate-1", X: 1, Y: 1, Height: 5}, nil)
+		mockRepo.EXPECT().FindEstate(ctx, &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).
+			Return(repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, Width: 5, Length: 5}, nil)
+		estateConflict := &repository.Error{Op: "CreateEstate", Kind: repository.ErrConflict}
+		var writes []repository.BatchOperation
+		mockRepo.EXPECT().ApplyBatch(ctx, gomock.Len(6), false).
+			DoAndReturn(func(_ context.Context, ops []repository.BatchOperation, _ bool) ([]error, error) {
+				writes = ops
+				return []error{
+					conflict,
+					repository.ErrBatchDependencyFailed,
+					estateConflict,
+					repository.ErrBatchDependencyFailed,
+					repository.ErrBatchDependencyFailed,
+					repository.ErrBatchDependencyFailed,
+				}, nil
+			})
+
+		results, err := New(Options{Repository: mockRepo}).Batch(ctx, "org-1", []BatchOperation{
+			{Op: repository.BatchUpdateTree, TreeID: "tree-1", X: intPtr(2)},
+			{Op: repository.BatchUpdateTree, TreeID: "tree-1", Height: intPtr(7)},
+			{Op: repository.BatchCreateEstate, Ref: "estate", Width: 5, Length: 5},
+			{Op: repository.BatchCreateTree, Ref: "tree", EstateRef: "estate", X: intPtr(1), Y: intPtr(1), Height: intPtr(1)},
+			{Op: repository.BatchUpdateTree, TreeRef: "tree", Height: intPtr(2)},
+			{Op: repository.BatchDeleteTree, TreeID: "tree-1"},
+		}, false)
+		require.NoError(t, err)
+
+		// The height update was checked against the move, and writes its
+		// plot.
+		assert.Equal(t, 2, writes[1].Tree.X)
+		dependencies := make([][]int, len(writes))
+		for i, write := range writes {
+			dependencies[i] = write.DependsOn
+		}
+		assert.Equal(t, [][]int{nil, {0}, nil, {2}, {3}, {1}}, dependencies)
+		assert.Equal(t, []BatchResult{
+			{Status: BatchFailed, Err: ErrPlotOccupied},
+			{Status: BatchAborted},
+			{Status: BatchFailed, Err: estateConflict},
+			{Status: BatchAborted},
+			{Status: BatchAborted},
+			{Status: BatchAborted},
+		}, results)
+	})
+
+	t.Run("Atomic batch is aborted before any write", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		results, err := New(Options{Repository: mockrepo.NewMockRepositoryInterface(ctrl)}).Batch(ctx, "org-1", []BatchOperation{
+			{Op: repository.BatchCreateEstate, Ref: "estate", Width: 5, Length: 5},
+			{Op: repository.BatchCreateTree, EstateRef: "estate", X: intPtr(6), Y: intPtr(1), Height: intPtr(1)},
+			{Op: repository.BatchCreateEstate, Width: 5, Length: 5},
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, []BatchResult{
+			{Status: BatchAborted},
+			{Status: BatchFailed, Err: ErrOutOfBounds},
+			{Status: BatchAborted},
+		}, results)
+	})
+
+	t.Run("Atomic batch is rolled back", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().ApplyBatch(ctx, gomock.Len(3), true).Return([]error{nil, conflict}, nil)
+
+		results, err := New(Options{Repository: mockRepo}).Batch(ctx, "org-1", []BatchOperation{
+			{Op: repository.BatchCreateEstate, Ref: "estate", Width: 5, Length: 5},
+			{Op: repository.BatchCreateTree, EstateRef: "estate", X: intPtr(1), Y: intPtr(1), Height: intPtr(1)},
+			{Op: repository.BatchCreateTree, EstateRef: "estate", X: intPtr(1), Y: intPtr(1), Height: intPtr(2)},
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, []BatchResult{
+			{Status: BatchAborted},
+			{Status: BatchFailed, Err: ErrPlotOccupied},
+			{Status: BatchAborted},
+		}, results)
+	})
+
+	t.Run("Invalid references", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().ApplyBatch(ctx, gomock.Len(2), false).Return([]error{nil, nil}, nil)
+
+		results, err := New(Options{Repository: mockRepo}).Batch(ctx, "org-1", []BatchOperation{
+			{Op: repository.BatchCreateEstate, Ref: "estate", Width: 5, Length: 5},
+			{Op: repository.BatchCreateEstate, Ref: "estate", Width: 5, Length: 5},
+			{Op: repository.BatchCreateTree, Ref: "tree", EstateRef: "estate", X: intPtr(1), Y: intPtr(1), Height: intPtr(1)},
+			{Op: repository.BatchCreateTree, EstateRef: "tree", X: intPtr(2), Y: intPtr(1), Height: intPtr(1)},
+			{Op: repository.BatchDeleteTree, TreeRef: "unknown"},
+			{Op: repository.BatchDeleteTree, TreeID: "tree-1", TreeRef: "tree"},
+			{Op: repository.BatchDeleteTree},
+			{Op: repository.BatchDeleteTree, Ref: "deleted", TreeRef: "tree"},
+			{Op: repository.BatchUpdateTree, TreeRef: "tree"},
+			{Op: "plant_forest"},
+		}, false)
+		require.NoError(t, err)
+
+		want := []*FieldError{
+			nil,
+			{Field: "ref", Message: "is used by an earlier operation"},
+			nil,
+			{Field: "estate_ref", Message: "does not name a created estate"},
+			{Field: "tree_ref", Message: "does not name an earlier operation"},
+			{Field: "tree_ref", Message: "is not allowed with tree_id"},
+			{Field: "tree_id", Message: "or tree_ref is required"},
+			{Field: "ref", Message: "is only allowed on create_estate and create_tree"},
+			{Field: "height", Message: "or x or y is required"},
+			{Field: "op", Message: "must be create_estate, create_tree, update_tree or delete_tree"},
+		}
+		require.Len(t, results, len(want))
+		for i, fieldErr := range want {
+			if fieldErr == nil {
+				assert.Equal(t, BatchSucceeded, results[i].Status, i)
+				continue
+			}
+			assert.Equal(t, BatchFailed, results[i].Status, i)
+			assert.Equal(t, fieldErr, results[i].Err, i)
+		}
+	})
+
+	t.Run("Repository failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		unavailable := &repository.Error{Op: "FindEstate", Kind: repository.ErrUnavailable}
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(ctx, gomock.Any()).Return(repository.Estate{}, unavailable)
+
+		_, err := New(Options{Repository: mockRepo}).Batch(ctx, "org-1", []BatchOperation{
+			{Op: repository.BatchCreateTree, EstateID: "estate-1", X: intPtr(1), Y: intPtr(1), Height: intPtr(1)},
+		}, false)
+		assert.ErrorIs(t, err, repository.ErrUnavailable)
+	})
+
+	t.Run("Too many operations", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, err := New(Options{Repository: mockrepo.NewMockRepositoryInterface(ctrl)}).
+			Batch(ctx, "org-1", make([]BatchOperation, MaxBatchOperations+1), false)
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "operations", fieldErr.Field)
+	})
+}
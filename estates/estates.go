@@ -0,0 +1,283 @@
+// Package estates holds the rules for creating estates and trees and for
+// computing the stats of an estate. It is shared by every transport that
+// serves them, as the planner is for drone plans.
+package estates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/dimassantoso/drone-sawit/cache"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/google/uuid"
+)
+
+// Limits of estates and trees. api.yml states the same ones.
+const (
+	// MaxSize is the largest width or length of an estate, in plots.
+	MaxSize = 50000
+	// MaxTreeHeight is the height of the tallest tree.
+	MaxTreeHeight = 30
+)
+
+var (
+	// ErrOutOfBounds is returned for a tree planted outside its estate.
+	ErrOutOfBounds = errors.New("estates: coordinate out of bound")
+	// ErrPlotOccupied is returned for a tree planted where another one
+	// stands.
+	ErrPlotOccupied = errors.New("estates: plot already has tree")
+)
+
+// FieldError reports a field of a request outside its limits.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("estates: %s %s", e.Field, e.Message)
+}
+
+// Stats are the stats of the trees of an estate. They are zero without
+// trees.
+type Stats struct {
+	Count  int     `json:"count"`
+	Max    int     `json:"max"`
+	Median float32 `json:"median"`
+	Min    int     `json:"min"`
+}
+
+type Options struct {
+	Repository repository.RepositoryInterface
+	// Cache stores computed stats by StatsFingerprint. It defaults to no
+	// cache.
+	Cache cache.Cache
+}
+
+// Service creates estates and trees and computes stats.
+type Service struct {
+	repository repository.RepositoryInterface
+	cache      cache.Cache
+}
+
+func New(opts Options) *Service {
+	s := &Service{
+		repository: opts.Repository,
+		cache:      opts.Cache,
+	}
+	if s.cache == nil {
+		s.cache = cache.Nop{}
+	}
+	return s
+}
+
+// CreateEstate creates a width by length estate in organizationID.
+// Repository errors are returned as is, so repository.ErrNotFound reports
+// an unknown organization.
+func (s *Service) CreateEstate(ctx context.Context, organizationID string, width, length int) (repository.Estate, error) {
+	if err := checkRange("width", width, 1, MaxSize); err != nil {
+		return repository.Estate{}, err
+	}
+	if err := checkRange("length", length, 1, MaxSize); err != nil {
+		return repository.Estate{}, err
+	}
+
+	estate := repository.Estate{
+		BaseModel: repository.BaseModel{
+			ID: uuid.NewString(),
+		},
+		OrganizationID: organizationID,
+		Width:          width,
+		Length:         length,
+	}
+	if err := s.repository.CreateEstate(ctx, &estate); err != nil {
+		return repository.Estate{}, err
+	}
+	return estate, nil
+}
+
+// CreateTree plants a tree of height at (x, y) in estateID.
+// repository.ErrNotFound reports an unknown estate.
+func (s *Service) CreateTree(ctx context.Context, organizationID, estateID string, x, y, height int) (repository.EstateTree, error) {
+	if err := checkTree("", x, y, height); err != nil {
+		return repository.EstateTree{}, err
+	}
+
+	estate, err := s.repository.FindEstate(ctx, &repository.FilterEstate{ID: estateID, OrganizationID: organizationID})
+	if err != nil {
+		return repository.EstateTree{}, err
+	}
+	if estate.Length < x || estate.Width < y {
+		return repository.EstateTree{}, ErrOutOfBounds
+	}
+
+	_, err = s.repository.FindEstateTree(ctx, &repository.FilterEstateTree{
+		OrganizationID: organizationID,
+		EstateID:       estateID,
+		X:              x,
+		Y:              y,
+	})
+	if err == nil {
+		return repository.EstateTree{}, ErrPlotOccupied
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return repository.EstateTree{}, err
+	}
+
+	tree := repository.EstateTree{
+		BaseModel: repository.BaseModel{
+			ID: uuid.NewString(),
+		},
+		OrganizationID: organizationID,
+		EstateID:       estateID,
+		X:              x,
+		Y:              y,
+		Height:         height,
+	}
+	if err = s.repository.CreateEstateTree(ctx, &tree); err != nil {
+		// Another request planted the plot since it was checked.
+		if errors.Is(err, repository.ErrConflict) {
+			return repository.EstateTree{}, ErrPlotOccupied
+		}
+		return repository.EstateTree{}, err
+	}
+	return tree, nil
+}
+
+// CreateTrees plants trees in estateID at once, through
+// repository.CreateEstateTrees: either every tree is planted or none is. It
+// is meant for imports and generated estates, and sends trees.imported
+// webhook events listing the trees rather than a tree.created event each.
+// repository.ErrNotFound reports an unknown estate.
+func (s *Service) CreateTrees(ctx context.Context, organizationID, estateID string, trees []TreeRecord) error {
+	for i, tree := range trees {
+		if err := checkTree(fmt.Sprintf("trees[%d].", i), tree.X, tree.Y, tree.Height); err != nil {
+			return err
+		}
+	}
+
+	estate, err := s.repository.FindEstate(ctx, &repository.FilterEstate{ID: estateID, OrganizationID: organizationID})
+	if err != nil {
+		return err
+	}
+	planted := make(map[repository.CoordinatePoint]bool, len(trees))
+	data := make([]repository.EstateTree, 0, len(trees))
+	for i, tree := range trees {
+		if estate.Length < tree.X || estate.Width < tree.Y {
+			return fmt.Errorf("tree %d: %w", i, ErrOutOfBounds)
+		}
+		plot := repository.CoordinatePoint{X: tree.X, Y: tree.Y}
+		if planted[plot] {
+			return fmt.Errorf("tree %d: %w", i, ErrPlotOccupied)
+		}
+		planted[plot] = true
+		data = append(data, repository.EstateTree{
+			BaseModel: repository.BaseModel{
+				ID: uuid.NewString(),
+			},
+			OrganizationID: organizationID,
+			EstateID:       estateID,
+			X:              tree.X,
+			Y:              tree.Y,
+			Height:         tree.Height,
+		})
+	}
+
+	err = s.repository.CreateEstateTrees(ctx, data)
+	// A plot of the estate already has a tree.
+	if errors.Is(err, repository.ErrConflict) {
+		return ErrPlotOccupied
+	}
+	return err
+}
+
+// checkTree checks the plot and height of a tree against the limits, its
+// fields being named with prefix.
+func checkTree(prefix string, x, y, height int) error {
+	if err := checkRange(prefix+"x", x, 1, 0); err != nil {
+		return err
+	}
+	if err := checkRange(prefix+"y", y, 1, 0); err != nil {
+		return err
+	}
+	return checkRange(prefix+"height", height, 1, MaxTreeHeight)
+}
+
+// checkRange checks that value is at least min and, unless max is zero, at
+// most max.
+func checkRange(field string, value, min, max int) error {
+	if value < min {
+		return &FieldError{Field: field, Message: fmt.Sprintf("must be at least %d", min)}
+	}
+	if max > 0 && value > max {
+		return &FieldError{Field: field, Message: fmt.Sprintf("must be at most %d", max)}
+	}
+	return nil
+}
+
+// StatsFingerprint identifies the stats of estate at its current version.
+// It makes a good ETag.
+func StatsFingerprint(estate repository.Estate) string {
+	return cache.Key("stats", estate.OrganizationID, estate.ID, strconv.FormatInt(estate.Version, 10))
+}
+
+// Stats returns the stats of estate at its version, from the cache when
+// they were computed before.
+func (s *Service) Stats(ctx context.Context, estate repository.Estate) (Stats, error) {
+	fingerprint := StatsFingerprint(estate)
+	if stats, ok := s.cachedStats(ctx, fingerprint); ok {
+		return stats, nil
+	}
+
+	filterEstateTree := repository.FilterEstateTree{OrganizationID: estate.OrganizationID, EstateID: estate.ID}
+	count, err := s.repository.CountEstateTree(ctx, &filterEstateTree)
+	if err != nil {
+		return Stats{}, err
+	}
+	var treeStats repository.EstateTreeStats
+	if count > 0 {
+		treeStats, err = s.repository.GetEstateTreeStats(ctx, &filterEstateTree)
+		if err != nil {
+			return Stats{}, err
+		}
+	}
+
+	stats := Stats{
+		Count:  count,
+		Max:    treeStats.Max,
+		Min:    treeStats.Min,
+		Median: treeStats.Median,
+	}
+	s.storeStats(ctx, fingerprint, stats)
+	return stats, nil
+}
+
+// cachedStats returns the stats stored under fingerprint. Cache failures
+// are logged and treated as misses.
+func (s *Service) cachedStats(ctx context.Context, fingerprint string) (Stats, bool) {
+	var stats Stats
+	value, ok, err := s.cache.Get(ctx, fingerprint)
+	if err == nil && ok {
+		err = json.Unmarshal(value, &stats)
+	}
+	if err != nil {
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "read cached stats", slog.Any("error", err))
+		return Stats{}, false
+	}
+	return stats, ok
+}
+
+func (s *Service) storeStats(ctx context.Context, fingerprint string, stats Stats) {
+	value, err := json.Marshal(stats)
+	if err == nil {
+		err = s.cache.Set(ctx, fingerprint, value)
+	}
+	if err != nil {
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "cache stats", slog.Any("error", err))
+	}
+}
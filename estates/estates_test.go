@@ -0,0 +1,225 @@
+package estates
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/cache"
+	"github.com/dimassantoso/drone-sawit/generated"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsMatchSpec(t *testing.T) {
+	swagger, err := generated.GetSwagger()
+	require.NoError(t, err)
+	schemas := swagger.Components.Schemas
+
+	for _, field := range []string{"width", "length"} {
+		schema := schemas["EstateRequest"].Value.Properties[field].Value
+		assert.Equal(t, float64(1), *schema.Min, field)
+		assert.Equal(t, float64(MaxSize), *schema.Max, field)
+	}
+	for _, field := range []string{"x", "y"} {
+		schema := schemas["EstateTreeRequest"].Value.Properties[field].Value
+		assert.Equal(t, float64(1), *schema.Min, field)
+		assert.Nil(t, schema.Max, field)
+	}
+	height := schemas["EstateTreeRequest"].Value.Properties["height"].Value
+	assert.Equal(t, float64(1), *height.Min)
+	assert.Equal(t, float64(MaxTreeHeight), *height.Max)
+}
+
+func TestService_CreateEstate(t *testing.T) {
+	t.Run("Created", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).Return(nil)
+
+		estate, err := New(Options{Repository: mockRepo}).CreateEstate(context.Background(), "org-1", 5, 10)
+		require.NoError(t, err)
+		assert.NotEmpty(t, estate.ID)
+		assert.Equal(t, "org-1", estate.OrganizationID)
+		assert.Equal(t, 5, estate.Width)
+		assert.Equal(t, 10, estate.Length)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, err := New(Options{Repository: mockrepo.NewMockRepositoryInterface(ctrl)}).
+			CreateEstate(context.Background(), "org-1", MaxSize+1, 10)
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "width", fieldErr.Field)
+		assert.Equal(t, "must be at most 50000", fieldErr.Message)
+	})
+}
+
+func TestService_CreateTree(t *testing.T) {
+	estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 5, Length: 5}
+
+	t.Run("Created", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).Return(estate, nil)
+		mockRepo.EXPECT().FindEstateTree(gomock.Any(), &repository.FilterEstateTree{OrganizationID: "org-1", EstateID: "estate-1", X: 2, Y: 3}).
+			Return(repository.EstateTree{}, repository.ErrNotFound)
+		mockRepo.EXPECT().CreateEstateTree(gomock.Any(), gomock.Any()).Return(nil)
+
+		tree, err := New(Options{Repository: mockRepo}).CreateTree(context.Background(), "org-1", "estate-1", 2, 3, 10)
+		require.NoError(t, err)
+		assert.NotEmpty(t, tree.ID)
+		assert.Equal(t, 10, tree.Height)
+	})
+
+	t.Run("InvalidHeight", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, err := New(Options{Repository: mockrepo.NewMockRepositoryInterface(ctrl)}).
+			CreateTree(context.Background(), "org-1", "estate-1", 2, 3, 0)
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "height", fieldErr.Field)
+	})
+
+	t.Run("OutOfBounds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil)
+
+		_, err := New(Options{Repository: mockRepo}).CreateTree(context.Background(), "org-1", "estate-1", 6, 1, 10)
+		assert.ErrorIs(t, err, ErrOutOfBounds)
+	})
+
+	t.Run("PlotOccupied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil)
+		mockRepo.EXPECT().FindEstateTree(gomock.Any(), gomock.Any()).Return(repository.EstateTree{}, nil)
+
+		_, err := New(Options{Repository: mockRepo}).CreateTree(context.Background(), "org-1", "estate-1", 2, 3, 10)
+		assert.ErrorIs(t, err, ErrPlotOccupied)
+	})
+
+	t.Run("PlantedConcurrently", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil)
+		mockRepo.EXPECT().FindEstateTree(gomock.Any(), gomock.Any()).Return(repository.EstateTree{}, repository.ErrNotFound)
+		mockRepo.EXPECT().CreateEstateTree(gomock.Any(), gomock.Any()).Return(&repository.Error{Op: "CreateEstateTree", Kind: repository.ErrConflict})
+
+		_, err := New(Options{Repository: mockRepo}).CreateTree(context.Background(), "org-1", "estate-1", 2, 3, 10)
+		assert.ErrorIs(t, err, ErrPlotOccupied)
+	})
+
+	t.Run("EstateNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, repository.ErrNotFound)
+
+		_, err := New(Options{Repository: mockRepo}).CreateTree(context.Background(), "org-1", "estate-1", 2, 3, 10)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+}
+
+func TestService_CreateTrees(t *testing.T) {
+	estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 5, Length: 5}
+
+	t.Run("Created", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).Return(estate, nil)
+		mockRepo.EXPECT().CreateEstateTrees(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, data []repository.EstateTree) error {
+			require.Len(t, data, 2)
+			assert.Equal(t, "estate-1", data[1].EstateID)
+			assert.Equal(t, 4, data[1].X)
+			assert.Equal(t, 20, data[1].Height)
+			return nil
+		})
+
+		err := New(Options{Repository: mockRepo}).CreateTrees(context.Background(), "org-1", "estate-1",
+			[]TreeRecord{{X: 1, Y: 1, Height: 10}, {X: 4, Y: 2, Height: 20}})
+		require.NoError(t, err)
+	})
+
+	t.Run("InvalidHeight", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		err := New(Options{Repository: mockrepo.NewMockRepositoryInterface(ctrl)}).CreateTrees(context.Background(), "org-1", "estate-1",
+			[]TreeRecord{{X: 1, Y: 1, Height: 10}, {X: 2, Y: 1, Height: MaxTreeHeight + 1}})
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "trees[1].height", fieldErr.Field)
+	})
+
+	t.Run("SamePlotTwice", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil)
+
+		err := New(Options{Repository: mockRepo}).CreateTrees(context.Background(), "org-1", "estate-1",
+			[]TreeRecord{{X: 1, Y: 1, Height: 10}, {X: 1, Y: 1, Height: 20}})
+		assert.ErrorIs(t, err, ErrPlotOccupied)
+	})
+
+	t.Run("PlotOccupied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil)
+		mockRepo.EXPECT().CreateEstateTrees(gomock.Any(), gomock.Any()).Return(&repository.Error{Op: "CreateEstateTrees", Kind: repository.ErrConflict})
+
+		err := New(Options{Repository: mockRepo}).CreateTrees(context.Background(), "org-1", "estate-1",
+			[]TreeRecord{{X: 1, Y: 1, Height: 10}})
+		assert.ErrorIs(t, err, ErrPlotOccupied)
+	})
+}
+
+func TestService_Stats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Version: 1}
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().CountEstateTree(gomock.Any(), &repository.FilterEstateTree{OrganizationID: "org-1", EstateID: "estate-1"}).Return(2, nil)
+	mockRepo.EXPECT().GetEstateTreeStats(gomock.Any(), gomock.Any()).Return(repository.EstateTreeStats{Min: 10, Max: 30, Median: 20}, nil)
+
+	service := New(Options{Repository: mockRepo, Cache: cache.NewMemory(0)})
+	stats, err := service.Stats(context.Background(), estate)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Count: 2, Max: 30, Median: 20, Min: 10}, stats)
+
+	cached, err := service.Stats(context.Background(), estate)
+	require.NoError(t, err)
+	assert.Equal(t, stats, cached, "served from the cache")
+
+	mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(0, nil)
+	estate.Version++
+	stats, err = service.Stats(context.Background(), estate)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{}, stats, "no trees, no tree stats")
+}
@@ -0,0 +1,142 @@
+package estates
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Formats of tree import files.
+const (
+	// FormatCSV is a header line naming the x, y and height columns, in any
+	// order, then one tree per line.
+	FormatCSV = "csv"
+	// FormatJSON is an array of {"x", "y", "height"} objects.
+	FormatJSON = "json"
+)
+
+// TreeRecord is a tree of an import file.
+type TreeRecord struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Height int `json:"height"`
+}
+
+// ReadTrees reads the trees of an import file in format. The trees are not
+// validated: CreateTrees does that as it plants them, as does CreateTree for
+// a single tree.
+func ReadTrees(r io.Reader, format string) ([]TreeRecord, error) {
+	switch format {
+	case FormatCSV:
+		return readTreesCSV(r)
+	case FormatJSON:
+		var trees []TreeRecord
+		if err := json.NewDecoder(r).Decode(&trees); err != nil {
+			return nil, fmt.Errorf("read trees: %w", err)
+		}
+		return trees, nil
+	}
+	return nil, fmt.Errorf("unknown tree file format %q", format)
+}
+
+func readTreesCSV(r io.Reader) ([]TreeRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read trees: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"x", "y", "height"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("read trees: missing %s column", name)
+		}
+	}
+
+	var trees []TreeRecord
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return trees, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read trees: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		var tree TreeRecord
+		for name, field := range map[string]*int{"x": &tree.X, "y": &tree.Y, "height": &tree.Height} {
+			if *field, err = strconv.Atoi(strings.TrimSpace(record[columns[name]])); err != nil {
+				return nil, fmt.Errorf("read trees: line %d: invalid %s %q", line, name, record[columns[name]])
+			}
+		}
+		trees = append(trees, tree)
+	}
+}
+
+// TreeWriter writes an import file, one tree at a time.
+type TreeWriter struct {
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+	count  int
+}
+
+// NewTreeWriter returns a writer of an import file in format to w. Close
+// completes the file.
+func NewTreeWriter(w io.Writer, format string) (*TreeWriter, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"x", "y", "height"}); err != nil {
+			return nil, err
+		}
+		return &TreeWriter{format: format, csv: writer}, nil
+	case FormatJSON:
+		return &TreeWriter{format: format, w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown tree file format %q", format)
+}
+
+// Write writes tree.
+func (t *TreeWriter) Write(tree TreeRecord) error {
+	t.count++
+	if t.format == FormatCSV {
+		return t.csv.Write([]string{strconv.Itoa(tree.X), strconv.Itoa(tree.Y), strconv.Itoa(tree.Height)})
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if t.count == 1 {
+		separator = "[\n  "
+	}
+	_, err = fmt.Fprintf(t.w, "%s%s", separator, data)
+	return err
+}
+
+// Close flushes the file, and ends the array of a JSON file. It does not
+// close the underlying writer.
+func (t *TreeWriter) Close() error {
+	if t.format == FormatCSV {
+		t.csv.Flush()
+		return t.csv.Error()
+	}
+	end := "\n]\n"
+	if t.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(t.w, end)
+	return err
+}
@@ -0,0 +1,63 @@
+package estates
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTrees(t *testing.T) {
+	want := []TreeRecord{{X: 1, Y: 2, Height: 10}, {X: 3, Y: 4, Height: 20}}
+
+	t.Run("CSV", func(t *testing.T) {
+		trees, err := ReadTrees(strings.NewReader("height, x, y\n10, 1, 2\n20, 3, 4\n"), FormatCSV)
+		require.NoError(t, err)
+		assert.Equal(t, want, trees)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		trees, err := ReadTrees(strings.NewReader(`[{"x":1,"y":2,"height":10},{"x":3,"y":4,"height":20}]`), FormatJSON)
+		require.NoError(t, err)
+		assert.Equal(t, want, trees)
+	})
+
+	t.Run("MissingColumn", func(t *testing.T) {
+		_, err := ReadTrees(strings.NewReader("x,y\n1,2\n"), FormatCSV)
+		assert.EqualError(t, err, "read trees: missing height column")
+	})
+
+	t.Run("InvalidValue", func(t *testing.T) {
+		_, err := ReadTrees(strings.NewReader("x,y,height\n1,2,10\n1,two,10\n"), FormatCSV)
+		assert.EqualError(t, err, `read trees: line 3: invalid y "two"`)
+	})
+}
+
+func TestTreeWriter(t *testing.T) {
+	trees := []TreeRecord{{X: 1, Y: 2, Height: 10}, {X: 3, Y: 4, Height: 20}}
+
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var file strings.Builder
+			w, err := NewTreeWriter(&file, format)
+			require.NoError(t, err)
+			for _, tree := range trees {
+				require.NoError(t, w.Write(tree))
+			}
+			require.NoError(t, w.Close())
+
+			read, err := ReadTrees(strings.NewReader(file.String()), format)
+			require.NoError(t, err)
+			assert.Equal(t, trees, read)
+		})
+	}
+
+	t.Run("EmptyJSON", func(t *testing.T) {
+		var file strings.Builder
+		w, err := NewTreeWriter(&file, FormatJSON)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		assert.Equal(t, "[]\n", file.String())
+	})
+}
@@ -0,0 +1,165 @@
+// Package events tells the event streams of an estate that it changed. The
+// database records every change to the trees of an estate and notifies
+// repository.EstateEventsChannel when it commits; the Broker of each
+// process listens to that channel and wakes the streams of the estate,
+// which then read the events they have not sent yet.
+package events
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/lib/pq"
+)
+
+const (
+	// DefaultHeartbeatInterval is how often idle streams send a comment
+	// and look for missed events when Options.HeartbeatInterval is zero.
+	DefaultHeartbeatInterval = 15 * time.Second
+	// pingInterval is how often Listen checks its connection; pq only
+	// notices a dead connection when it is used.
+	pingInterval = 90 * time.Second
+)
+
+type Options struct {
+	// HeartbeatInterval keeps streams open through proxies and bounds how
+	// long a lost notification delays an event.
+	HeartbeatInterval time.Duration
+}
+
+// Broker wakes the subscribers of an estate when it is published.
+type Broker struct {
+	heartbeatInterval time.Duration
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+func New(opts Options) *Broker {
+	b := &Broker{
+		heartbeatInterval: opts.HeartbeatInterval,
+		subscribers:       make(map[string]map[chan struct{}]struct{}),
+		done:              make(chan struct{}),
+	}
+	if b.heartbeatInterval <= 0 {
+		b.heartbeatInterval = DefaultHeartbeatInterval
+	}
+	return b
+}
+
+// HeartbeatInterval is how often idle streams send a heartbeat.
+func (b *Broker) HeartbeatInterval() time.Duration {
+	return b.heartbeatInterval
+}
+
+// Subscribe returns a channel that receives a value after estateID is
+// published, and a func that must be called once the channel is no longer
+// read. Publications made while the previous one was not received yet are
+// coalesced into it.
+func (b *Broker) Subscribe(estateID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	b.mu.Lock()
+	if b.subscribers[estateID] == nil {
+		b.subscribers[estateID] = make(map[chan struct{}]struct{})
+	}
+	b.subscribers[estateID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[estateID], ch)
+		if len(b.subscribers[estateID]) == 0 {
+			delete(b.subscribers, estateID)
+		}
+	}
+}
+
+// Publish wakes the subscribers of estateID.
+func (b *Broker) Publish(estateID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[estateID] {
+		wake(ch)
+	}
+}
+
+// publishAll wakes every subscriber, after notifications may have been
+// lost.
+func (b *Broker) publishAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			wake(ch)
+		}
+	}
+}
+
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Done is closed by Close; streams end when it is.
+func (b *Broker) Done() <-chan struct{} {
+	return b.done
+}
+
+// Close ends the streams of b, e.g. when the server shuts down.
+func (b *Broker) Close() {
+	b.closeOnce.Do(func() { close(b.done) })
+}
+
+// Run publishes the estates of notifications until ctx is done, then closes
+// b. A nil notification, sent by pq after reconnecting, wakes every
+// subscriber.
+func (b *Broker) Run(ctx context.Context, notifications <-chan *pq.Notification) {
+	defer b.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-notifications:
+			if n == nil {
+				b.publishAll()
+				continue
+			}
+			estateID, _, _ := strings.Cut(n.Extra, ":")
+			b.Publish(estateID)
+		}
+	}
+}
+
+// Listen runs b with the notifications of repository.EstateEventsChannel
+// received by listener, and closes listener when ctx is done. Without
+// notifications, streams still see changes at every heartbeat.
+func (b *Broker) Listen(ctx context.Context, listener *pq.Listener) {
+	defer listener.Close()
+	if err := listener.Listen(repository.EstateEventsChannel); err != nil {
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelError, "listen for estate events", slog.Any("error", err))
+	}
+
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = listener.Ping()
+			}
+		}
+	}()
+	b.Run(ctx, listener.Notify)
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func received(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func pending(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestBroker_Publish(t *testing.T) {
+	b := New(Options{})
+	assert.Equal(t, DefaultHeartbeatInterval, b.HeartbeatInterval())
+
+	estate1, unsubscribe1 := b.Subscribe("estate-1")
+	estate2, unsubscribe2 := b.Subscribe("estate-2")
+	defer unsubscribe2()
+
+	b.Publish("estate-1")
+	b.Publish("estate-1")
+	assert.True(t, pending(estate1))
+	assert.False(t, pending(estate1), "publications not received yet are coalesced")
+	assert.False(t, pending(estate2))
+
+	unsubscribe1()
+	b.Publish("estate-1")
+	assert.False(t, pending(estate1))
+	assert.Empty(t, b.subscribers["estate-1"])
+}
+
+func TestBroker_Run(t *testing.T) {
+	b := New(Options{HeartbeatInterval: time.Minute})
+	assert.Equal(t, time.Minute, b.HeartbeatInterval())
+
+	estate1, unsubscribe1 := b.Subscribe("estate-1")
+	defer unsubscribe1()
+	estate2, unsubscribe2 := b.Subscribe("estate-2")
+	defer unsubscribe2()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	notifications := make(chan *pq.Notification)
+	stopped := make(chan struct{})
+	go func() {
+		b.Run(ctx, notifications)
+		close(stopped)
+	}()
+
+	notifications <- &pq.Notification{Channel: "estate_events", Extra: "estate-1:7"}
+	assert.True(t, received(estate1))
+	assert.False(t, pending(estate2))
+
+	notifications <- nil
+	assert.True(t, received(estate1), "a reconnection wakes every subscriber")
+	assert.True(t, received(estate2))
+
+	cancel()
+	<-stopped
+	select {
+	case <-b.Done():
+	default:
+		t.Fatal("the broker is closed when Run returns")
+	}
+}
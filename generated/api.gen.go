@@ -12,15 +12,196 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/labstack/echo/v4"
 	"github.com/oapi-codegen/runtime"
 )
 
+const (
+	ApiKeyAuthScopes = "ApiKeyAuth.Scopes"
+	BearerAuthScopes = "BearerAuth.Scopes"
+)
+
+// Defines values for BatchOperationStatus.
+const (
+	BatchAborted   BatchOperationStatus = "aborted"
+	BatchFailed    BatchOperationStatus = "failed"
+	BatchSucceeded BatchOperationStatus = "succeeded"
+)
+
+// Defines values for BatchOperationType.
+const (
+	CreateEstate BatchOperationType = "create_estate"
+	CreateTree   BatchOperationType = "create_tree"
+	DeleteTree   BatchOperationType = "delete_tree"
+	UpdateTree   BatchOperationType = "update_tree"
+)
+
+// Defines values for ErrorCode.
+const (
+	CONFLICT                 ErrorCode = "CONFLICT"
+	ESTATENOTFOUND           ErrorCode = "ESTATE_NOT_FOUND"
+	FORBIDDEN                ErrorCode = "FORBIDDEN"
+	IDEMPOTENCYKEYINPROGRESS ErrorCode = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	IDEMPOTENCYKEYREUSED     ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	INTERNALERROR            ErrorCode = "INTERNAL_ERROR"
+	INVALIDFILTER            ErrorCode = "INVALID_FILTER"
+	INVALIDREQUEST           ErrorCode = "INVALID_REQUEST"
+	METHODNOTALLOWED         ErrorCode = "METHOD_NOT_ALLOWED"
+	NOTFOUND                 ErrorCode = "NOT_FOUND"
+	OUTOFBOUNDS              ErrorCode = "OUT_OF_BOUNDS"
+	PAYLOADTOOLARGE          ErrorCode = "PAYLOAD_TOO_LARGE"
+	PLOTOCCUPIED             ErrorCode = "PLOT_OCCUPIED"
+	RATELIMITED              ErrorCode = "RATE_LIMITED"
+	SERVICEUNAVAILABLE       ErrorCode = "SERVICE_UNAVAILABLE"
+	UNAUTHORIZED             ErrorCode = "UNAUTHORIZED"
+	VALIDATIONFAILED         ErrorCode = "VALIDATION_FAILED"
+)
+
+// Defines values for PlanJobStatus.
+const (
+	Cancelled PlanJobStatus = "cancelled"
+	Failed    PlanJobStatus = "failed"
+	Queued    PlanJobStatus = "queued"
+	Running   PlanJobStatus = "running"
+	Succeeded PlanJobStatus = "succeeded"
+)
+
+// Defines values for WebhookDeliveryStatus.
+const (
+	Dead      WebhookDeliveryStatus = "dead"
+	Delivered WebhookDeliveryStatus = "delivered"
+	Pending   WebhookDeliveryStatus = "pending"
+)
+
+// Defines values for WebhookEventType.
+const (
+	EstateCreated WebhookEventType = "estate.created"
+	TreeCreated   WebhookEventType = "tree.created"
+	TreeDeleted   WebhookEventType = "tree.deleted"
+	TreeUpdated   WebhookEventType = "tree.updated"
+	TreesImported WebhookEventType = "trees.imported"
+)
+
+// AuditEntryResponse defines model for AuditEntryResponse.
+type AuditEntryResponse struct {
+	// Action One of `create`, `update`, `revoke`, `cancel`, `delete`, `replay` and `import`.
+	Action string `json:"action"`
+	Actor  string `json:"actor"`
+
+	// After The entity after the write; absent for deletions.
+	After *map[string]interface{} `json:"after,omitempty"`
+
+	// Before The entity before the write; absent for creations.
+	Before    *map[string]interface{} `json:"before,omitempty"`
+	CreatedAt time.Time               `json:"created_at"`
+	EntityId  string                  `json:"entity_id"`
+
+	// EntityType One of `organization`, `estate`, `tree`, `api_key`, `plan_job` and
+	// `webhook_subscription`.
+	EntityType string `json:"entity_type"`
+
+	// EstateId The estate the entity belongs to, if any.
+	EstateId *string `json:"estate_id,omitempty"`
+
+	// Id Increases with every entry.
+	Id int64 `json:"id"`
+
+	// RequestId The `X-Request-ID` of the request that made the write.
+	RequestId *string `json:"request_id,omitempty"`
+}
+
+// AuditLogResponse defines model for AuditLogResponse.
+type AuditLogResponse struct {
+	Entries []AuditEntryResponse `json:"entries"`
+}
+
+// BatchOperation `create_estate` takes `width` and `length`; `create_tree` takes
+// `estate_id` or `estate_ref`, `x`, `y` and `height`; `update_tree` takes
+// `tree_id` or `tree_ref` and any of `x`, `y` and `height`; `delete_tree`
+// takes `tree_id` or `tree_ref`.
+type BatchOperation struct {
+	EstateId *string `json:"estate_id,omitempty"`
+
+	// EstateRef The `ref` of an earlier `create_estate`.
+	EstateRef *string            `json:"estate_ref,omitempty"`
+	Height    *int               `json:"height,omitempty"`
+	Length    *int               `json:"length,omitempty"`
+	Op        BatchOperationType `json:"op"`
+
+	// Ref Names the estate or tree created, for later operations to refer to.
+	Ref    *string `json:"ref,omitempty"`
+	TreeId *string `json:"tree_id,omitempty"`
+
+	// TreeRef The `ref` of an earlier `create_tree`.
+	TreeRef *string `json:"tree_ref,omitempty"`
+	Width   *int    `json:"width,omitempty"`
+	X       *int    `json:"x,omitempty"`
+	Y       *int    `json:"y,omitempty"`
+}
+
+// BatchOperationResult defines model for BatchOperationResult.
+type BatchOperationResult struct {
+	Error *ErrorResponse `json:"error,omitempty"`
+
+	// Id The estate or tree written, when the operation succeeded.
+	Id *string `json:"id,omitempty"`
+
+	// Status `aborted` operations were not applied because another operation of
+	// their atomic batch failed, or, in a batch that is not atomic, because
+	// an operation they depend on failed as it was written.
+	Status BatchOperationStatus `json:"status"`
+}
+
+// BatchOperationStatus `aborted` operations were not applied because another operation of
+// their atomic batch failed, or, in a batch that is not atomic, because
+// an operation they depend on failed as it was written.
+type BatchOperationStatus string
+
+// BatchOperationType defines model for BatchOperationType.
+type BatchOperationType string
+
+// BatchRequest defines model for BatchRequest.
+type BatchRequest struct {
+	// Atomic Apply every operation or none.
+	Atomic     *bool            `json:"atomic,omitempty"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchResponse defines model for BatchResponse.
+type BatchResponse struct {
+	Results []BatchOperationResult `json:"results"`
+}
+
+// ErrorCode Stable machine-readable error code.
+type ErrorCode string
+
+// ErrorDetail defines model for ErrorDetail.
+type ErrorDetail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // ErrorResponse defines model for ErrorResponse.
 type ErrorResponse struct {
-	Message string `json:"message"`
+	// Code Stable machine-readable error code.
+	Code      ErrorCode      `json:"code"`
+	Details   *[]ErrorDetail `json:"details,omitempty"`
+	Message   string         `json:"message"`
+	RequestId *string        `json:"request_id,omitempty"`
+}
+
+// EstateDetailResponse defines model for EstateDetailResponse.
+type EstateDetailResponse struct {
+	CreatedAt time.Time `json:"created_at"`
+	Id        string    `json:"id"`
+	Length    int       `json:"length"`
+
+	// Version Bumped on every change to the estate or its trees.
+	Version int64 `json:"version"`
+	Width   int   `json:"width"`
 }
 
 // EstateDronePlanResponse defines model for EstateDronePlanResponse.
@@ -32,6 +213,11 @@ type EstateDronePlanResponse struct {
 	} `json:"rest,omitempty"`
 }
 
+// EstateListResponse defines model for EstateListResponse.
+type EstateListResponse struct {
+	Estates []EstateDetailResponse `json:"estates"`
+}
+
 // EstateRequest defines model for EstateRequest.
 type EstateRequest struct {
 	Length int `json:"length"`
@@ -63,31 +249,206 @@ type EstateTreeResponse struct {
 	Id string `json:"id"`
 }
 
+// PlanJobRequest defines model for PlanJobRequest.
+type PlanJobRequest struct {
+	MaxDistance *int `json:"max_distance,omitempty"`
+}
+
+// PlanJobResponse defines model for PlanJobResponse.
+type PlanJobResponse struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Error Why the job failed.
+	Error       *string    `json:"error,omitempty"`
+	EstateId    string     `json:"estate_id"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Id          string     `json:"id"`
+	MaxDistance *int       `json:"max_distance,omitempty"`
+
+	// Progress Fraction of the estate planned, from 0 to 1.
+	Progress  float32                  `json:"progress"`
+	Result    *EstateDronePlanResponse `json:"result,omitempty"`
+	StartedAt *time.Time               `json:"started_at,omitempty"`
+	Status    PlanJobStatus            `json:"status"`
+}
+
+// PlanJobStatus defines model for PlanJobStatus.
+type PlanJobStatus string
+
+// WebhookDeliveryListResponse defines model for WebhookDeliveryListResponse.
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+}
+
+// WebhookDeliveryResponse defines model for WebhookDeliveryResponse.
+type WebhookDeliveryResponse struct {
+	Attempts    int        `json:"attempts"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	EventId     string     `json:"event_id"`
+
+	// EventType `tree.created`, `tree.updated` and `tree.deleted` carry the tree, as it is after the write or was before its deletion. `trees.imported` lists the trees of a bulk import, up to 1000 per event, as `{"estate_id": ..., "trees": [...]}`, instead of a `tree.created` event per tree.
+	EventType      WebhookEventType `json:"event_type"`
+	Id             string           `json:"id"`
+	LastError      *string          `json:"last_error,omitempty"`
+	LastStatusCode *int             `json:"last_status_code,omitempty"`
+
+	// NextAttemptAt When a pending delivery is sent next.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+
+	// Status `pending` deliveries are sent, and retried with exponential backoff,
+	// until they are `delivered` or run out of attempts and are `dead`.
+	Status WebhookDeliveryStatus `json:"status"`
+}
+
+// WebhookDeliveryStatus `pending` deliveries are sent, and retried with exponential backoff,
+// until they are `delivered` or run out of attempts and are `dead`.
+type WebhookDeliveryStatus string
+
+// WebhookEventType `tree.created`, `tree.updated` and `tree.deleted` carry the tree, as it is after the write or was before its deletion. `trees.imported` lists the trees of a bulk import, up to 1000 per event, as `{"estate_id": ..., "trees": [...]}`, instead of a `tree.created` event per tree.
+type WebhookEventType string
+
+// WebhookReplayRequest defines model for WebhookReplayRequest.
+type WebhookReplayRequest struct {
+	DeliveryIds *[]string `json:"delivery_ids,omitempty"`
+}
+
+// WebhookReplayResponse defines model for WebhookReplayResponse.
+type WebhookReplayResponse struct {
+	// Replayed Number of dead deliveries queued again.
+	Replayed int `json:"replayed"`
+}
+
+// WebhookSubscriptionListResponse defines model for WebhookSubscriptionListResponse.
+type WebhookSubscriptionListResponse struct {
+	Subscriptions []WebhookSubscriptionResponse `json:"subscriptions"`
+}
+
+// WebhookSubscriptionRequest defines model for WebhookSubscriptionRequest.
+type WebhookSubscriptionRequest struct {
+	Events []WebhookEventType `json:"events"`
+
+	// Url An absolute http or https URL whose host resolves, and not to a loopback, private, shared (CGNAT), NAT64, link-local or unspecified address.
+	Url string `json:"url"`
+}
+
+// WebhookSubscriptionResponse defines model for WebhookSubscriptionResponse.
+type WebhookSubscriptionResponse struct {
+	CreatedAt time.Time          `json:"created_at"`
+	Events    []WebhookEventType `json:"events"`
+	Id        string             `json:"id"`
+
+	// Secret Signs the deliveries. Only returned on creation.
+	Secret *string `json:"secret,omitempty"`
+	Url    string  `json:"url"`
+}
+
+// GetAuditParams defines parameters for GetAudit.
+type GetAuditParams struct {
+	// EstateId Only entries about this estate and what belongs to it.
+	EstateId *string `form:"estate_id,omitempty" json:"estate_id,omitempty"`
+
+	// Actor Only entries of this actor, e.g. `apikey:<id>` or `jwt:<sub>`.
+	Actor *string `form:"actor,omitempty" json:"actor,omitempty"`
+
+	// From Only entries recorded at or after this time.
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Only entries recorded before this time.
+	To    *time.Time `form:"to,omitempty" json:"to,omitempty"`
+	Limit *int       `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetEstateParams defines parameters for GetEstate.
+type GetEstateParams struct {
+	// After Only estates created after this one. An ID that is not one of the
+	// caller's estates gives an empty page.
+	After *string `form:"after,omitempty" json:"after,omitempty"`
+	Limit *int    `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
 // GetEstateIdDronePlanParams defines parameters for GetEstateIdDronePlan.
 type GetEstateIdDronePlanParams struct {
 	MaxDistance *int `form:"max_distance,omitempty" json:"max_distance,omitempty"`
 }
 
+// GetWebhooksIdDeliveriesParams defines parameters for GetWebhooksIdDeliveries.
+type GetWebhooksIdDeliveriesParams struct {
+	Status *WebhookDeliveryStatus `form:"status,omitempty" json:"status,omitempty"`
+	Limit  *int                   `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// PostBatchJSONRequestBody defines body for PostBatch for application/json ContentType.
+type PostBatchJSONRequestBody = BatchRequest
+
 // PostEstateJSONRequestBody defines body for PostEstate for application/json ContentType.
 type PostEstateJSONRequestBody = EstateRequest
 
+// PostEstateIdDronePlanJobsJSONRequestBody defines body for PostEstateIdDronePlanJobs for application/json ContentType.
+type PostEstateIdDronePlanJobsJSONRequestBody = PlanJobRequest
+
 // PostEstateIdTreeJSONRequestBody defines body for PostEstateIdTree for application/json ContentType.
 type PostEstateIdTreeJSONRequestBody = EstateTreeRequest
 
+// PostWebhooksJSONRequestBody defines body for PostWebhooks for application/json ContentType.
+type PostWebhooksJSONRequestBody = WebhookSubscriptionRequest
+
+// PostWebhooksIdReplayJSONRequestBody defines body for PostWebhooksIdReplay for application/json ContentType.
+type PostWebhooksIdReplayJSONRequestBody = WebhookReplayRequest
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// List the audit log of the organization, newest first
+	// (GET /audit)
+	GetAudit(ctx echo.Context, params GetAuditParams) error
+	// Run a batch of estate and tree operations
+	// (POST /batch)
+	PostBatch(ctx echo.Context) error
+	// List the estates of the organization, oldest first
+	// (GET /estate)
+	GetEstate(ctx echo.Context, params GetEstateParams) error
 	// Create New Estate
 	// (POST /estate)
 	PostEstate(ctx echo.Context) error
+	// Get an estate
+	// (GET /estate/{id})
+	GetEstateId(ctx echo.Context, id string) error
 	// Get dron plan for the estate
 	// (GET /estate/{id}/drone-plan)
 	GetEstateIdDronePlan(ctx echo.Context, id string, params GetEstateIdDronePlanParams) error
+	// Compute the drone plan of the estate in the background
+	// (POST /estate/{id}/drone-plan/jobs)
+	PostEstateIdDronePlanJobs(ctx echo.Context, id string) error
+	// Get a drone plan job
+	// (GET /estate/{id}/drone-plan/jobs/{job_id})
+	GetEstateIdDronePlanJobsJobId(ctx echo.Context, id string, jobId string) error
+	// Cancel a drone plan job
+	// (POST /estate/{id}/drone-plan/jobs/{job_id}/cancel)
+	PostEstateIdDronePlanJobsJobIdCancel(ctx echo.Context, id string, jobId string) error
+	// Stream changes of estate
+	// (GET /estate/{id}/events)
+	GetEstateIdEvents(ctx echo.Context, id string) error
 	// Get stats of estate
 	// (GET /estate/{id}/stats)
 	GetEstateIdStats(ctx echo.Context, id string) error
 	// Create New Estate Tree
 	// (POST /estate/{id}/tree)
 	PostEstateIdTree(ctx echo.Context, id string) error
+	// List the webhook subscriptions
+	// (GET /webhooks)
+	GetWebhooks(ctx echo.Context) error
+	// Subscribe to events
+	// (POST /webhooks)
+	PostWebhooks(ctx echo.Context) error
+	// Delete a webhook subscription
+	// (DELETE /webhooks/{id})
+	DeleteWebhooksId(ctx echo.Context, id string) error
+	// List the deliveries of a webhook subscription, newest first
+	// (GET /webhooks/{id}/deliveries)
+	GetWebhooksIdDeliveries(ctx echo.Context, id string, params GetWebhooksIdDeliveriesParams) error
+	// Replay dead deliveries
+	// (POST /webhooks/{id}/replay)
+	PostWebhooksIdReplay(ctx echo.Context, id string) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -95,15 +456,131 @@ type ServerInterfaceWrapper struct {
 	Handler ServerInterface
 }
 
+// GetAudit converts echo context to params.
+func (w *ServerInterfaceWrapper) GetAudit(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAuditParams
+	// ------------- Optional query parameter "estate_id" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "estate_id", ctx.QueryParams(), &params.EstateId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter estate_id: %s", err))
+	}
+
+	// ------------- Optional query parameter "actor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "actor", ctx.QueryParams(), &params.Actor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter actor: %s", err))
+	}
+
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", ctx.QueryParams(), &params.From)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter from: %s", err))
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "to", ctx.QueryParams(), &params.To)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter to: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetAudit(ctx, params)
+	return err
+}
+
+// PostBatch converts echo context to params.
+func (w *ServerInterfaceWrapper) PostBatch(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostBatch(ctx)
+	return err
+}
+
+// GetEstate converts echo context to params.
+func (w *ServerInterfaceWrapper) GetEstate(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetEstateParams
+	// ------------- Optional query parameter "after" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "after", ctx.QueryParams(), &params.After)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter after: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetEstate(ctx, params)
+	return err
+}
+
 // PostEstate converts echo context to params.
 func (w *ServerInterfaceWrapper) PostEstate(ctx echo.Context) error {
 	var err error
 
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
 	// Invoke the callback with all the unmarshaled arguments
 	err = w.Handler.PostEstate(ctx)
 	return err
 }
 
+// GetEstateId converts echo context to params.
+func (w *ServerInterfaceWrapper) GetEstateId(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetEstateId(ctx, id)
+	return err
+}
+
 // GetEstateIdDronePlan converts echo context to params.
 func (w *ServerInterfaceWrapper) GetEstateIdDronePlan(ctx echo.Context) error {
 	var err error
@@ -115,6 +592,10 @@ func (w *ServerInterfaceWrapper) GetEstateIdDronePlan(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
 	// Parameter object where we will unmarshal all parameters from the context
 	var params GetEstateIdDronePlanParams
 	// ------------- Optional query parameter "max_distance" -------------
@@ -129,6 +610,102 @@ func (w *ServerInterfaceWrapper) GetEstateIdDronePlan(ctx echo.Context) error {
 	return err
 }
 
+// PostEstateIdDronePlanJobs converts echo context to params.
+func (w *ServerInterfaceWrapper) PostEstateIdDronePlanJobs(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostEstateIdDronePlanJobs(ctx, id)
+	return err
+}
+
+// GetEstateIdDronePlanJobsJobId converts echo context to params.
+func (w *ServerInterfaceWrapper) GetEstateIdDronePlanJobsJobId(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// ------------- Path parameter "job_id" -------------
+	var jobId string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "job_id", runtime.ParamLocationPath, ctx.Param("job_id"), &jobId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter job_id: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetEstateIdDronePlanJobsJobId(ctx, id, jobId)
+	return err
+}
+
+// PostEstateIdDronePlanJobsJobIdCancel converts echo context to params.
+func (w *ServerInterfaceWrapper) PostEstateIdDronePlanJobsJobIdCancel(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// ------------- Path parameter "job_id" -------------
+	var jobId string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "job_id", runtime.ParamLocationPath, ctx.Param("job_id"), &jobId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter job_id: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostEstateIdDronePlanJobsJobIdCancel(ctx, id, jobId)
+	return err
+}
+
+// GetEstateIdEvents converts echo context to params.
+func (w *ServerInterfaceWrapper) GetEstateIdEvents(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetEstateIdEvents(ctx, id)
+	return err
+}
+
 // GetEstateIdStats converts echo context to params.
 func (w *ServerInterfaceWrapper) GetEstateIdStats(ctx echo.Context) error {
 	var err error
@@ -140,6 +717,10 @@ func (w *ServerInterfaceWrapper) GetEstateIdStats(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
 	// Invoke the callback with all the unmarshaled arguments
 	err = w.Handler.GetEstateIdStats(ctx, id)
 	return err
@@ -156,11 +737,117 @@ func (w *ServerInterfaceWrapper) PostEstateIdTree(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
 	// Invoke the callback with all the unmarshaled arguments
 	err = w.Handler.PostEstateIdTree(ctx, id)
 	return err
 }
 
+// GetWebhooks converts echo context to params.
+func (w *ServerInterfaceWrapper) GetWebhooks(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetWebhooks(ctx)
+	return err
+}
+
+// PostWebhooks converts echo context to params.
+func (w *ServerInterfaceWrapper) PostWebhooks(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostWebhooks(ctx)
+	return err
+}
+
+// DeleteWebhooksId converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteWebhooksId(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DeleteWebhooksId(ctx, id)
+	return err
+}
+
+// GetWebhooksIdDeliveries converts echo context to params.
+func (w *ServerInterfaceWrapper) GetWebhooksIdDeliveries(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetWebhooksIdDeliveriesParams
+	// ------------- Optional query parameter "status" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "status", ctx.QueryParams(), &params.Status)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter status: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetWebhooksIdDeliveries(ctx, id, params)
+	return err
+}
+
+// PostWebhooksIdReplay converts echo context to params.
+func (w *ServerInterfaceWrapper) PostWebhooksIdReplay(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	ctx.Set(BearerAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostWebhooksIdReplay(ctx, id)
+	return err
+}
+
 // This is a simple interface which specifies echo.Route addition functions which
 // are present on both echo.Echo and echo.Group, since we want to allow using
 // either of them for path registration
@@ -189,30 +876,126 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 		Handler: si,
 	}
 
+	router.GET(baseURL+"/audit", wrapper.GetAudit)
+	router.POST(baseURL+"/batch", wrapper.PostBatch)
+	router.GET(baseURL+"/estate", wrapper.GetEstate)
 	router.POST(baseURL+"/estate", wrapper.PostEstate)
+	router.GET(baseURL+"/estate/:id", wrapper.GetEstateId)
 	router.GET(baseURL+"/estate/:id/drone-plan", wrapper.GetEstateIdDronePlan)
+	router.POST(baseURL+"/estate/:id/drone-plan/jobs", wrapper.PostEstateIdDronePlanJobs)
+	router.GET(baseURL+"/estate/:id/drone-plan/jobs/:job_id", wrapper.GetEstateIdDronePlanJobsJobId)
+	router.POST(baseURL+"/estate/:id/drone-plan/jobs/:job_id/cancel", wrapper.PostEstateIdDronePlanJobsJobIdCancel)
+	router.GET(baseURL+"/estate/:id/events", wrapper.GetEstateIdEvents)
 	router.GET(baseURL+"/estate/:id/stats", wrapper.GetEstateIdStats)
 	router.POST(baseURL+"/estate/:id/tree", wrapper.PostEstateIdTree)
+	router.GET(baseURL+"/webhooks", wrapper.GetWebhooks)
+	router.POST(baseURL+"/webhooks", wrapper.PostWebhooks)
+	router.DELETE(baseURL+"/webhooks/:id", wrapper.DeleteWebhooksId)
+	router.GET(baseURL+"/webhooks/:id/deliveries", wrapper.GetWebhooksIdDeliveries)
+	router.POST(baseURL+"/webhooks/:id/replay", wrapper.PostWebhooksIdReplay)
 
 }
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/9RWTW/rNhD8K8K2R/lJ/oiLp+Nr08CHBkGSWxAUtLi2GEikQq4SG4b/e0FStiNbihPU",
-	"bvNusrVczuwMOVpBqopSSZRkIFmBSTMsmHu81FrpWzSlkgbtH6VWJWoS6F4XaAybuxe0LBESMKSFnMN6",
-	"HYLG50po5JA8bAsfw02hmj5hSrAO4dIQI/xDK4k3OZPdu3FhiMnUvcEFK8ocIRnE8bankIRz1OA2N3TY",
-	"YdFY2m9buDxWssdsAXbNIa+9si327gnc4nPVijpHOaesiSsOoWALUVQFJBdxbIdQCOl/t/J6Ffxf9thj",
-	"VKPadH6PV5eggjcAAUvH32cjPu6xcTrujS5Gv/Wm/cH3HhumQzaexhcsnkF4xGqCv4PljhiZbkCpqiQ1",
-	"MLWaq2CLDxQhF0x21MmqmNZlQh7rtUfQg/Qo/PrtZt3E7zV2GyxDMc+avIdvzTE86q7FobPerV9+qr71",
-	"yIUb2MdIf9J9feu+wUndZ4uEnCl3mEWKNRzJClv11+TeQiZBDoa7CHt37FVYkV9QG6EkJND/Fn+LbaEq",
-	"UbJSQAJD91cIJaPMUYrQ8XZclVfaMmYklJxwSOBGGfKzAY8bDf1QfOnNLwm9/VlZ5iJ1y6Ino+QuFezT",
-	"rxpnkMAv0S42ojozouZVtm6Oh3SF/m52kjjIg7h/8s1rxd3uHE2qRUl+iL4iSDUyQh6YKk3RmFmV50s7",
-	"2lEcnw5MIzpbsPxgPNDbQYVgqqJgegkJ/O7gBdf4GtRi2fe1uNFK8HXEnUvK3N8wc2zR+gprqSd8G67O",
-	"LJoVSKgNJA8rsNePMxCEG0MKDvuqhW9IHxyAuslzhXq561Kwxd/b1GtZvzvdjweWiE9sicOPixY97rwZ",
-	"vA9G/50PrhUFf6pK8j0XXCEFVubAqhzMlA4owwA7DGEfzUe84CLwHD44v4zN9P5JJHTCBGrWqRxp/NCN",
-	"PeE2z86m3Lmy4O2Xx/+SB42vgO5MsGVfMBi+iJMPIsnNy3cwqF82Zqx0DglkRGUSRblKWZ5ZW68f1/8E",
-	"AAD//42WkrxmDgAA",
+	"H4sIAAAAAAAC/+x9a3PjNtLuX0HxnKqzW0XJ8nVmnDoflLEmUdZje21NsvtGKREiWxbGFKAAkG1tyv/9",
+	"rW6AFCmRlufmmWz4xRZJsAE0Gn150AD/CGI1mysJ0prg+I/AxFOYcfrZXSTC9qTVy0swcyUN4N25VnPQ",
+	"VgCV4bEVSuKvBEysxdxdBucSmJqwKNbALUQhixbzxP/ScKtu6FfMZQwp/koghezpPOXLiHGZsEjM5krb",
+	"qB2EAdzz2TyF4DhwNIMwsMs5XhurhbwOHkJsjdLYmFVhPhc3sDzujI8m+/EutPaSA946mLyE1qv4xbh1",
+	"yHeTPdifHPDDcSXFiQWiyJNEYN94elFggNULCNe6PpgCA2mFXTJ6m9kpsDstLHzH+NiAtGyiNKMeCyVN",
+	"e1WvGr+H2GK9Y5goDR9dsXu9pmbiX23N9BSSEbdY+0TpGf4KcPBaVswq+e5qHYkEX6l76u7XCYrS11yK",
+	"/1DDUAzAWC8uVgP953MxuoEl/pynXI7eqzFJyVBGdzCeKnUzMotxTjtqD2VJbJBOZeOpJt/4Co7SY2Jl",
+	"ztxUyWvDrAqZmDAul+0qulUE+xLZa8CwO2GnDG5BL5GsJhI5s4W0RwcrmkJauAaNRDX8vgBja1sb/at1",
+	"6Yq0+icRchYb7t9idsotm/GkIBkVTffVCA1JcPwr9iObWWE238uDWhSAkgD9ViFfpFVO1XW9TkF++J/C",
+	"wox+/F8Nk+A4+D87K22141XVToWeesgr5lrz5UafsiqqGvg9t/H0fA6aV6s2r9NGXkKZ5TdgWHQnEjv1",
+	"aisFeW2n0XeZ/huRDLuSQxnlEhcxpTNRH2mYoGzf459M/01BXE9t9F2mPtcI4VVOhi6QCL3K5ZLmVQ05",
+	"p28duaH0Xagm5+bR2hAV50zdjKIhqxRSaqXCqcOA61SAZmtcLWt8d7PFq6aZ61NJ6e93wmDG78VsMfMX",
+	"QrqL3ao55UarRGG3SOGw0+lsJaLm28S0LFcDpEBSWcGkMz4D41SO0z5KM6sBmJ9bIenxlFvQTGUUUSEx",
+	"DRPQzKpa/s34/anv797hYQU/vRBUDmwmEx8+rCRolWqS5s0nMv9+8/1Hyy8/qPya7lDzJ6iNSzCL1Fbo",
+	"Nq2V3iYpPSxU1GVbbFMmHajQLciQ3U1BkvjkwsHMIo4BEkjKksHjo1eTg+SoxY/io9bB4cGL1nh371WL",
+	"78f7/GjcOeSdSdWoYcUL82Eif+XeWeenJ7Wdp1d5nWsKmY+VtoB6azUX7kADk8oyPp+nAhI2hpgvDDAu",
+	"lZ0W5w1Tk6G0UxCacatmImZjrJZNuEhxqikdMiEZ97fJhgrjaFP5MKM9lFwW6NopLFkCc5AJU9LTY9ww",
+	"YdkdN9lweT9FovT9GuTjhP4AvYFW13Uw+G19JMLgvoVvtm65lqg0kARx7apAh268yYjRVTejuMHlgXfT",
+	"sgaV9HJu3kfemyqYpSAMClZls61ZTd4/qYgliJtufCecps+Ep2bDz+3O5+nS+06FUdRMKln0Z8ZKpcCl",
+	"086+2NO9ijJXkMiM3/fdm7uZTsqut7gbheprxbzeH9KkSz625V4TbfOIskqq2kf66LVKKtz3K8vHKbAZ",
+	"j6dCQksDT+gG6TkWq4QGJJOl/tnP3dP+yeiy9893vatBEAZ03R30z89Gb7r9095JEAbvzrrvBj+eX/b/",
+	"hy7fnF9+3z856Z0FYXD+bjA6fzP6/vzd2clVEAYXp+eD0fnr1+8u+lS2dzXoDnqjs/PB6A2WCcKg+Ptt",
+	"b/Dj+Qk97p6env9C77w+P3tz2n+Nremf9N5enA96Z6//PfpH79+j/tno4vL8h8ve1VXF08veuyuikHXr",
+	"Tf900LvEZnX/fXrePRkNzs9Hp93LH3pBGFxiw077b/sDeueqd/lz/3Vv9O6s+3O3f9r9/rRHlAa9y7Pu",
+	"6ah3eXl+WTmHaDBOwHKRborKRECalANg7x9VUJqBMfwaysVnC2PZGNgY7B2AZLvkOe53toYJruoV1VpB",
+	"qhf02IvYVttIsviAegHZ8PSZUeTdxoSoYUislE6EJDu7sOjejNVCJlUMLQdnKxL7/zM+ve+Y3X/+5/D+",
+	"59/3rvu749+PFnsX89kP/zm43Y238pYYs4W1pJ9d3x7h8EcE9+u9+ViXocLN3utUeWe3oE1l5PX9YjYH",
+	"sqVO+cdTLq8B3d6yqyysIYfIlHyd/ScF2NXu6BaXkOJe92bez1U/tobEfuy0knCRclk/fIkwlssY1pjY",
+	"qUYKqmzsmqf8BOd4a+fvA3xns19rxfK213PgVBhb33k3vh8w2atmxFZgwFdS38ha9+WzhJGfHg2tdSiX",
+	"Rkf5sX7VMf7zKIDNKVPfFvT0zWN2YiHLEX/lDJjx+ycUgkRwWVNOLmZjX0zIbbQ2dDY20rXCvZ9XVt/x",
+	"gYZ6Aft0pONZ42SnF3KAZlunP1D6dlH69j6z9KH2/UmNa0dgxu9HlTrYBwTZSLzqdF7svnq1d3jw4qDz",
+	"6tVugW/VglPfks9pyXPwoWxWf5kuyYC+V2MfpFahRxTwTupcnxIg+Om+wkRIYaaf6Kgcjju4rrPX2o8P",
+	"oHUw2T9svUoO91t7k6N4N34Bu/xlJZ0to7w5TeZaXWswFdDEG+0A8wyI96zE9QtJMJ5WM9ZBD2a3xPNO",
+	"+7BCCekcVHqC3dvwJhxqoz9UaJ6G9Hh5rYF4yD9aiUhOtMC6rU5SuYYCQPH7AhYEbOiFlA4UqQRR3GJj",
+	"WgWjPITBL24J6QRSgb7l465I4kp9yCLFGv0nOySFqqq4Uke2AlyxMJs7FGFTgj9Gm/iWfeBbcAvS1i4c",
+	"0MNsofAJ7OzhCxmUXkM05caOctW3Ug8LCfdziC0kzIkjO+zssyvQtyIG9k7yWy5SBDOCsIaoe22Uha05",
+	"6cPOfpWakHBvR34cPM/WFTEgzjgHmQh5zTyDl4g20uItEiitE36GibsmQI9O4GzoSgNVmMy5jG2dzNW1",
+	"biK7nhURW00DxjUQO0JCJjRYjfCuW0+9d90TPGVjHt+oySQcyoW0InVoLL4b5YJLC116IbPAPms/EfZF",
+	"eRKVAVrfpKAwAeg3f1SvrAR1s5NWA7Q9w7IF77YDVhO/cEe3HLqaRCzmWjuTjfdDDykLs55vgN1DoNln",
+	"A2BYnGUdtB1N03YZFkg1FcaanKohhrDxIr1hrkzIFnOyVJ1Oh81BMxICqj36Y7hS78PgmLXb7ZANAyKE",
+	"17+22+3fHiIE0o0Fnjji5Z47ekSZ7hd47mhnJQO3ELV+6TmWXXpu+ctVRx8bpUvKPal1/7IJORJJWfFX",
+	"uRArqHgbUlw3O7LW1OPC+Bwq1obOyGVAJqNcFiePM5aMX3Mh28FWHz6v4pFJfFVIunjcbBbTMz7Ychar",
+	"ebL1LNf4xE7Ujj8J6Ac3vGSjHpGEMFjodHMsuxJzd1S6sMCm1s5xUuN/w95dnrK7qTLApspYpsGo9BaM",
+	"04tSWZysnKVKzVEXhmyuxS23EDIz5RoS9rfXP5x1B38P2Vl3cHQQslTIm1aqYp5iHQtp5hCLCSpXniQa",
+	"TBlOC6gRxzs7oOdtf7cdq9kO9tnsJFpJaBl+J+zaqnPn4OW28AwZEWbsfvKofdZQ6dOHen1018OTzuTl",
+	"hCeH41byKh63Do5eTVp89+iw9aLz8ujFi72Xrw471XYdYg0V3sOVuJZOf6/me5udy3TJNNiFlg46zbK/",
+	"yoN5NzUQjw4ne+N2u3KZ3svmxw3/ExKMSkO+xX9wTFhoYZdXOABumLtz8Q9YdhcOPxPIkinwBHQQBpLP",
+	"kMC/Wt2LfusfsFy1idNbtAoHXIPO3h/T1ZtMWH76BdeHaLhpaZGerqggM4IHbJiQE4XvpyIGL42+8rf9",
+	"AYmFsMQ/CtBaV55DOeYd7LY77Y5fs5R8LnD9gG6FwZzbKXV1h2PKE/66rpKEHnmNzgWgbC8l2RimPJ04",
+	"uxvzNAXN/uZEQV6zYvKdCYfSo6Ch8wRC1r3osxtYmpAiVwQKnI/k8+5YSc2in5AQXVwPT7zLIa8zYpT+",
+	"ideOog/L8AbSDfPyQ1ki6709tEcF51iA+ftQCsM0xEonmSOIs8AvB0zKqXvkCSEp5ysJ22Y9lww2lDGX",
+	"qDbH4FcWEpblaULinMB8IbefBMfBD2Ap94yGRvMZWNC4Ar+Z45i6LD9yX8fobdqpMBkaQJzErIJVYiG2",
+	"KwidEP++AL1cyXApjCb1U+GBPISPtoGYgv5ibJUOGbSv2yzymbLDRaezH4uE/oPLBXt/Z/19sxj7B3Xt",
+	"yzIFP7Zt+UByi3VnLq0wDHV1Xa0IpJQqfYqaf2pL8mzaLY2w6qOaUEUqFTNhS9TynIjdEtS4u31B4DdC",
+	"jsg4kvbY63QCQtGlBYejU45MTIK98964pbdVzVtzL4vZnKQE1wwTojHGoE47+IxVr2VJbdb7PU+yzFdX",
+	"9+7z1f1WGOM0KxPylqciQcObuNjUs2L/+ZtjYuWck4O9V89X+yVqOZJoBvcOmPuOAvdihnx0iTdaXbwR",
+	"MQOxkokJQm/DSXALJcqt2xB5bMPhc8paX1rQkqfMgL4F7fJfAmrFMw5zBl4tCuAVljKL2YzrZXAcYIBG",
+	"7CYPgqXqOrOQRR8gZBLuwFg2Edo4h2uHst/IuVamwue4XEjDolWiU8SEZKg+Nf2QwKzm0jg43JscrNYs",
+	"9C0sDeNDOVNjkQIm7LFYpamD5tRkkgoJbdYtptblnguXhXV/PpRWA/o8S4Z6lBLt0B2IXG41Js2WsiJN",
+	"7gUUc2eH0r1UyMteS7AuohiS9U/aQzmUvbWEtCk3jDOH2VMGIXUXm0VsaTOC+yKX9RaFDISdgh7K9cQ2",
+	"tNM+h9HnuDFhjokaDQ82HUFuLBhNCO2OnOdFfUWihuU5km12jnfuhIF1XlBmI1HiGlgKEzuUaoEID7ol",
+	"mWOlDDhOafLpKPdi5mLOQn1Iwbe6PZSlscMaaOgy1CpPWyWh4C6XdaZuscOSYth5qix2iYi751apm5BR",
+	"r8x6R+IpxDcZymEsEzbE/Ncp42Youc+bdnBRJv00LoQbDWU3S9VctTlR4DI+iT2FLRWYayviKfF+r9OJ",
+	"sFfELawKy7mk0Vgt0gShRpKUK6AEfRb1E5jNlQUZLzEqiZhTdjkn3r3rn4RONFFZGj6BdHmMcu5urHxd",
+	"bP4NApwyYWOVLEN6JiTbO2BTtdCGjZfMuxChd6PNSoaQoFMrjmapcbZ16WGgY2b1ArJmVrnEF8pYyj8M",
+	"8nSo71Wy/GwqsJRB+lCOJLFtD1/Q1ymnaVao38EU/HxHsVqbx4378y25P51ndH+62TCszdfN6S8MM1ak",
+	"KVqLfGWUvLW952vuYFrVNFxHWJgsvuYsEZMJaJC2JGNf2auk9RzPa9SCaCVYyvU1sL9FG0m5Uaa8SZkS",
+	"qJCreXH/98ZF/Tou6uVitdkC1egKJCHDv7Lz9N6Oe16LhV1w4+xcRBvbnLXH1duMMCFic34NtJDF3dha",
+	"xa7BOcq46DqUWKAGAuplOyOegAFRUZNt4yqCG8r7uP2T0g4TJTMXZSgdbPf/TE7mWtyCIRd4NrdLtmpj",
+	"JS6DdW3DZf7MUERFDmkDRjRgRANGfPtgRKbQKqEIlSYFKCKswR/+y2Oq3Mh8iaCqnNj+pKhq97NXXi9I",
+	"rkRuNI3T4JNFmi4bNd4EVU1Q9WWtY0hR1AwPlKBVfVoDNkVuNmHXf4Exfk3qlZ3BHfPGphBe7fwhkodC",
+	"jFUTBPWTzTCIQgrMXFhFFLR2XbYwj8UlXz5oWN8ktiVsaHR+QecfPF/tZ8qyN7QDpQka/rJ66gewq1W/",
+	"DR3lE8/QRNVCQh6md655zLVLy5Es6g34deTgF5cAZOgkFbj1A1zaYezWGo0v2WYUgAhLeedoHKP+pHWG",
+	"bXmLSFYOKUX7nQOGgvxWJZRcGQ3l3RTXPQs1CMMW0hFOHkWd+km+z+dLaN4aRKi0Qar4/qO73J5BjW/u",
+	"earX5KV5iSNfOSFzViChfafsNpXSzI8lM0LGbiTXh59qaKKVxnJ9G6sk21z6xrp9PeuGQ0IjkiesbDd2",
+	"O5g7W5+d80/c9uGwKSLsd04ttDSUZyljaLMLlab5RmDukLksc3woo1PlmBIxt6FKWJNtnMPsh3zPJW5h",
+	"8mkolDaT77vEjVQ/qbHfwWWVhiRLi0m45WNuIGRGuZ1amBckbslQW66teRwYK9hBrOGLRSGfH3tb23L+",
+	"4NG3kp3c+/y11cvoT2rM8i21hcmdDf92M9mYuMbENYkAjS38ZERKzeYLf/Tyyk1ZO0rBGxCMuq61E78t",
+	"VnLnj/dqPHoinlUyKz+pcT/5knFWmYhr5jcDlT3BdjSL640RaBC6JoaxuJa10tjv1fjpWnnHxQv1sUw3",
+	"28SOYYowLI8vVpFMl/mzYLIyxqr5HHdyLR10d6f0DWhmpkrb1EuIy2EvRDXG4np+5EllYY+dZkf2PjEY",
+	"Iavx2nWqsR21cuU4lBL1fOtdY0kaS/LcmQWDDAFJNfBkybKzyBqT9hcORUg5PcGqrc6NqFx66rLIwr11",
+	"xVrGauCzPCs5W3Py59nmp/Csoh3awjaUuPOdJdxMx4rrxLRZj8dTf3yOMBsH62wcKYQ01o4Uci+700QQ",
+	"jGPCL4nlJzTS2RYn3PLIG0ozlBOVpurO2VXOIixrqmk5LHGiAWfSUEYV53xGbTagbfvIFKbmIE2WF1Oi",
+	"TFuoBoWMbv8Vnaz7WJU/TmE9VrRsjMbO+HzqVOArVrXZJc4bCXGeTcQtE4RORqfc2BZ1Hr+oQ8cdjbW6",
+	"M6ANS1Q4lJRKGAOOCdWFRQ2bCWMcvpmdpF34JpPv47XCAUZv4rWazei17HArVrUmKJKUTju+AZjTI99o",
+	"oeRQqnm1X1IIZnvZ6RpfIUVjQ+y34IhNAkYT3jW28Fu0hVdOeWXWKt+is2kM8af5a6RhkCX7cya/lYzw",
+	"N5kx0aj7Rt03WQvfOOJHyv4xa2A1QD2q95fYv9NPBu5DWX+W7ITNrxJ8ld1BpS8E1O8QGmhotgk1Juzb",
+	"gBEvUmVzDNGdBeROa1aa8Uw4ms1LzealxgH5ApuXyBZQoR1/SKl5LN3jl6zMF4y2th2X3YBezYEBzTR/",
+	"2oEBlQcP1x8O4IDvquMFCHC/OL8aQIIxRbTQKZ3h9tPV+Vnm9g9l9K+Wn70tPGWb24WGY2b/vzsTdyHF",
+	"fTbkdAfC213/bAr32UG6WRxzNwUNLLrdjWgNBVv049vu69bVj929w6MQQxa3ouKOR1x1kLmTv0P6YHFU",
+	"V3fbPUBTlp3U69ZV6GVGR80UjwTH1tRFLSW1+Pmji0fO3n/mMOPRbwxU6eXCqPiAo4kxGgPR5B//Vy10",
+	"uFk+pjVXv7Bf8ijzXfFuGb3i8LHyl5zEaj2/pNfRBiWaMtTaG3r4hGhnmvi5dthXwPYlpZd9XadRPA0+",
+	"3/jEX1NLOf3AeKVPXKGvdsofL9wWEfeTk1X559tinn9T7oP8t82v2H3wkYZ7aycafsUDDR/7JGWz+aIx",
+	"B405aMxBPURS9jmrzUPVdx7KxsKtij6+bOvP16VvZW51dfFI/jBbMqB8UGYAPzSw+vzmsf/KQCqMdSmU",
+	"UfHLi5FbQklTX8GMqKmFxTVgDLa2oRn9xK3d/onWYSu/T1m5V7zzpeqsF8qT6s9LNoaoMUQNFNNYtc9y",
+	"JjwpofWv2QYPxU9BkgYvfgTy19/Q/y9+1vHX31BBu746jU+ftaTPNx7v7NC3T6fK2OOXnZedndvd4OG3",
+	"h/8dALndxuMhmwAA",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,1018 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: dronesawit/v1/dronesawit.proto
+
+// The gRPC API of the drone-sawit service. It serves the estates, trees,
+// stats and drone plans of the REST API, with the same rules, credentials
+// and scopes; see the REST endpoint named on each RPC.
+
+package dronesawitv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Estate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id     string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Width  int32  `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Length int32  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	// version changes whenever the estate or its trees change.
+	Version int64 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (x *Estate) Reset() {
+	*x = Estate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Estate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Estate) ProtoMessage() {}
+
+func (x *Estate) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Estate.ProtoReflect.Descriptor instead.
+func (*Estate) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Estate) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Estate) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Estate) GetLength() int32 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *Estate) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type CreateEstateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// width and length are between 1 and 50000.
+	Width  int32 `protobuf:"varint,1,opt,name=width,proto3" json:"width,omitempty"`
+	Length int32 `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+}
+
+func (x *CreateEstateRequest) Reset() {
+	*x = CreateEstateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreateEstateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateEstateRequest) ProtoMessage() {}
+
+func (x *CreateEstateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateEstateRequest.ProtoReflect.Descriptor instead.
+func (*CreateEstateRequest) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateEstateRequest) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *CreateEstateRequest) GetLength() int32 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type GetEstateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *GetEstateRequest) Reset() {
+	*x = GetEstateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetEstateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEstateRequest) ProtoMessage() {}
+
+func (x *GetEstateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEstateRequest.ProtoReflect.Descriptor instead.
+func (*GetEstateRequest) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetEstateRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Tree struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id       string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	EstateId string `protobuf:"bytes,2,opt,name=estate_id,json=estateId,proto3" json:"estate_id,omitempty"`
+	X        int32  `protobuf:"varint,3,opt,name=x,proto3" json:"x,omitempty"`
+	Y        int32  `protobuf:"varint,4,opt,name=y,proto3" json:"y,omitempty"`
+	Height   int32  `protobuf:"varint,5,opt,name=height,proto3" json:"height,omitempty"`
+}
+
+func (x *Tree) Reset() {
+	*x = Tree{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Tree) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tree) ProtoMessage() {}
+
+func (x *Tree) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tree.ProtoReflect.Descriptor instead.
+func (*Tree) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Tree) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Tree) GetEstateId() string {
+	if x != nil {
+		return x.EstateId
+	}
+	return ""
+}
+
+func (x *Tree) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Tree) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *Tree) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+type CreateTreeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EstateId string `protobuf:"bytes,1,opt,name=estate_id,json=estateId,proto3" json:"estate_id,omitempty"`
+	// x and y are at least 1 and within the estate.
+	X int32 `protobuf:"varint,2,opt,name=x,proto3" json:"x,omitempty"`
+	Y int32 `protobuf:"varint,3,opt,name=y,proto3" json:"y,omitempty"`
+	// height is between 1 and 30.
+	Height int32 `protobuf:"varint,4,opt,name=height,proto3" json:"height,omitempty"`
+}
+
+func (x *CreateTreeRequest) Reset() {
+	*x = CreateTreeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreateTreeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTreeRequest) ProtoMessage() {}
+
+func (x *CreateTreeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTreeRequest.ProtoReflect.Descriptor instead.
+func (*CreateTreeRequest) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateTreeRequest) GetEstateId() string {
+	if x != nil {
+		return x.EstateId
+	}
+	return ""
+}
+
+func (x *CreateTreeRequest) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *CreateTreeRequest) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *CreateTreeRequest) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+type GetStatsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EstateId string `protobuf:"bytes,1,opt,name=estate_id,json=estateId,proto3" json:"estate_id,omitempty"`
+}
+
+func (x *GetStatsRequest) Reset() {
+	*x = GetStatsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsRequest) ProtoMessage() {}
+
+func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetStatsRequest) GetEstateId() string {
+	if x != nil {
+		return x.EstateId
+	}
+	return ""
+}
+
+// Stats are zero for an estate without trees.
+type Stats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Count  int64   `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Max    int32   `protobuf:"varint,2,opt,name=max,proto3" json:"max,omitempty"`
+	Min    int32   `protobuf:"varint,3,opt,name=min,proto3" json:"min,omitempty"`
+	Median float32 `protobuf:"fixed32,4,opt,name=median,proto3" json:"median,omitempty"`
+}
+
+func (x *Stats) Reset() {
+	*x = Stats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Stats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stats) ProtoMessage() {}
+
+func (x *Stats) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stats.ProtoReflect.Descriptor instead.
+func (*Stats) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Stats) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Stats) GetMax() int32 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *Stats) GetMin() int32 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *Stats) GetMedian() float32 {
+	if x != nil {
+		return x.Median
+	}
+	return 0
+}
+
+type GetDronePlanRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EstateId string `protobuf:"bytes,1,opt,name=estate_id,json=estateId,proto3" json:"estate_id,omitempty"`
+	// max_distance, when set, limits how far the drone may fly; the server
+	// default applies otherwise. It is not negative.
+	MaxDistance *int64 `protobuf:"varint,2,opt,name=max_distance,json=maxDistance,proto3,oneof" json:"max_distance,omitempty"`
+}
+
+func (x *GetDronePlanRequest) Reset() {
+	*x = GetDronePlanRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetDronePlanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDronePlanRequest) ProtoMessage() {}
+
+func (x *GetDronePlanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDronePlanRequest.ProtoReflect.Descriptor instead.
+func (*GetDronePlanRequest) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetDronePlanRequest) GetEstateId() string {
+	if x != nil {
+		return x.EstateId
+	}
+	return ""
+}
+
+func (x *GetDronePlanRequest) GetMaxDistance() int64 {
+	if x != nil && x.MaxDistance != nil {
+		return *x.MaxDistance
+	}
+	return 0
+}
+
+type Point struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X int32 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y int32 `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+}
+
+func (x *Point) Reset() {
+	*x = Point{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Point) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Point) ProtoMessage() {}
+
+func (x *Point) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Point.ProtoReflect.Descriptor instead.
+func (*Point) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Point) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Point) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+type DronePlan struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// distance is the total distance flown, including take-off and landing.
+	Distance int64 `protobuf:"varint,1,opt,name=distance,proto3" json:"distance,omitempty"`
+	// rest is where the drone lands, when a maximum distance applies.
+	Rest *Point `protobuf:"bytes,2,opt,name=rest,proto3" json:"rest,omitempty"`
+}
+
+func (x *DronePlan) Reset() {
+	*x = DronePlan{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DronePlan) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DronePlan) ProtoMessage() {}
+
+func (x *DronePlan) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DronePlan.ProtoReflect.Descriptor instead.
+func (*DronePlan) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DronePlan) GetDistance() int64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+func (x *DronePlan) GetRest() *Point {
+	if x != nil {
+		return x.Rest
+	}
+	return nil
+}
+
+type Waypoint struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X int32 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y int32 `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	// altitude is the height of the drone above the ground; it is 0 when
+	// the drone lands.
+	Altitude int32 `protobuf:"varint,3,opt,name=altitude,proto3" json:"altitude,omitempty"`
+	// distance is the distance flown to reach the waypoint.
+	Distance int64 `protobuf:"varint,4,opt,name=distance,proto3" json:"distance,omitempty"`
+}
+
+func (x *Waypoint) Reset() {
+	*x = Waypoint{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Waypoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Waypoint) ProtoMessage() {}
+
+func (x *Waypoint) ProtoReflect() protoreflect.Message {
+	mi := &file_dronesawit_v1_dronesawit_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Waypoint.ProtoReflect.Descriptor instead.
+func (*Waypoint) Descriptor() ([]byte, []int) {
+	return file_dronesawit_v1_dronesawit_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Waypoint) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Waypoint) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *Waypoint) GetAltitude() int32 {
+	if x != nil {
+		return x.Altitude
+	}
+	return 0
+}
+
+func (x *Waypoint) GetDistance() int64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+var File_dronesawit_v1_dronesawit_proto protoreflect.FileDescriptor
+
+var file_dronesawit_v1_dronesawit_proto_rawDesc = []byte{
+	0x0a, 0x1e, 0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2f, 0x76, 0x31, 0x2f,
+	0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x0d, 0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x22,
+	0x60, 0x0a, 0x06, 0x45, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x77, 0x69, 0x64,
+	0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x77, 0x69, 0x64, 0x74, 0x68, 0x12,
+	0x16, 0x0a, 0x06, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x06, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x22, 0x43, 0x0a, 0x13, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x45, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x77, 0x69, 0x64, 0x74,
+	0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x77, 0x69, 0x64, 0x74, 0x68, 0x12, 0x16,
+	0x0a, 0x06, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06,
+	0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x22, 0x22, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x45, 0x73, 0x74,
+	0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x67, 0x0a, 0x04, 0x54, 0x72,
+	0x65, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02,
+	0x69, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x65, 0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x69, 0x64, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x65, 0x73, 0x74, 0x61, 0x74, 0x65, 0x49, 0x64, 0x12,
+	0x0c, 0x0a, 0x01, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a,
+	0x01, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x01, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x68,
+	0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x68, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x22, 0x64, 0x0a, 0x11, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x54, 0x72, 0x65,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x65, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x65, 0x73, 0x74,
+	0x61, 0x74, 0x65, 0x49, 0x64, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x01,
+	0x79, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x22, 0x2e, 0x0a, 0x0f, 0x47, 0x65, 0x74,
+	0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09,
+	0x65, 0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x65, 0x73, 0x74, 0x61, 0x74, 0x65, 0x49, 0x64, 0x22, 0x59, 0x0a, 0x05, 0x53, 0x74, 0x61,
+	0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x61, 0x78, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x6d, 0x61, 0x78, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x69,
+	0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x6d, 0x69, 0x6e, 0x12, 0x16, 0x0a, 0x06,
+	0x6d, 0x65, 0x64, 0x69, 0x61, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x02, 0x52, 0x06, 0x6d, 0x65,
+	0x64, 0x69, 0x61, 0x6e, 0x22, 0x6b, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x44, 0x72, 0x6f, 0x6e, 0x65,
+	0x50, 0x6c, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x65,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x65, 0x73, 0x74, 0x61, 0x74, 0x65, 0x49, 0x64, 0x12, 0x26, 0x0a, 0x0c, 0x6d, 0x61, 0x78, 0x5f,
+	0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00,
+	0x52, 0x0b, 0x6d, 0x61, 0x78, 0x44, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x88, 0x01, 0x01,
+	0x42, 0x0f, 0x0a, 0x0d, 0x5f, 0x6d, 0x61, 0x78, 0x5f, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63,
+	0x65, 0x22, 0x23, 0x0a, 0x05, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x01, 0x79, 0x22, 0x51, 0x0a, 0x09, 0x44, 0x72, 0x6f, 0x6e, 0x65, 0x50,
+	0x6c, 0x61, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x12,
+	0x28, 0x0a, 0x04, 0x72, 0x65, 0x73, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e,
+	0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f,
+	0x69, 0x6e, 0x74, 0x52, 0x04, 0x72, 0x65, 0x73, 0x74, 0x22, 0x5e, 0x0a, 0x08, 0x57, 0x61, 0x79,
+	0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x01,
+	0x79, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x6c, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x08, 0x61, 0x6c, 0x74, 0x69, 0x74, 0x75, 0x64, 0x65, 0x12, 0x1a, 0x0a,
+	0x08, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x08, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x32, 0xc3, 0x03, 0x0a, 0x0a, 0x44, 0x72,
+	0x6f, 0x6e, 0x65, 0x53, 0x61, 0x77, 0x69, 0x74, 0x12, 0x49, 0x0a, 0x0c, 0x43, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x45, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x22, 0x2e, 0x64, 0x72, 0x6f, 0x6e, 0x65,
+	0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x45,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x64,
+	0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x73, 0x74,
+	0x61, 0x74, 0x65, 0x12, 0x43, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x45, 0x73, 0x74, 0x61, 0x74, 0x65,
+	0x12, 0x1f, 0x2e, 0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x47, 0x65, 0x74, 0x45, 0x73, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x15, 0x2e, 0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76,
+	0x31, 0x2e, 0x45, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x43, 0x0a, 0x0a, 0x43, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x54, 0x72, 0x65, 0x65, 0x12, 0x20, 0x2e, 0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61,
+	0x77, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x54, 0x72, 0x65,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x64, 0x72, 0x6f, 0x6e, 0x65,
+	0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x65, 0x65, 0x12, 0x40, 0x0a,
+	0x08, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x1e, 0x2e, 0x64, 0x72, 0x6f, 0x6e,
+	0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61,
+	0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x64, 0x72, 0x6f, 0x6e,
+	0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12,
+	0x4c, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x44, 0x72, 0x6f, 0x6e, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x12,
+	0x22, 0x2e, 0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76, 0x31, 0x2e,
+	0x47, 0x65, 0x74, 0x44, 0x72, 0x6f, 0x6e, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74,
+	0x2e, 0x76, 0x31, 0x2e, 0x44, 0x72, 0x6f, 0x6e, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x12, 0x50, 0x0a,
+	0x0f, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x57, 0x61, 0x79, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x73,
+	0x12, 0x22, 0x2e, 0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x47, 0x65, 0x74, 0x44, 0x72, 0x6f, 0x6e, 0x65, 0x50, 0x6c, 0x61, 0x6e, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69,
+	0x74, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x79, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x30, 0x01, 0x42,
+	0x49, 0x5a, 0x47, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x64, 0x69,
+	0x6d, 0x61, 0x73, 0x73, 0x61, 0x6e, 0x74, 0x6f, 0x73, 0x6f, 0x2f, 0x64, 0x72, 0x6f, 0x6e, 0x65,
+	0x2d, 0x73, 0x61, 0x77, 0x69, 0x74, 0x2f, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64,
+	0x2f, 0x64, 0x72, 0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x76, 0x31, 0x3b, 0x64, 0x72,
+	0x6f, 0x6e, 0x65, 0x73, 0x61, 0x77, 0x69, 0x74, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_dronesawit_v1_dronesawit_proto_rawDescOnce sync.Once
+	file_dronesawit_v1_dronesawit_proto_rawDescData = file_dronesawit_v1_dronesawit_proto_rawDesc
+)
+
+func file_dronesawit_v1_dronesawit_proto_rawDescGZIP() []byte {
+	file_dronesawit_v1_dronesawit_proto_rawDescOnce.Do(func() {
+		file_dronesawit_v1_dronesawit_proto_rawDescData = protoimpl.X.CompressGZIP(file_dronesawit_v1_dronesawit_proto_rawDescData)
+	})
+	return file_dronesawit_v1_dronesawit_proto_rawDescData
+}
+
+var file_dronesawit_v1_dronesawit_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_dronesawit_v1_dronesawit_proto_goTypes = []any{
+	(*Estate)(nil),              // 0: dronesawit.v1.Estate
+	(*CreateEstateRequest)(nil), // 1: dronesawit.v1.CreateEstateRequest
+	(*GetEstateRequest)(nil),    // 2: dronesawit.v1.GetEstateRequest
+	(*Tree)(nil),                // 3: dronesawit.v1.Tree
+	(*CreateTreeRequest)(nil),   // 4: dronesawit.v1.CreateTreeRequest
+	(*GetStatsRequest)(nil),     // 5: dronesawit.v1.GetStatsRequest
+	(*Stats)(nil),               // 6: dronesawit.v1.Stats
+	(*GetDronePlanRequest)(nil), // 7: dronesawit.v1.GetDronePlanRequest
+	(*Point)(nil),               // 8: dronesawit.v1.Point
+	(*DronePlan)(nil),           // 9: dronesawit.v1.DronePlan
+	(*Waypoint)(nil),            // 10: dronesawit.v1.Waypoint
+}
+var file_dronesawit_v1_dronesawit_proto_depIdxs = []int32{
+	8,  // 0: dronesawit.v1.DronePlan.rest:type_name -> dronesawit.v1.Point
+	1,  // 1: dronesawit.v1.DroneSawit.CreateEstate:input_type -> dronesawit.v1.CreateEstateRequest
+	2,  // 2: dronesawit.v1.DroneSawit.GetEstate:input_type -> dronesawit.v1.GetEstateRequest
+	4,  // 3: dronesawit.v1.DroneSawit.CreateTree:input_type -> dronesawit.v1.CreateTreeRequest
+	5,  // 4: dronesawit.v1.DroneSawit.GetStats:input_type -> dronesawit.v1.GetStatsRequest
+	7,  // 5: dronesawit.v1.DroneSawit.GetDronePlan:input_type -> dronesawit.v1.GetDronePlanRequest
+	7,  // 6: dronesawit.v1.DroneSawit.StreamWaypoints:input_type -> dronesawit.v1.GetDronePlanRequest
+	0,  // 7: dronesawit.v1.DroneSawit.CreateEstate:output_type -> dronesawit.v1.Estate
+	0,  // 8: dronesawit.v1.DroneSawit.GetEstate:output_type -> dronesawit.v1.Estate
+	3,  // 9: dronesawit.v1.DroneSawit.CreateTree:output_type -> dronesawit.v1.Tree
+	6,  // 10: dronesawit.v1.DroneSawit.GetStats:output_type -> dronesawit.v1.Stats
+	9,  // 11: dronesawit.v1.DroneSawit.GetDronePlan:output_type -> dronesawit.v1.DronePlan
+	10, // 12: dronesawit.v1.DroneSawit.StreamWaypoints:output_type -> dronesawit.v1.Waypoint
+	7,  // [7:13] is the sub-list for method output_type
+	1,  // [1:7] is the sub-list for method input_type
+	1,  // [1:1] is the sub-list for extension type_name
+	1,  // [1:1] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_dronesawit_v1_dronesawit_proto_init() }
+func file_dronesawit_v1_dronesawit_proto_init() {
+	if File_dronesawit_v1_dronesawit_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_dronesawit_v1_dronesawit_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*Estate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dronesawit_v1_dronesawit_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*CreateEstateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dronesawit_v1_dronesawit_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*GetEstateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dronesawit_v1_dronesawit_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*Tree); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dronesawit_v1_dronesawit_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*CreateTreeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dronesawit_v1_dronesawit_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*GetStatsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dronesawit_v1_dronesawit_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*Stats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dronesawit_v1_dronesawit_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*GetDronePlanRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dronesawit_v1_dronesawit_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*Point); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dronesawit_v1_dronesawit_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*DronePlan); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dronesawit_v1_dronesawit_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*Waypoint); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_dronesawit_v1_dronesawit_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_dronesawit_v1_dronesawit_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_dronesawit_v1_dronesawit_proto_goTypes,
+		DependencyIndexes: file_dronesawit_v1_dronesawit_proto_depIdxs,
+		MessageInfos:      file_dronesawit_v1_dronesawit_proto_msgTypes,
+	}.Build()
+	File_dronesawit_v1_dronesawit_proto = out.File
+	file_dronesawit_v1_dronesawit_proto_rawDesc = nil
+	file_dronesawit_v1_dronesawit_proto_goTypes = nil
+	file_dronesawit_v1_dronesawit_proto_depIdxs = nil
+}
@@ -0,0 +1,348 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: dronesawit/v1/dronesawit.proto
+
+// The gRPC API of the drone-sawit service. It serves the estates, trees,
+// stats and drone plans of the REST API, with the same rules, credentials
+// and scopes; see the REST endpoint named on each RPC.
+
+package dronesawitv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	DroneSawit_CreateEstate_FullMethodName    = "/dronesawit.v1.DroneSawit/CreateEstate"
+	DroneSawit_GetEstate_FullMethodName       = "/dronesawit.v1.DroneSawit/GetEstate"
+	DroneSawit_CreateTree_FullMethodName      = "/dronesawit.v1.DroneSawit/CreateTree"
+	DroneSawit_GetStats_FullMethodName        = "/dronesawit.v1.DroneSawit/GetStats"
+	DroneSawit_GetDronePlan_FullMethodName    = "/dronesawit.v1.DroneSawit/GetDronePlan"
+	DroneSawit_StreamWaypoints_FullMethodName = "/dronesawit.v1.DroneSawit/StreamWaypoints"
+)
+
+// DroneSawitClient is the client API for DroneSawit service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DroneSawitClient interface {
+	// CreateEstate is POST /estate. Needs the write scope.
+	CreateEstate(ctx context.Context, in *CreateEstateRequest, opts ...grpc.CallOption) (*Estate, error)
+	// GetEstate returns an estate. Needs the read scope.
+	GetEstate(ctx context.Context, in *GetEstateRequest, opts ...grpc.CallOption) (*Estate, error)
+	// CreateTree is POST /estate/{id}/tree. Needs the write scope.
+	CreateTree(ctx context.Context, in *CreateTreeRequest, opts ...grpc.CallOption) (*Tree, error)
+	// GetStats is GET /estate/{id}/stats. Needs the read scope.
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error)
+	// GetDronePlan is GET /estate/{id}/drone-plan. Needs the plan scope.
+	GetDronePlan(ctx context.Context, in *GetDronePlanRequest, opts ...grpc.CallOption) (*DronePlan, error)
+	// StreamWaypoints streams the flight of the drone plan once it is
+	// computed: a waypoint per plot flown over, then the landing. Needs the
+	// plan scope.
+	StreamWaypoints(ctx context.Context, in *GetDronePlanRequest, opts ...grpc.CallOption) (DroneSawit_StreamWaypointsClient, error)
+}
+
+type droneSawitClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDroneSawitClient(cc grpc.ClientConnInterface) DroneSawitClient {
+	return &droneSawitClient{cc}
+}
+
+func (c *droneSawitClient) CreateEstate(ctx context.Context, in *CreateEstateRequest, opts ...grpc.CallOption) (*Estate, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Estate)
+	err := c.cc.Invoke(ctx, DroneSawit_CreateEstate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *droneSawitClient) GetEstate(ctx context.Context, in *GetEstateRequest, opts ...grpc.CallOption) (*Estate, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Estate)
+	err := c.cc.Invoke(ctx, DroneSawit_GetEstate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *droneSawitClient) CreateTree(ctx context.Context, in *CreateTreeRequest, opts ...grpc.CallOption) (*Tree, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Tree)
+	err := c.cc.Invoke(ctx, DroneSawit_CreateTree_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *droneSawitClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Stats)
+	err := c.cc.Invoke(ctx, DroneSawit_GetStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *droneSawitClient) GetDronePlan(ctx context.Context, in *GetDronePlanRequest, opts ...grpc.CallOption) (*DronePlan, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DronePlan)
+	err := c.cc.Invoke(ctx, DroneSawit_GetDronePlan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *droneSawitClient) StreamWaypoints(ctx context.Context, in *GetDronePlanRequest, opts ...grpc.CallOption) (DroneSawit_StreamWaypointsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DroneSawit_ServiceDesc.Streams[0], DroneSawit_StreamWaypoints_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &droneSawitStreamWaypointsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DroneSawit_StreamWaypointsClient interface {
+	Recv() (*Waypoint, error)
+	grpc.ClientStream
+}
+
+type droneSawitStreamWaypointsClient struct {
+	grpc.ClientStream
+}
+
+func (x *droneSawitStreamWaypointsClient) Recv() (*Waypoint, error) {
+	m := new(Waypoint)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DroneSawitServer is the server API for DroneSawit service.
+// All implementations must embed UnimplementedDroneSawitServer
+// for forward compatibility
+type DroneSawitServer interface {
+	// CreateEstate is POST /estate. Needs the write scope.
+	CreateEstate(context.Context, *CreateEstateRequest) (*Estate, error)
+	// GetEstate returns an estate. Needs the read scope.
+	GetEstate(context.Context, *GetEstateRequest) (*Estate, error)
+	// CreateTree is POST /estate/{id}/tree. Needs the write scope.
+	CreateTree(context.Context, *CreateTreeRequest) (*Tree, error)
+	// GetStats is GET /estate/{id}/stats. Needs the read scope.
+	GetStats(context.Context, *GetStatsRequest) (*Stats, error)
+	// GetDronePlan is GET /estate/{id}/drone-plan. Needs the plan scope.
+	GetDronePlan(context.Context, *GetDronePlanRequest) (*DronePlan, error)
+	// StreamWaypoints streams the flight of the drone plan once it is
+	// computed: a waypoint per plot flown over, then the landing. Needs the
+	// plan scope.
+	StreamWaypoints(*GetDronePlanRequest, DroneSawit_StreamWaypointsServer) error
+	mustEmbedUnimplementedDroneSawitServer()
+}
+
+// UnimplementedDroneSawitServer must be embedded to have forward compatible implementations.
+type UnimplementedDroneSawitServer struct {
+}
+
+func (UnimplementedDroneSawitServer) CreateEstate(context.Context, *CreateEstateRequest) (*Estate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateEstate not implemented")
+}
+func (UnimplementedDroneSawitServer) GetEstate(context.Context, *GetEstateRequest) (*Estate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEstate not implemented")
+}
+func (UnimplementedDroneSawitServer) CreateTree(context.Context, *CreateTreeRequest) (*Tree, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTree not implemented")
+}
+func (UnimplementedDroneSawitServer) GetStats(context.Context, *GetStatsRequest) (*Stats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedDroneSawitServer) GetDronePlan(context.Context, *GetDronePlanRequest) (*DronePlan, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDronePlan not implemented")
+}
+func (UnimplementedDroneSawitServer) StreamWaypoints(*GetDronePlanRequest, DroneSawit_StreamWaypointsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamWaypoints not implemented")
+}
+func (UnimplementedDroneSawitServer) mustEmbedUnimplementedDroneSawitServer() {}
+
+// UnsafeDroneSawitServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DroneSawitServer will
+// result in compilation errors.
+type UnsafeDroneSawitServer interface {
+	mustEmbedUnimplementedDroneSawitServer()
+}
+
+func RegisterDroneSawitServer(s grpc.ServiceRegistrar, srv DroneSawitServer) {
+	s.RegisterService(&DroneSawit_ServiceDesc, srv)
+}
+
+func _DroneSawit_CreateEstate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateEstateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DroneSawitServer).CreateEstate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DroneSawit_CreateEstate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DroneSawitServer).CreateEstate(ctx, req.(*CreateEstateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DroneSawit_GetEstate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEstateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DroneSawitServer).GetEstate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DroneSawit_GetEstate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DroneSawitServer).GetEstate(ctx, req.(*GetEstateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DroneSawit_CreateTree_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTreeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DroneSawitServer).CreateTree(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DroneSawit_CreateTree_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DroneSawitServer).CreateTree(ctx, req.(*CreateTreeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DroneSawit_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DroneSawitServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DroneSawit_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DroneSawitServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DroneSawit_GetDronePlan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDronePlanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DroneSawitServer).GetDronePlan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DroneSawit_GetDronePlan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DroneSawitServer).GetDronePlan(ctx, req.(*GetDronePlanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DroneSawit_StreamWaypoints_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetDronePlanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DroneSawitServer).StreamWaypoints(m, &droneSawitStreamWaypointsServer{ServerStream: stream})
+}
+
+type DroneSawit_StreamWaypointsServer interface {
+	Send(*Waypoint) error
+	grpc.ServerStream
+}
+
+type droneSawitStreamWaypointsServer struct {
+	grpc.ServerStream
+}
+
+func (x *droneSawitStreamWaypointsServer) Send(m *Waypoint) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// DroneSawit_ServiceDesc is the grpc.ServiceDesc for DroneSawit service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DroneSawit_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dronesawit.v1.DroneSawit",
+	HandlerType: (*DroneSawitServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateEstate",
+			Handler:    _DroneSawit_CreateEstate_Handler,
+		},
+		{
+			MethodName: "GetEstate",
+			Handler:    _DroneSawit_GetEstate_Handler,
+		},
+		{
+			MethodName: "CreateTree",
+			Handler:    _DroneSawit_CreateTree_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _DroneSawit_GetStats_Handler,
+		},
+		{
+			MethodName: "GetDronePlan",
+			Handler:    _DroneSawit_GetDronePlan_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamWaypoints",
+			Handler:       _DroneSawit_StreamWaypoints_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "dronesawit/v1/dronesawit.proto",
+}
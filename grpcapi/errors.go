@@ -0,0 +1,66 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus converts err into the status of an RPC, as toError does for the
+// REST API. notFound, when set, is the message of repository.ErrNotFound.
+// Unknown errors become Internal so database details never reach the
+// client; they are logged unless the repository already logged them.
+func toStatus(ctx context.Context, err error, notFound string) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var fieldErr *estates.FieldError
+	if errors.As(err, &fieldErr) {
+		st := status.New(codes.InvalidArgument, fieldErr.Field+" "+fieldErr.Message)
+		if detailed, detailErr := st.WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: fieldErr.Field, Description: fieldErr.Message}},
+		}); detailErr == nil {
+			st = detailed
+		}
+		return st.Err()
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request cancelled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, estates.ErrOutOfBounds):
+		return status.Error(codes.InvalidArgument, "coordinate out of bound")
+	case errors.Is(err, estates.ErrPlotOccupied):
+		return status.Error(codes.AlreadyExists, "plot already has tree")
+	case errors.Is(err, planner.ErrBusy):
+		return status.Error(codes.ResourceExhausted, "too many drone plans in progress")
+	case errors.Is(err, repository.ErrNotFound):
+		if notFound == "" {
+			notFound = "resource not found"
+		}
+		return status.Error(codes.NotFound, notFound)
+	case errors.Is(err, repository.ErrConflict):
+		return status.Error(codes.AlreadyExists, "resource conflicts with existing data")
+	case errors.Is(err, repository.ErrUnavailable):
+		return status.Error(codes.Unavailable, "service temporarily unavailable")
+	case errors.Is(err, repository.ErrInvalidFilter):
+		return status.Error(codes.InvalidArgument, "invalid filter")
+	}
+
+	var repoErr *repository.Error
+	if !errors.As(err, &repoErr) {
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelError, "rpc failed", slog.Any("error", err))
+	}
+	return status.Error(codes.Internal, "internal server error")
+}
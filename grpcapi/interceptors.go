@@ -0,0 +1,264 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/ratelimit"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// requestIDKey is the metadata carrying the request ID, both ways.
+const requestIDKey = "x-request-id"
+
+type identityKey struct{}
+
+// authorize returns the identity of the caller when it was granted scope.
+// Estates are always looked up within identity.OrganizationID.
+func authorize(ctx context.Context, scope auth.Scope) (auth.Identity, error) {
+	identity, ok := ctx.Value(identityKey{}).(auth.Identity)
+	if !ok {
+		return auth.Identity{}, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	if !identity.HasScope(scope) {
+		return auth.Identity{}, status.Error(codes.PermissionDenied, "missing scope "+string(scope))
+	}
+	return identity, nil
+}
+
+// authenticate resolves the caller of an RPC with the authenticators of the
+// REST API: the metadata of the RPC stands in for the headers of a request.
+// The returned context carries the identity, and its subject as the actor
+// of the repository writes of the RPC.
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	c := s.echo.NewContext(req, nil)
+
+	for _, authenticator := range s.authenticators {
+		identity, err := authenticator.Authenticate(c)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			continue
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		}
+		if err != nil {
+			return nil, toStatus(ctx, err, "")
+		}
+
+		ctx = context.WithValue(ctx, identityKey{}, identity)
+		return repository.WithActor(ctx, identity.Subject), nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "missing credentials")
+}
+
+func (s *Server) authenticateUnary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authenticateStream(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+// RateLimit limits the RPCs of each caller, keyed by authenticated subject
+// as ratelimit.ClientKey keys requests, with token buckets that can be
+// shared with the REST API.
+type RateLimit struct {
+	Buckets *ratelimit.Buckets
+	// Default limits the RPCs no rule lists, under ratelimit.DefaultRule.
+	Default ratelimit.Limit
+	// Rules are tried in order; the first listing the method wins.
+	Rules []RateLimitRule
+	// IP limits every RPC per peer IP address before it is authenticated,
+	// in IPBuckets, as ratelimit.IPKey keys the requests of the REST API,
+	// so that invalid credentials cannot be tried at any rate. Nil
+	// IPBuckets disables it.
+	IP        ratelimit.Limit
+	IPBuckets *ratelimit.Buckets
+}
+
+// RateLimitRule applies its own Limit to Methods, in the buckets of the
+// rule of the REST API of the same Name.
+type RateLimitRule struct {
+	Name string
+	// Methods are full method names, e.g.
+	// pb.DroneSawit_GetDronePlan_FullMethodName.
+	Methods []string
+	Limit   ratelimit.Limit
+}
+
+func (r *RateLimit) match(method string) (string, ratelimit.Limit) {
+	for _, rule := range r.Rules {
+		if slices.Contains(rule.Methods, method) {
+			return rule.Name, rule.Limit
+		}
+	}
+	return ratelimit.DefaultRule, r.Default
+}
+
+// limit returns ResourceExhausted, with the delay to wait in its RetryInfo,
+// when the caller of ctx has no token left for method. It runs after
+// authenticate.
+func (s *Server) limit(ctx context.Context, method string) error {
+	if s.rateLimit == nil {
+		return nil
+	}
+	key := peerKey(ctx)
+	if identity, ok := ctx.Value(identityKey{}).(auth.Identity); ok && identity.Subject != "" {
+		key = identity.Subject
+	}
+
+	name, limit := s.rateLimit.match(method)
+	return exhausted(s.rateLimit.Buckets.Reserve(name, key, limit))
+}
+
+// limitIP is limit for the peer IP address of ctx, before authenticate.
+func (s *Server) limitIP(ctx context.Context) error {
+	if s.rateLimit == nil || s.rateLimit.IPBuckets == nil {
+		return nil
+	}
+	return exhausted(s.rateLimit.IPBuckets.Reserve(ratelimit.DefaultRule, peerKey(ctx), s.rateLimit.IP))
+}
+
+// peerKey keys the peer of ctx by IP address, as ratelimit.IPKey does.
+func peerKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return "ip:" + host
+}
+
+// exhausted returns ResourceExhausted, with wait in its RetryInfo, when
+// wait is positive.
+func exhausted(wait time.Duration) error {
+	if wait <= 0 {
+		return nil
+	}
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func (s *Server) limitIPUnary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.limitIP(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) limitIPStream(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.limitIP(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+func (s *Server) rateLimitUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.limit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) rateLimitStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.limit(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// contextStream is a stream whose context was replaced by an interceptor.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// withLogger gives ctx the request ID sent in its metadata, or a new one
+// which is sent back, and a logger carrying it, as the HTTP middlewares do.
+func withLogger(ctx context.Context) (context.Context, *slog.Logger) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	ctx = logging.WithRequestID(ctx, id)
+	id = logging.RequestIDFromContext(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+
+	logger := logging.FromContext(ctx).With(slog.String("request_id", id))
+	return logging.NewContext(ctx, logger), logger
+}
+
+// logRPC logs a finished RPC; server errors are logged as errors.
+func logRPC(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		level = slog.LevelError
+	}
+	logger.LogAttrs(ctx, level, "rpc",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+	)
+}
+
+func logUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx, logger := withLogger(ctx)
+	resp, err := handler(ctx, req)
+	logRPC(ctx, logger, info.FullMethod, start, err)
+	return resp, err
+}
+
+func logStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, logger := withLogger(stream.Context())
+	err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	logRPC(ctx, logger, info.FullMethod, start, err)
+	return err
+}
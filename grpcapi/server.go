@@ -0,0 +1,215 @@
+// Package grpcapi serves the gRPC API defined in
+// proto/dronesawit/v1/dronesawit.proto. Its RPCs go through the same
+// repository, estates service and planner as the REST handlers, and accept
+// the same credentials, sent as "x-api-key" or "authorization" metadata.
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/estates"
+	pb "github.com/dimassantoso/drone-sawit/generated/dronesawitv1"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc"
+)
+
+type Options struct {
+	Repository repository.RepositoryInterface
+	// Planner computes drone plans. It defaults to a planner backed by
+	// Repository.
+	Planner *planner.Planner
+	// Estates creates estates and trees and computes stats. It defaults to
+	// a service backed by Repository.
+	Estates *estates.Service
+	// Authenticators are tried in order until one recognises the
+	// credentials of an RPC, as over HTTP.
+	Authenticators []auth.Authenticator
+	// RateLimit limits the RPCs of each caller. Nil means unlimited.
+	RateLimit *RateLimit
+}
+
+// Server implements the DroneSawit service.
+type Server struct {
+	pb.UnimplementedDroneSawitServer
+
+	repository     repository.RepositoryInterface
+	planner        *planner.Planner
+	estates        *estates.Service
+	authenticators []auth.Authenticator
+	rateLimit      *RateLimit
+	// echo builds the contexts the authenticators read credentials from.
+	echo *echo.Echo
+}
+
+func NewServer(opts Options) *Server {
+	s := &Server{
+		repository:     opts.Repository,
+		planner:        opts.Planner,
+		estates:        opts.Estates,
+		authenticators: opts.Authenticators,
+		rateLimit:      opts.RateLimit,
+		echo:           echo.New(),
+	}
+	if s.planner == nil {
+		s.planner = planner.New(planner.Options{Repository: opts.Repository})
+	}
+	if s.estates == nil {
+		s.estates = estates.New(estates.Options{Repository: opts.Repository})
+	}
+	return s
+}
+
+// New returns a gRPC server serving s, which logs, authenticates and rate
+// limits every RPC, per IP address before authenticating it.
+func New(s *Server, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(logUnary, s.limitIPUnary, s.authenticateUnary, s.rateLimitUnary),
+		grpc.ChainStreamInterceptor(logStream, s.limitIPStream, s.authenticateStream, s.rateLimitStream),
+	)
+	server := grpc.NewServer(opts...)
+	pb.RegisterDroneSawitServer(server, s)
+	return server
+}
+
+func (s *Server) CreateEstate(ctx context.Context, req *pb.CreateEstateRequest) (*pb.Estate, error) {
+	identity, err := authorize(ctx, auth.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	estate, err := s.estates.CreateEstate(ctx, identity.OrganizationID, int(req.GetWidth()), int(req.GetLength()))
+	if err != nil {
+		return nil, toStatus(ctx, err, fmt.Sprintf("organization %s not found", identity.OrganizationID))
+	}
+	return estateMessage(estate), nil
+}
+
+func (s *Server) GetEstate(ctx context.Context, req *pb.GetEstateRequest) (*pb.Estate, error) {
+	identity, err := authorize(ctx, auth.ScopeRead)
+	if err != nil {
+		return nil, err
+	}
+
+	estate, err := s.repository.FindEstate(ctx, &repository.FilterEstate{ID: req.GetId(), OrganizationID: identity.OrganizationID})
+	if err != nil {
+		return nil, toStatus(ctx, err, estateNotFound(req.GetId()))
+	}
+	return estateMessage(estate), nil
+}
+
+func (s *Server) CreateTree(ctx context.Context, req *pb.CreateTreeRequest) (*pb.Tree, error) {
+	identity, err := authorize(ctx, auth.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.estates.CreateTree(ctx, identity.OrganizationID, req.GetEstateId(), int(req.GetX()), int(req.GetY()), int(req.GetHeight()))
+	if err != nil {
+		return nil, toStatus(ctx, err, estateNotFound(req.GetEstateId()))
+	}
+	return &pb.Tree{
+		Id:       tree.ID,
+		EstateId: tree.EstateID,
+		X:        int32(tree.X),
+		Y:        int32(tree.Y),
+		Height:   int32(tree.Height),
+	}, nil
+}
+
+func (s *Server) GetStats(ctx context.Context, req *pb.GetStatsRequest) (*pb.Stats, error) {
+	identity, err := authorize(ctx, auth.ScopeRead)
+	if err != nil {
+		return nil, err
+	}
+
+	estate, err := s.repository.FindEstate(ctx, &repository.FilterEstate{ID: req.GetEstateId(), OrganizationID: identity.OrganizationID})
+	if err != nil {
+		return nil, toStatus(ctx, err, estateNotFound(req.GetEstateId()))
+	}
+	stats, err := s.estates.Stats(ctx, estate)
+	if err != nil {
+		return nil, toStatus(ctx, err, estateNotFound(req.GetEstateId()))
+	}
+	return &pb.Stats{
+		Count:  int64(stats.Count),
+		Max:    int32(stats.Max),
+		Min:    int32(stats.Min),
+		Median: stats.Median,
+	}, nil
+}
+
+func (s *Server) GetDronePlan(ctx context.Context, req *pb.GetDronePlanRequest) (*pb.DronePlan, error) {
+	identity, err := authorize(ctx, auth.ScopePlan)
+	if err != nil {
+		return nil, err
+	}
+	maxDistance, err := maxDistance(req)
+	if err != nil {
+		return nil, toStatus(ctx, err, "")
+	}
+
+	result, err := s.planner.Plan(ctx, identity.OrganizationID, req.GetEstateId(), maxDistance)
+	if err != nil {
+		return nil, toStatus(ctx, err, estateNotFound(req.GetEstateId()))
+	}
+	plan := &pb.DronePlan{Distance: int64(result.Distance)}
+	if result.Rest != nil {
+		plan.Rest = &pb.Point{X: int32(result.Rest.X), Y: int32(result.Rest.Y)}
+	}
+	return plan, nil
+}
+
+func (s *Server) StreamWaypoints(req *pb.GetDronePlanRequest, stream pb.DroneSawit_StreamWaypointsServer) error {
+	ctx := stream.Context()
+	identity, err := authorize(ctx, auth.ScopePlan)
+	if err != nil {
+		return err
+	}
+	maxDistance, err := maxDistance(req)
+	if err != nil {
+		return toStatus(ctx, err, "")
+	}
+
+	_, err = s.planner.Waypoints(ctx, identity.OrganizationID, req.GetEstateId(), maxDistance, func(waypoint planner.Waypoint) error {
+		return stream.Send(&pb.Waypoint{
+			X:        int32(waypoint.X),
+			Y:        int32(waypoint.Y),
+			Altitude: int32(waypoint.Altitude),
+			Distance: int64(waypoint.Distance),
+		})
+	})
+	if err != nil {
+		return toStatus(ctx, err, estateNotFound(req.GetEstateId()))
+	}
+	return nil
+}
+
+// maxDistance returns the max_distance of req, which the REST API also
+// requires not to be negative.
+func maxDistance(req *pb.GetDronePlanRequest) (*int, error) {
+	if req.MaxDistance == nil {
+		return nil, nil
+	}
+	if req.GetMaxDistance() < 0 {
+		return nil, &estates.FieldError{Field: "max_distance", Message: "must be at least 0"}
+	}
+	maxDistance := int(req.GetMaxDistance())
+	return &maxDistance, nil
+}
+
+func estateMessage(estate repository.Estate) *pb.Estate {
+	return &pb.Estate{
+		Id:      estate.ID,
+		Width:   int32(estate.Width),
+		Length:  int32(estate.Length),
+		Version: estate.Version,
+	}
+}
+
+func estateNotFound(estateID string) string {
+	return fmt.Sprintf("estate %s not found", estateID)
+}
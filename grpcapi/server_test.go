@@ -0,0 +1,397 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/cache"
+	pb "github.com/dimassantoso/drone-sawit/generated/dronesawitv1"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/ratelimit"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// testAuthenticator accepts the keys of testIdentities as x-api-key.
+type testAuthenticator struct{}
+
+var testIdentities = map[string]auth.Identity{
+	"reader": {Subject: "apikey:reader", OrganizationID: "org-1", Scopes: []auth.Scope{auth.ScopeRead}},
+	"admin": {Subject: "apikey:admin", OrganizationID: "org-1",
+		Scopes: []auth.Scope{auth.ScopeRead, auth.ScopeWrite, auth.ScopePlan}},
+}
+
+func (testAuthenticator) Authenticate(c echo.Context) (auth.Identity, error) {
+	key := c.Request().Header.Get("X-Api-Key")
+	if key == "" {
+		return auth.Identity{}, auth.ErrNoCredentials
+	}
+	identity, ok := testIdentities[key]
+	if !ok {
+		return auth.Identity{}, auth.ErrInvalidCredentials
+	}
+	return identity, nil
+}
+
+// newTestClient serves repo over an in-memory connection and returns a
+// client of it.
+func newTestClient(t *testing.T, repo repository.RepositoryInterface) pb.DroneSawitClient {
+	return newTestClientWithOptions(t, Options{Repository: repo})
+}
+
+// newTestClientWithOptions is newTestClient serving a server created with
+// opts, authenticated by testAuthenticator.
+func newTestClientWithOptions(t *testing.T, opts Options) pb.DroneSawitClient {
+	listener := bufconn.Listen(1 << 20)
+	opts.Authenticators = []auth.Authenticator{testAuthenticator{}}
+	server := New(NewServer(opts))
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewDroneSawitClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func TestServer_Authentication(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := newTestClient(t, mockrepo.NewMockRepositoryInterface(ctrl))
+	req := &pb.GetEstateRequest{Id: "estate-1"}
+
+	_, err := client.GetEstate(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, "missing credentials", status.Convert(err).Message())
+
+	_, err = client.GetEstate(withKey("unknown"), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, "invalid credentials", status.Convert(err).Message())
+
+	_, err = client.CreateEstate(withKey("reader"), &pb.CreateEstateRequest{Width: 5, Length: 5})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, "missing scope write", status.Convert(err).Message())
+
+	stream, err := client.StreamWaypoints(withKey("reader"), &pb.GetDronePlanRequest{EstateId: "estate-1"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestServer_CreateEstate(t *testing.T) {
+	t.Run("Created", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, estate *repository.Estate) error {
+				assert.Equal(t, "org-1", estate.OrganizationID)
+				assert.Equal(t, "apikey:admin", repository.ActorFromContext(ctx), "writes are attributed to the caller")
+				return nil
+			})
+
+		estate, err := newTestClient(t, mockRepo).CreateEstate(withKey("admin"), &pb.CreateEstateRequest{Width: 5, Length: 10})
+		require.NoError(t, err)
+		assert.NotEmpty(t, estate.GetId())
+		assert.Equal(t, int32(5), estate.GetWidth())
+		assert.Equal(t, int32(10), estate.GetLength())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, err := newTestClient(t, mockrepo.NewMockRepositoryInterface(ctrl)).
+			CreateEstate(withKey("admin"), &pb.CreateEstateRequest{Width: 0, Length: 10})
+		st := status.Convert(err)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		require.Len(t, st.Details(), 1)
+		violations := st.Details()[0].(*errdetails.BadRequest).GetFieldViolations()
+		require.Len(t, violations, 1)
+		assert.Equal(t, "width", violations[0].GetField())
+	})
+}
+
+func TestServer_CreateTree(t *testing.T) {
+	estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 5, Length: 5}
+
+	t.Run("Created", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).Return(estate, nil)
+		mockRepo.EXPECT().FindEstateTree(gomock.Any(), gomock.Any()).Return(repository.EstateTree{}, repository.ErrNotFound)
+		mockRepo.EXPECT().CreateEstateTree(gomock.Any(), gomock.Any()).Return(nil)
+
+		tree, err := newTestClient(t, mockRepo).
+			CreateTree(withKey("admin"), &pb.CreateTreeRequest{EstateId: "estate-1", X: 2, Y: 3, Height: 10})
+		require.NoError(t, err)
+		assert.NotEmpty(t, tree.GetId())
+		assert.Equal(t, "estate-1", tree.GetEstateId())
+		assert.Equal(t, int32(10), tree.GetHeight())
+	})
+
+	t.Run("OutOfBounds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil)
+
+		_, err := newTestClient(t, mockRepo).
+			CreateTree(withKey("admin"), &pb.CreateTreeRequest{EstateId: "estate-1", X: 6, Y: 1, Height: 10})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("PlotOccupied", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil)
+		mockRepo.EXPECT().FindEstateTree(gomock.Any(), gomock.Any()).Return(repository.EstateTree{}, nil)
+
+		_, err := newTestClient(t, mockRepo).
+			CreateTree(withKey("admin"), &pb.CreateTreeRequest{EstateId: "estate-1", X: 2, Y: 3, Height: 10})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("EstateNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, repository.ErrNotFound)
+
+		_, err := newTestClient(t, mockRepo).
+			CreateTree(withKey("admin"), &pb.CreateTreeRequest{EstateId: "estate-1", X: 2, Y: 3, Height: 10})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, "estate estate-1 not found", status.Convert(err).Message())
+	})
+}
+
+func TestServer_GetStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().FindEstate(gomock.Any(), &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).
+		Return(repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1"}, nil)
+	mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(3, nil)
+	mockRepo.EXPECT().GetEstateTreeStats(gomock.Any(), gomock.Any()).Return(repository.EstateTreeStats{Min: 5, Max: 20, Median: 10}, nil)
+
+	stats, err := newTestClient(t, mockRepo).GetStats(withKey("reader"), &pb.GetStatsRequest{EstateId: "estate-1"})
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&pb.Stats{Count: 3, Max: 20, Min: 5, Median: 10}, stats), stats.String())
+
+	t.Run("LargeCount", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).
+			Return(repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1"}, nil)
+		mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(math.MaxInt32+1, nil)
+		mockRepo.EXPECT().GetEstateTreeStats(gomock.Any(), gomock.Any()).Return(repository.EstateTreeStats{Min: 1, Max: 30, Median: 15}, nil)
+
+		stats, err := newTestClient(t, mockRepo).GetStats(withKey("reader"), &pb.GetStatsRequest{EstateId: "estate-1"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(math.MaxInt32+1), stats.GetCount())
+	})
+}
+
+// expectEstate expects the planner to load a 3x2 estate with a tree at (2, 1).
+func expectEstate(mockRepo *mockrepo.MockRepositoryInterface) {
+	mockRepo.EXPECT().FindEstate(gomock.Any(), &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).
+		Return(repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 3, Length: 2}, nil)
+	mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).Return(map[repository.CoordinatePoint]repository.EstateTree{
+		{X: 2, Y: 1}: {X: 2, Y: 1, Height: 5},
+	}, nil)
+}
+
+func TestServer_GetDronePlan(t *testing.T) {
+	t.Run("Planned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		expectEstate(mockRepo)
+
+		plan, err := newTestClient(t, mockRepo).GetDronePlan(withKey("admin"), &pb.GetDronePlanRequest{EstateId: "estate-1"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(62), plan.GetDistance())
+		assert.Nil(t, plan.GetRest())
+	})
+
+	t.Run("LargeDistance", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// The largest estates are flown for longer than an int32 holds; the
+		// plan is cached rather than computed to keep the test fast.
+		estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 50000, Length: 50000}
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil)
+
+		maxDistance := 3 * math.MaxInt32
+		fingerprint := planner.Fingerprint(estate, &maxDistance)
+		value, err := json.Marshal(planner.Result{Distance: 3*math.MaxInt32 - 7, Rest: &planner.Point{X: 30000, Y: 40000}, Fingerprint: fingerprint})
+		require.NoError(t, err)
+		plans := cache.NewMemory(0)
+		require.NoError(t, plans.Set(context.Background(), fingerprint, value))
+
+		client := newTestClientWithOptions(t, Options{
+			Repository: mockRepo,
+			Planner:    planner.New(planner.Options{Repository: mockRepo, Cache: plans}),
+		})
+		plan, err := client.GetDronePlan(withKey("admin"), &pb.GetDronePlanRequest{EstateId: "estate-1", MaxDistance: proto.Int64(int64(maxDistance))})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3*math.MaxInt32-7), plan.GetDistance())
+		assert.True(t, proto.Equal(&pb.Point{X: 30000, Y: 40000}, plan.GetRest()), plan.GetRest().String())
+	})
+
+	t.Run("NegativeMaxDistance", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, err := newTestClient(t, mockrepo.NewMockRepositoryInterface(ctrl)).
+			GetDronePlan(withKey("admin"), &pb.GetDronePlanRequest{EstateId: "estate-1", MaxDistance: proto.Int64(-1)})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestServer_StreamWaypoints(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	expectEstate(mockRepo)
+
+	stream, err := newTestClient(t, mockRepo).StreamWaypoints(withKey("admin"), &pb.GetDronePlanRequest{EstateId: "estate-1"})
+	require.NoError(t, err)
+
+	var waypoints [][4]int64
+	for {
+		waypoint, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		waypoints = append(waypoints, [4]int64{int64(waypoint.GetX()), int64(waypoint.GetY()), int64(waypoint.GetAltitude()), waypoint.GetDistance()})
+	}
+	assert.Equal(t, [][4]int64{
+		{1, 1, 1, 1}, {2, 1, 6, 16}, {3, 1, 1, 31},
+		{3, 2, 1, 41}, {2, 2, 1, 51}, {1, 2, 1, 61},
+		{1, 2, 0, 62},
+	}, waypoints)
+
+	header, err := stream.Header()
+	require.NoError(t, err)
+	assert.NotEmpty(t, header.Get(requestIDKey), "the request ID is sent back")
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(1_700_000_000, 0)
+	buckets := ratelimit.NewBuckets(0, func() time.Time { return now })
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).
+		Return(repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 3, Length: 2}, nil).Times(3)
+	mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).Return(map[repository.CoordinatePoint]repository.EstateTree{}, nil)
+
+	client := newTestClientWithOptions(t, Options{
+		Repository: mockRepo,
+		RateLimit: &RateLimit{
+			Buckets: buckets,
+			Default: ratelimit.Limit{Rate: 1, Burst: 1},
+			Rules: []RateLimitRule{{
+				Name:    "drone-plan",
+				Methods: []string{pb.DroneSawit_GetDronePlan_FullMethodName, pb.DroneSawit_StreamWaypoints_FullMethodName},
+				Limit:   ratelimit.Limit{Rate: 0.5, Burst: 1},
+			}},
+		},
+	})
+	req := &pb.GetEstateRequest{Id: "estate-1"}
+
+	_, err := client.GetEstate(withKey("admin"), req)
+	require.NoError(t, err)
+	_, err = client.GetEstate(withKey("admin"), req)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	assert.Equal(t, time.Second, details[0].(*errdetails.RetryInfo).GetRetryDelay().AsDuration())
+
+	_, err = client.GetEstate(withKey("reader"), req)
+	assert.NoError(t, err, "callers are limited by subject")
+
+	stream, err := client.StreamWaypoints(withKey("admin"), &pb.GetDronePlanRequest{EstateId: "estate-1"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.NoError(t, err, "drone plans have a budget of their own")
+
+	stream, err = client.StreamWaypoints(withKey("admin"), &pb.GetDronePlanRequest{EstateId: "estate-1"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "streams are limited too")
+
+	assert.Equal(t, 2*time.Second, buckets.Reserve("drone-plan", "apikey:admin", ratelimit.Limit{Rate: 0.5, Burst: 1}),
+		"the buckets are shared with the REST API")
+}
+
+func TestServer_RateLimitIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(1_700_000_000, 0)
+	client := newTestClientWithOptions(t, Options{
+		Repository: mockrepo.NewMockRepositoryInterface(ctrl),
+		RateLimit: &RateLimit{
+			Buckets:   ratelimit.NewBuckets(0, nil),
+			IP:        ratelimit.Limit{Rate: 1, Burst: 1},
+			IPBuckets: ratelimit.NewBuckets(0, func() time.Time { return now }),
+		},
+	})
+	req := &pb.GetEstateRequest{Id: "estate-1"}
+
+	_, err := client.GetEstate(withKey("invalid"), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GetEstate(withKey("invalid"), req)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "callers are limited before they are authenticated")
+
+	stream, err := client.StreamWaypoints(withKey("admin"), &pb.GetDronePlanRequest{EstateId: "estate-1"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "streams are limited too")
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultAuditLimit is the number of audit entries listed without a limit.
+const defaultAuditLimit = 100
+
+func (s *Server) GetAudit(c echo.Context, params generated.GetAuditParams) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeAudit)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	filter := repository.FilterAuditEntry{
+		Filter:         repository.Filter{Limit: defaultAuditLimit},
+		OrganizationID: identity.OrganizationID,
+	}
+	if params.EstateId != nil {
+		filter.EstateID = *params.EstateId
+	}
+	if params.Actor != nil {
+		filter.Actor = *params.Actor
+	}
+	if params.From != nil {
+		filter.From = *params.From
+	}
+	if params.To != nil {
+		filter.To = *params.To
+	}
+	if params.Limit != nil {
+		filter.Limit = *params.Limit
+	}
+	entries, err := s.Repository.FindAllAuditEntry(ctx, &filter)
+	if err != nil {
+		return writeRepositoryError(c, err, nil)
+	}
+
+	response := generated.AuditLogResponse{Entries: []generated.AuditEntryResponse{}}
+	for _, entry := range entries {
+		entryResponse, err := auditEntryResponse(entry)
+		if err != nil {
+			return writeError(c, err)
+		}
+		response.Entries = append(response.Entries, entryResponse)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func auditEntryResponse(entry repository.AuditEntry) (generated.AuditEntryResponse, error) {
+	response := generated.AuditEntryResponse{
+		Id:         entry.ID,
+		Actor:      entry.Actor,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityId:   entry.EntityID,
+		CreatedAt:  entry.CreatedAt,
+	}
+	if entry.EstateID != "" {
+		response.EstateId = &entry.EstateID
+	}
+	if entry.RequestID != "" {
+		response.RequestId = &entry.RequestID
+	}
+	if entry.Before != nil {
+		var before map[string]interface{}
+		if err := json.Unmarshal(entry.Before, &before); err != nil {
+			return generated.AuditEntryResponse{}, err
+		}
+		response.Before = &before
+	}
+	if entry.After != nil {
+		var after map[string]interface{}
+		if err := json.Unmarshal(entry.After, &after); err != nil {
+			return generated.AuditEntryResponse{}, err
+		}
+		response.After = &after
+	}
+	return response, nil
+}
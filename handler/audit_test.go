@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_GetAudit(t *testing.T) {
+	t.Run("Filtered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 1, 0)
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindAllAuditEntry(gomock.Any(), &repository.FilterAuditEntry{
+			Filter:         repository.Filter{Limit: 10},
+			OrganizationID: "org-1",
+			EstateID:       "estate-1",
+			Actor:          "apikey:key-1",
+			From:           from,
+			To:             to,
+		}).Return([]repository.AuditEntry{{
+			ID:         7,
+			Actor:      "apikey:key-1",
+			Action:     repository.AuditActionCancel,
+			EntityType: repository.AuditEntityPlanJob,
+			EntityID:   "job-1",
+			EstateID:   "estate-1",
+			Before:     []byte(`{"status":"queued"}`),
+			After:      []byte(`{"status":"cancelled"}`),
+			RequestID:  "req-1",
+			CreatedAt:  from,
+		}}, nil)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodGet, "/audit", "")
+
+		estateID, actor, limit := "estate-1", "apikey:key-1", 10
+		require.NoError(t, server.GetAudit(c, generated.GetAuditParams{EstateId: &estateID, Actor: &actor, From: &from, To: &to, Limit: &limit}))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response generated.AuditLogResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		require.Len(t, response.Entries, 1)
+		entry := response.Entries[0]
+		assert.Equal(t, int64(7), entry.Id)
+		assert.Equal(t, "cancel", entry.Action)
+		assert.Equal(t, "queued", (*entry.Before)["status"])
+		assert.Equal(t, "cancelled", (*entry.After)["status"])
+		assert.Equal(t, "req-1", *entry.RequestId)
+	})
+
+	t.Run("Creation", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindAllAuditEntry(gomock.Any(), &repository.FilterAuditEntry{
+			Filter:         repository.Filter{Limit: defaultAuditLimit},
+			OrganizationID: "org-1",
+		}).Return([]repository.AuditEntry{{
+			ID:         1,
+			Actor:      repository.SystemActor,
+			Action:     repository.AuditActionCreate,
+			EntityType: repository.AuditEntityEstate,
+			EntityID:   "estate-1",
+			After:      []byte(`{"id":"estate-1","width":10,"length":10}`),
+		}}, nil)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodGet, "/audit", "")
+
+		require.NoError(t, server.GetAudit(c, generated.GetAuditParams{}))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), `"before"`)
+		assert.NotContains(t, rec.Body.String(), `"request_id"`)
+	})
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/labstack/echo/v4"
+)
+
+// authorize returns the identity of the caller when it was granted scope.
+// Estates are always looked up within identity.OrganizationID, so a caller
+// can never reach another organization's estate.
+func authorize(c echo.Context, scope auth.Scope) (auth.Identity, error) {
+	identity, ok := auth.IdentityFromContext(c)
+	if !ok {
+		return auth.Identity{}, newError(http.StatusUnauthorized, generated.UNAUTHORIZED, "missing credentials")
+	}
+	if !identity.HasScope(scope) {
+		return auth.Identity{}, newError(http.StatusForbidden, generated.FORBIDDEN, "missing scope "+string(scope))
+	}
+	return identity, nil
+}
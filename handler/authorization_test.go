@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/generated"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorization(t *testing.T) {
+	estateID := uuid.NewString()
+
+	endpoints := []struct {
+		name  string
+		scope auth.Scope
+		call  func(s *Server, c echo.Context) error
+	}{
+		{name: "PostEstate", scope: auth.ScopeWrite, call: func(s *Server, c echo.Context) error {
+			return s.PostEstate(c)
+		}},
+		{name: "GetEstate", scope: auth.ScopeRead, call: func(s *Server, c echo.Context) error {
+			return s.GetEstate(c, generated.GetEstateParams{})
+		}},
+		{name: "GetEstateId", scope: auth.ScopeRead, call: func(s *Server, c echo.Context) error {
+			return s.GetEstateId(c, estateID)
+		}},
+		{name: "PostEstateIdTree", scope: auth.ScopeWrite, call: func(s *Server, c echo.Context) error {
+			return s.PostEstateIdTree(c, estateID)
+		}},
+		{name: "GetEstateIdStats", scope: auth.ScopeRead, call: func(s *Server, c echo.Context) error {
+			return s.GetEstateIdStats(c, estateID)
+		}},
+		{name: "GetEstateIdEvents", scope: auth.ScopeRead, call: func(s *Server, c echo.Context) error {
+			return s.GetEstateIdEvents(c, estateID)
+		}},
+		{name: "GetEstateIdDronePlan", scope: auth.ScopePlan, call: func(s *Server, c echo.Context) error {
+			return s.GetEstateIdDronePlan(c, estateID, generated.GetEstateIdDronePlanParams{})
+		}},
+		{name: "PostEstateIdDronePlanJobs", scope: auth.ScopePlan, call: func(s *Server, c echo.Context) error {
+			return s.PostEstateIdDronePlanJobs(c, estateID)
+		}},
+		{name: "GetEstateIdDronePlanJobsJobId", scope: auth.ScopePlan, call: func(s *Server, c echo.Context) error {
+			return s.GetEstateIdDronePlanJobsJobId(c, estateID, "job-1")
+		}},
+		{name: "PostEstateIdDronePlanJobsJobIdCancel", scope: auth.ScopePlan, call: func(s *Server, c echo.Context) error {
+			return s.PostEstateIdDronePlanJobsJobIdCancel(c, estateID, "job-1")
+		}},
+		{name: "PostWebhooks", scope: auth.ScopeWebhooks, call: func(s *Server, c echo.Context) error {
+			return s.PostWebhooks(c)
+		}},
+		{name: "GetWebhooks", scope: auth.ScopeWebhooks, call: func(s *Server, c echo.Context) error {
+			return s.GetWebhooks(c)
+		}},
+		{name: "DeleteWebhooksId", scope: auth.ScopeWebhooks, call: func(s *Server, c echo.Context) error {
+			return s.DeleteWebhooksId(c, "sub-1")
+		}},
+		{name: "GetWebhooksIdDeliveries", scope: auth.ScopeWebhooks, call: func(s *Server, c echo.Context) error {
+			return s.GetWebhooksIdDeliveries(c, "sub-1", generated.GetWebhooksIdDeliveriesParams{})
+		}},
+		{name: "PostWebhooksIdReplay", scope: auth.ScopeWebhooks, call: func(s *Server, c echo.Context) error {
+			return s.PostWebhooksIdReplay(c, "sub-1")
+		}},
+		{name: "GetAudit", scope: auth.ScopeAudit, call: func(s *Server, c echo.Context) error {
+			return s.GetAudit(c, generated.GetAuditParams{})
+		}},
+	}
+
+	for _, endpoint := range endpoints {
+		t.Run(endpoint.name+": unauthenticated", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			handler := NewServer(NewServerOptions{Repository: mockrepo.NewMockRepositoryInterface(ctrl)})
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := endpoint.call(handler, c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"code":"UNAUTHORIZED"`)
+		})
+
+		t.Run(endpoint.name+": missing scope", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			handler := NewServer(NewServerOptions{Repository: mockrepo.NewMockRepositoryInterface(ctrl)})
+
+			var scopes []auth.Scope
+			for _, scope := range auth.Scopes {
+				if scope != endpoint.scope {
+					scopes = append(scopes, scope)
+				}
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			auth.SetIdentity(c, auth.Identity{Subject: "apikey:test", OrganizationID: "org-1", Scopes: scopes})
+
+			err := endpoint.call(handler, c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"code":"FORBIDDEN"`)
+		})
+	}
+
+	t.Run("Estate is created for the caller's organization", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, estate *repository.Estate) error {
+			assert.Equal(t, testIdentity.OrganizationID, estate.OrganizationID)
+			return nil
+		})
+
+		handler := NewServer(NewServerOptions{Repository: mockRepo})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/estate", strings.NewReader(`{"width": 3, "length": 4}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
+
+		err := handler.PostEstate(c)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+
+	t.Run("Estate of another organization is not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().
+			FindEstate(gomock.Any(), &repository.FilterEstate{ID: estateID, OrganizationID: "org-2"}).
+			Return(repository.Estate{}, repository.ErrNotFound)
+
+		handler := NewServer(NewServerOptions{Repository: mockRepo})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/estate/:id/stats", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, auth.Identity{Subject: "apikey:other", OrganizationID: "org-2", Scopes: auth.Scopes})
+
+		err := handler.GetEstateIdStats(c, estateID)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"ESTATE_NOT_FOUND"`)
+	})
+}
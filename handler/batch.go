@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) PostBatch(c echo.Context) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeWrite)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	var req generated.BatchRequest
+	if err = c.Bind(&req); err != nil {
+		return writeError(c, newError(http.StatusBadRequest, generated.INVALIDREQUEST, "invalid request body"))
+	}
+
+	ops := make([]estates.BatchOperation, len(req.Operations))
+	for i, op := range req.Operations {
+		ops[i] = estates.BatchOperation{
+			Op:        string(op.Op),
+			Ref:       stringValue(op.Ref),
+			EstateID:  stringValue(op.EstateId),
+			EstateRef: stringValue(op.EstateRef),
+			TreeID:    stringValue(op.TreeId),
+			TreeRef:   stringValue(op.TreeRef),
+			Width:     intValue(op.Width),
+			Length:    intValue(op.Length),
+			X:         op.X,
+			Y:         op.Y,
+			Height:    op.Height,
+		}
+	}
+	atomic := req.Atomic != nil && *req.Atomic
+	results, err := s.Estates.Batch(ctx, identity.OrganizationID, ops, atomic)
+	if err != nil {
+		return writeRepositoryError(c, err, nil)
+	}
+
+	response := generated.BatchResponse{Results: make([]generated.BatchOperationResult, len(results))}
+	for i, result := range results {
+		response.Results[i] = batchOperationResult(ops[i], result)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// batchOperationResult renders the result of op, its error rendered as the
+// request would have been had op been sent alone.
+func batchOperationResult(op estates.BatchOperation, result estates.BatchResult) generated.BatchOperationResult {
+	response := generated.BatchOperationResult{Status: generated.BatchOperationStatus(result.Status)}
+	if result.ID != "" {
+		id := result.ID
+		response.Id = &id
+	}
+	if result.Err == nil {
+		return response
+	}
+
+	var notFound *Error
+	switch {
+	case op.Op == repository.BatchCreateTree && op.EstateID != "":
+		notFound = errEstateNotFound(op.EstateID)
+	case op.Op != repository.BatchCreateTree && op.TreeID != "":
+		notFound = errTreeNotFound(op.TreeID)
+	}
+	apiErr := toError(result.Err, notFound)
+	response.Error = &generated.ErrorResponse{
+		Code:    apiErr.Code,
+		Message: apiErr.Message,
+	}
+	if len(apiErr.Details) > 0 {
+		response.Error.Details = &apiErr.Details
+	}
+	return response
+}
+
+func errTreeNotFound(treeID string) *Error {
+	return newError(http.StatusNotFound, generated.NOTFOUND, fmt.Sprintf("tree %s not found", treeID))
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func intValue(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
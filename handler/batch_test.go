@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_PostBatch(t *testing.T) {
+	t.Run("Results", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).
+			Return(repository.Estate{}, &repository.Error{Op: "FindEstate", Kind: repository.ErrNotFound})
+		mockRepo.EXPECT().FindEstateTree(gomock.Any(), &repository.FilterEstateTree{ID: "tree-1", OrganizationID: "org-1"}).
+			Return(repository.EstateTree{}, &repository.Error{Op: "FindEstateTree", Kind: repository.ErrNotFound})
+		mockRepo.EXPECT().ApplyBatch(gomock.Any(), gomock.Len(2), false).
+			DoAndReturn(func(_ interface{}, ops []repository.BatchOperation, _ bool) ([]error, error) {
+				assert.Equal(t, repository.BatchCreateEstate, ops[0].Kind)
+				assert.Equal(t, "org-1", ops[0].Estate.OrganizationID)
+				assert.Equal(t, ops[0].Estate.ID, ops[1].Tree.EstateID)
+				return []error{nil, nil}, nil
+			})
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodPost, "/batch", `{"operations": [
+			{"op": "create_estate", "ref": "a", "width": 5, "length": 5},
+			{"op": "create_tree", "estate_ref": "a", "x": 1, "y": 1, "height": 10},
+			{"op": "create_tree", "estate_ref": "a", "x": 9, "y": 1, "height": 10},
+			{"op": "create_tree", "estate_id": "estate-1", "x": 1, "y": 1, "height": 10},
+			{"op": "delete_tree", "tree_id": "tree-1"},
+			{"op": "delete_tree", "tree_ref": "b"}
+		]}`)
+		require.NoError(t, server.PostBatch(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response generated.BatchResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		require.Len(t, response.Results, 6)
+		assert.Equal(t, generated.BatchSucceeded, response.Results[0].Status)
+		assert.NotEmpty(t, *response.Results[0].Id)
+		assert.Equal(t, generated.BatchSucceeded, response.Results[1].Status)
+		assert.Nil(t, response.Results[1].Error)
+
+		for i, want := range []struct {
+			code    generated.ErrorCode
+			message string
+		}{
+			{code: generated.OUTOFBOUNDS, message: "coordinate out of bound"},
+			{code: generated.ESTATENOTFOUND, message: "estate estate-1 not found"},
+			{code: generated.NOTFOUND, message: "tree tree-1 not found"},
+			{code: generated.VALIDATIONFAILED, message: "request does not match the API specification"},
+		} {
+			result := response.Results[i+2]
+			assert.Equal(t, generated.BatchFailed, result.Status)
+			assert.Nil(t, result.Id)
+			require.NotNil(t, result.Error)
+			assert.Equal(t, want.code, result.Error.Code)
+			assert.Equal(t, want.message, result.Error.Message)
+		}
+		assert.Equal(t, []generated.ErrorDetail{{Field: "tree_ref", Message: "does not name an earlier operation"}}, *response.Results[5].Error.Details)
+	})
+
+	t.Run("Atomic", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().ApplyBatch(gomock.Any(), gomock.Len(2), true).
+			Return([]error{nil, &repository.Error{Op: "CreateEstateTree", Kind: repository.ErrConflict}}, nil)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodPost, "/batch", `{"atomic": true, "operations": [
+			{"op": "create_estate", "ref": "a", "width": 5, "length": 5},
+			{"op": "create_tree", "estate_ref": "a", "x": 1, "y": 1, "height": 10}
+		]}`)
+		require.NoError(t, server.PostBatch(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response generated.BatchResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, generated.BatchOperationResult{Status: generated.BatchAborted}, response.Results[0])
+		assert.Equal(t, generated.BatchFailed, response.Results[1].Status)
+		assert.Equal(t, generated.PLOTOCCUPIED, response.Results[1].Error.Code)
+	})
+
+	t.Run("Repository unavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().ApplyBatch(gomock.Any(), gomock.Any(), false).
+			Return(nil, &repository.Error{Op: "ApplyBatch", Kind: repository.ErrUnavailable})
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodPost, "/batch", `{"operations": [{"op": "create_estate", "width": 5, "length": 5}]}`)
+		require.NoError(t, server.PostBatch(c))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"SERVICE_UNAVAILABLE"`)
+	})
+
+	t.Run("Invalid body", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		server := NewServer(NewServerOptions{Repository: mockrepo.NewMockRepositoryInterface(ctrl)})
+		c, rec := newJobContext(http.MethodPost, "/batch", `{"operations": {}}`)
+		require.NoError(t, server.PostBatch(c))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"INVALID_REQUEST"`)
+	})
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// writeJSONWithETag writes body with fingerprint as its ETag, or only 304
+// Not Modified when the client's If-None-Match already names it.
+func writeJSONWithETag(c echo.Context, fingerprint string, body interface{}) error {
+	etag := `"` + fingerprint + `"`
+	c.Response().Header().Set("ETag", etag)
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(http.StatusOK, body)
+}
+
+// etagMatches reports whether the If-None-Match header value names etag,
+// comparing weakly as RFC 9110 requires for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
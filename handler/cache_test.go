@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/cache"
+	"github.com/dimassantoso/drone-sawit/generated"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, serve func(c echo.Context) error, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	auth.SetIdentity(c, testIdentity)
+	require.NoError(t, serve(c))
+	return rec
+}
+
+func TestServer_GetEstateIdStats_Cache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Version: 1}
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, _ *repository.FilterEstate) (repository.Estate, error) { return estate, nil }).
+		Times(4)
+	mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(2, nil).Times(2)
+	mockRepo.EXPECT().GetEstateTreeStats(gomock.Any(), gomock.Any()).
+		Return(repository.EstateTreeStats{Min: 10, Max: 30, Median: 20}, nil).Times(2)
+
+	server := NewServer(NewServerOptions{Repository: mockRepo, Cache: cache.NewMemory(0)})
+	stats := func(c echo.Context) error { return server.GetEstateIdStats(c, "estate-1") }
+
+	first := get(t, stats, "")
+	assert.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	cached := get(t, stats, "")
+	assert.Equal(t, first.Body.String(), cached.Body.String(), "served from the cache")
+	assert.Equal(t, etag, cached.Header().Get("ETag"))
+
+	notModified := get(t, stats, etag)
+	assert.Equal(t, http.StatusNotModified, notModified.Code)
+	assert.Empty(t, notModified.Body.String())
+
+	estate.Version++
+	changed := get(t, stats, etag)
+	assert.Equal(t, http.StatusOK, changed.Code, "a write changes the ETag")
+	assert.NotEqual(t, etag, changed.Header().Get("ETag"))
+}
+
+func TestServer_GetEstateIdDronePlan_ETag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 5, Length: 1, Version: 1}
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil).Times(2)
+	mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).
+		Return(map[repository.CoordinatePoint]repository.EstateTree{}, nil)
+
+	server := NewServer(NewServerOptions{
+		Repository: mockRepo,
+		Planner:    planner.New(planner.Options{Repository: mockRepo, Cache: cache.NewMemory(0)}),
+	})
+	plan := func(c echo.Context) error {
+		return server.GetEstateIdDronePlan(c, "estate-1", generated.GetEstateIdDronePlanParams{})
+	}
+
+	first := get(t, plan, "")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, `"`+planner.Fingerprint(estate, nil)+`"`, first.Header().Get("ETag"))
+
+	notModified := get(t, plan, `"other", W/`+first.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, notModified.Code)
+}
+
+func TestEtagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"a"`, `"a"`))
+	assert.True(t, etagMatches(`W/"a"`, `"a"`))
+	assert.True(t, etagMatches(`"b", "a"`, `"a"`))
+	assert.True(t, etagMatches(`*`, `"a"`))
+	assert.False(t, etagMatches(``, `"a"`))
+	assert.False(t, etagMatches(`"b"`, `"a"`))
+}
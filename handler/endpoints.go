@@ -1,44 +1,30 @@
 package handler
 
 import (
-	"fmt"
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/estates"
 	"github.com/dimassantoso/drone-sawit/generated"
 	"github.com/dimassantoso/drone-sawit/repository"
-	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
-	"math"
 	"net/http"
+	"strings"
 )
 
 func (s *Server) PostEstate(c echo.Context) error {
 	ctx := c.Request().Context()
-
-	var (
-		req         generated.EstateRequest
-		errResponse generated.ErrorResponse
-	)
-
-	if err := c.Bind(&req); err != nil {
-		errResponse.Message = "invalid request body"
-		return c.JSON(http.StatusBadRequest, errResponse)
-	}
-
-	if req.Width <= 0 || req.Length <= 0 || req.Width > 50000 || req.Length > 50000 {
-		errResponse.Message = "width and length must be between 1 and 50000"
-		return c.JSON(http.StatusBadRequest, errResponse)
+	identity, err := authorize(c, auth.ScopeWrite)
+	if err != nil {
+		return writeError(c, err)
 	}
 
-	estate := repository.Estate{
-		BaseModel: repository.BaseModel{
-			ID: uuid.NewString(),
-		},
-		Width:  req.Width,
-		Length: req.Length,
+	var req generated.EstateRequest
+	if err = c.Bind(&req); err != nil {
+		return writeError(c, newError(http.StatusBadRequest, generated.INVALIDREQUEST, "invalid request body"))
 	}
 
-	if err := s.Repository.CreateEstate(ctx, &estate); err != nil {
-		errResponse.Message = "failed to create estate"
-		return c.JSON(http.StatusBadRequest, errResponse)
+	estate, err := s.Estates.CreateEstate(ctx, identity.OrganizationID, req.Width, req.Length)
+	if err != nil {
+		return writeRepositoryError(c, err, errOrganizationNotFound(identity.OrganizationID))
 	}
 
 	return c.JSON(http.StatusCreated, generated.EstateResponse{
@@ -46,152 +32,193 @@ func (s *Server) PostEstate(c echo.Context) error {
 	})
 }
 
-func (s *Server) PostEstateIdTree(c echo.Context, estateID string) error {
+// defaultEstateLimit is the number of estates listed without a limit.
+const defaultEstateLimit = 100
+
+func (s *Server) GetEstate(c echo.Context, params generated.GetEstateParams) error {
 	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeRead)
+	if err != nil {
+		return writeError(c, err)
+	}
 
-	var (
-		req         generated.EstateTreeRequest
-		errResponse generated.ErrorResponse
-	)
+	filter := repository.FilterEstate{
+		Filter:         repository.Filter{Limit: defaultEstateLimit},
+		OrganizationID: identity.OrganizationID,
+	}
+	if params.After != nil {
+		filter.After = *params.After
+	}
+	if params.Limit != nil {
+		filter.Limit = *params.Limit
+	}
+	estateList, err := s.Repository.FindAllEstate(ctx, &filter)
+	if err != nil {
+		return writeRepositoryError(c, err, nil)
+	}
 
-	if err := c.Bind(&req); err != nil {
-		errResponse.Message = err.Error()
-		return c.JSON(http.StatusBadRequest, errResponse)
+	response := generated.EstateListResponse{Estates: []generated.EstateDetailResponse{}}
+	for _, estate := range estateList {
+		response.Estates = append(response.Estates, estateDetailResponse(estate))
 	}
+	return c.JSON(http.StatusOK, response)
+}
 
-	if req.X < 1 || req.Y < 1 || req.Height < 1 || req.Height > 30 {
-		errResponse.Message = "x and y must be greatest equal 1, height must be between 1 and 30"
-		return c.JSON(http.StatusBadRequest, errResponse)
+func (s *Server) GetEstateId(c echo.Context, estateID string) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeRead)
+	if err != nil {
+		return writeError(c, err)
 	}
 
-	estate, err := s.Repository.FindEstate(ctx, &repository.FilterEstate{ID: estateID})
+	estate, err := s.Repository.FindEstate(ctx, &repository.FilterEstate{ID: estateID, OrganizationID: identity.OrganizationID})
 	if err != nil {
-		errResponse.Message = fmt.Sprintf("estate %s not found", estateID)
-		return c.JSON(http.StatusNotFound, errResponse)
+		return writeRepositoryError(c, err, errEstateNotFound(estateID))
 	}
+	return c.JSON(http.StatusOK, estateDetailResponse(estate))
+}
 
-	if estate.Length < req.X || estate.Width < req.Y {
-		errResponse.Message = "coordinate out of bound"
-		return c.JSON(http.StatusBadRequest, errResponse)
+func estateDetailResponse(estate repository.Estate) generated.EstateDetailResponse {
+	return generated.EstateDetailResponse{
+		Id:        estate.ID,
+		Width:     estate.Width,
+		Length:    estate.Length,
+		Version:   estate.Version,
+		CreatedAt: estate.CreatedAt,
 	}
+}
 
-	_, err = s.Repository.FindEstateTree(ctx, &repository.FilterEstateTree{
-		EstateID: estateID,
-		X:        req.X,
-		Y:        req.Y,
-	})
-	if err == nil {
-		errResponse.Message = "plot already has tree"
-		return c.JSON(http.StatusBadRequest, errResponse)
+func (s *Server) PostEstateIdTree(c echo.Context, estateID string) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeWrite)
+	if err != nil {
+		return writeError(c, err)
 	}
 
-	data := repository.EstateTree{
-		BaseModel: repository.BaseModel{
-			ID: uuid.NewString(),
-		},
-		EstateID: estateID,
-		X:        req.X,
-		Y:        req.Y,
-		Height:   req.Height,
+	var req generated.EstateTreeRequest
+	if err = c.Bind(&req); err != nil {
+		return writeError(c, newError(http.StatusBadRequest, generated.INVALIDREQUEST, "invalid request body"))
 	}
-	if err = s.Repository.CreateEstateTree(ctx, &data); err != nil {
-		errResponse.Message = "failed to create estate tree"
-		return c.JSON(http.StatusBadRequest, errResponse)
+
+	tree, err := s.Estates.CreateTree(ctx, identity.OrganizationID, estateID, req.X, req.Y, req.Height)
+	if err != nil {
+		return writeRepositoryError(c, err, errEstateNotFound(estateID))
 	}
 
 	return c.JSON(http.StatusCreated, generated.EstateTreeResponse{
-		Id: data.ID,
+		Id: tree.ID,
 	})
 }
 
 func (s *Server) GetEstateIdStats(c echo.Context, estateID string) error {
 	ctx := c.Request().Context()
-	var errResponse generated.ErrorResponse
-	_, err := s.Repository.FindEstate(ctx, &repository.FilterEstate{ID: estateID})
+	identity, err := authorize(c, auth.ScopeRead)
 	if err != nil {
-		errResponse.Message = fmt.Sprintf("estate %s not found", estateID)
-		return c.JSON(http.StatusNotFound, errResponse)
+		return writeError(c, err)
 	}
 
-	countEstateTree := s.Repository.CountEstateTree(ctx, &repository.FilterEstateTree{EstateID: estateID})
-	var stats repository.EstateTreeStats
-	if countEstateTree > 0 {
-		stats, err = s.Repository.GetEstateTreeStats(ctx, &repository.FilterEstateTree{EstateID: estateID})
-		if err != nil {
-			errResponse.Message = err.Error()
-			return c.JSON(http.StatusBadRequest, errResponse)
-		}
+	estate, err := s.Repository.FindEstate(ctx, &repository.FilterEstate{ID: estateID, OrganizationID: identity.OrganizationID})
+	if err != nil {
+		return writeRepositoryError(c, err, errEstateNotFound(estateID))
 	}
 
-	return c.JSON(http.StatusOK, generated.EstateStatsResponse{
-		Count:  countEstateTree,
-		Max:    stats.Max,
-		Min:    stats.Min,
-		Median: stats.Median,
-	})
+	stats, err := s.Estates.Stats(ctx, estate)
+	if err != nil {
+		return writeRepositoryError(c, err, errEstateNotFound(estateID))
+	}
+	return writeJSONWithETag(c, estates.StatsFingerprint(estate), generated.EstateStatsResponse(stats))
 }
 
 func (s *Server) GetEstateIdDronePlan(c echo.Context, estateID string, params generated.GetEstateIdDronePlanParams) error {
 	ctx := c.Request().Context()
-	var errResponse generated.ErrorResponse
-	estate, err := s.Repository.FindEstate(ctx, &repository.FilterEstate{ID: estateID})
+	identity, err := authorize(c, auth.ScopePlan)
 	if err != nil {
-		errResponse.Message = fmt.Sprintf("estate %s not found", estateID)
-		return c.JSON(http.StatusNotFound, errResponse)
+		return writeError(c, err)
 	}
 
-	filterEstateTree := repository.FilterEstateTree{
-		Filter: repository.Filter{
-			Page:    1,
-			ShowAll: true,
-		},
-		EstateID: estateID,
+	result, err := s.Planner.Plan(ctx, identity.OrganizationID, estateID, params.MaxDistance)
+	if err != nil {
+		return writeRepositoryError(c, err, errEstateNotFound(estateID))
+	}
+
+	response := generated.EstateDronePlanResponse{Distance: result.Distance}
+	if result.Rest != nil {
+		response = setResponseMaxDistance(result.Distance, result.Rest.X, result.Rest.Y)
 	}
-	estateTree, err := s.Repository.FindAllMapEstateTree(ctx, &filterEstateTree)
+	return writeJSONWithETag(c, result.Fingerprint, response)
+}
+
+func (s *Server) PostEstateIdDronePlanJobs(c echo.Context, estateID string) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopePlan)
 	if err != nil {
-		errResponse.Message = err.Error()
-		return c.JSON(http.StatusBadRequest, errResponse)
+		return writeError(c, err)
 	}
 
-	var totalDistance, currentHeight int
-	for y := 1; y <= estate.Length; y++ {
-		var xStart, xEnd, xStep int
-		if y%2 == 1 {
-			xStart, xEnd, xStep = 1, estate.Width, 1
-		} else {
-			xStart, xEnd, xStep = estate.Width, 1, -1
-		}
-		for x := xStart; x != xEnd+xStep; x += xStep {
-			tree := estateTree[repository.CoordinatePoint{X: x, Y: y}]
-			targetHeight := tree.Height + 1
-			totalDistance += int(math.Abs(float64(targetHeight - currentHeight)))
-			currentHeight = targetHeight
-
-			if params.MaxDistance != nil && totalDistance > *params.MaxDistance {
-				return c.JSON(http.StatusOK, setResponseMaxDistance(totalDistance, x, y))
-			}
-
-			if x != xEnd {
-				totalDistance += 10
-				if params.MaxDistance != nil && totalDistance > *params.MaxDistance {
-					return c.JSON(http.StatusOK, setResponseMaxDistance(totalDistance, x, y))
-				}
-			}
-		}
+	var req generated.PlanJobRequest
+	if err = c.Bind(&req); err != nil {
+		return writeError(c, newError(http.StatusBadRequest, generated.INVALIDREQUEST, "invalid request body"))
+	}
 
-		if y != estate.Length {
-			totalDistance += 10
-			if params.MaxDistance != nil && totalDistance > *params.MaxDistance {
-				return c.JSON(http.StatusOK, setResponseMaxDistance(totalDistance, xStart, y+1))
-			}
-		}
+	job, err := s.Jobs.Submit(ctx, identity.OrganizationID, estateID, req.MaxDistance)
+	if err != nil {
+		return writeRepositoryError(c, err, errEstateNotFound(estateID))
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, strings.TrimSuffix(c.Request().URL.Path, "/")+"/"+job.ID)
+	return c.JSON(http.StatusAccepted, planJobResponse(job))
+}
+
+func (s *Server) GetEstateIdDronePlanJobsJobId(c echo.Context, estateID string, jobID string) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopePlan)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	job, err := s.Jobs.Get(ctx, identity.OrganizationID, estateID, jobID)
+	if err != nil {
+		return writeRepositoryError(c, err, errJobNotFound(jobID))
+	}
+	return c.JSON(http.StatusOK, planJobResponse(job))
+}
+
+func (s *Server) PostEstateIdDronePlanJobsJobIdCancel(c echo.Context, estateID string, jobID string) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopePlan)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	job, err := s.Jobs.Cancel(ctx, identity.OrganizationID, estateID, jobID)
+	if err != nil {
+		return writeRepositoryError(c, err, errJobNotFound(jobID))
 	}
+	return c.JSON(http.StatusOK, planJobResponse(job))
+}
 
-	totalDistance += currentHeight
-	if params.MaxDistance != nil {
-		return c.JSON(http.StatusOK, setResponseMaxDistance(totalDistance, estate.Width, estate.Length))
+func planJobResponse(job repository.PlanJob) generated.PlanJobResponse {
+	response := generated.PlanJobResponse{
+		Id:          job.ID,
+		EstateId:    job.EstateID,
+		Status:      generated.PlanJobStatus(job.Status),
+		Progress:    float32(job.Progress),
+		MaxDistance: job.MaxDistance,
+		CreatedAt:   job.CreatedAt,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+	}
+	if job.Error != "" {
+		response.Error = &job.Error
+	}
+	if job.Status == repository.PlanJobSucceeded && job.Distance != nil {
+		result := generated.EstateDronePlanResponse{Distance: *job.Distance}
+		if job.RestX != nil && job.RestY != nil {
+			result = setResponseMaxDistance(*job.Distance, *job.RestX, *job.RestY)
+		}
+		response.Result = &result
 	}
-	return c.JSON(http.StatusOK, generated.EstateDronePlanResponse{Distance: totalDistance})
+	return response
 }
 
 func setResponseMaxDistance(distance, x, y int) generated.EstateDronePlanResponse {
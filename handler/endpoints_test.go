@@ -2,6 +2,7 @@ package handler
 
 import (
 	"errors"
+	"github.com/dimassantoso/drone-sawit/auth"
 	"github.com/dimassantoso/drone-sawit/generated"
 	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
 	"github.com/dimassantoso/drone-sawit/repository"
@@ -13,8 +14,15 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
+var testIdentity = auth.Identity{
+	Subject:        "apikey:test",
+	OrganizationID: "org-1",
+	Scopes:         auth.Scopes,
+}
+
 func TestPostEstate(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -30,6 +38,7 @@ func TestPostEstate(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.PostEstate(c)
 		assert.NoError(t, err)
@@ -37,32 +46,35 @@ func TestPostEstate(t *testing.T) {
 		assert.Contains(t, rec.Body.String(), `"id"`)
 	})
 
-	t.Run("Failed: invalid input", func(t *testing.T) {
+	t.Run("Failed: in repo", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).Return(errors.New("database error"))
 
 		handler := NewServer(NewServerOptions{Repository: mockRepo})
 
 		e := echo.New()
-		req := httptest.NewRequest(http.MethodPost, "/estate", strings.NewReader(`{"width": -1}`))
+		req := httptest.NewRequest(http.MethodPost, "/estate", strings.NewReader(`{"width": 5, "length": 10}`))
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.PostEstate(c)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
-		assert.Contains(t, rec.Body.String(), "width and length must be between 1 and 50000")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"INTERNAL_ERROR"`)
+		assert.NotContains(t, rec.Body.String(), `database error`)
 	})
 
-	t.Run("Failed: in repo", func(t *testing.T) {
+	t.Run("Failed: database unavailable", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
-		mockRepo.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).Return(errors.New("database error"))
+		mockRepo.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).Return(&repository.Error{Op: "CreateEstate", Kind: repository.ErrUnavailable})
 
 		handler := NewServer(NewServerOptions{Repository: mockRepo})
 
@@ -71,11 +83,11 @@ func TestPostEstate(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.PostEstate(c)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
-		assert.Contains(t, rec.Body.String(), `failed`)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
 	})
 
 	t.Run("Failed: invalid body", func(t *testing.T) {
@@ -91,6 +103,7 @@ func TestPostEstate(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.PostEstate(c)
 		assert.NoError(t, err)
@@ -113,7 +126,7 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 			Width:  10,
 			Length: 10,
 		}, nil)
-		mockRepo.EXPECT().FindEstateTree(gomock.Any(), gomock.Any()).Return(repository.EstateTree{}, errors.New("not found"))
+		mockRepo.EXPECT().FindEstateTree(gomock.Any(), gomock.Any()).Return(repository.EstateTree{}, repository.ErrNotFound)
 		mockRepo.EXPECT().CreateEstateTree(gomock.Any(), gomock.Any()).Return(nil)
 
 		handler := NewServer(NewServerOptions{Repository: mockRepo})
@@ -123,6 +136,7 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.PostEstateIdTree(c, estateID)
 		assert.NoError(t, err)
@@ -137,7 +151,7 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		estateID := uuid.NewString()
 
 		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
-		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, errors.New("not found"))
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, repository.ErrNotFound)
 
 		handler := NewServer(NewServerOptions{Repository: mockRepo})
 
@@ -146,32 +160,13 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.PostEstateIdTree(c, estateID)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusNotFound, rec.Code)
 		assert.Contains(t, rec.Body.String(), `not found`)
-	})
-
-	t.Run("Failed: invalid estate", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
-
-		estateID := uuid.NewString()
-
-		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
-		handler := NewServer(NewServerOptions{Repository: mockRepo})
-
-		e := echo.New()
-		req := httptest.NewRequest(http.MethodPost, "/estate/:id/tree", strings.NewReader(`{"x": 1, "y": 1, "height": 50}`))
-		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-		rec := httptest.NewRecorder()
-		c := e.NewContext(req, rec)
-
-		err := handler.PostEstateIdTree(c, estateID)
-		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
-		assert.Contains(t, rec.Body.String(), `x and y must be greatest equal 1, height must be between 1 and 30`)
+		assert.Contains(t, rec.Body.String(), `"code":"ESTATE_NOT_FOUND"`)
 	})
 
 	t.Run("Failed: Out of Bound", func(t *testing.T) {
@@ -196,11 +191,13 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.PostEstateIdTree(c, estateID)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 		assert.Contains(t, rec.Body.String(), `"coordinate out of bound"`)
+		assert.Contains(t, rec.Body.String(), `"code":"OUT_OF_BOUNDS"`)
 	})
 
 	t.Run("Failed: plot have tree", func(t *testing.T) {
@@ -226,11 +223,12 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.PostEstateIdTree(c, estateID)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
-		assert.Contains(t, rec.Body.String(), `plot`)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"PLOT_OCCUPIED"`)
 	})
 
 	t.Run("Failed: invalid body payload", func(t *testing.T) {
@@ -247,6 +245,7 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.PostEstateIdTree(c, estateID)
 		assert.NoError(t, err)
@@ -267,7 +266,7 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 			Width:  10,
 			Length: 10,
 		}, nil)
-		mockRepo.EXPECT().FindEstateTree(gomock.Any(), gomock.Any()).Return(repository.EstateTree{}, errors.New("not found"))
+		mockRepo.EXPECT().FindEstateTree(gomock.Any(), gomock.Any()).Return(repository.EstateTree{}, repository.ErrNotFound)
 		mockRepo.EXPECT().CreateEstateTree(gomock.Any(), gomock.Any()).Return(errors.New("unexpected error"))
 
 		handler := NewServer(NewServerOptions{Repository: mockRepo})
@@ -277,10 +276,88 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.PostEstateIdTree(c, estateID)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("Failed : concurrent create conflict", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		estateID := uuid.NewString()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{Width: 10, Length: 10}, nil)
+		mockRepo.EXPECT().FindEstateTree(gomock.Any(), gomock.Any()).Return(repository.EstateTree{}, repository.ErrNotFound)
+		mockRepo.EXPECT().CreateEstateTree(gomock.Any(), gomock.Any()).Return(&repository.Error{Op: "CreateEstateTree", Kind: repository.ErrConflict})
+
+		handler := NewServer(NewServerOptions{Repository: mockRepo})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/estate/:id/tree", strings.NewReader(`{"x": 1, "y": 1, "height": 30}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
+
+		err := handler.PostEstateIdTree(c, estateID)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"PLOT_OCCUPIED"`)
+	})
+}
+
+func TestServer_GetEstate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().FindAllEstate(gomock.Any(), &repository.FilterEstate{
+		Filter:         repository.Filter{Limit: 2},
+		OrganizationID: "org-1",
+		After:          "estate-1",
+	}).Return([]repository.Estate{
+		{BaseModel: repository.BaseModel{ID: "estate-2", CreatedAt: createdAt}, OrganizationID: "org-1", Width: 10, Length: 20, Version: 3},
+	}, nil)
+
+	c, rec := newJobContext(http.MethodGet, "/estate?after=estate-1&limit=2", "")
+	after, limit := "estate-1", 2
+	err := NewServer(NewServerOptions{Repository: mockRepo}).GetEstate(c, generated.GetEstateParams{After: &after, Limit: &limit})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"estates":[{"id":"estate-2","width":10,"length":20,"version":3,"created_at":"2024-01-02T03:04:05Z"}]}`, rec.Body.String())
+}
+
+func TestServer_GetEstateId(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).
+			Return(repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 5, Length: 6, Version: 1}, nil)
+
+		c, rec := newJobContext(http.MethodGet, "/estate/estate-1", "")
+		assert.NoError(t, NewServer(NewServerOptions{Repository: mockRepo}).GetEstateId(c, "estate-1"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"id":"estate-1","width":5,"length":6,"version":1,"created_at":"0001-01-01T00:00:00Z"}`, rec.Body.String())
+	})
+
+	t.Run("Failed: Not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, repository.ErrNotFound)
+
+		c, rec := newJobContext(http.MethodGet, "/estate/estate-1", "")
+		assert.NoError(t, NewServer(NewServerOptions{Repository: mockRepo}).GetEstateId(c, "estate-1"))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"ESTATE_NOT_FOUND"`)
 	})
 }
 
@@ -293,7 +370,7 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 
 		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
 		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, nil)
-		mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(10)
+		mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(10, nil)
 		mockRepo.EXPECT().GetEstateTreeStats(gomock.Any(), gomock.Any()).Return(repository.EstateTreeStats{
 			Min:    10,
 			Max:    30,
@@ -307,6 +384,7 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.GetEstateIdStats(c, estateID)
 		assert.NoError(t, err)
@@ -324,7 +402,7 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 		estateID := uuid.NewString()
 
 		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
-		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, errors.New("not found"))
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, repository.ErrNotFound)
 
 		handler := NewServer(NewServerOptions{Repository: mockRepo})
 
@@ -333,6 +411,7 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.GetEstateIdStats(c, estateID)
 		assert.NoError(t, err)
@@ -347,7 +426,7 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 
 		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
 		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, nil)
-		mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(10)
+		mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(10, nil)
 		mockRepo.EXPECT().GetEstateTreeStats(gomock.Any(), gomock.Any()).Return(repository.EstateTreeStats{}, errors.New("unexpected error"))
 
 		handler := NewServer(NewServerOptions{Repository: mockRepo})
@@ -357,10 +436,36 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.GetEstateIdStats(c, estateID)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("Failed : count tree unavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		estateID := uuid.NewString()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, nil)
+		mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(0, &repository.Error{Op: "CountEstateTree", Kind: repository.ErrUnavailable})
+
+		handler := NewServer(NewServerOptions{Repository: mockRepo})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/estate/:id/stats", nil)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
+
+		err := handler.GetEstateIdStats(c, estateID)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"SERVICE_UNAVAILABLE"`)
 	})
 }
 
@@ -386,6 +491,7 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.GetEstateIdDronePlan(c, estateID, generated.GetEstateIdDronePlanParams{MaxDistance: nil})
 		assert.NoError(t, err)
@@ -416,6 +522,7 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.GetEstateIdDronePlan(c, estateID, generated.GetEstateIdDronePlanParams{MaxDistance: nil})
 		assert.NoError(t, err)
@@ -448,6 +555,7 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
+			auth.SetIdentity(c, testIdentity)
 
 			err := handler.GetEstateIdDronePlan(c, estateID, generated.GetEstateIdDronePlanParams{MaxDistance: &v})
 			assert.NoError(t, err)
@@ -463,7 +571,7 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 		estateID := uuid.NewString()
 
 		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
-		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, errors.New("not found"))
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, repository.ErrNotFound)
 
 		handler := NewServer(NewServerOptions{Repository: mockRepo})
 
@@ -472,6 +580,7 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.GetEstateIdDronePlan(c, estateID, generated.GetEstateIdDronePlanParams{MaxDistance: nil})
 		assert.NoError(t, err)
@@ -495,9 +604,10 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		auth.SetIdentity(c, testIdentity)
 
 		err := handler.GetEstateIdDronePlan(c, estateID, generated.GetEstateIdDronePlanParams{MaxDistance: nil})
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 }
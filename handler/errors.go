@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/dimassantoso/drone-sawit/idempotency"
+	"github.com/dimassantoso/drone-sawit/jobs"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// Error is an API error. It is rendered as a generated.ErrorResponse so
+// clients can branch on Code instead of parsing Message.
+type Error struct {
+	Status  int
+	Code    generated.ErrorCode
+	Message string
+	Details []generated.ErrorDetail
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+func newError(status int, code generated.ErrorCode, message string, details ...generated.ErrorDetail) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+		Details: details,
+	}
+}
+
+// defaultRetryAfter is the Retry-After, in seconds, of 429 responses whose
+// cause does not say when to retry.
+const defaultRetryAfter = "1"
+
+var errPlotOccupied = newError(http.StatusConflict, generated.PLOTOCCUPIED, "plot already has tree")
+
+func errOrganizationNotFound(organizationID string) *Error {
+	return newError(http.StatusNotFound, generated.NOTFOUND, fmt.Sprintf("organization %s not found", organizationID))
+}
+
+func errEstateNotFound(estateID string) *Error {
+	return newError(http.StatusNotFound, generated.ESTATENOTFOUND, fmt.Sprintf("estate %s not found", estateID))
+}
+
+func errJobNotFound(jobID string) *Error {
+	return newError(http.StatusNotFound, generated.NOTFOUND, fmt.Sprintf("job %s not found", jobID))
+}
+
+// toError converts any error into an API error. Errors from the repository
+// are mapped by their sentinel kind; notFound, when set, replaces the generic
+// response for repository.ErrNotFound. Unknown errors become INTERNAL_ERROR
+// so database details never reach the client.
+func toError(err error, notFound *Error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return fromHTTPError(httpErr)
+	}
+
+	var fieldErr *estates.FieldError
+	if errors.As(err, &fieldErr) {
+		return newError(http.StatusBadRequest, generated.VALIDATIONFAILED, "request does not match the API specification",
+			generated.ErrorDetail{Field: fieldErr.Field, Message: fieldErr.Message})
+	}
+
+	switch {
+	case errors.Is(err, estates.ErrOutOfBounds):
+		return newError(http.StatusBadRequest, generated.OUTOFBOUNDS, "coordinate out of bound")
+	case errors.Is(err, estates.ErrPlotOccupied):
+		return errPlotOccupied
+	case errors.Is(err, idempotency.ErrInvalidKey):
+		return newError(http.StatusBadRequest, generated.INVALIDREQUEST, "Idempotency-Key must be 1 to 255 printable ASCII characters")
+	case errors.Is(err, idempotency.ErrKeyReused):
+		return newError(http.StatusUnprocessableEntity, generated.IDEMPOTENCYKEYREUSED, "Idempotency-Key was used with a different request")
+	case errors.Is(err, idempotency.ErrInProgress):
+		return newError(http.StatusConflict, generated.IDEMPOTENCYKEYINPROGRESS, "a request with this Idempotency-Key is in progress")
+	case errors.Is(err, jobs.ErrFinished):
+		return newError(http.StatusConflict, generated.CONFLICT, "job already finished")
+	case errors.Is(err, planner.ErrBusy):
+		return newError(http.StatusTooManyRequests, generated.RATELIMITED, "too many drone plans in progress")
+	case errors.Is(err, repository.ErrNotFound):
+		if notFound != nil {
+			return notFound
+		}
+		return newError(http.StatusNotFound, generated.NOTFOUND, "resource not found")
+	case errors.Is(err, repository.ErrConflict):
+		return newError(http.StatusConflict, generated.CONFLICT, "resource conflicts with existing data")
+	case errors.Is(err, repository.ErrUnavailable):
+		return newError(http.StatusServiceUnavailable, generated.SERVICEUNAVAILABLE, "service temporarily unavailable")
+	case errors.Is(err, repository.ErrInvalidFilter):
+		return newError(http.StatusBadRequest, generated.INVALIDFILTER, "invalid filter")
+	default:
+		return newError(http.StatusInternalServerError, generated.INTERNALERROR, "internal server error")
+	}
+}
+
+func fromHTTPError(httpErr *echo.HTTPError) *Error {
+	message := http.StatusText(httpErr.Code)
+	if msg, ok := httpErr.Message.(string); ok && httpErr.Code < http.StatusInternalServerError {
+		message = msg
+	}
+
+	switch httpErr.Code {
+	case http.StatusUnauthorized:
+		return newError(httpErr.Code, generated.UNAUTHORIZED, message)
+	case http.StatusForbidden:
+		return newError(httpErr.Code, generated.FORBIDDEN, message)
+	case http.StatusNotFound:
+		return newError(httpErr.Code, generated.NOTFOUND, message)
+	case http.StatusMethodNotAllowed:
+		return newError(httpErr.Code, generated.METHODNOTALLOWED, message)
+	case http.StatusRequestEntityTooLarge:
+		// Every limit is reported as 429 with a Retry-After. The code tells
+		// clients that retrying the same body cannot succeed.
+		return newError(http.StatusTooManyRequests, generated.PAYLOADTOOLARGE, message)
+	case http.StatusTooManyRequests:
+		return newError(httpErr.Code, generated.RATELIMITED, message)
+	case http.StatusServiceUnavailable:
+		return newError(httpErr.Code, generated.SERVICEUNAVAILABLE, message)
+	}
+	if httpErr.Code >= http.StatusInternalServerError {
+		return newError(httpErr.Code, generated.INTERNALERROR, message)
+	}
+	return newError(httpErr.Code, generated.INVALIDREQUEST, message)
+}
+
+// writeError renders err as the JSON error envelope.
+func writeError(c echo.Context, err error) error {
+	return render(c, toError(err, nil), err)
+}
+
+// writeRepositoryError renders an error returned by the repository. notFound
+// is reported when the requested resource does not exist.
+func writeRepositoryError(c echo.Context, err error, notFound *Error) error {
+	return render(c, toError(err, notFound), err)
+}
+
+// render writes apiErr. Server errors are logged with their cause, which
+// the client never sees, unless the repository already logged it.
+func render(c echo.Context, apiErr *Error, cause error) error {
+	var repoErr *repository.Error
+	if apiErr.Status >= http.StatusInternalServerError && !errors.As(cause, &repoErr) {
+		ctx := c.Request().Context()
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelError, "request failed",
+			slog.String("code", string(apiErr.Code)),
+			slog.Any("error", cause),
+		)
+	}
+
+	// Throttled clients are told when to come back, unless the rate limiter
+	// already computed it.
+	if apiErr.Status == http.StatusTooManyRequests && c.Response().Header().Get("Retry-After") == "" {
+		c.Response().Header().Set("Retry-After", defaultRetryAfter)
+	}
+
+	errResponse := generated.ErrorResponse{
+		Code:    apiErr.Code,
+		Message: apiErr.Message,
+	}
+	if len(apiErr.Details) > 0 {
+		errResponse.Details = &apiErr.Details
+	}
+	if requestID := requestID(c); requestID != "" {
+		errResponse.RequestId = &requestID
+	}
+	return c.JSON(apiErr.Status, errResponse)
+}
+
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+// HTTPErrorHandler renders errors that escape the handlers, such as unknown
+// routes or malformed path parameters, using the same envelope.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	var writeErr error
+	if c.Request().Method == http.MethodHead {
+		writeErr = c.NoContent(toError(err, nil).Status)
+	} else {
+		writeErr = writeError(c, err)
+	}
+	if writeErr != nil {
+		ctx := c.Request().Context()
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelError, "write error response",
+			slog.Any("error", writeErr),
+		)
+	}
+}
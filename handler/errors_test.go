@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/dimassantoso/drone-sawit/idempotency"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToError(t *testing.T) {
+	testcases := []struct {
+		name   string
+		err    error
+		status int
+		code   generated.ErrorCode
+	}{
+		{name: "not found", err: &repository.Error{Kind: repository.ErrNotFound}, status: http.StatusNotFound, code: generated.NOTFOUND},
+		{name: "conflict", err: &repository.Error{Kind: repository.ErrConflict}, status: http.StatusConflict, code: generated.CONFLICT},
+		{name: "unavailable", err: &repository.Error{Kind: repository.ErrUnavailable}, status: http.StatusServiceUnavailable, code: generated.SERVICEUNAVAILABLE},
+		{name: "invalid filter", err: &repository.Error{Kind: repository.ErrInvalidFilter}, status: http.StatusBadRequest, code: generated.INVALIDFILTER},
+		{name: "unknown", err: errors.New("pq: relation does not exist"), status: http.StatusInternalServerError, code: generated.INTERNALERROR},
+		{name: "echo bad request", err: echo.NewHTTPError(http.StatusBadRequest, "Invalid format for parameter id"), status: http.StatusBadRequest, code: generated.INVALIDREQUEST},
+		{name: "echo route not found", err: echo.ErrNotFound, status: http.StatusNotFound, code: generated.NOTFOUND},
+		{name: "echo method not allowed", err: echo.ErrMethodNotAllowed, status: http.StatusMethodNotAllowed, code: generated.METHODNOTALLOWED},
+		{name: "echo body too large", err: echo.ErrStatusRequestEntityTooLarge, status: http.StatusTooManyRequests, code: generated.PAYLOADTOOLARGE},
+		{name: "echo too many requests", err: echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded"), status: http.StatusTooManyRequests, code: generated.RATELIMITED},
+		{name: "planner busy", err: planner.ErrBusy, status: http.StatusTooManyRequests, code: generated.RATELIMITED},
+		{name: "invalid idempotency key", err: idempotency.ErrInvalidKey, status: http.StatusBadRequest, code: generated.INVALIDREQUEST},
+		{name: "idempotency key reused", err: idempotency.ErrKeyReused, status: http.StatusUnprocessableEntity, code: generated.IDEMPOTENCYKEYREUSED},
+		{name: "idempotency key in progress", err: idempotency.ErrInProgress, status: http.StatusConflict, code: generated.IDEMPOTENCYKEYINPROGRESS},
+		{name: "api error", err: errPlotOccupied, status: http.StatusConflict, code: generated.PLOTOCCUPIED},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiErr := toError(tc.err, nil)
+			assert.Equal(t, tc.status, apiErr.Status)
+			assert.Equal(t, tc.code, apiErr.Code)
+		})
+	}
+
+	t.Run("not found override", func(t *testing.T) {
+		apiErr := toError(&repository.Error{Kind: repository.ErrNotFound}, errEstateNotFound("abc"))
+		assert.Equal(t, generated.ESTATENOTFOUND, apiErr.Code)
+		assert.Equal(t, "estate abc not found", apiErr.Message)
+	})
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	var logs bytes.Buffer
+	req = req.WithContext(logging.NewContext(req.Context(), slog.New(slog.NewTextHandler(&logs, nil))))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	HTTPErrorHandler(errors.New("pq: connection reset by peer"), c)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"code":"INTERNAL_ERROR","message":"internal server error","request_id":"req-1"}`, rec.Body.String())
+	assert.Contains(t, logs.String(), `msg="request failed" code=INTERNAL_ERROR error="pq: connection reset by peer"`)
+
+	t.Run("repository errors are logged by the repository", func(t *testing.T) {
+		logs.Reset()
+		rec := httptest.NewRecorder()
+		HTTPErrorHandler(&repository.Error{Op: "CountEstateTree", Err: errors.New("boom")}, e.NewContext(req, rec))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Empty(t, logs.String())
+	})
+}
+
+func TestHTTPErrorHandler_RetryAfter(t *testing.T) {
+	e := echo.New()
+
+	t.Run("default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		HTTPErrorHandler(planner.ErrBusy, e.NewContext(httptest.NewRequest(http.MethodGet, "/estate/abc/drone-plan", nil), rec))
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"code":"RATE_LIMITED","message":"too many drone plans in progress"}`, rec.Body.String())
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		HTTPErrorHandler(echo.ErrStatusRequestEntityTooLarge, e.NewContext(httptest.NewRequest(http.MethodPost, "/estate", nil), rec))
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"code":"PAYLOAD_TOO_LARGE","message":"Request Entity Too Large"}`, rec.Body.String())
+	})
+
+	t.Run("kept when already set", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/estate", nil), rec)
+		c.Response().Header().Set("Retry-After", "7")
+		HTTPErrorHandler(echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded"), c)
+
+		assert.Equal(t, "7", rec.Header().Get("Retry-After"))
+	})
+}
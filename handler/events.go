@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// estateEventsPageSize is the number of estate events a stream reads at
+// once.
+const estateEventsPageSize = 500
+
+// statsEvent is the type of the events carrying the stats of the estate.
+const statsEvent = "stats"
+
+func (s *Server) GetEstateIdEvents(c echo.Context, estateID string) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeRead)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	// Subscribe first, so changes committed while the stream opens wake it.
+	changed, unsubscribe := s.Events.Subscribe(estateID)
+	defer unsubscribe()
+
+	filterEstate := repository.FilterEstate{ID: estateID, OrganizationID: identity.OrganizationID}
+	estate, err := s.Repository.FindEstate(ctx, &filterEstate)
+	if err != nil {
+		return writeRepositoryError(c, err, errEstateNotFound(estateID))
+	}
+
+	stream := &estateStream{server: s, res: c.Response(), filter: filterEstate, version: estate.Version}
+	// Resuming clients get the events they missed first. Unknown or future
+	// ids resume from now.
+	if lastEventID, err := strconv.ParseInt(c.Request().Header.Get("Last-Event-ID"), 10, 64); err == nil && lastEventID >= 0 && lastEventID < estate.Version {
+		stream.version = lastEventID
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "text/event-stream")
+	header.Set(echo.HeaderCacheControl, "no-cache")
+	// Keep proxies such as nginx from buffering the stream.
+	header.Set("X-Accel-Buffering", "no")
+	// Streams outlive the write timeout of the server. Writers that cannot
+	// lift it, e.g. in tests, do not need to.
+	_ = http.NewResponseController(c.Response()).SetWriteDeadline(time.Time{})
+	c.Response().WriteHeader(http.StatusOK)
+
+	// Once the stream has started, failures can only end it: the client
+	// reconnects with the id of the last event it received. Repository
+	// errors are logged by the repository.
+	if err := stream.sync(ctx, true); err != nil {
+		return nil
+	}
+	heartbeat := time.NewTicker(s.Events.HeartbeatInterval())
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.Events.Done():
+			return nil
+		case <-changed:
+		case <-heartbeat.C:
+			// Heartbeats also catch changes whose notification was lost.
+			if err := stream.comment("keepalive"); err != nil {
+				return nil
+			}
+		}
+		if err := stream.sync(ctx, false); err != nil {
+			return nil
+		}
+	}
+}
+
+// estateStream writes the events of an estate after version, the version
+// of the estate its client has seen.
+type estateStream struct {
+	server  *Server
+	res     *echo.Response
+	filter  repository.FilterEstate
+	version int64
+}
+
+// sync sends the events after the version of the stream, followed by the
+// stats of the estate when there were any or when stats is set.
+func (st *estateStream) sync(ctx context.Context, stats bool) error {
+	for {
+		events, err := st.server.Repository.FindAllEstateEvent(ctx, &repository.FilterEstateEvent{
+			Filter:         repository.Filter{Limit: estateEventsPageSize},
+			OrganizationID: st.filter.OrganizationID,
+			EstateID:       st.filter.ID,
+			AfterVersion:   st.version,
+		})
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := st.send(event.Version, event.Type, event.Data); err != nil {
+				return err
+			}
+			st.version = event.Version
+			stats = true
+		}
+		if len(events) < estateEventsPageSize {
+			break
+		}
+	}
+	if !stats {
+		return nil
+	}
+
+	estate, err := st.server.Repository.FindEstate(ctx, &st.filter)
+	if err != nil {
+		return err
+	}
+	response, err := st.server.Estates.Stats(ctx, estate)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(generated.EstateStatsResponse(response))
+	if err != nil {
+		return err
+	}
+	return st.send(st.version, statsEvent, data)
+}
+
+// send writes an event whose data is single-line JSON.
+func (st *estateStream) send(id int64, event string, data []byte) error {
+	if _, err := fmt.Fprintf(st.res, "id: %d\nevent: %s\ndata: %s\n\n", id, event, data); err != nil {
+		return err
+	}
+	st.res.Flush()
+	return nil
+}
+
+func (st *estateStream) comment(text string) error {
+	if _, err := fmt.Fprintf(st.res, ": %s\n\n", text); err != nil {
+		return err
+	}
+	st.res.Flush()
+	return nil
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/events"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEventsContext returns the context of a stream request, and the func
+// that ends it as a disconnecting client would.
+func newEventsContext(lastEventID string) (echo.Context, func() string, context.CancelFunc) {
+	c, rec := newJobContext(http.MethodGet, "/estate/estate-1/events", "")
+	ctx, cancel := context.WithCancel(c.Request().Context())
+	req := c.Request().WithContext(ctx)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	c.SetRequest(req)
+	return c, rec.Body.String, cancel
+}
+
+func TestServer_GetEstateIdEvents(t *testing.T) {
+	estateFilter := &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}
+	estate := func(version int64) repository.Estate {
+		return repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Version: version}
+	}
+	eventsAfter := func(version int64) *repository.FilterEstateEvent {
+		return &repository.FilterEstateEvent{
+			Filter:         repository.Filter{Limit: estateEventsPageSize},
+			OrganizationID: "org-1",
+			EstateID:       "estate-1",
+			AfterVersion:   version,
+		}
+	}
+
+	t.Run("Resume", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		c, body, cancel := newEventsContext("3")
+		defer cancel()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		gomock.InOrder(
+			mockRepo.EXPECT().FindEstate(gomock.Any(), estateFilter).Return(estate(5), nil),
+			mockRepo.EXPECT().FindAllEstateEvent(gomock.Any(), eventsAfter(3)).Return([]repository.EstateEvent{
+				{EstateID: "estate-1", Version: 4, Type: repository.EstateEventTreeCreated, Data: []byte(`{"id":"tree-1"}`)},
+				{EstateID: "estate-1", Version: 5, Type: repository.EstateEventTreeDeleted, Data: []byte(`{"id":"tree-1"}`)},
+			}, nil),
+			mockRepo.EXPECT().FindEstate(gomock.Any(), estateFilter).Return(estate(5), nil),
+			mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(context.Context, *repository.FilterEstateTree) (int, error) {
+					cancel()
+					return 0, nil
+				}),
+		)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		require.NoError(t, server.GetEstateIdEvents(c, "estate-1"))
+		assert.Equal(t, http.StatusOK, c.Response().Status)
+		assert.Equal(t, "text/event-stream", c.Response().Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "no-cache", c.Response().Header().Get(echo.HeaderCacheControl))
+		assert.Equal(t, "id: 4\nevent: tree.created\ndata: {\"id\":\"tree-1\"}\n\n"+
+			"id: 5\nevent: tree.deleted\ndata: {\"id\":\"tree-1\"}\n\n"+
+			"id: 5\nevent: stats\ndata: {\"count\":0,\"max\":0,\"median\":0,\"min\":0}\n\n", body())
+	})
+
+	t.Run("Live", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		c, body, cancel := newEventsContext("")
+		defer cancel()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		gomock.InOrder(
+			mockRepo.EXPECT().FindEstate(gomock.Any(), estateFilter).Return(estate(5), nil),
+			mockRepo.EXPECT().FindAllEstateEvent(gomock.Any(), eventsAfter(5)).Return(nil, nil),
+			mockRepo.EXPECT().FindEstate(gomock.Any(), estateFilter).Return(estate(5), nil),
+			mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(1, nil),
+			mockRepo.EXPECT().GetEstateTreeStats(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(context.Context, *repository.FilterEstateTree) (repository.EstateTreeStats, error) {
+					server.Events.Publish("estate-1")
+					return repository.EstateTreeStats{Max: 10, Min: 10, Median: 10}, nil
+				}),
+			mockRepo.EXPECT().FindAllEstateEvent(gomock.Any(), eventsAfter(5)).Return([]repository.EstateEvent{
+				{EstateID: "estate-1", Version: 6, Type: repository.EstateEventTreeCreated, Data: []byte(`{"id":"tree-2"}`)},
+			}, nil),
+			mockRepo.EXPECT().FindEstate(gomock.Any(), estateFilter).Return(estate(6), nil),
+			mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(context.Context, *repository.FilterEstateTree) (int, error) {
+					cancel()
+					return 2, nil
+				}),
+			mockRepo.EXPECT().GetEstateTreeStats(gomock.Any(), gomock.Any()).Return(repository.EstateTreeStats{Max: 20, Min: 10, Median: 15}, nil),
+		)
+
+		require.NoError(t, server.GetEstateIdEvents(c, "estate-1"))
+		assert.Equal(t, "id: 5\nevent: stats\ndata: {\"count\":1,\"max\":10,\"median\":10,\"min\":10}\n\n"+
+			"id: 6\nevent: tree.created\ndata: {\"id\":\"tree-2\"}\n\n"+
+			"id: 6\nevent: stats\ndata: {\"count\":2,\"max\":20,\"median\":15,\"min\":10}\n\n", body())
+	})
+
+	t.Run("Heartbeat", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		c, body, cancel := newEventsContext("")
+		defer cancel()
+
+		broker := events.New(events.Options{HeartbeatInterval: 50 * time.Millisecond})
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		gomock.InOrder(
+			mockRepo.EXPECT().FindEstate(gomock.Any(), estateFilter).Return(estate(5), nil),
+			mockRepo.EXPECT().FindAllEstateEvent(gomock.Any(), eventsAfter(5)).Return(nil, nil),
+			mockRepo.EXPECT().FindEstate(gomock.Any(), estateFilter).Return(estate(5), nil),
+			mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).Return(0, nil),
+			// Nothing changed at the heartbeat, so no stats are sent; the
+			// server then shuts down.
+			mockRepo.EXPECT().FindAllEstateEvent(gomock.Any(), eventsAfter(5)).
+				DoAndReturn(func(context.Context, *repository.FilterEstateEvent) ([]repository.EstateEvent, error) {
+					broker.Close()
+					return nil, nil
+				}),
+		)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo, Events: broker})
+		require.NoError(t, server.GetEstateIdEvents(c, "estate-1"))
+		assert.Equal(t, "id: 5\nevent: stats\ndata: {\"count\":0,\"max\":0,\"median\":0,\"min\":0}\n\n"+
+			": keepalive\n\n", body())
+	})
+
+	t.Run("EstateNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		c, body, cancel := newEventsContext("")
+		defer cancel()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), estateFilter).Return(repository.Estate{}, repository.ErrNotFound)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		require.NoError(t, server.GetEstateIdEvents(c, "estate-1"))
+		assert.Equal(t, http.StatusNotFound, c.Response().Status)
+		assert.Contains(t, body(), `"code":"ESTATE_NOT_FOUND"`)
+	})
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/generated"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJobContext(method, target, body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	auth.SetIdentity(c, testIdentity)
+	return c, rec
+}
+
+func TestServer_PostEstateIdDronePlanJobs(t *testing.T) {
+	t.Run("Accepted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).
+			Return(repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}}, nil)
+		mockRepo.EXPECT().CreatePlanJob(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, job *repository.PlanJob) error {
+				assert.Equal(t, "org-1", job.OrganizationID)
+				assert.Equal(t, 100, *job.MaxDistance)
+				job.Status = repository.PlanJobQueued
+				return nil
+			})
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodPost, "/estate/estate-1/drone-plan/jobs", `{"max_distance":100}`)
+
+		require.NoError(t, server.PostEstateIdDronePlanJobs(c, "estate-1"))
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		var response generated.PlanJobResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.NotEmpty(t, response.Id)
+		assert.Equal(t, generated.Queued, response.Status)
+		assert.Equal(t, "/estate/estate-1/drone-plan/jobs/"+response.Id, rec.Header().Get(echo.HeaderLocation))
+	})
+
+	t.Run("WithoutBody", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, nil)
+		mockRepo.EXPECT().CreatePlanJob(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, job *repository.PlanJob) error {
+				assert.Nil(t, job.MaxDistance)
+				return nil
+			})
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodPost, "/estate/estate-1/drone-plan/jobs", "")
+
+		require.NoError(t, server.PostEstateIdDronePlanJobs(c, "estate-1"))
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("EstateNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, repository.ErrNotFound)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodPost, "/estate/estate-1/drone-plan/jobs", "")
+
+		require.NoError(t, server.PostEstateIdDronePlanJobs(c, "estate-1"))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), string(generated.ESTATENOTFOUND))
+	})
+
+	t.Run("MaxDistanceTooLarge", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		server := NewServer(NewServerOptions{Repository: mockrepo.NewMockRepositoryInterface(ctrl)})
+		c, rec := newJobContext(http.MethodPost, "/estate/estate-1/drone-plan/jobs", `{"max_distance":9007199254740992}`)
+
+		require.NoError(t, server.PostEstateIdDronePlanJobs(c, "estate-1"))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), string(generated.VALIDATIONFAILED))
+	})
+}
+
+func TestServer_GetEstateIdDronePlanJobsJobId(t *testing.T) {
+	t.Run("Succeeded", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		distance, restX, restY := 42, 3, 1
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindPlanJob(gomock.Any(), &repository.FilterPlanJob{ID: "job-1", OrganizationID: "org-1", EstateID: "estate-1"}).
+			Return(repository.PlanJob{
+				BaseModel: repository.BaseModel{ID: "job-1"},
+				EstateID:  "estate-1",
+				Status:    repository.PlanJobSucceeded,
+				Progress:  1,
+				Distance:  &distance,
+				RestX:     &restX,
+				RestY:     &restY,
+			}, nil)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodGet, "/estate/estate-1/drone-plan/jobs/job-1", "")
+
+		require.NoError(t, server.GetEstateIdDronePlanJobsJobId(c, "estate-1", "job-1"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response generated.PlanJobResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, generated.Succeeded, response.Status)
+		require.NotNil(t, response.Result)
+		assert.Equal(t, 42, response.Result.Distance)
+		assert.Equal(t, 3, response.Result.Rest.X)
+		assert.Nil(t, response.Error)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindPlanJob(gomock.Any(), gomock.Any()).Return(repository.PlanJob{}, repository.ErrNotFound)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodGet, "/estate/estate-1/drone-plan/jobs/job-1", "")
+
+		require.NoError(t, server.GetEstateIdDronePlanJobsJobId(c, "estate-1", "job-1"))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "job job-1 not found")
+	})
+}
+
+func TestServer_PostEstateIdDronePlanJobsJobIdCancel(t *testing.T) {
+	t.Run("Cancelled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		queued := repository.PlanJob{BaseModel: repository.BaseModel{ID: "job-1"}, Status: repository.PlanJobQueued}
+		cancelled := queued
+		cancelled.Status, cancelled.CancelRequested = repository.PlanJobCancelled, true
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindPlanJob(gomock.Any(), gomock.Any()).Return(queued, nil)
+		mockRepo.EXPECT().CancelPlanJob(gomock.Any(), &repository.FilterPlanJob{ID: "job-1", OrganizationID: "org-1"}).Return(cancelled, nil)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodPost, "/estate/estate-1/drone-plan/jobs/job-1/cancel", "")
+
+		require.NoError(t, server.PostEstateIdDronePlanJobsJobIdCancel(c, "estate-1", "job-1"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"status":"cancelled"`)
+	})
+
+	t.Run("Finished", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindPlanJob(gomock.Any(), gomock.Any()).
+			Return(repository.PlanJob{BaseModel: repository.BaseModel{ID: "job-1"}, Status: repository.PlanJobSucceeded}, nil)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodPost, "/estate/estate-1/drone-plan/jobs/job-1/cancel", "")
+
+		require.NoError(t, server.PostEstateIdDronePlanJobsJobIdCancel(c, "estate-1", "job-1"))
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), string(generated.CONFLICT))
+	})
+}
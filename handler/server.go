@@ -1,17 +1,76 @@
 package handler
 
-import "github.com/dimassantoso/drone-sawit/repository"
+import (
+	"net"
+
+	"github.com/dimassantoso/drone-sawit/cache"
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/events"
+	"github.com/dimassantoso/drone-sawit/jobs"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/dimassantoso/drone-sawit/webhooks"
+)
 
 type Server struct {
 	Repository repository.RepositoryInterface
+	Planner    *planner.Planner
+	Estates    *estates.Service
+	Cache      cache.Cache
+	Jobs       *jobs.Manager
+	Events     *events.Broker
+	Resolver   webhooks.Resolver
 }
 
 type NewServerOptions struct {
 	Repository repository.RepositoryInterface
+	// Planner computes drone plans. It defaults to a planner backed by
+	// Repository.
+	Planner *planner.Planner
+	// Cache stores computed estate stats. It defaults to no cache; plans
+	// are cached by the Planner.
+	Cache cache.Cache
+	// Estates creates estates and trees and computes stats. It defaults to
+	// a service backed by Repository and Cache.
+	Estates *estates.Service
+	// Jobs queues background drone plans. It defaults to a manager backed
+	// by Repository and Planner; its workers are started separately.
+	Jobs *jobs.Manager
+	// Events wakes the event streams of estates. It defaults to a broker
+	// that is never published, so streams only see changes at heartbeats;
+	// it is fed separately.
+	Events *events.Broker
+	// Resolver looks up the hosts of webhook URLs, which must not resolve
+	// to internal addresses. It defaults to net.DefaultResolver.
+	Resolver webhooks.Resolver
 }
 
 func NewServer(opts NewServerOptions) *Server {
+	if opts.Planner == nil {
+		opts.Planner = planner.New(planner.Options{Repository: opts.Repository})
+	}
+	if opts.Cache == nil {
+		opts.Cache = cache.Nop{}
+	}
+	if opts.Estates == nil {
+		opts.Estates = estates.New(estates.Options{Repository: opts.Repository, Cache: opts.Cache})
+	}
+	if opts.Jobs == nil {
+		opts.Jobs = jobs.New(jobs.Options{Repository: opts.Repository, Planner: opts.Planner})
+	}
+	if opts.Events == nil {
+		opts.Events = events.New(events.Options{})
+	}
+	if opts.Resolver == nil {
+		opts.Resolver = net.DefaultResolver
+	}
 	return &Server{
 		Repository: opts.Repository,
+		Planner:    opts.Planner,
+		Estates:    opts.Estates,
+		Cache:      opts.Cache,
+		Jobs:       opts.Jobs,
+		Events:     opts.Events,
+		Resolver:   opts.Resolver,
 	}
 }
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/generated"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTenantRepository returns a mock that behaves like the real repository
+// for a single estate owned by owner: lookups outside owner's organization
+// find nothing. Every tree call records the organization it was scoped to.
+func newTenantRepository(ctrl *gomock.Controller, owner string, estate repository.Estate, scopedTo *[]string) *mockrepo.MockRepositoryInterface {
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	record := func(organizationID string) bool {
+		*scopedTo = append(*scopedTo, organizationID)
+		return organizationID == owner
+	}
+
+	mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, filter *repository.FilterEstate) (repository.Estate, error) {
+			if !record(filter.OrganizationID) || filter.ID != estate.ID {
+				return repository.Estate{}, repository.ErrNotFound
+			}
+			return estate, nil
+		})
+	mockRepo.EXPECT().FindEstateTree(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, filter *repository.FilterEstateTree) (repository.EstateTree, error) {
+			record(filter.OrganizationID)
+			return repository.EstateTree{}, repository.ErrNotFound
+		})
+	mockRepo.EXPECT().CreateEstateTree(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, data *repository.EstateTree) error {
+			if !record(data.OrganizationID) {
+				return repository.ErrNotFound
+			}
+			return nil
+		})
+	mockRepo.EXPECT().CountEstateTree(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, filter *repository.FilterEstateTree) (int, error) {
+			record(filter.OrganizationID)
+			return 0, nil
+		})
+	mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, filter *repository.FilterEstateTree) (map[repository.CoordinatePoint]repository.EstateTree, error) {
+			record(filter.OrganizationID)
+			return map[repository.CoordinatePoint]repository.EstateTree{}, nil
+		})
+	return mockRepo
+}
+
+func TestTenantIsolation(t *testing.T) {
+	const owner, intruder = "org-1", "org-2"
+	estate := repository.Estate{
+		BaseModel:      repository.BaseModel{ID: uuid.NewString()},
+		OrganizationID: owner,
+		Width:          5,
+		Length:         5,
+	}
+
+	operations := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{name: "read stats", method: http.MethodGet, target: "/estate/" + estate.ID + "/stats", status: http.StatusOK},
+		{name: "compute plan", method: http.MethodGet, target: "/estate/" + estate.ID + "/drone-plan", status: http.StatusOK},
+		{name: "add tree", method: http.MethodPost, target: "/estate/" + estate.ID + "/tree", body: `{"x": 1, "y": 1, "height": 10}`, status: http.StatusCreated},
+	}
+
+	for _, op := range operations {
+		for _, caller := range []string{owner, intruder} {
+			t.Run(op.name+" as "+caller, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				var scopedTo []string
+				e := echo.New()
+				e.HTTPErrorHandler = HTTPErrorHandler
+				e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+					return func(c echo.Context) error {
+						auth.SetIdentity(c, auth.Identity{Subject: "user", OrganizationID: caller, Scopes: auth.Scopes})
+						return next(c)
+					}
+				})
+				generated.RegisterHandlers(e, NewServer(NewServerOptions{
+					Repository: newTenantRepository(ctrl, owner, estate, &scopedTo),
+				}))
+
+				req := httptest.NewRequest(op.method, op.target, strings.NewReader(op.body))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+
+				// Every repository call must be scoped to the caller.
+				assert.NotEmpty(t, scopedTo)
+				for _, organizationID := range scopedTo {
+					assert.Equal(t, caller, organizationID)
+				}
+
+				if caller == owner {
+					assert.Equal(t, op.status, rec.Code)
+					return
+				}
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+				assert.Contains(t, rec.Body.String(), `"code":"ESTATE_NOT_FOUND"`)
+				assert.Len(t, scopedTo, 1, "nothing but the estate lookup may run for another tenant")
+			})
+		}
+	}
+}
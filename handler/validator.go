@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/labstack/echo/v4"
+)
+
+// NewRequestValidator returns a middleware that validates request bodies,
+// path and query parameters against swagger, normally the spec embedded in
+// the generated package. Requests for routes the spec does not describe are
+// passed through so echo can answer them.
+func NewRequestValidator(swagger *openapi3.T) (echo.MiddlewareFunc, error) {
+	return NewRequestValidatorWithBaseURL(swagger, "")
+}
+
+// NewRequestValidatorWithBaseURL is NewRequestValidator for routes
+// registered under baseURL, e.g. "/v1", which the paths of swagger leave
+// out.
+func NewRequestValidatorWithBaseURL(swagger *openapi3.T, baseURL string) (echo.MiddlewareFunc, error) {
+	// Match on the path only: the servers listed in api.yml describe where
+	// the API is deployed, not the Host header of every request.
+	doc := *swagger
+	doc.Servers = nil
+
+	router, err := legacy.NewRouter(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("build openapi router: %w", err)
+	}
+
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			route, pathParams, err := router.FindRoute(withoutBaseURL(req, baseURL))
+			if err != nil {
+				return next(c)
+			}
+
+			err = openapi3filter.ValidateRequest(req.Context(), &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			})
+			if err != nil {
+				return writeError(c, validationError(err))
+			}
+			return next(c)
+		}
+	}, nil
+}
+
+// withoutBaseURL returns req with baseURL trimmed from its path, for
+// matching against the paths of the spec.
+func withoutBaseURL(req *http.Request, baseURL string) *http.Request {
+	if baseURL == "" {
+		return req
+	}
+	u := *req.URL
+	u.Path = strings.TrimPrefix(u.Path, baseURL)
+	u.RawPath = ""
+	r := *req
+	r.URL = &u
+	return &r
+}
+
+// validationError converts the errors reported by openapi3filter into a
+// VALIDATION_FAILED error with one detail per offending field.
+func validationError(err error) *Error {
+	var details []generated.ErrorDetail
+	for _, e := range flattenErrors(err) {
+		var reqErr *openapi3filter.RequestError
+		if !errors.As(e, &reqErr) {
+			continue
+		}
+
+		switch {
+		case reqErr.Parameter != nil:
+			schemaErrs := schemaErrors(reqErr.Err)
+			if len(schemaErrs) == 0 {
+				details = append(details, generated.ErrorDetail{Field: reqErr.Parameter.Name, Message: parameterReason(reqErr)})
+			}
+			for _, schemaErr := range schemaErrs {
+				details = append(details, generated.ErrorDetail{Field: reqErr.Parameter.Name, Message: schemaErr.Reason})
+			}
+		case reqErr.RequestBody != nil:
+			if errors.Is(reqErr.Err, openapi3filter.ErrInvalidRequired) {
+				details = append(details, generated.ErrorDetail{Field: "body", Message: "request body is required"})
+				continue
+			}
+			schemaErrs := schemaErrors(reqErr.Err)
+			if len(schemaErrs) == 0 {
+				// The body could not be decoded at all, e.g. malformed JSON
+				// or an unsupported content type.
+				return newError(http.StatusBadRequest, generated.INVALIDREQUEST, "invalid request body")
+			}
+			for _, schemaErr := range schemaErrs {
+				details = append(details, generated.ErrorDetail{Field: schemaErrorField(schemaErr), Message: schemaErr.Reason})
+			}
+		}
+	}
+
+	return newError(http.StatusBadRequest, generated.VALIDATIONFAILED, "request does not match the API specification", details...)
+}
+
+func flattenErrors(err error) []error {
+	multiErr, ok := err.(openapi3.MultiError)
+	if !ok {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, e := range multiErr {
+		errs = append(errs, flattenErrors(e)...)
+	}
+	return errs
+}
+
+func schemaErrors(err error) []*openapi3.SchemaError {
+	if err == nil {
+		return nil
+	}
+
+	var schemaErrs []*openapi3.SchemaError
+	for _, e := range flattenErrors(err) {
+		var schemaErr *openapi3.SchemaError
+		if errors.As(e, &schemaErr) {
+			schemaErrs = append(schemaErrs, schemaErr)
+		}
+	}
+	return schemaErrs
+}
+
+// schemaErrorField names the body field a schema error refers to, using dots
+// for nested fields.
+func schemaErrorField(schemaErr *openapi3.SchemaError) string {
+	path := schemaErr.JSONPointer()
+	if len(path) == 0 {
+		return "body"
+	}
+	return strings.Join(path, ".")
+}
+
+func parameterReason(reqErr *openapi3filter.RequestError) string {
+	var parseErr *openapi3filter.ParseError
+	if errors.As(reqErr.Err, &parseErr) {
+		return "invalid value"
+	}
+	if reqErr.Err != nil {
+		return reqErr.Err.Error()
+	}
+	return reqErr.Reason
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/generated"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newValidatedEcho(t *testing.T, repo repository.RepositoryInterface) *echo.Echo {
+	swagger, err := generated.GetSwagger()
+	require.NoError(t, err)
+	requestValidator, err := NewRequestValidator(swagger)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth.SetIdentity(c, testIdentity)
+			return next(c)
+		}
+	})
+	e.Use(requestValidator)
+	generated.RegisterHandlers(e, NewServer(NewServerOptions{Repository: repo}))
+	return e
+}
+
+func TestNewRequestValidator(t *testing.T) {
+	estateID := uuid.NewString()
+
+	testcases := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		code    generated.ErrorCode
+		details []generated.ErrorDetail
+	}{
+		{
+			name:   "estate width below minimum",
+			method: http.MethodPost,
+			target: "/estate",
+			body:   `{"width": -1}`,
+			code:   generated.VALIDATIONFAILED,
+			details: []generated.ErrorDetail{
+				{Field: "length", Message: `property "length" is missing`},
+				{Field: "width", Message: "number must be at least 1"},
+			},
+		},
+		{
+			name:   "estate length above maximum",
+			method: http.MethodPost,
+			target: "/estate",
+			body:   `{"width": 10, "length": 50001}`,
+			code:   generated.VALIDATIONFAILED,
+			details: []generated.ErrorDetail{
+				{Field: "length", Message: "number must be at most 50000"},
+			},
+		},
+		{
+			name:   "estate without body",
+			method: http.MethodPost,
+			target: "/estate",
+			code:   generated.VALIDATIONFAILED,
+			details: []generated.ErrorDetail{
+				{Field: "body", Message: "request body is required"},
+			},
+		},
+		{
+			name:   "estate malformed body",
+			method: http.MethodPost,
+			target: "/estate",
+			body:   `{"width": 3, "length":`,
+			code:   generated.INVALIDREQUEST,
+		},
+		{
+			name:   "tree height above maximum",
+			method: http.MethodPost,
+			target: "/estate/" + estateID + "/tree",
+			body:   `{"x": 1, "y": 1, "height": 50}`,
+			code:   generated.VALIDATIONFAILED,
+			details: []generated.ErrorDetail{
+				{Field: "height", Message: "number must be at most 30"},
+			},
+		},
+		{
+			name:   "tree coordinate below minimum",
+			method: http.MethodPost,
+			target: "/estate/" + estateID + "/tree",
+			body:   `{"x": 0, "y": 1, "height": 10}`,
+			code:   generated.VALIDATIONFAILED,
+			details: []generated.ErrorDetail{
+				{Field: "x", Message: "number must be at least 1"},
+			},
+		},
+		{
+			name:   "negative max distance",
+			method: http.MethodGet,
+			target: "/estate/" + estateID + "/drone-plan?max_distance=-1",
+			code:   generated.VALIDATIONFAILED,
+			details: []generated.ErrorDetail{
+				{Field: "max_distance", Message: "number must be at least 0"},
+			},
+		},
+		{
+			name:   "non numeric max distance",
+			method: http.MethodGet,
+			target: "/estate/" + estateID + "/drone-plan?max_distance=far",
+			code:   generated.VALIDATIONFAILED,
+			details: []generated.ErrorDetail{
+				{Field: "max_distance", Message: "invalid value"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			e := newValidatedEcho(t, mockrepo.NewMockRepositoryInterface(ctrl))
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var errResponse generated.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResponse))
+			assert.Equal(t, tc.code, errResponse.Code)
+			if tc.details == nil {
+				assert.Nil(t, errResponse.Details)
+			} else {
+				require.NotNil(t, errResponse.Details)
+				assert.ElementsMatch(t, tc.details, *errResponse.Details)
+			}
+		})
+	}
+
+	t.Run("Valid request reaches handler", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).Return(nil)
+		e := newValidatedEcho(t, mockRepo)
+
+		req := httptest.NewRequest(http.MethodPost, "/estate", strings.NewReader(`{"width": 50000, "length": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+
+	t.Run("Unknown route is left to echo", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		e := newValidatedEcho(t, mockrepo.NewMockRepositoryInterface(ctrl))
+
+		req := httptest.NewRequest(http.MethodGet, "/unknown", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"NOT_FOUND"`)
+	})
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/labstack/echo/v4"
+)
+
+// Versions of the REST API, each served under its own prefix, e.g.
+// /v1/estate.
+const (
+	V1 = "v1"
+)
+
+// UnprefixedVersion is the version the unprefixed routes serve, as the API
+// was served before it was versioned.
+const UnprefixedVersion = V1
+
+// Headers announcing the deprecation of a route.
+const (
+	// HeaderDeprecation is the date the route was deprecated, as in
+	// RFC 9745, e.g. "@1792281600".
+	HeaderDeprecation = "Deprecation"
+	// HeaderSunset is the date the route is to be removed, as in RFC 8594.
+	HeaderSunset = "Sunset"
+)
+
+// Version is a version of the REST API.
+type Version struct {
+	// Name is the prefix of its routes, without the slash.
+	Name string
+	// Spec loads its OpenAPI spec, which its requests are validated
+	// against.
+	Spec func() (*openapi3.T, error)
+	// Register registers its routes on router under baseURL, served by s.
+	Register func(router generated.EchoRouter, s *Server, baseURL string)
+}
+
+// Versions lists the versions of the API, oldest first. The generated
+// package holds v1. A v2 has its own spec, generated into a package of its
+// own, and a type implementing its ServerInterface that leaves the
+// operations it does not change to Server; it is then listed here.
+var Versions = []Version{
+	{
+		Name: V1,
+		Spec: generated.GetSwagger,
+		Register: func(router generated.EchoRouter, s *Server, baseURL string) {
+			generated.RegisterHandlersWithBaseURL(router, s, baseURL)
+		},
+	},
+}
+
+// Deprecation describes the deprecation of routes.
+type Deprecation struct {
+	// Date is when the routes were deprecated.
+	Date time.Time
+	// Sunset is when the routes are to be removed. Zero leaves the Sunset
+	// header out.
+	Sunset time.Time
+}
+
+type RouteOptions struct {
+	// Unprefixed, when set, also serves UnprefixedVersion without its
+	// prefix, announcing the deprecation in the headers of the responses.
+	Unprefixed *Deprecation
+}
+
+// RegisterRoutes registers the routes of every version on e, served by s.
+// The requests of each version are validated against its spec.
+func RegisterRoutes(e *echo.Echo, s *Server, opts RouteOptions) error {
+	for _, version := range Versions {
+		swagger, err := version.Spec()
+		if err != nil {
+			return fmt.Errorf("load %s spec: %w", version.Name, err)
+		}
+
+		baseURL := "/" + version.Name
+		validator, err := NewRequestValidatorWithBaseURL(swagger, baseURL)
+		if err != nil {
+			return fmt.Errorf("%s: %w", version.Name, err)
+		}
+		version.Register(routeMiddleware{router: e, middleware: []echo.MiddlewareFunc{validator}}, s, baseURL)
+
+		if opts.Unprefixed == nil || version.Name != UnprefixedVersion {
+			continue
+		}
+		validator, err = NewRequestValidator(swagger)
+		if err != nil {
+			return fmt.Errorf("%s: %w", version.Name, err)
+		}
+		version.Register(routeMiddleware{
+			router:     e,
+			middleware: []echo.MiddlewareFunc{deprecated(*opts.Unprefixed, baseURL), validator},
+		}, s, "")
+	}
+	return nil
+}
+
+// deprecated returns a middleware announcing d in the headers of every
+// response, with a link to the same route under successor, e.g. "/v1".
+// The headers are set before the handler runs so that errors carry them
+// too.
+func deprecated(d Deprecation, successor string) echo.MiddlewareFunc {
+	deprecation := "@" + strconv.FormatInt(d.Date.Unix(), 10)
+	var sunset string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set(HeaderDeprecation, deprecation)
+			if sunset != "" {
+				header.Set(HeaderSunset, sunset)
+			}
+			header.Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successor, c.Request().URL.Path))
+			return next(c)
+		}
+	}
+}
+
+// routeMiddleware registers routes on router with middleware appended to
+// theirs. Unlike an echo.Group, it adds no catch-all routes, so requests
+// outside the API still get echo's own 404 and 405.
+type routeMiddleware struct {
+	router     generated.EchoRouter
+	middleware []echo.MiddlewareFunc
+}
+
+func (r routeMiddleware) with(m []echo.MiddlewareFunc) []echo.MiddlewareFunc {
+	return append(append([]echo.MiddlewareFunc(nil), r.middleware...), m...)
+}
+
+func (r routeMiddleware) CONNECT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.CONNECT(path, h, r.with(m)...)
+}
+
+func (r routeMiddleware) DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.DELETE(path, h, r.with(m)...)
+}
+
+func (r routeMiddleware) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.GET(path, h, r.with(m)...)
+}
+
+func (r routeMiddleware) HEAD(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.HEAD(path, h, r.with(m)...)
+}
+
+func (r routeMiddleware) OPTIONS(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.OPTIONS(path, h, r.with(m)...)
+}
+
+func (r routeMiddleware) PATCH(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.PATCH(path, h, r.with(m)...)
+}
+
+func (r routeMiddleware) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.POST(path, h, r.with(m)...)
+}
+
+func (r routeMiddleware) PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.PUT(path, h, r.with(m)...)
+}
+
+func (r routeMiddleware) TRACE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route {
+	return r.router.TRACE(path, h, r.with(m)...)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterRoutes(t *testing.T) {
+	deprecation := Deprecation{
+		Date:   time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2027, time.April, 18, 0, 0, 0, 0, time.UTC),
+	}
+	newEcho := func(t *testing.T, mockRepo *mockrepo.MockRepositoryInterface, opts RouteOptions) *echo.Echo {
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				auth.SetIdentity(c, testIdentity)
+				return next(c)
+			}
+		})
+		require.NoError(t, RegisterRoutes(e, NewServer(NewServerOptions{Repository: mockRepo}), opts))
+		return e
+	}
+	post := func(e *echo.Echo, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Versioned route is not deprecated", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).Return(nil)
+		e := newEcho(t, mockRepo, RouteOptions{Unprefixed: &deprecation})
+
+		rec := post(e, "/v1/estate", `{"width": 10, "length": 10}`)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Empty(t, rec.Header().Get(HeaderDeprecation))
+		assert.Empty(t, rec.Header().Get(HeaderSunset))
+	})
+
+	t.Run("Unprefixed route is deprecated", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).Return(nil)
+		e := newEcho(t, mockRepo, RouteOptions{Unprefixed: &deprecation})
+
+		rec := post(e, "/estate", `{"width": 10, "length": 10}`)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "@1792281600", rec.Header().Get(HeaderDeprecation))
+		assert.Equal(t, "Sun, 18 Apr 2027 00:00:00 GMT", rec.Header().Get(HeaderSunset))
+		assert.Equal(t, `</v1/estate>; rel="successor-version"`, rec.Header().Get("Link"))
+	})
+
+	t.Run("Requests are validated on both routes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		e := newEcho(t, mockrepo.NewMockRepositoryInterface(ctrl), RouteOptions{Unprefixed: &deprecation})
+		estateID := uuid.NewString()
+
+		for _, target := range []string{"/v1/estate/" + estateID + "/tree", "/estate/" + estateID + "/tree"} {
+			rec := post(e, target, `{"x": 1, "y": 1, "height": 50}`)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+			assert.Contains(t, rec.Body.String(), `"field":"height"`, target)
+		}
+	})
+
+	t.Run("Deprecation without sunset", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		e := newEcho(t, mockrepo.NewMockRepositoryInterface(ctrl), RouteOptions{Unprefixed: &Deprecation{Date: deprecation.Date}})
+
+		rec := post(e, "/estate", `{"width": -1}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "@1792281600", rec.Header().Get(HeaderDeprecation), "errors carry the headers too")
+		assert.NotContains(t, rec.Header(), HeaderSunset)
+	})
+
+	t.Run("Unprefixed routes can be turned off", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		e := newEcho(t, mockrepo.NewMockRepositoryInterface(ctrl), RouteOptions{})
+
+		rec := post(e, "/estate", `{"width": 10, "length": 10}`)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"NOT_FOUND"`)
+	})
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/dimassantoso/drone-sawit/webhooks"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultDeliveryLimit is the number of deliveries listed without a limit.
+const defaultDeliveryLimit = 20
+
+func errSubscriptionNotFound(subscriptionID string) *Error {
+	return newError(http.StatusNotFound, generated.NOTFOUND, fmt.Sprintf("webhook subscription %s not found", subscriptionID))
+}
+
+func errInvalidWebhookURL(message string) *Error {
+	return newError(http.StatusBadRequest, generated.VALIDATIONFAILED, "request does not match the API specification",
+		generated.ErrorDetail{Field: "url", Message: message})
+}
+
+func (s *Server) PostWebhooks(c echo.Context) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeWebhooks)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	var req generated.WebhookSubscriptionRequest
+	if err = c.Bind(&req); err != nil {
+		return writeError(c, newError(http.StatusBadRequest, generated.INVALIDREQUEST, "invalid request body"))
+	}
+	u, err := url.Parse(req.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return writeError(c, errInvalidWebhookURL("must be an absolute http or https URL"))
+	}
+	if err = webhooks.CheckHost(ctx, s.Resolver, u.Hostname()); err != nil {
+		if errors.Is(err, webhooks.ErrForbiddenAddress) {
+			return writeError(c, errInvalidWebhookURL("must not be a loopback, private, shared, NAT64, link-local or unspecified address"))
+		}
+		return writeError(c, errInvalidWebhookURL("must have a host that resolves"))
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		return writeError(c, err)
+	}
+	subscription := repository.WebhookSubscription{
+		BaseModel:      repository.BaseModel{ID: uuid.NewString()},
+		OrganizationID: identity.OrganizationID,
+		URL:            req.Url,
+		Secret:         secret,
+		Events:         eventTypes(req.Events),
+	}
+	if err = s.Repository.CreateWebhookSubscription(ctx, &subscription); err != nil {
+		return writeRepositoryError(c, err, errOrganizationNotFound(identity.OrganizationID))
+	}
+
+	response := subscriptionResponse(subscription)
+	response.Secret = &subscription.Secret
+	return c.JSON(http.StatusCreated, response)
+}
+
+func (s *Server) GetWebhooks(c echo.Context) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeWebhooks)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	subscriptions, err := s.Repository.FindAllWebhookSubscription(ctx, &repository.FilterWebhookSubscription{OrganizationID: identity.OrganizationID})
+	if err != nil {
+		return writeRepositoryError(c, err, nil)
+	}
+
+	response := generated.WebhookSubscriptionListResponse{Subscriptions: []generated.WebhookSubscriptionResponse{}}
+	for _, subscription := range subscriptions {
+		response.Subscriptions = append(response.Subscriptions, subscriptionResponse(subscription))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (s *Server) DeleteWebhooksId(c echo.Context, subscriptionID string) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeWebhooks)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	err = s.Repository.DeleteWebhookSubscription(ctx, &repository.FilterWebhookSubscription{ID: subscriptionID, OrganizationID: identity.OrganizationID})
+	if err != nil {
+		return writeRepositoryError(c, err, errSubscriptionNotFound(subscriptionID))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) GetWebhooksIdDeliveries(c echo.Context, subscriptionID string, params generated.GetWebhooksIdDeliveriesParams) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeWebhooks)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	// An unknown subscription is reported as such rather than as having no
+	// deliveries.
+	_, err = s.Repository.FindWebhookSubscription(ctx, &repository.FilterWebhookSubscription{ID: subscriptionID, OrganizationID: identity.OrganizationID})
+	if err != nil {
+		return writeRepositoryError(c, err, errSubscriptionNotFound(subscriptionID))
+	}
+
+	filter := repository.FilterWebhookDelivery{
+		Filter:         repository.Filter{Limit: defaultDeliveryLimit},
+		OrganizationID: identity.OrganizationID,
+		SubscriptionID: subscriptionID,
+	}
+	if params.Status != nil {
+		filter.Status = repository.WebhookDeliveryStatus(*params.Status)
+	}
+	if params.Limit != nil {
+		filter.Limit = *params.Limit
+	}
+	deliveries, err := s.Repository.FindAllWebhookDelivery(ctx, &filter)
+	if err != nil {
+		return writeRepositoryError(c, err, nil)
+	}
+
+	response := generated.WebhookDeliveryListResponse{Deliveries: []generated.WebhookDeliveryResponse{}}
+	for _, delivery := range deliveries {
+		response.Deliveries = append(response.Deliveries, deliveryResponse(delivery))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (s *Server) PostWebhooksIdReplay(c echo.Context, subscriptionID string) error {
+	ctx := c.Request().Context()
+	identity, err := authorize(c, auth.ScopeWebhooks)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	var req generated.WebhookReplayRequest
+	if err = c.Bind(&req); err != nil {
+		return writeError(c, newError(http.StatusBadRequest, generated.INVALIDREQUEST, "invalid request body"))
+	}
+
+	_, err = s.Repository.FindWebhookSubscription(ctx, &repository.FilterWebhookSubscription{ID: subscriptionID, OrganizationID: identity.OrganizationID})
+	if err != nil {
+		return writeRepositoryError(c, err, errSubscriptionNotFound(subscriptionID))
+	}
+
+	filter := repository.FilterWebhookDelivery{OrganizationID: identity.OrganizationID, SubscriptionID: subscriptionID}
+	if req.DeliveryIds != nil {
+		filter.IDs = *req.DeliveryIds
+	}
+	replayed, err := s.Repository.ReplayWebhookDeliveries(ctx, &filter)
+	if err != nil {
+		return writeRepositoryError(c, err, nil)
+	}
+	return c.JSON(http.StatusOK, generated.WebhookReplayResponse{Replayed: int(replayed)})
+}
+
+// eventTypes returns the distinct event types of events.
+func eventTypes(events []generated.WebhookEventType) []string {
+	seen := map[generated.WebhookEventType]bool{}
+	var result []string
+	for _, event := range events {
+		if !seen[event] {
+			seen[event] = true
+			result = append(result, string(event))
+		}
+	}
+	return result
+}
+
+func subscriptionResponse(subscription repository.WebhookSubscription) generated.WebhookSubscriptionResponse {
+	response := generated.WebhookSubscriptionResponse{
+		Id:        subscription.ID,
+		Url:       subscription.URL,
+		Events:    []generated.WebhookEventType{},
+		CreatedAt: subscription.CreatedAt,
+	}
+	for _, event := range subscription.Events {
+		response.Events = append(response.Events, generated.WebhookEventType(event))
+	}
+	return response
+}
+
+func deliveryResponse(delivery repository.WebhookDelivery) generated.WebhookDeliveryResponse {
+	response := generated.WebhookDeliveryResponse{
+		Id:             delivery.ID,
+		EventId:        delivery.EventID,
+		EventType:      generated.WebhookEventType(delivery.EventType),
+		Status:         generated.WebhookDeliveryStatus(delivery.Status),
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == repository.WebhookDeliveryPending {
+		response.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.LastError != "" {
+		response.LastError = &delivery.LastError
+	}
+	return response
+}
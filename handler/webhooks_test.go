@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/generated"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_PostWebhooks(t *testing.T) {
+	t.Run("Created", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, subscription *repository.WebhookSubscription) error {
+				assert.Equal(t, "org-1", subscription.OrganizationID)
+				assert.Equal(t, "https://erp.example/hook", subscription.URL)
+				assert.Equal(t, []string{repository.WebhookEventTreeCreated}, subscription.Events, "duplicates are dropped")
+				assert.NotEmpty(t, subscription.Secret)
+				return nil
+			})
+
+		server := NewServer(NewServerOptions{Repository: mockRepo, Resolver: resolver})
+		c, rec := newJobContext(http.MethodPost, "/webhooks", `{"url":"https://erp.example/hook","events":["tree.created","tree.created"]}`)
+
+		require.NoError(t, server.PostWebhooks(c))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var response generated.WebhookSubscriptionResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.NotEmpty(t, response.Id)
+		assert.Equal(t, []generated.WebhookEventType{generated.TreeCreated}, response.Events)
+		require.NotNil(t, response.Secret, "the secret is returned once, on creation")
+	})
+
+	t.Run("InvalidURL", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		server := NewServer(NewServerOptions{Repository: mockrepo.NewMockRepositoryInterface(ctrl)})
+		c, rec := newJobContext(http.MethodPost, "/webhooks", `{"url":"ftp://erp.example/hook","events":["tree.created"]}`)
+
+		require.NoError(t, server.PostWebhooks(c))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"url"`)
+	})
+
+	t.Run("ForbiddenAddress", func(t *testing.T) {
+		for _, url := range []string{
+			"http://127.0.0.1:8081/metrics",
+			"http://localhost/hook",
+			"http://[::1]/hook",
+			"http://10.0.0.5/hook",
+			"https://192.168.1.10/hook",
+			"http://169.254.169.254/latest/meta-data",
+			"http://[fe80::1]/hook",
+			"http://0.0.0.0/hook",
+			"https://internal.example/hook",
+		} {
+			t.Run(url, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				server := NewServer(NewServerOptions{Repository: mockrepo.NewMockRepositoryInterface(ctrl), Resolver: resolver})
+				c, rec := newJobContext(http.MethodPost, "/webhooks", `{"url":"`+url+`","events":["tree.created"]}`)
+
+				require.NoError(t, server.PostWebhooks(c))
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.Contains(t, rec.Body.String(), `"message":"must not be a loopback, private, shared, NAT64, link-local or unspecified address"`)
+			})
+		}
+	})
+
+	t.Run("UnresolvableHost", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		server := NewServer(NewServerOptions{Repository: mockrepo.NewMockRepositoryInterface(ctrl), Resolver: resolver})
+		c, rec := newJobContext(http.MethodPost, "/webhooks", `{"url":"https://unknown.example/hook","events":["tree.created"]}`)
+
+		require.NoError(t, server.PostWebhooks(c))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"message":"must have a host that resolves"`)
+	})
+}
+
+// resolver resolves the hosts of the webhook URLs of the tests.
+var resolver = fakeResolver{
+	"erp.example":      {"203.0.113.10"},
+	"localhost":        {"127.0.0.1", "::1"},
+	"internal.example": {"10.0.0.5"},
+}
+
+// fakeResolver resolves the hosts in it, and no others.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs, nil
+}
+
+func TestServer_GetWebhooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().FindAllWebhookSubscription(gomock.Any(), &repository.FilterWebhookSubscription{OrganizationID: "org-1"}).
+		Return([]repository.WebhookSubscription{{
+			BaseModel: repository.BaseModel{ID: "sub-1"},
+			URL:       "https://erp.example/hook",
+			Secret:    "whsec_test",
+			Events:    repository.WebhookEventTypes,
+		}}, nil)
+
+	server := NewServer(NewServerOptions{Repository: mockRepo})
+	c, rec := newJobContext(http.MethodGet, "/webhooks", "")
+
+	require.NoError(t, server.GetWebhooks(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "whsec_test", "secrets are not listed")
+
+	var response generated.WebhookSubscriptionListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Subscriptions, 1)
+	assert.Equal(t, "sub-1", response.Subscriptions[0].Id)
+	assert.Len(t, response.Subscriptions[0].Events, len(repository.WebhookEventTypes))
+}
+
+func TestServer_DeleteWebhooksId(t *testing.T) {
+	t.Run("Deleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().DeleteWebhookSubscription(gomock.Any(), &repository.FilterWebhookSubscription{ID: "sub-1", OrganizationID: "org-1"}).
+			Return(nil)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodDelete, "/webhooks/sub-1", "")
+
+		require.NoError(t, server.DeleteWebhooksId(c, "sub-1"))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().DeleteWebhookSubscription(gomock.Any(), gomock.Any()).Return(repository.ErrNotFound)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodDelete, "/webhooks/sub-1", "")
+
+		require.NoError(t, server.DeleteWebhooksId(c, "sub-1"))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestServer_GetWebhooksIdDeliveries(t *testing.T) {
+	t.Run("Listed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		now := time.Now().UTC()
+		statusCode := http.StatusInternalServerError
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindWebhookSubscription(gomock.Any(), &repository.FilterWebhookSubscription{ID: "sub-1", OrganizationID: "org-1"}).
+			Return(repository.WebhookSubscription{}, nil)
+		mockRepo.EXPECT().FindAllWebhookDelivery(gomock.Any(), &repository.FilterWebhookDelivery{
+			Filter:         repository.Filter{Limit: defaultDeliveryLimit},
+			OrganizationID: "org-1",
+			SubscriptionID: "sub-1",
+			Status:         repository.WebhookDeliveryDead,
+		}).Return([]repository.WebhookDelivery{{
+			BaseModel:      repository.BaseModel{ID: "delivery-1", CreatedAt: now},
+			EventID:        "event-1",
+			EventType:      repository.WebhookEventEstateCreated,
+			Status:         repository.WebhookDeliveryDead,
+			Attempts:       8,
+			NextAttemptAt:  now,
+			LastStatusCode: &statusCode,
+			LastError:      "unexpected status 500 Internal Server Error",
+		}}, nil)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodGet, "/webhooks/sub-1/deliveries?status=dead", "")
+
+		status := generated.Dead
+		require.NoError(t, server.GetWebhooksIdDeliveries(c, "sub-1", generated.GetWebhooksIdDeliveriesParams{Status: &status}))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response generated.WebhookDeliveryListResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		require.Len(t, response.Deliveries, 1)
+		delivery := response.Deliveries[0]
+		assert.Equal(t, generated.EstateCreated, delivery.EventType)
+		assert.Equal(t, generated.Dead, delivery.Status)
+		assert.Equal(t, statusCode, *delivery.LastStatusCode)
+		assert.Nil(t, delivery.NextAttemptAt, "dead deliveries are not retried")
+	})
+
+	t.Run("SubscriptionNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindWebhookSubscription(gomock.Any(), gomock.Any()).Return(repository.WebhookSubscription{}, repository.ErrNotFound)
+
+		server := NewServer(NewServerOptions{Repository: mockRepo})
+		c, rec := newJobContext(http.MethodGet, "/webhooks/sub-1/deliveries", "")
+
+		require.NoError(t, server.GetWebhooksIdDeliveries(c, "sub-1", generated.GetWebhooksIdDeliveriesParams{}))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestServer_PostWebhooksIdReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().FindWebhookSubscription(gomock.Any(), gomock.Any()).Return(repository.WebhookSubscription{}, nil)
+	mockRepo.EXPECT().ReplayWebhookDeliveries(gomock.Any(), &repository.FilterWebhookDelivery{
+		OrganizationID: "org-1",
+		SubscriptionID: "sub-1",
+		IDs:            []string{"delivery-1", "delivery-2"},
+	}).Return(int64(2), nil)
+
+	server := NewServer(NewServerOptions{Repository: mockRepo})
+	c, rec := newJobContext(http.MethodPost, "/webhooks/sub-1/replay", `{"delivery_ids":["delivery-1","delivery-2"]}`)
+
+	require.NoError(t, server.PostWebhooksIdReplay(c, "sub-1"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"replayed":2}`, rec.Body.String())
+}
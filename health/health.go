@@ -0,0 +1,136 @@
+// Package health serves the liveness and readiness endpoints of the
+// service.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// Statuses reported by the endpoints.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// DefaultTimeout bounds the readiness checks when Checker.Timeout is zero.
+const DefaultTimeout = 2 * time.Second
+
+// Check is a dependency the service needs to serve requests.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Pinger is implemented by *repository.Repository.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Database checks that the database answers.
+func Database(db Pinger) Check {
+	return Check{Name: "database", Run: db.Ping}
+}
+
+// MigrationLister is implemented by *repository.Repository.
+type MigrationLister interface {
+	PendingMigrations(ctx context.Context) ([]repository.Migration, error)
+}
+
+// Migrations checks that the database schema is up to date.
+func Migrations(db MigrationLister) Check {
+	return Check{Name: "migrations", Run: func(ctx context.Context) error {
+		pending, err := db.PendingMigrations(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations, first is %d_%s", len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}}
+}
+
+// Response is the body of both endpoints.
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Checker runs the readiness checks.
+type Checker struct {
+	Checks  []Check
+	Timeout time.Duration
+
+	draining atomic.Bool
+}
+
+// New returns a checker running checks.
+func New(checks ...Check) *Checker {
+	return &Checker{Checks: checks}
+}
+
+// Drain makes the readiness endpoint fail from now on, so load balancers
+// stop routing requests while the server shuts down.
+func (h *Checker) Drain() {
+	h.draining.Store(true)
+}
+
+// Live answers as long as the process serves HTTP.
+func (h *Checker) Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, Response{Status: StatusOK})
+}
+
+// Ready runs every check concurrently and answers 503 when one fails. The
+// reason of a failure is logged, not returned, since the endpoint is not
+// authenticated.
+func (h *Checker) Ready(c echo.Context) error {
+	if h.draining.Load() {
+		return c.JSON(http.StatusServiceUnavailable, Response{Status: StatusDraining})
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+	defer cancel()
+
+	errs := make([]error, len(h.Checks))
+	var wg sync.WaitGroup
+	for i, check := range h.Checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			errs[i] = check.Run(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	response := Response{Status: StatusOK, Checks: make(map[string]string, len(h.Checks))}
+	status := http.StatusOK
+	for i, check := range h.Checks {
+		if errs[i] == nil {
+			response.Checks[check.Name] = StatusOK
+			continue
+		}
+		response.Status = StatusUnavailable
+		response.Checks[check.Name] = StatusUnavailable
+		status = http.StatusServiceUnavailable
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "readiness check failed",
+			slog.String("check", check.Name),
+			slog.Any("error", errs[i]),
+		)
+	}
+	return c.JSON(status, response)
+}
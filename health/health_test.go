@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDB struct {
+	pingErr error
+	pending []repository.Migration
+}
+
+func (f *fakeDB) Ping(context.Context) error { return f.pingErr }
+
+func (f *fakeDB) PendingMigrations(context.Context) ([]repository.Migration, error) {
+	return f.pending, nil
+}
+
+func serve(checker *Checker, path string) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.GET("/healthz", checker.Live)
+	e.GET("/readyz", checker.Ready)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestChecker_Live(t *testing.T) {
+	db := &fakeDB{pingErr: errors.New("down")}
+	rec := serve(New(Database(db)), "/healthz")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
+
+func TestChecker_Ready(t *testing.T) {
+	tests := []struct {
+		name   string
+		db     *fakeDB
+		status int
+		body   string
+	}{
+		{
+			name:   "ready",
+			db:     &fakeDB{},
+			status: http.StatusOK,
+			body:   `{"status":"ok","checks":{"database":"ok","migrations":"ok"}}`,
+		},
+		{
+			name:   "database down",
+			db:     &fakeDB{pingErr: errors.New("connection refused")},
+			status: http.StatusServiceUnavailable,
+			body:   `{"status":"unavailable","checks":{"database":"unavailable","migrations":"ok"}}`,
+		},
+		{
+			name:   "pending migrations",
+			db:     &fakeDB{pending: []repository.Migration{{Version: 2, Name: "audit"}}},
+			status: http.StatusServiceUnavailable,
+			body:   `{"status":"unavailable","checks":{"database":"ok","migrations":"unavailable"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(New(Database(tt.db), Migrations(tt.db)), "/readyz")
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.JSONEq(t, tt.body, rec.Body.String())
+		})
+	}
+}
+
+func TestChecker_ReadyTimeout(t *testing.T) {
+	checker := New(Check{Name: "slow", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	checker.Timeout = 10 * time.Millisecond
+
+	rec := serve(checker, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestChecker_Drain(t *testing.T) {
+	checker := New(Database(&fakeDB{}))
+	checker.Drain()
+
+	rec := serve(checker, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"draining"}`, rec.Body.String())
+
+	rec = serve(checker, "/healthz")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMigrations(t *testing.T) {
+	err := Migrations(&fakeDB{pending: []repository.Migration{{Version: 2, Name: "audit"}, {Version: 3, Name: "jobs"}}}).Run(context.Background())
+	assert.EqualError(t, err, "2 pending migrations, first is 2_audit")
+}
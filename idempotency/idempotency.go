@@ -0,0 +1,227 @@
+// Package idempotency lets clients retry POST requests safely. A request
+// carrying an Idempotency-Key header runs once; retries with the same key
+// and body replay the stored response.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	// HeaderKey carries the key chosen by the client, e.g. a UUID.
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed is set on replayed responses.
+	HeaderReplayed = "Idempotent-Replayed"
+)
+
+const (
+	// DefaultTTL is how long responses are kept when Config.TTL is zero.
+	DefaultTTL = 24 * time.Hour
+	// DefaultLockTimeout is how long a request may hold its key when
+	// Config.LockTimeout is zero.
+	DefaultLockTimeout = time.Minute
+	// maxKeyLength bounds the keys accepted from clients.
+	maxKeyLength = 255
+)
+
+// Errors returned by the middleware. The handler package renders them.
+var (
+	ErrInvalidKey = errors.New("idempotency: invalid key")
+	ErrKeyReused  = errors.New("idempotency: key reused with a different request")
+	ErrInProgress = errors.New("idempotency: a request with this key is in progress")
+)
+
+// Store persists keys and responses. It is implemented by
+// *repository.Repository.
+type Store interface {
+	ClaimIdempotencyKey(ctx context.Context, data *repository.IdempotencyKey, staleBefore time.Time) (bool, error)
+	FindIdempotencyKey(ctx context.Context, filter *repository.FilterIdempotencyKey) (repository.IdempotencyKey, error)
+	CompleteIdempotencyKey(ctx context.Context, data *repository.IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, filter *repository.FilterIdempotencyKey) error
+}
+
+// Config configures Middleware.
+type Config struct {
+	Skipper middleware.Skipper
+	Store   Store
+	// TTL is how long a response is replayed.
+	TTL time.Duration
+	// LockTimeout is how long a request may hold its key. A key held longer
+	// is considered abandoned, e.g. by a crashed server, and is taken over.
+	LockTimeout time.Duration
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// Middleware makes the requests carrying HeaderKey idempotent per
+// organization. Responses below 500, except 429, are stored and replayed;
+// other failures release the key so the client can retry. It must run
+// after the authentication middleware.
+func Middleware(config Config) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultTTL
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = DefaultLockTimeout
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderKey)
+			if key == "" || config.Skipper(c) {
+				return next(c)
+			}
+			identity, ok := auth.IdentityFromContext(c)
+			if !ok {
+				return next(c)
+			}
+			if !validKey(key) {
+				return ErrInvalidKey
+			}
+
+			req := c.Request()
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := config.Now()
+			record := repository.IdempotencyKey{
+				OrganizationID: identity.OrganizationID,
+				Key:            key,
+				Method:         req.Method,
+				Path:           req.URL.RequestURI(),
+				RequestHash:    requestHash(req.Method, req.URL.RequestURI(), body),
+				ExpiresAt:      now.Add(config.TTL),
+			}
+			ctx := req.Context()
+			claimed, err := config.Store.ClaimIdempotencyKey(ctx, &record, now.Add(-config.LockTimeout))
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				return replay(c, config.Store, record)
+			}
+			return run(c, next, config.Store, &record)
+		}
+	}
+}
+
+// replay answers a retry of the request recorded under the same key.
+func replay(c echo.Context, store Store, record repository.IdempotencyKey) error {
+	stored, err := store.FindIdempotencyKey(c.Request().Context(), &repository.FilterIdempotencyKey{
+		OrganizationID: record.OrganizationID,
+		Key:            record.Key,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		// Released or expired between the claim and now.
+		return ErrInProgress
+	}
+	if err != nil {
+		return err
+	}
+	if stored.RequestHash != record.RequestHash {
+		return ErrKeyReused
+	}
+	if stored.StatusCode == 0 {
+		return ErrInProgress
+	}
+
+	c.Response().Header().Set(HeaderReplayed, "true")
+	if len(stored.ResponseBody) == 0 {
+		return c.NoContent(stored.StatusCode)
+	}
+	return c.Blob(stored.StatusCode, stored.ContentType, stored.ResponseBody)
+}
+
+// run serves the request and stores its response, or releases the key when
+// the request may succeed on retry.
+func run(c echo.Context, next echo.HandlerFunc, store Store, record *repository.IdempotencyKey) error {
+	res := c.Response()
+	recorder := &responseRecorder{ResponseWriter: res.Writer}
+	res.Writer = recorder
+
+	err := next(c)
+	if err != nil {
+		// Let the error handler write the response now so it is the one
+		// stored.
+		c.Error(err)
+	}
+	res.Writer = recorder.ResponseWriter
+
+	// The response must be recorded even if the client went away, or the
+	// key would stay locked.
+	ctx := context.WithoutCancel(c.Request().Context())
+	var storeErr error
+	if res.Status >= http.StatusInternalServerError || res.Status == http.StatusTooManyRequests {
+		storeErr = store.DeleteIdempotencyKey(ctx, &repository.FilterIdempotencyKey{
+			OrganizationID: record.OrganizationID,
+			Key:            record.Key,
+		})
+	} else {
+		record.StatusCode = res.Status
+		record.ContentType = res.Header().Get(echo.HeaderContentType)
+		record.ResponseBody = recorder.body.Bytes()
+		storeErr = store.CompleteIdempotencyKey(ctx, record)
+	}
+	if storeErr != nil {
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelError, "store idempotent response",
+			slog.String("idempotency_key", record.Key),
+			slog.Any("error", storeErr),
+		)
+	}
+	return err
+}
+
+// requestHash identifies a request by its method, URI and body.
+func requestHash(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + uri + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// validKey accepts printable ASCII keys of reasonable length.
+func validKey(key string) bool {
+	if len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < '!' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// responseRecorder copies the response body as it is written.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
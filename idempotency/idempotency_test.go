@@ -0,0 +1,268 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is a Store keeping keys in memory, with the semantics of the
+// repository queries.
+type memoryStore struct {
+	mu   sync.Mutex
+	now  func() time.Time
+	keys map[string]repository.IdempotencyKey
+}
+
+func newMemoryStore(now func() time.Time) *memoryStore {
+	return &memoryStore{now: now, keys: map[string]repository.IdempotencyKey{}}
+}
+
+func (s *memoryStore) ClaimIdempotencyKey(_ context.Context, data *repository.IdempotencyKey, staleBefore time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := data.OrganizationID + "|" + data.Key
+	if held, ok := s.keys[id]; ok && held.ExpiresAt.After(s.now()) && (held.StatusCode != 0 || !held.CreatedAt.Before(staleBefore)) {
+		return false, nil
+	}
+	data.CreatedAt = s.now()
+	s.keys[id] = *data
+	return true, nil
+}
+
+func (s *memoryStore) FindIdempotencyKey(_ context.Context, filter *repository.FilterIdempotencyKey) (repository.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[filter.OrganizationID+"|"+filter.Key]
+	if !ok || !key.ExpiresAt.After(s.now()) {
+		return repository.IdempotencyKey{}, repository.ErrNotFound
+	}
+	return key, nil
+}
+
+func (s *memoryStore) CompleteIdempotencyKey(_ context.Context, data *repository.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[data.OrganizationID+"|"+data.Key] = *data
+	return nil
+}
+
+func (s *memoryStore) DeleteIdempotencyKey(_ context.Context, filter *repository.FilterIdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, filter.OrganizationID+"|"+filter.Key)
+	return nil
+}
+
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+type server struct {
+	*echo.Echo
+	calls int
+	// status is returned by the next call of the handler.
+	status int
+	// block, when set, is waited on by the handler.
+	block chan struct{}
+}
+
+func newServer(config Config) *server {
+	s := &server{Echo: echo.New(), status: http.StatusCreated}
+	s.HTTPErrorHandler = func(err error, c echo.Context) {
+		status := http.StatusInternalServerError
+		var httpErr *echo.HTTPError
+		switch {
+		case errors.As(err, &httpErr):
+			status = httpErr.Code
+		case errors.Is(err, ErrInvalidKey):
+			status = http.StatusBadRequest
+		case errors.Is(err, ErrKeyReused):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, ErrInProgress):
+			status = http.StatusConflict
+		}
+		_ = c.JSON(status, map[string]string{"error": err.Error()})
+	}
+	s.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if org := c.Request().Header.Get("X-Org"); org != "" {
+				auth.SetIdentity(c, auth.Identity{Subject: "apikey:" + org, OrganizationID: org})
+			}
+			return next(c)
+		}
+	})
+	s.Use(Middleware(config))
+	s.POST("/estate", func(c echo.Context) error {
+		s.calls++
+		if s.block != nil {
+			<-s.block
+		}
+		if s.status >= http.StatusBadRequest {
+			return echo.NewHTTPError(s.status)
+		}
+		return c.JSON(s.status, map[string]int{"call": s.calls})
+	})
+	return s
+}
+
+func (s *server) post(org, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/estate", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if org != "" {
+		req.Header.Set("X-Org", org)
+	}
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_Replay(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	s := newServer(Config{Store: newMemoryStore(clk.Now), TTL: time.Hour, Now: clk.Now})
+
+	first := s.post("org-1", "key-1", `{"width":10}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(HeaderReplayed))
+
+	retry := s.post("org-1", "key-1", `{"width":10}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(HeaderReplayed))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, echo.MIMEApplicationJSON, retry.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, 1, s.calls, "a retry does not run the handler")
+
+	other := s.post("org-2", "key-1", `{"width":10}`)
+	assert.Equal(t, http.StatusCreated, other.Code, "keys are scoped to the organization")
+	assert.Equal(t, 2, s.calls)
+
+	clk.Advance(time.Hour)
+	expired := s.post("org-1", "key-1", `{"width":10}`)
+	assert.Equal(t, http.StatusCreated, expired.Code)
+	assert.Empty(t, expired.Header().Get(HeaderReplayed), "expired keys are claimed again")
+	assert.Equal(t, 3, s.calls)
+}
+
+func TestMiddleware_KeyReused(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	s := newServer(Config{Store: newMemoryStore(clk.Now), Now: clk.Now})
+
+	require.Equal(t, http.StatusCreated, s.post("org-1", "key-1", `{"width":10}`).Code)
+
+	rec := s.post("org-1", "key-1", `{"width":20}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, 1, s.calls)
+}
+
+func TestMiddleware_ClientErrorsAreReplayed(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	s := newServer(Config{Store: newMemoryStore(clk.Now), Now: clk.Now})
+
+	s.status = http.StatusBadRequest
+	require.Equal(t, http.StatusBadRequest, s.post("org-1", "key-1", `{}`).Code)
+
+	s.status = http.StatusCreated
+	rec := s.post("org-1", "key-1", `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(HeaderReplayed))
+	assert.Equal(t, 1, s.calls)
+}
+
+func TestMiddleware_RetryableErrorsReleaseTheKey(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			clk := &clock{now: time.Unix(1_700_000_000, 0)}
+			s := newServer(Config{Store: newMemoryStore(clk.Now), Now: clk.Now})
+
+			s.status = status
+			require.Equal(t, status, s.post("org-1", "key-1", `{}`).Code)
+
+			s.status = http.StatusCreated
+			rec := s.post("org-1", "key-1", `{}`)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.Empty(t, rec.Header().Get(HeaderReplayed))
+			assert.Equal(t, 2, s.calls)
+		})
+	}
+}
+
+func TestMiddleware_InProgress(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	store := newMemoryStore(clk.Now)
+	s := newServer(Config{Store: store, LockTimeout: time.Minute, Now: clk.Now})
+
+	s.block = make(chan struct{})
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- s.post("org-1", "key-1", `{}`) }()
+	require.Eventually(t, func() bool {
+		_, err := store.FindIdempotencyKey(context.Background(), &repository.FilterIdempotencyKey{OrganizationID: "org-1", Key: "key-1"})
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, http.StatusConflict, s.post("org-1", "key-1", `{}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, s.post("org-1", "key-1", `{"width":1}`).Code,
+		"a different request is rejected even while the first runs")
+
+	close(s.block)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+	assert.Equal(t, 1, s.calls)
+}
+
+func TestMiddleware_StaleLockIsTakenOver(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	store := newMemoryStore(clk.Now)
+	s := newServer(Config{Store: store, LockTimeout: time.Minute, Now: clk.Now})
+
+	// A request that never completed, e.g. because the server crashed.
+	claimed, err := store.ClaimIdempotencyKey(context.Background(), &repository.IdempotencyKey{
+		OrganizationID: "org-1",
+		Key:            "key-1",
+		RequestHash:    requestHash(http.MethodPost, "/estate", []byte(`{}`)),
+		ExpiresAt:      clk.Now().Add(time.Hour),
+	}, clk.Now())
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	assert.Equal(t, http.StatusConflict, s.post("org-1", "key-1", `{}`).Code)
+
+	clk.Advance(2 * time.Minute)
+	assert.Equal(t, http.StatusCreated, s.post("org-1", "key-1", `{}`).Code)
+	assert.Equal(t, 1, s.calls)
+}
+
+func TestMiddleware_Passthrough(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	store := newMemoryStore(clk.Now)
+	s := newServer(Config{Store: store, Now: clk.Now})
+
+	assert.Equal(t, http.StatusCreated, s.post("org-1", "", `{}`).Code, "requests without a key")
+	assert.Equal(t, http.StatusCreated, s.post("", "key-1", `{}`).Code, "anonymous requests")
+	assert.Equal(t, 2, s.calls)
+	assert.Empty(t, store.keys)
+}
+
+func TestMiddleware_InvalidKey(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	s := newServer(Config{Store: newMemoryStore(clk.Now), Now: clk.Now})
+
+	for _, key := range []string{"has space", "tab\tkey", "ключ", strings.Repeat("k", maxKeyLength+1)} {
+		assert.Equal(t, http.StatusBadRequest, s.post("org-1", key, `{}`).Code, key)
+	}
+	assert.Zero(t, s.calls)
+}
@@ -0,0 +1,348 @@
+// Package jobs computes drone plans in the background. Jobs are stored in
+// Postgres and run by a pool of workers, so a job submitted to one replica
+// may run on another and survives restarts.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultWorkers is the size of the worker pool when Options.Workers is
+	// zero.
+	DefaultWorkers = 2
+	// DefaultPollInterval is how often idle workers look for jobs when
+	// Options.PollInterval is zero.
+	DefaultPollInterval = time.Second
+	// DefaultHeartbeatInterval is how often a running job records its
+	// progress when Options.HeartbeatInterval is zero.
+	DefaultHeartbeatInterval = 5 * time.Second
+	// DefaultStaleAfter is how long a running job may go without a
+	// heartbeat before another worker takes it over, when Options.StaleAfter
+	// is zero.
+	DefaultStaleAfter = time.Minute
+	// DefaultMaxAttempts is how many times a job is claimed before it fails,
+	// when Options.MaxAttempts is zero.
+	DefaultMaxAttempts = 3
+	// MaxDistance is the largest max_distance of a job: 2^53-1, the largest
+	// integer JSON clients hold exactly. Drones fly no estate that far.
+	MaxDistance = 1<<53 - 1
+)
+
+// ErrFinished is returned when cancelling a job that already finished.
+var ErrFinished = errors.New("jobs: job already finished")
+
+// Messages stored as the error of failed jobs. Causes are logged instead,
+// as they may reveal database details.
+const (
+	errEstateNotFound = "estate not found"
+	errInternal       = "internal error"
+	errAbandoned      = "abandoned after %d attempts"
+)
+
+type Options struct {
+	Repository repository.RepositoryInterface
+	Planner    *planner.Planner
+	// Workers is the number of jobs computed at once by this process.
+	Workers           int
+	PollInterval      time.Duration
+	HeartbeatInterval time.Duration
+	// StaleAfter must be well above HeartbeatInterval, or slow heartbeats
+	// get jobs taken over while they run.
+	StaleAfter  time.Duration
+	MaxAttempts int
+}
+
+// Manager submits, inspects and cancels jobs, and runs them with Run.
+type Manager struct {
+	repository        repository.RepositoryInterface
+	planner           *planner.Planner
+	workers           int
+	pollInterval      time.Duration
+	heartbeatInterval time.Duration
+	staleAfter        time.Duration
+	maxAttempts       int
+
+	// wake nudges an idle worker when a job is submitted.
+	wake chan struct{}
+
+	mu sync.Mutex
+	// running holds a function stopping each job running here, so a
+	// cancellation received by this process takes effect at once.
+	running map[string]func()
+}
+
+func New(opts Options) *Manager {
+	m := &Manager{
+		repository:        opts.Repository,
+		planner:           opts.Planner,
+		workers:           opts.Workers,
+		pollInterval:      opts.PollInterval,
+		heartbeatInterval: opts.HeartbeatInterval,
+		staleAfter:        opts.StaleAfter,
+		maxAttempts:       opts.MaxAttempts,
+		wake:              make(chan struct{}, 1),
+		running:           map[string]func(){},
+	}
+	if m.planner == nil {
+		m.planner = planner.New(planner.Options{Repository: opts.Repository})
+	}
+	if m.workers <= 0 {
+		m.workers = DefaultWorkers
+	}
+	if m.pollInterval <= 0 {
+		m.pollInterval = DefaultPollInterval
+	}
+	if m.heartbeatInterval <= 0 {
+		m.heartbeatInterval = DefaultHeartbeatInterval
+	}
+	if m.staleAfter <= 0 {
+		m.staleAfter = DefaultStaleAfter
+	}
+	if m.maxAttempts <= 0 {
+		m.maxAttempts = DefaultMaxAttempts
+	}
+	return m
+}
+
+// Submit queues a plan of estateID within organizationID. A maxDistance
+// above MaxDistance returns an *estates.FieldError. Repository errors are
+// returned as is, so repository.ErrNotFound reports an unknown estate.
+func (m *Manager) Submit(ctx context.Context, organizationID, estateID string, maxDistance *int) (repository.PlanJob, error) {
+	if maxDistance != nil && *maxDistance > MaxDistance {
+		return repository.PlanJob{}, &estates.FieldError{Field: "max_distance", Message: fmt.Sprintf("must be at most %d", MaxDistance)}
+	}
+	_, err := m.repository.FindEstate(ctx, &repository.FilterEstate{ID: estateID, OrganizationID: organizationID})
+	if err != nil {
+		return repository.PlanJob{}, err
+	}
+
+	job := repository.PlanJob{
+		BaseModel:      repository.BaseModel{ID: uuid.NewString()},
+		OrganizationID: organizationID,
+		EstateID:       estateID,
+		MaxDistance:    maxDistance,
+	}
+	if err = m.repository.CreatePlanJob(ctx, &job); err != nil {
+		return repository.PlanJob{}, err
+	}
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Get returns the job jobID of estateID.
+func (m *Manager) Get(ctx context.Context, organizationID, estateID, jobID string) (repository.PlanJob, error) {
+	return m.repository.FindPlanJob(ctx, &repository.FilterPlanJob{ID: jobID, OrganizationID: organizationID, EstateID: estateID})
+}
+
+// Cancel cancels a queued job, or asks the worker running it to stop; the
+// job is cancelled once it does. Cancelling a finished job returns
+// ErrFinished.
+func (m *Manager) Cancel(ctx context.Context, organizationID, estateID, jobID string) (repository.PlanJob, error) {
+	// The estate is checked first so a job of another estate is reported
+	// as not found rather than cancelled.
+	job, err := m.Get(ctx, organizationID, estateID, jobID)
+	if err != nil {
+		return repository.PlanJob{}, err
+	}
+	if job.Status != repository.PlanJobQueued && job.Status != repository.PlanJobRunning {
+		return job, ErrFinished
+	}
+
+	job, err = m.repository.CancelPlanJob(ctx, &repository.FilterPlanJob{ID: jobID, OrganizationID: organizationID})
+	if errors.Is(err, repository.ErrNotFound) {
+		// Finished in the meantime.
+		if job, err = m.Get(ctx, organizationID, estateID, jobID); err != nil {
+			return repository.PlanJob{}, err
+		}
+		return job, ErrFinished
+	}
+	if err != nil {
+		return repository.PlanJob{}, err
+	}
+
+	m.mu.Lock()
+	if stop, ok := m.running[jobID]; ok {
+		stop()
+	}
+	m.mu.Unlock()
+	return job, nil
+}
+
+// Run runs the worker pool until ctx is done. Jobs interrupted by ctx stay
+// running in the database and are taken over, here or by another replica,
+// once StaleAfter passes.
+func (m *Manager) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < m.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (m *Manager) work(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-m.wake:
+		}
+
+		// Drain the queue before waiting again.
+		for ctx.Err() == nil {
+			job, err := m.repository.ClaimPlanJob(ctx, time.Now().Add(-m.staleAfter))
+			if err != nil {
+				// Nothing to do, or a failure the repository already logged.
+				break
+			}
+			m.run(ctx, job)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(m.pollInterval)
+	}
+}
+
+// run computes a claimed job and records its outcome.
+func (m *Manager) run(ctx context.Context, job repository.PlanJob) {
+	logger := logging.FromContext(ctx).With(slog.String("job_id", job.ID), slog.Int("attempt", job.Attempts))
+	// Outcomes are recorded even when ctx is done, so a computed plan is not
+	// thrown away at shutdown.
+	finishCtx := context.WithoutCancel(ctx)
+
+	switch {
+	case job.CancelRequested:
+		m.finish(finishCtx, logger, job, repository.PlanJobCancelled, "")
+		return
+	case job.Attempts > m.maxAttempts:
+		m.finish(finishCtx, logger, job, repository.PlanJobFailed, fmt.Sprintf(errAbandoned, job.Attempts-1))
+		return
+	}
+
+	var progress atomic.Uint64
+	var cancelRequested, lost atomic.Bool
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m.mu.Lock()
+	m.running[job.ID] = func() {
+		cancelRequested.Store(true)
+		cancel()
+	}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, job.ID)
+		m.mu.Unlock()
+	}()
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		m.heartbeat(jobCtx, logger, job, &progress, &cancelRequested, &lost, cancel)
+	}()
+
+	result, err := m.planner.PlanWithProgress(jobCtx, job.OrganizationID, job.EstateID, job.MaxDistance, func(p float64) {
+		progress.Store(math.Float64bits(p))
+	})
+	cancel()
+	<-heartbeatDone
+
+	switch {
+	case lost.Load():
+		logger.Warn("plan job taken over by another worker")
+	case err == nil:
+		job.Progress = 1
+		job.Distance = &result.Distance
+		if result.Rest != nil {
+			job.RestX, job.RestY = &result.Rest.X, &result.Rest.Y
+		}
+		m.finish(finishCtx, logger, job, repository.PlanJobSucceeded, "")
+	case cancelRequested.Load():
+		job.Progress = math.Float64frombits(progress.Load())
+		m.finish(finishCtx, logger, job, repository.PlanJobCancelled, "")
+	case ctx.Err() != nil:
+		// Shutting down: leave the job to be taken over.
+		logger.Info("plan job interrupted")
+	case errors.Is(err, repository.ErrNotFound):
+		m.finish(finishCtx, logger, job, repository.PlanJobFailed, errEstateNotFound)
+	default:
+		logger.Error("plan job failed", slog.Any("error", err))
+		m.finish(finishCtx, logger, job, repository.PlanJobFailed, errInternal)
+	}
+}
+
+// heartbeat records the progress of job every HeartbeatInterval until ctx
+// is done, and stops the job when it is cancelled or taken over.
+func (m *Manager) heartbeat(ctx context.Context, logger *slog.Logger, job repository.PlanJob, progress *atomic.Uint64, cancelRequested, lost *atomic.Bool, cancel context.CancelFunc) {
+	ticker := time.NewTicker(m.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		job.Progress = math.Float64frombits(progress.Load())
+		requested, err := m.repository.UpdatePlanJobProgress(ctx, &job)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			lost.Store(true)
+			cancel()
+			return
+		case err != nil:
+			// Retried on the next tick; the job is only taken over after
+			// StaleAfter without a heartbeat.
+			logger.Warn("record plan job progress", slog.Any("error", err))
+		case requested:
+			cancelRequested.Store(true)
+			cancel()
+			return
+		}
+	}
+}
+
+func (m *Manager) finish(ctx context.Context, logger *slog.Logger, job repository.PlanJob, status repository.PlanJobStatus, message string) {
+	job.Status = status
+	job.Error = message
+	err := m.repository.FinishPlanJob(ctx, &job)
+	if errors.Is(err, repository.ErrNotFound) {
+		logger.Warn("plan job taken over by another worker")
+		return
+	}
+	if err != nil {
+		// The job is retried once it goes stale.
+		logger.Error("finish plan job", slog.Any("error", err))
+		return
+	}
+	logger.Info("plan job finished", slog.String("status", string(status)))
+}
@@ -0,0 +1,405 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/cache"
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository keeps estates and jobs in memory, with the semantics of
+// the plan job queries. Other methods are not implemented.
+type fakeRepository struct {
+	repository.RepositoryInterface
+
+	mu      sync.Mutex
+	estates map[string]repository.Estate
+	jobs    map[string]repository.PlanJob
+	// loadTrees, when set, is called before the trees of an estate are
+	// returned.
+	loadTrees func(ctx context.Context)
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		estates: map[string]repository.Estate{
+			"estate-1": {BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 5, Length: 4},
+		},
+		jobs: map[string]repository.PlanJob{},
+	}
+}
+
+func (r *fakeRepository) FindEstate(_ context.Context, filter *repository.FilterEstate) (repository.Estate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	estate, ok := r.estates[filter.ID]
+	if !ok || estate.OrganizationID != filter.OrganizationID {
+		return repository.Estate{}, repository.ErrNotFound
+	}
+	return estate, nil
+}
+
+func (r *fakeRepository) FindAllMapEstateTree(ctx context.Context, _ *repository.FilterEstateTree) (map[repository.CoordinatePoint]repository.EstateTree, error) {
+	if r.loadTrees != nil {
+		r.loadTrees(ctx)
+	}
+	return map[repository.CoordinatePoint]repository.EstateTree{{X: 2, Y: 1}: {X: 2, Y: 1, Height: 5}}, nil
+}
+
+func (r *fakeRepository) CreatePlanJob(_ context.Context, data *repository.PlanJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data.Status = repository.PlanJobQueued
+	data.CreatedAt = time.Now()
+	r.jobs[data.ID] = *data
+	return nil
+}
+
+func (r *fakeRepository) FindPlanJob(_ context.Context, filter *repository.FilterPlanJob) (repository.PlanJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[filter.ID]
+	if !ok || job.OrganizationID != filter.OrganizationID || (filter.EstateID != "" && job.EstateID != filter.EstateID) {
+		return repository.PlanJob{}, repository.ErrNotFound
+	}
+	return job, nil
+}
+
+func (r *fakeRepository) ClaimPlanJob(_ context.Context, staleBefore time.Time) (repository.PlanJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var candidates []repository.PlanJob
+	for _, job := range r.jobs {
+		if job.Status == repository.PlanJobQueued ||
+			(job.Status == repository.PlanJobRunning && job.HeartbeatAt.Before(staleBefore)) {
+			candidates = append(candidates, job)
+		}
+	}
+	if len(candidates) == 0 {
+		return repository.PlanJob{}, repository.ErrNotFound
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].CreatedAt.Before(candidates[j].CreatedAt) })
+	job := candidates[0]
+	now := time.Now()
+	job.Status = repository.PlanJobRunning
+	job.Attempts++
+	job.StartedAt, job.HeartbeatAt = &now, &now
+	r.jobs[job.ID] = job
+	return job, nil
+}
+
+func (r *fakeRepository) UpdatePlanJobProgress(_ context.Context, data *repository.PlanJob) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[data.ID]
+	if !ok || job.Attempts != data.Attempts || job.Status != repository.PlanJobRunning {
+		return false, repository.ErrNotFound
+	}
+	now := time.Now()
+	job.Progress, job.HeartbeatAt = data.Progress, &now
+	r.jobs[data.ID] = job
+	return job.CancelRequested, nil
+}
+
+func (r *fakeRepository) FinishPlanJob(_ context.Context, data *repository.PlanJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[data.ID]
+	if !ok || job.Attempts != data.Attempts || job.Status != repository.PlanJobRunning {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	job.Status, job.Progress, job.Error = data.Status, data.Progress, data.Error
+	job.Distance, job.RestX, job.RestY = data.Distance, data.RestX, data.RestY
+	job.FinishedAt, job.HeartbeatAt = &now, nil
+	r.jobs[data.ID] = job
+	return nil
+}
+
+func (r *fakeRepository) CancelPlanJob(_ context.Context, filter *repository.FilterPlanJob) (repository.PlanJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[filter.ID]
+	if !ok || job.OrganizationID != filter.OrganizationID ||
+		(job.Status != repository.PlanJobQueued && job.Status != repository.PlanJobRunning) {
+		return repository.PlanJob{}, repository.ErrNotFound
+	}
+	job.CancelRequested = true
+	if job.Status == repository.PlanJobQueued {
+		now := time.Now()
+		job.Status, job.FinishedAt = repository.PlanJobCancelled, &now
+	}
+	r.jobs[job.ID] = job
+	return job, nil
+}
+
+func (r *fakeRepository) job(id string) repository.PlanJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.jobs[id]
+}
+
+func newManager(repo *fakeRepository) *Manager {
+	return New(Options{
+		Repository:        repo,
+		Planner:           planner.New(planner.Options{Repository: repo}),
+		Workers:           1,
+		PollInterval:      10 * time.Millisecond,
+		HeartbeatInterval: 10 * time.Millisecond,
+		StaleAfter:        time.Minute,
+	})
+}
+
+// start runs m until the test ends.
+func start(t *testing.T, m *Manager) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func waitForStatus(t *testing.T, repo *fakeRepository, id string, status repository.PlanJobStatus) repository.PlanJob {
+	t.Helper()
+	require.Eventually(t, func() bool { return repo.job(id).Status == status }, 5*time.Second, time.Millisecond,
+		"job %s never became %s", id, status)
+	return repo.job(id)
+}
+
+func TestManager_Submit(t *testing.T) {
+	repo := newFakeRepository()
+	m := newManager(repo)
+	start(t, m)
+
+	maxDistance := 15
+	job, err := m.Submit(context.Background(), "org-1", "estate-1", &maxDistance)
+	require.NoError(t, err)
+	assert.Equal(t, repository.PlanJobQueued, job.Status)
+
+	job = waitForStatus(t, repo, job.ID, repository.PlanJobSucceeded)
+	assert.Equal(t, 1.0, job.Progress)
+	want := planner.Compute(5, 4, func(x, y int) int {
+		if x == 2 && y == 1 {
+			return 5
+		}
+		return 0
+	}, &maxDistance)
+	assert.Equal(t, want.Distance, *job.Distance)
+	assert.Equal(t, want.Rest.X, *job.RestX)
+	assert.Equal(t, want.Rest.Y, *job.RestY)
+	assert.Equal(t, 1, job.Attempts)
+
+	got, err := m.Get(context.Background(), "org-1", "estate-1", job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, job, got)
+
+	_, err = m.Get(context.Background(), "org-2", "estate-1", job.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "jobs are scoped to the organization")
+}
+
+func TestManager_Submit_LargeDistance(t *testing.T) {
+	repo := newFakeRepository()
+	estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-2"}, OrganizationID: "org-1", Width: 50000, Length: 50000}
+	repo.estates[estate.ID] = estate
+
+	// The largest estates are flown for longer than an int32 holds; the plan
+	// is cached rather than computed to keep the test fast.
+	maxDistance := 3 * math.MaxInt32
+	fingerprint := planner.Fingerprint(estate, &maxDistance)
+	value, err := json.Marshal(planner.Result{Distance: 3*math.MaxInt32 - 7, Rest: &planner.Point{X: 30000, Y: 40000}, Fingerprint: fingerprint})
+	require.NoError(t, err)
+	plans := cache.NewMemory(0)
+	require.NoError(t, plans.Set(context.Background(), fingerprint, value))
+
+	m := New(Options{
+		Repository:        repo,
+		Planner:           planner.New(planner.Options{Repository: repo, Cache: plans}),
+		Workers:           1,
+		PollInterval:      10 * time.Millisecond,
+		HeartbeatInterval: 10 * time.Millisecond,
+		StaleAfter:        time.Minute,
+	})
+	start(t, m)
+
+	job, err := m.Submit(context.Background(), "org-1", estate.ID, &maxDistance)
+	require.NoError(t, err)
+	job = waitForStatus(t, repo, job.ID, repository.PlanJobSucceeded)
+	assert.Equal(t, 3*math.MaxInt32, *job.MaxDistance)
+	assert.Equal(t, 3*math.MaxInt32-7, *job.Distance)
+}
+
+func TestManager_Submit_MaxDistanceTooLarge(t *testing.T) {
+	m := newManager(newFakeRepository())
+
+	maxDistance := MaxDistance + 1
+	_, err := m.Submit(context.Background(), "org-1", "estate-1", &maxDistance)
+	var fieldErr *estates.FieldError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "max_distance", fieldErr.Field)
+}
+
+func TestManager_Submit_UnknownEstate(t *testing.T) {
+	m := newManager(newFakeRepository())
+
+	_, err := m.Submit(context.Background(), "org-2", "estate-1", nil)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestManager_Cancel(t *testing.T) {
+	t.Run("queued", func(t *testing.T) {
+		repo := newFakeRepository()
+		m := newManager(repo)
+
+		job, err := m.Submit(context.Background(), "org-1", "estate-1", nil)
+		require.NoError(t, err)
+		job, err = m.Cancel(context.Background(), "org-1", "estate-1", job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, repository.PlanJobCancelled, job.Status)
+
+		start(t, m)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, repository.PlanJobCancelled, repo.job(job.ID).Status, "cancelled jobs are not run")
+	})
+
+	t.Run("running here", func(t *testing.T) {
+		repo := newFakeRepository()
+		loading := make(chan struct{})
+		repo.loadTrees = func(ctx context.Context) {
+			close(loading)
+			<-ctx.Done()
+		}
+		m := newManager(repo)
+		m.heartbeatInterval = time.Hour
+		start(t, m)
+
+		job, err := m.Submit(context.Background(), "org-1", "estate-1", nil)
+		require.NoError(t, err)
+		<-loading
+
+		job, err = m.Cancel(context.Background(), "org-1", "estate-1", job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, repository.PlanJobRunning, job.Status)
+		assert.True(t, job.CancelRequested)
+		waitForStatus(t, repo, job.ID, repository.PlanJobCancelled)
+	})
+
+	t.Run("running elsewhere", func(t *testing.T) {
+		repo := newFakeRepository()
+		loading := make(chan struct{})
+		repo.loadTrees = func(ctx context.Context) {
+			close(loading)
+			<-ctx.Done()
+		}
+		m := newManager(repo)
+		start(t, m)
+
+		job, err := m.Submit(context.Background(), "org-1", "estate-1", nil)
+		require.NoError(t, err)
+		<-loading
+
+		// Another replica received the cancellation; the heartbeat finds
+		// out.
+		_, err = newManager(repo).Cancel(context.Background(), "org-1", "estate-1", job.ID)
+		require.NoError(t, err)
+		waitForStatus(t, repo, job.ID, repository.PlanJobCancelled)
+	})
+
+	t.Run("finished", func(t *testing.T) {
+		repo := newFakeRepository()
+		m := newManager(repo)
+		start(t, m)
+
+		job, err := m.Submit(context.Background(), "org-1", "estate-1", nil)
+		require.NoError(t, err)
+		waitForStatus(t, repo, job.ID, repository.PlanJobSucceeded)
+
+		job, err = m.Cancel(context.Background(), "org-1", "estate-1", job.ID)
+		assert.ErrorIs(t, err, ErrFinished)
+		assert.Equal(t, repository.PlanJobSucceeded, job.Status)
+	})
+
+	t.Run("other estate", func(t *testing.T) {
+		repo := newFakeRepository()
+		m := newManager(repo)
+
+		job, err := m.Submit(context.Background(), "org-1", "estate-1", nil)
+		require.NoError(t, err)
+		_, err = m.Cancel(context.Background(), "org-1", "estate-2", job.ID)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+		assert.Equal(t, repository.PlanJobQueued, repo.job(job.ID).Status)
+	})
+}
+
+func TestManager_TakesOverStaleJobs(t *testing.T) {
+	repo := newFakeRepository()
+	m := newManager(repo)
+
+	// A job whose worker died: it stopped heartbeating long ago.
+	job, err := m.Submit(context.Background(), "org-1", "estate-1", nil)
+	require.NoError(t, err)
+	_, err = repo.ClaimPlanJob(context.Background(), time.Now())
+	require.NoError(t, err)
+	stale := time.Now().Add(-time.Hour)
+	repo.mu.Lock()
+	abandoned := repo.jobs[job.ID]
+	abandoned.HeartbeatAt = &stale
+	repo.jobs[job.ID] = abandoned
+	repo.mu.Unlock()
+
+	start(t, m)
+	job = waitForStatus(t, repo, job.ID, repository.PlanJobSucceeded)
+	assert.Equal(t, 2, job.Attempts)
+
+	// The first attempt can no longer write to the job.
+	abandoned.Status = repository.PlanJobFailed
+	assert.ErrorIs(t, repo.FinishPlanJob(context.Background(), &abandoned), repository.ErrNotFound)
+}
+
+func TestManager_GivesUpAfterMaxAttempts(t *testing.T) {
+	repo := newFakeRepository()
+	m := newManager(repo)
+	m.maxAttempts = 1
+
+	job, err := m.Submit(context.Background(), "org-1", "estate-1", nil)
+	require.NoError(t, err)
+	stale := time.Now().Add(-time.Hour)
+	repo.mu.Lock()
+	abandoned := repo.jobs[job.ID]
+	abandoned.Status, abandoned.Attempts, abandoned.HeartbeatAt = repository.PlanJobRunning, 1, &stale
+	repo.jobs[job.ID] = abandoned
+	repo.mu.Unlock()
+
+	start(t, m)
+	job = waitForStatus(t, repo, job.ID, repository.PlanJobFailed)
+	assert.Equal(t, "abandoned after 1 attempts", job.Error)
+}
+
+func TestManager_EstateDeleted(t *testing.T) {
+	repo := newFakeRepository()
+	m := newManager(repo)
+
+	job, err := m.Submit(context.Background(), "org-1", "estate-1", nil)
+	require.NoError(t, err)
+	repo.mu.Lock()
+	delete(repo.estates, "estate-1")
+	repo.mu.Unlock()
+
+	start(t, m)
+	job = waitForStatus(t, repo, job.ID, repository.PlanJobFailed)
+	assert.Equal(t, "estate not found", job.Error)
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("defaults to json at info", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, err := New(&buf, Config{})
+		require.NoError(t, err)
+
+		logger.Debug("hidden")
+		logger.Info("shown", slog.String("key", "value"))
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "shown", entry["msg"])
+		assert.Equal(t, "value", entry["key"])
+	})
+
+	t.Run("text at debug", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, err := New(&buf, Config{Level: "debug", Format: "text"})
+		require.NoError(t, err)
+
+		logger.Debug("shown")
+		assert.True(t, strings.HasPrefix(buf.String(), "time="))
+		assert.Contains(t, buf.String(), "level=DEBUG msg=shown")
+	})
+
+	t.Run("level is case insensitive", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger, err := New(&buf, Config{Level: "WARN"})
+		require.NoError(t, err)
+
+		logger.Info("hidden")
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		_, err := New(&bytes.Buffer{}, Config{Level: "verbose"})
+		assert.ErrorContains(t, err, `invalid level "verbose"`)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := New(&bytes.Buffer{}, Config{Format: "xml"})
+		assert.ErrorContains(t, err, `invalid format "xml"`)
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Same(t, slog.Default(), FromContext(context.Background()))
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	assert.Same(t, logger, FromContext(NewContext(context.Background(), logger)))
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestIDLength bounds the request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID accepts the X-Request-ID of the request when it is well formed
+// and generates one otherwise. The ID is echoed in the response header and
+// stored in the request context.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := WithRequestID(req.Context(), req.Header.Get(echo.HeaderXRequestID))
+			c.Response().Header().Set(echo.HeaderXRequestID, RequestIDFromContext(ctx))
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// WithRequestID returns a copy of ctx carrying id as its request ID when it
+// is well formed, and a generated one otherwise. Transports other than HTTP
+// use it where RequestID does not run.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if !validRequestID(id) {
+		id = uuid.NewString()
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// validRequestID allows IDs that are safe to log and echo back: letters,
+// digits and a few separators.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDFromContext returns the ID stored by RequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Middleware stores a logger carrying the request and trace IDs in the
+// request context and logs every request once it is answered. It must run
+// after RequestID and the tracing middleware.
+func Middleware(logger *slog.Logger, skipper middleware.Skipper) echo.MiddlewareFunc {
+	if skipper == nil {
+		skipper = middleware.DefaultSkipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := req.Context()
+
+			requestLogger := logger
+			if id := RequestIDFromContext(ctx); id != "" {
+				requestLogger = requestLogger.With(slog.String("request_id", id))
+			}
+			if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+				requestLogger = requestLogger.With(slog.String("trace_id", spanContext.TraceID().String()))
+			}
+			ctx = NewContext(ctx, requestLogger)
+			c.SetRequest(req.WithContext(ctx))
+
+			if skipper(c) {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Let the error handler write the response now so the
+				// status it picks is the one logged.
+				c.Error(err)
+			}
+
+			res := c.Response()
+			level := slog.LevelInfo
+			if res.Status >= 500 {
+				level = slog.LevelError
+			}
+			requestLogger.LogAttrs(ctx, level, "request",
+				slog.String("method", req.Method),
+				slog.String("route", c.Path()),
+				slog.String("path", req.URL.Path),
+				slog.Int("status", res.Status),
+				slog.Int64("bytes_out", res.Size),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("remote_ip", c.RealIP()),
+				slog.String("user_agent", req.UserAgent()),
+			)
+			return err
+		}
+	}
+}
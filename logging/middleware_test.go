@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func decodeEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestRequestID(t *testing.T) {
+	e := echo.New()
+	e.Use(RequestID())
+	var seen string
+	e.GET("/", func(c echo.Context) error {
+		seen = RequestIDFromContext(c.Request().Context())
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "accepts a well formed id", incoming: "req-42_a.b:c", keep: true},
+		{name: "generates when missing", incoming: ""},
+		{name: "replaces unsafe characters", incoming: "evil\nid"},
+		{name: "replaces long ids", incoming: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(echo.HeaderXRequestID, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(echo.HeaderXRequestID)
+			assert.Equal(t, id, seen)
+			if tt.keep {
+				assert.Equal(t, tt.incoming, id)
+				return
+			}
+			_, err := uuid.Parse(id)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	withSpan := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := trace.ContextWithSpanContext(c.Request().Context(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: traceID,
+				SpanID:  spanID,
+			}))
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+
+	e := echo.New()
+	e.Use(RequestID(), withSpan, Middleware(logger, func(c echo.Context) bool { return c.Path() == "/metrics" }))
+	e.GET("/estate/:id/stats", func(c echo.Context) error {
+		FromContext(c.Request().Context()).Info("handling")
+		return echo.NewHTTPError(http.StatusServiceUnavailable)
+	})
+	e.GET("/metrics", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/estate/abc/stats", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	entries := decodeEntries(t, &buf)
+	require.Len(t, entries, 2, "the skipped route is not logged")
+
+	handling, request := entries[0], entries[1]
+	assert.Equal(t, "handling", handling["msg"])
+	assert.Equal(t, "req-1", handling["request_id"])
+	assert.Equal(t, traceID.String(), handling["trace_id"])
+
+	assert.Equal(t, "request", request["msg"])
+	assert.Equal(t, "ERROR", request["level"])
+	assert.Equal(t, "req-1", request["request_id"])
+	assert.Equal(t, traceID.String(), request["trace_id"])
+	assert.Equal(t, http.MethodGet, request["method"])
+	assert.Equal(t, "/estate/:id/stats", request["route"])
+	assert.Equal(t, "/estate/abc/stats", request["path"])
+	assert.EqualValues(t, http.StatusServiceUnavailable, request["status"])
+	assert.Contains(t, request, "latency")
+}
@@ -0,0 +1,179 @@
+// Package metrics exposes Prometheus metrics for the HTTP API, the
+// repository and the drone planner.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dronesawit"
+
+// unmatchedRoute labels requests that matched no route, so scanners cannot
+// blow up the label cardinality with arbitrary paths.
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the collectors of the service.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+	planPlots       prometheus.Histogram
+	planDuration    prometheus.Histogram
+	planArea        prometheus.Histogram
+}
+
+// New registers the collectors of the service, along with the Go runtime
+// and process collectors, on a new registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "repository",
+			Name:      "query_duration_seconds",
+			Help:      "Repository method latency by method and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method", "outcome"}),
+		planPlots: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "drone_plan",
+			Name:      "plots_evaluated",
+			Help:      "Plots visited per computed drone plan.",
+			Buckets:   prometheus.ExponentialBuckets(1, 10, 10),
+		}),
+		planDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "drone_plan",
+			Name:      "compute_duration_seconds",
+			Help:      "Time spent computing a drone plan, excluding database access.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 12),
+		}),
+		planArea: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "drone_plan",
+			Name:      "estate_area_plots",
+			Help:      "Area, in plots, of the estates plans are computed for.",
+			Buckets:   prometheus.ExponentialBuckets(1, 10, 10),
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.queryDuration,
+		m.planPlots,
+		m.planDuration,
+		m.planArea,
+	)
+	return m
+}
+
+// Registry returns the registry holding the collectors.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func (m *Metrics) RegisterDB(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Middleware counts and times every request by its route template.
+func (m *Metrics) Middleware(skipper middleware.Skipper) echo.MiddlewareFunc {
+	if skipper == nil {
+		skipper = middleware.DefaultSkipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if skipper(c) {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Let the error handler write the response now so the
+				// status it picks is the one recorded.
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" || errors.Is(err, echo.ErrNotFound) || errors.Is(err, echo.ErrMethodNotAllowed) {
+				route = unmatchedRoute
+			}
+			status := strconv.Itoa(c.Response().Status)
+			m.requests.WithLabelValues(c.Request().Method, route, status).Inc()
+			m.requestDuration.WithLabelValues(c.Request().Method, route, status).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// QueryHook times repository methods. It is meant for
+// repository.NewRepositoryOptions.Hooks.
+func (m *Metrics) QueryHook(ctx context.Context, query repository.QueryInfo) (context.Context, func(err error)) {
+	start := time.Now()
+	return ctx, func(err error) {
+		m.queryDuration.WithLabelValues(query.Method, queryOutcome(err)).Observe(time.Since(start).Seconds())
+	}
+}
+
+// queryOutcome buckets repository errors by kind. Not found is a normal
+// answer, not a failure.
+func queryOutcome(err error) string {
+	switch {
+	case err == nil, errors.Is(err, repository.ErrNotFound):
+		return "ok"
+	case errors.Is(err, repository.ErrConflict):
+		return "conflict"
+	case errors.Is(err, repository.ErrInvalidFilter):
+		return "invalid_filter"
+	case errors.Is(err, repository.ErrUnavailable):
+		return "unavailable"
+	default:
+		return "error"
+	}
+}
+
+// ObservePlan records a computed drone plan. It implements planner.Observer.
+func (m *Metrics) ObservePlan(result planner.Result, duration time.Duration) {
+	m.planPlots.Observe(float64(result.PlotsEvaluated))
+	m.planDuration.Observe(duration.Seconds())
+	m.planArea.Observe(float64(result.Area))
+}
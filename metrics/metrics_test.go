@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dimassantoso/drone-sawit/planner"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_Middleware(t *testing.T) {
+	m := New()
+
+	e := echo.New()
+	e.Use(m.Middleware(func(c echo.Context) bool { return c.Path() == "/metrics" }))
+	e.GET("/estate/:id/stats", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.POST("/estate", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid")
+	})
+	e.GET("/metrics", echo.WrapHandler(m.Handler()))
+
+	for _, target := range []string{"/estate/a/stats", "/estate/b/stats"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/estate", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/wp-login.php", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, "/estate/:id/stats", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodPost, "/estate", "400")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.requests), "scrapes are skipped")
+	assert.Equal(t, 3, testutil.CollectAndCount(m.requestDuration))
+}
+
+func TestMetrics_QueryHook(t *testing.T) {
+	m := New()
+
+	testcases := []struct {
+		err     error
+		outcome string
+	}{
+		{err: nil, outcome: "ok"},
+		{err: &repository.Error{Op: "FindEstate", Kind: repository.ErrNotFound}, outcome: "ok"},
+		{err: &repository.Error{Op: "CreateEstateTree", Kind: repository.ErrConflict}, outcome: "conflict"},
+		{err: &repository.Error{Op: "FindEstate", Kind: repository.ErrUnavailable}, outcome: "unavailable"},
+		{err: assert.AnError, outcome: "error"},
+	}
+	for _, tc := range testcases {
+		_, end := m.QueryHook(context.Background(), repository.QueryInfo{Method: "FindEstate", Statement: "GetEstateQuery"})
+		end(tc.err)
+	}
+
+	families, err := m.Registry().Gather()
+	require.NoError(t, err)
+
+	// Durations depend on the machine, so only the sample counts are checked.
+	counts := map[string]uint64{}
+	for _, family := range families {
+		if family.GetName() != "dronesawit_repository_query_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			assert.Equal(t, "FindEstate", labels["method"])
+			counts[labels["outcome"]] = metric.GetHistogram().GetSampleCount()
+		}
+	}
+	assert.Equal(t, map[string]uint64{"ok": 2, "conflict": 1, "unavailable": 1, "error": 1}, counts)
+}
+
+func TestMetrics_ObservePlan(t *testing.T) {
+	m := New()
+
+	m.ObservePlan(planner.Result{Distance: 312, PlotsEvaluated: 18, Area: 18}, 2*time.Millisecond)
+	m.ObservePlan(planner.Result{Distance: 21, PlotsEvaluated: 2, Area: 18}, time.Millisecond)
+
+	expected := `
+# HELP dronesawit_drone_plan_plots_evaluated Plots visited per computed drone plan.
+# TYPE dronesawit_drone_plan_plots_evaluated histogram
+dronesawit_drone_plan_plots_evaluated_bucket{le="1"} 0
+dronesawit_drone_plan_plots_evaluated_bucket{le="10"} 1
+dronesawit_drone_plan_plots_evaluated_bucket{le="100"} 2
+dronesawit_drone_plan_plots_evaluated_bucket{le="1000"} 2
+dronesawit_drone_plan_plots_evaluated_bucket{le="10000"} 2
+dronesawit_drone_plan_plots_evaluated_bucket{le="100000"} 2
+dronesawit_drone_plan_plots_evaluated_bucket{le="1e+06"} 2
+dronesawit_drone_plan_plots_evaluated_bucket{le="1e+07"} 2
+dronesawit_drone_plan_plots_evaluated_bucket{le="1e+08"} 2
+dronesawit_drone_plan_plots_evaluated_bucket{le="1e+09"} 2
+dronesawit_drone_plan_plots_evaluated_bucket{le="+Inf"} 2
+dronesawit_drone_plan_plots_evaluated_sum 20
+dronesawit_drone_plan_plots_evaluated_count 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "dronesawit_drone_plan_plots_evaluated"))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.planDuration))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.planArea))
+}
+
+func TestMetrics_RegisterDB(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := New()
+	m.RegisterDB(db)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `go_sql_open_connections{db_name="dronesawit"}`)
+	assert.Contains(t, rec.Body.String(), "go_goroutines")
+}
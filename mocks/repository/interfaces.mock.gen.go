@@ -7,6 +7,7 @@ package repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	repository "github.com/dimassantoso/drone-sawit/repository"
 	gomock "github.com/golang/mock/gomock"
@@ -35,12 +36,102 @@ func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder
 	return m.recorder
 }
 
+// ApplyBatch mocks base method.
+func (m *MockRepositoryInterface) ApplyBatch(ctx context.Context, ops []repository.BatchOperation, atomic bool) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyBatch", ctx, ops, atomic)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyBatch indicates an expected call of ApplyBatch.
+func (mr *MockRepositoryInterfaceMockRecorder) ApplyBatch(ctx, ops, atomic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyBatch", reflect.TypeOf((*MockRepositoryInterface)(nil).ApplyBatch), ctx, ops, atomic)
+}
+
+// CancelPlanJob mocks base method.
+func (m *MockRepositoryInterface) CancelPlanJob(ctx context.Context, filter *repository.FilterPlanJob) (repository.PlanJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelPlanJob", ctx, filter)
+	ret0, _ := ret[0].(repository.PlanJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelPlanJob indicates an expected call of CancelPlanJob.
+func (mr *MockRepositoryInterfaceMockRecorder) CancelPlanJob(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelPlanJob", reflect.TypeOf((*MockRepositoryInterface)(nil).CancelPlanJob), ctx, filter)
+}
+
+// ClaimIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) ClaimIdempotencyKey(ctx context.Context, data *repository.IdempotencyKey, staleBefore time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimIdempotencyKey", ctx, data, staleBefore)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimIdempotencyKey indicates an expected call of ClaimIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) ClaimIdempotencyKey(ctx, data, staleBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).ClaimIdempotencyKey), ctx, data, staleBefore)
+}
+
+// ClaimPlanJob mocks base method.
+func (m *MockRepositoryInterface) ClaimPlanJob(ctx context.Context, staleBefore time.Time) (repository.PlanJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimPlanJob", ctx, staleBefore)
+	ret0, _ := ret[0].(repository.PlanJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimPlanJob indicates an expected call of ClaimPlanJob.
+func (mr *MockRepositoryInterfaceMockRecorder) ClaimPlanJob(ctx, staleBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimPlanJob", reflect.TypeOf((*MockRepositoryInterface)(nil).ClaimPlanJob), ctx, staleBefore)
+}
+
+// ClaimWebhookDelivery mocks base method.
+func (m *MockRepositoryInterface) ClaimWebhookDelivery(ctx context.Context, lease time.Duration) (repository.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimWebhookDelivery", ctx, lease)
+	ret0, _ := ret[0].(repository.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimWebhookDelivery indicates an expected call of ClaimWebhookDelivery.
+func (mr *MockRepositoryInterfaceMockRecorder) ClaimWebhookDelivery(ctx, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimWebhookDelivery", reflect.TypeOf((*MockRepositoryInterface)(nil).ClaimWebhookDelivery), ctx, lease)
+}
+
+// CompleteIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) CompleteIdempotencyKey(ctx context.Context, data *repository.IdempotencyKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteIdempotencyKey", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteIdempotencyKey indicates an expected call of CompleteIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) CompleteIdempotencyKey(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).CompleteIdempotencyKey), ctx, data)
+}
+
 // CountEstateTree mocks base method.
-func (m *MockRepositoryInterface) CountEstateTree(ctx context.Context, filter *repository.FilterEstateTree) int {
+func (m *MockRepositoryInterface) CountEstateTree(ctx context.Context, filter *repository.FilterEstateTree) (int, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CountEstateTree", ctx, filter)
 	ret0, _ := ret[0].(int)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CountEstateTree indicates an expected call of CountEstateTree.
@@ -49,6 +140,20 @@ func (mr *MockRepositoryInterfaceMockRecorder) CountEstateTree(ctx, filter inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountEstateTree", reflect.TypeOf((*MockRepositoryInterface)(nil).CountEstateTree), ctx, filter)
 }
 
+// CreateAPIKey mocks base method.
+func (m *MockRepositoryInterface) CreateAPIKey(ctx context.Context, data *repository.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateAPIKey(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateAPIKey), ctx, data)
+}
+
 // CreateEstate mocks base method.
 func (m *MockRepositoryInterface) CreateEstate(ctx context.Context, data *repository.Estate) error {
 	m.ctrl.T.Helper()
@@ -77,6 +182,194 @@ func (mr *MockRepositoryInterfaceMockRecorder) CreateEstateTree(ctx, data interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEstateTree", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateEstateTree), ctx, data)
 }
 
+// CreateEstateTrees mocks base method.
+func (m *MockRepositoryInterface) CreateEstateTrees(ctx context.Context, data []repository.EstateTree) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEstateTrees", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateEstateTrees indicates an expected call of CreateEstateTrees.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateEstateTrees(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEstateTrees", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateEstateTrees), ctx, data)
+}
+
+// CreateOrganization mocks base method.
+func (m *MockRepositoryInterface) CreateOrganization(ctx context.Context, data *repository.Organization) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrganization", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOrganization indicates an expected call of CreateOrganization.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateOrganization(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrganization", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateOrganization), ctx, data)
+}
+
+// CreatePlanJob mocks base method.
+func (m *MockRepositoryInterface) CreatePlanJob(ctx context.Context, data *repository.PlanJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePlanJob", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePlanJob indicates an expected call of CreatePlanJob.
+func (mr *MockRepositoryInterfaceMockRecorder) CreatePlanJob(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePlanJob", reflect.TypeOf((*MockRepositoryInterface)(nil).CreatePlanJob), ctx, data)
+}
+
+// CreateWebhookSubscription mocks base method.
+func (m *MockRepositoryInterface) CreateWebhookSubscription(ctx context.Context, data *repository.WebhookSubscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhookSubscription", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWebhookSubscription indicates an expected call of CreateWebhookSubscription.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateWebhookSubscription(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhookSubscription", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateWebhookSubscription), ctx, data)
+}
+
+// DeleteEstateTree mocks base method.
+func (m *MockRepositoryInterface) DeleteEstateTree(ctx context.Context, filter *repository.FilterEstateTree) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteEstateTree", ctx, filter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteEstateTree indicates an expected call of DeleteEstateTree.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteEstateTree(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEstateTree", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteEstateTree), ctx, filter)
+}
+
+// DeleteExpiredIdempotencyKeys mocks base method.
+func (m *MockRepositoryInterface) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredIdempotencyKeys", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredIdempotencyKeys indicates an expected call of DeleteExpiredIdempotencyKeys.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteExpiredIdempotencyKeys(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredIdempotencyKeys", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteExpiredIdempotencyKeys), ctx)
+}
+
+// DeleteIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) DeleteIdempotencyKey(ctx context.Context, filter *repository.FilterIdempotencyKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdempotencyKey", ctx, filter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIdempotencyKey indicates an expected call of DeleteIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteIdempotencyKey(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteIdempotencyKey), ctx, filter)
+}
+
+// DeleteWebhookSubscription mocks base method.
+func (m *MockRepositoryInterface) DeleteWebhookSubscription(ctx context.Context, filter *repository.FilterWebhookSubscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhookSubscription", ctx, filter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhookSubscription indicates an expected call of DeleteWebhookSubscription.
+func (mr *MockRepositoryInterfaceMockRecorder) DeleteWebhookSubscription(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhookSubscription", reflect.TypeOf((*MockRepositoryInterface)(nil).DeleteWebhookSubscription), ctx, filter)
+}
+
+// FindAPIKey mocks base method.
+func (m *MockRepositoryInterface) FindAPIKey(ctx context.Context, filter *repository.FilterAPIKey) (repository.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAPIKey", ctx, filter)
+	ret0, _ := ret[0].(repository.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAPIKey indicates an expected call of FindAPIKey.
+func (mr *MockRepositoryInterfaceMockRecorder) FindAPIKey(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAPIKey", reflect.TypeOf((*MockRepositoryInterface)(nil).FindAPIKey), ctx, filter)
+}
+
+// FindAllAPIKey mocks base method.
+func (m *MockRepositoryInterface) FindAllAPIKey(ctx context.Context, filter *repository.FilterAPIKey) ([]repository.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAllAPIKey", ctx, filter)
+	ret0, _ := ret[0].([]repository.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAllAPIKey indicates an expected call of FindAllAPIKey.
+func (mr *MockRepositoryInterfaceMockRecorder) FindAllAPIKey(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllAPIKey", reflect.TypeOf((*MockRepositoryInterface)(nil).FindAllAPIKey), ctx, filter)
+}
+
+// FindAllAuditEntry mocks base method.
+func (m *MockRepositoryInterface) FindAllAuditEntry(ctx context.Context, filter *repository.FilterAuditEntry) ([]repository.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAllAuditEntry", ctx, filter)
+	ret0, _ := ret[0].([]repository.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAllAuditEntry indicates an expected call of FindAllAuditEntry.
+func (mr *MockRepositoryInterfaceMockRecorder) FindAllAuditEntry(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllAuditEntry", reflect.TypeOf((*MockRepositoryInterface)(nil).FindAllAuditEntry), ctx, filter)
+}
+
+// FindAllEstate mocks base method.
+func (m *MockRepositoryInterface) FindAllEstate(ctx context.Context, filter *repository.FilterEstate) ([]repository.Estate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAllEstate", ctx, filter)
+	ret0, _ := ret[0].([]repository.Estate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAllEstate indicates an expected call of FindAllEstate.
+func (mr *MockRepositoryInterfaceMockRecorder) FindAllEstate(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllEstate", reflect.TypeOf((*MockRepositoryInterface)(nil).FindAllEstate), ctx, filter)
+}
+
+// FindAllEstateEvent mocks base method.
+func (m *MockRepositoryInterface) FindAllEstateEvent(ctx context.Context, filter *repository.FilterEstateEvent) ([]repository.EstateEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAllEstateEvent", ctx, filter)
+	ret0, _ := ret[0].([]repository.EstateEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAllEstateEvent indicates an expected call of FindAllEstateEvent.
+func (mr *MockRepositoryInterfaceMockRecorder) FindAllEstateEvent(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllEstateEvent", reflect.TypeOf((*MockRepositoryInterface)(nil).FindAllEstateEvent), ctx, filter)
+}
+
 // FindAllMapEstateTree mocks base method.
 func (m *MockRepositoryInterface) FindAllMapEstateTree(ctx context.Context, filter *repository.FilterEstateTree) (map[repository.CoordinatePoint]repository.EstateTree, error) {
 	m.ctrl.T.Helper()
@@ -92,6 +385,51 @@ func (mr *MockRepositoryInterfaceMockRecorder) FindAllMapEstateTree(ctx, filter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllMapEstateTree", reflect.TypeOf((*MockRepositoryInterface)(nil).FindAllMapEstateTree), ctx, filter)
 }
 
+// FindAllOrganization mocks base method.
+func (m *MockRepositoryInterface) FindAllOrganization(ctx context.Context, filter *repository.FilterOrganization) ([]repository.Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAllOrganization", ctx, filter)
+	ret0, _ := ret[0].([]repository.Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAllOrganization indicates an expected call of FindAllOrganization.
+func (mr *MockRepositoryInterfaceMockRecorder) FindAllOrganization(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllOrganization", reflect.TypeOf((*MockRepositoryInterface)(nil).FindAllOrganization), ctx, filter)
+}
+
+// FindAllWebhookDelivery mocks base method.
+func (m *MockRepositoryInterface) FindAllWebhookDelivery(ctx context.Context, filter *repository.FilterWebhookDelivery) ([]repository.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAllWebhookDelivery", ctx, filter)
+	ret0, _ := ret[0].([]repository.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAllWebhookDelivery indicates an expected call of FindAllWebhookDelivery.
+func (mr *MockRepositoryInterfaceMockRecorder) FindAllWebhookDelivery(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllWebhookDelivery", reflect.TypeOf((*MockRepositoryInterface)(nil).FindAllWebhookDelivery), ctx, filter)
+}
+
+// FindAllWebhookSubscription mocks base method.
+func (m *MockRepositoryInterface) FindAllWebhookSubscription(ctx context.Context, filter *repository.FilterWebhookSubscription) ([]repository.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAllWebhookSubscription", ctx, filter)
+	ret0, _ := ret[0].([]repository.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAllWebhookSubscription indicates an expected call of FindAllWebhookSubscription.
+func (mr *MockRepositoryInterfaceMockRecorder) FindAllWebhookSubscription(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllWebhookSubscription", reflect.TypeOf((*MockRepositoryInterface)(nil).FindAllWebhookSubscription), ctx, filter)
+}
+
 // FindEstate mocks base method.
 func (m *MockRepositoryInterface) FindEstate(ctx context.Context, filter *repository.FilterEstate) (repository.Estate, error) {
 	m.ctrl.T.Helper()
@@ -122,6 +460,94 @@ func (mr *MockRepositoryInterfaceMockRecorder) FindEstateTree(ctx, filter interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindEstateTree", reflect.TypeOf((*MockRepositoryInterface)(nil).FindEstateTree), ctx, filter)
 }
 
+// FindIdempotencyKey mocks base method.
+func (m *MockRepositoryInterface) FindIdempotencyKey(ctx context.Context, filter *repository.FilterIdempotencyKey) (repository.IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindIdempotencyKey", ctx, filter)
+	ret0, _ := ret[0].(repository.IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindIdempotencyKey indicates an expected call of FindIdempotencyKey.
+func (mr *MockRepositoryInterfaceMockRecorder) FindIdempotencyKey(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindIdempotencyKey", reflect.TypeOf((*MockRepositoryInterface)(nil).FindIdempotencyKey), ctx, filter)
+}
+
+// FindOrganization mocks base method.
+func (m *MockRepositoryInterface) FindOrganization(ctx context.Context, filter *repository.FilterOrganization) (repository.Organization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrganization", ctx, filter)
+	ret0, _ := ret[0].(repository.Organization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrganization indicates an expected call of FindOrganization.
+func (mr *MockRepositoryInterfaceMockRecorder) FindOrganization(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrganization", reflect.TypeOf((*MockRepositoryInterface)(nil).FindOrganization), ctx, filter)
+}
+
+// FindPlanJob mocks base method.
+func (m *MockRepositoryInterface) FindPlanJob(ctx context.Context, filter *repository.FilterPlanJob) (repository.PlanJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPlanJob", ctx, filter)
+	ret0, _ := ret[0].(repository.PlanJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPlanJob indicates an expected call of FindPlanJob.
+func (mr *MockRepositoryInterfaceMockRecorder) FindPlanJob(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPlanJob", reflect.TypeOf((*MockRepositoryInterface)(nil).FindPlanJob), ctx, filter)
+}
+
+// FindWebhookSubscription mocks base method.
+func (m *MockRepositoryInterface) FindWebhookSubscription(ctx context.Context, filter *repository.FilterWebhookSubscription) (repository.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindWebhookSubscription", ctx, filter)
+	ret0, _ := ret[0].(repository.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindWebhookSubscription indicates an expected call of FindWebhookSubscription.
+func (mr *MockRepositoryInterfaceMockRecorder) FindWebhookSubscription(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindWebhookSubscription", reflect.TypeOf((*MockRepositoryInterface)(nil).FindWebhookSubscription), ctx, filter)
+}
+
+// FinishPlanJob mocks base method.
+func (m *MockRepositoryInterface) FinishPlanJob(ctx context.Context, data *repository.PlanJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishPlanJob", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishPlanJob indicates an expected call of FinishPlanJob.
+func (mr *MockRepositoryInterfaceMockRecorder) FinishPlanJob(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishPlanJob", reflect.TypeOf((*MockRepositoryInterface)(nil).FinishPlanJob), ctx, data)
+}
+
+// FinishWebhookDelivery mocks base method.
+func (m *MockRepositoryInterface) FinishWebhookDelivery(ctx context.Context, data *repository.WebhookDelivery, retryAfter time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishWebhookDelivery", ctx, data, retryAfter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishWebhookDelivery indicates an expected call of FinishWebhookDelivery.
+func (mr *MockRepositoryInterfaceMockRecorder) FinishWebhookDelivery(ctx, data, retryAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishWebhookDelivery", reflect.TypeOf((*MockRepositoryInterface)(nil).FinishWebhookDelivery), ctx, data, retryAfter)
+}
+
 // GetEstateTreeStats mocks base method.
 func (m *MockRepositoryInterface) GetEstateTreeStats(ctx context.Context, filter *repository.FilterEstateTree) (repository.EstateTreeStats, error) {
 	m.ctrl.T.Helper()
@@ -136,3 +562,61 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetEstateTreeStats(ctx, filter in
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEstateTreeStats", reflect.TypeOf((*MockRepositoryInterface)(nil).GetEstateTreeStats), ctx, filter)
 }
+
+// ReplayWebhookDeliveries mocks base method.
+func (m *MockRepositoryInterface) ReplayWebhookDeliveries(ctx context.Context, filter *repository.FilterWebhookDelivery) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplayWebhookDeliveries", ctx, filter)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplayWebhookDeliveries indicates an expected call of ReplayWebhookDeliveries.
+func (mr *MockRepositoryInterfaceMockRecorder) ReplayWebhookDeliveries(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayWebhookDeliveries", reflect.TypeOf((*MockRepositoryInterface)(nil).ReplayWebhookDeliveries), ctx, filter)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockRepositoryInterface) RevokeAPIKey(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockRepositoryInterfaceMockRecorder) RevokeAPIKey(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockRepositoryInterface)(nil).RevokeAPIKey), ctx, id)
+}
+
+// UpdateEstateTree mocks base method.
+func (m *MockRepositoryInterface) UpdateEstateTree(ctx context.Context, data *repository.EstateTree) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEstateTree", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEstateTree indicates an expected call of UpdateEstateTree.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateEstateTree(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEstateTree", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateEstateTree), ctx, data)
+}
+
+// UpdatePlanJobProgress mocks base method.
+func (m *MockRepositoryInterface) UpdatePlanJobProgress(ctx context.Context, data *repository.PlanJob) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePlanJobProgress", ctx, data)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePlanJobProgress indicates an expected call of UpdatePlanJobProgress.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdatePlanJobProgress(ctx, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePlanJobProgress", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdatePlanJobProgress), ctx, data)
+}
@@ -0,0 +1,378 @@
+// Package planner computes the flight plan of the monitoring drone over an
+// estate. It is shared by every transport that serves plans.
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/cache"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const instrumentationName = "github.com/dimassantoso/drone-sawit/planner"
+
+const (
+	// PlotDistance is the horizontal distance between two neighbouring plots.
+	PlotDistance = 10
+	// Clearance is how far the drone stays above a tree.
+	Clearance = 1
+)
+
+// ErrBusy is returned by Plan when every computation slot stays taken for
+// longer than the queue timeout.
+var ErrBusy = errors.New("planner: too many plans in progress")
+
+// Point is a plot on the estate.
+type Point struct {
+	X int
+	Y int
+}
+
+// Result is a computed plan.
+type Result struct {
+	// Distance is the total distance flown, including take-off and landing.
+	Distance int
+	// Rest is where the drone lands. It is only set when a maximum distance
+	// was requested.
+	Rest *Point
+	// PlotsEvaluated counts the plots visited before the plan completed or
+	// ran out of distance.
+	PlotsEvaluated int
+	// Area is the number of plots of the estate.
+	Area int
+	// Fingerprint identifies the inputs of the plan: the estate, its version
+	// and the maximum distance applied. Equal fingerprints mean equal plans,
+	// so it makes a good ETag.
+	Fingerprint string
+}
+
+// Waypoint is a point of the flight of the drone.
+type Waypoint struct {
+	X int
+	Y int
+	// Altitude is the height of the drone above the ground.
+	Altitude int
+	// Distance is the distance flown to reach the waypoint.
+	Distance int
+}
+
+// Observer is notified of every computed plan, e.g. to export metrics.
+type Observer interface {
+	ObservePlan(result Result, duration time.Duration)
+}
+
+// Planner loads estates from the repository and computes their plans.
+type Planner struct {
+	Repository         repository.RepositoryInterface
+	Observer           Observer
+	Cache              cache.Cache
+	DefaultMaxDistance int
+	QueueTimeout       time.Duration
+
+	// slots holds a token per running computation. It is nil when
+	// computations are not capped.
+	slots chan struct{}
+}
+
+type Options struct {
+	Repository repository.RepositoryInterface
+	Observer   Observer
+	// Cache stores computed plans by fingerprint. It defaults to no cache.
+	Cache cache.Cache
+	// DefaultMaxDistance limits plans requested without a maximum
+	// distance. Zero means unlimited.
+	DefaultMaxDistance int
+	// MaxConcurrent caps the computations running at once. Zero means
+	// unlimited.
+	MaxConcurrent int
+	// QueueTimeout is how long Plan waits for a computation slot before
+	// returning ErrBusy.
+	QueueTimeout time.Duration
+}
+
+func New(opts Options) *Planner {
+	p := &Planner{
+		Repository:         opts.Repository,
+		Observer:           opts.Observer,
+		Cache:              opts.Cache,
+		DefaultMaxDistance: opts.DefaultMaxDistance,
+		QueueTimeout:       opts.QueueTimeout,
+	}
+	if p.Cache == nil {
+		p.Cache = cache.Nop{}
+	}
+	if opts.MaxConcurrent > 0 {
+		p.slots = make(chan struct{}, opts.MaxConcurrent)
+	}
+	return p
+}
+
+// acquire takes a computation slot, waiting up to QueueTimeout. The
+// returned function releases it.
+func (p *Planner) acquire(ctx context.Context) (func(), error) {
+	if p.slots == nil {
+		return func() {}, nil
+	}
+	release := func() { <-p.slots }
+
+	select {
+	case p.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if p.QueueTimeout <= 0 {
+		return nil, ErrBusy
+	}
+
+	timer := time.NewTimer(p.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case p.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Plan computes the plan of estateID within organizationID. maxDistance,
+// when set, limits how far the drone may fly; DefaultMaxDistance applies
+// otherwise. Repository errors are returned as is, so repository.ErrNotFound
+// reports an unknown estate. A plan cached for the current version of the
+// estate is returned without computing it again. When MaxConcurrent
+// computations are running, Plan waits for one to finish and returns
+// ErrBusy after QueueTimeout.
+func (p *Planner) Plan(ctx context.Context, organizationID, estateID string, maxDistance *int) (Result, error) {
+	return p.plan(ctx, organizationID, estateID, maxDistance, nil, nil, true)
+}
+
+// Waypoints is Plan calling visit with every waypoint of the flight: one
+// per plot flown over, at the altitude kept above it, and the landing. The
+// plan is always computed, as waypoints are not cached, but visit is only
+// called once the computation slot is released, so a slow visit does not
+// hold it. An error from visit stops the flight and is returned.
+func (p *Planner) Waypoints(ctx context.Context, organizationID, estateID string, maxDistance *int, visit func(Waypoint) error) (Result, error) {
+	return p.plan(ctx, organizationID, estateID, maxDistance, nil, visit, true)
+}
+
+// PlanWithProgress is Plan for background jobs. It reports the share of
+// plots evaluated, between 0 and 1, to progress as the computation goes,
+// and stops with ctx.Err() once ctx is done. It takes no computation slot:
+// callers such as the job workers bound their own concurrency.
+func (p *Planner) PlanWithProgress(ctx context.Context, organizationID, estateID string, maxDistance *int, progress func(float64)) (Result, error) {
+	return p.plan(ctx, organizationID, estateID, maxDistance, progress, nil, false)
+}
+
+func (p *Planner) plan(ctx context.Context, organizationID, estateID string, maxDistance *int, progress func(float64), visit func(Waypoint) error, limited bool) (Result, error) {
+	if maxDistance == nil && p.DefaultMaxDistance > 0 {
+		maxDistance = &p.DefaultMaxDistance
+	}
+
+	estate, err := p.Repository.FindEstate(ctx, &repository.FilterEstate{ID: estateID, OrganizationID: organizationID})
+	if err != nil {
+		return Result{}, err
+	}
+	fingerprint := Fingerprint(estate, maxDistance)
+	if visit == nil {
+		if result, ok := p.cached(ctx, fingerprint); ok {
+			return result, nil
+		}
+	}
+
+	// The slot also covers loading the trees, which takes as much memory as
+	// the computation.
+	release := func() {}
+	if limited {
+		if release, err = p.acquire(ctx); err != nil {
+			return Result{}, err
+		}
+	}
+	defer func() { release() }()
+
+	estateTree, err := p.Repository.FindAllMapEstateTree(ctx, &repository.FilterEstateTree{
+		Filter: repository.Filter{
+			Page:    1,
+			ShowAll: true,
+		},
+		OrganizationID: organizationID,
+		EstateID:       estateID,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	_, span := otel.Tracer(instrumentationName).Start(ctx, "planner.Compute")
+	span.SetAttributes(
+		attribute.Int("estate.width", estate.Width),
+		attribute.Int("estate.length", estate.Length),
+		attribute.Int("estate.trees", len(estateTree)),
+	)
+	start := time.Now()
+	heightAt := func(x, y int) int {
+		return estateTree[repository.CoordinatePoint{X: x, Y: y}].Height
+	}
+	result, err := compute(ctx, estate.Width, estate.Length, heightAt, maxDistance, progress, nil)
+	duration := time.Since(start)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return Result{}, err
+	}
+	span.SetAttributes(
+		attribute.Int("plan.plots_evaluated", result.PlotsEvaluated),
+		attribute.Int("plan.distance", result.Distance),
+	)
+	span.End()
+	result.Fingerprint = fingerprint
+	if p.Observer != nil {
+		p.Observer.ObservePlan(result, duration)
+	}
+	p.store(ctx, result)
+
+	if visit != nil {
+		// visit goes at the pace of the caller, e.g. of a client reading a
+		// stream, so the slot is released before flying the plan again over
+		// the trees already loaded.
+		release()
+		release = func() {}
+		if _, err = compute(ctx, estate.Width, estate.Length, heightAt, maxDistance, nil, visit); err != nil {
+			return Result{}, err
+		}
+	}
+	return result, nil
+}
+
+// Fingerprint identifies the plan of estate, at its current version, flown
+// with maxDistance.
+func Fingerprint(estate repository.Estate, maxDistance *int) string {
+	limit := "none"
+	if maxDistance != nil {
+		limit = strconv.Itoa(*maxDistance)
+	}
+	return cache.Key("plan", estate.OrganizationID, estate.ID, strconv.FormatInt(estate.Version, 10), limit)
+}
+
+// cached returns the plan stored under fingerprint. Cache failures are
+// logged and treated as misses.
+func (p *Planner) cached(ctx context.Context, fingerprint string) (Result, bool) {
+	value, ok, err := p.Cache.Get(ctx, fingerprint)
+	if err != nil {
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "read cached plan", slog.Any("error", err))
+		return Result{}, false
+	}
+	if !ok {
+		return Result{}, false
+	}
+	var result Result
+	if err = json.Unmarshal(value, &result); err != nil {
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "decode cached plan", slog.Any("error", err))
+		return Result{}, false
+	}
+	return result, true
+}
+
+func (p *Planner) store(ctx context.Context, result Result) {
+	value, err := json.Marshal(result)
+	if err == nil {
+		err = p.Cache.Set(ctx, result.Fingerprint, value)
+	}
+	if err != nil {
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "cache plan", slog.Any("error", err))
+	}
+}
+
+// Compute plans a flight over a width by length estate. The drone starts at
+// plot (1, 1) on the ground, sweeps every row alternating direction, keeps
+// Clearance above each tree given by heightAt and lands at the last plot.
+func Compute(width, length int, heightAt func(x, y int) int, maxDistance *int) Result {
+	result, _ := compute(context.Background(), width, length, heightAt, maxDistance, nil, nil)
+	return result
+}
+
+// compute is Compute checking ctx and reporting progress after every row.
+// visit, when set, is called with every waypoint.
+func compute(ctx context.Context, width, length int, heightAt func(x, y int) int, maxDistance *int, progress func(float64), visit func(Waypoint) error) (Result, error) {
+	result := Result{Area: width * length}
+	exceeded := func() bool {
+		return maxDistance != nil && result.Distance > *maxDistance
+	}
+	land := func(x, y int) (Result, error) {
+		if visit != nil {
+			if err := visit(Waypoint{X: x, Y: y, Distance: result.Distance}); err != nil {
+				return Result{}, err
+			}
+		}
+		return result, nil
+	}
+	rest := func(x, y int) (Result, error) {
+		result.Rest = &Point{X: x, Y: y}
+		return land(x, y)
+	}
+
+	var currentHeight, lastX, lastY int
+	for y := 1; y <= length; y++ {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		if progress != nil && y > 1 {
+			progress(float64(result.PlotsEvaluated) / float64(result.Area))
+		}
+		var xStart, xEnd, xStep int
+		if y%2 == 1 {
+			xStart, xEnd, xStep = 1, width, 1
+		} else {
+			xStart, xEnd, xStep = width, 1, -1
+		}
+		for x := xStart; x != xEnd+xStep; x += xStep {
+			result.PlotsEvaluated++
+			targetHeight := heightAt(x, y) + Clearance
+			result.Distance += abs(targetHeight - currentHeight)
+			currentHeight = targetHeight
+			if exceeded() {
+				return rest(x, y)
+			}
+			lastX, lastY = x, y
+			if visit != nil {
+				if err := visit(Waypoint{X: x, Y: y, Altitude: currentHeight, Distance: result.Distance}); err != nil {
+					return Result{}, err
+				}
+			}
+
+			if x != xEnd {
+				result.Distance += PlotDistance
+				if exceeded() {
+					return rest(x, y)
+				}
+			}
+		}
+
+		if y != length {
+			result.Distance += PlotDistance
+			if exceeded() {
+				return rest(xStart, y+1)
+			}
+		}
+	}
+
+	result.Distance += currentHeight
+	if maxDistance != nil {
+		return rest(width, length)
+	}
+	return land(lastX, lastY)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
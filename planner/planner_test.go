@@ -0,0 +1,422 @@
+package planner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/cache"
+	mockrepo "github.com/dimassantoso/drone-sawit/mocks/repository"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func heights(trees map[Point]int) func(x, y int) int {
+	return func(x, y int) int {
+		return trees[Point{X: x, Y: y}]
+	}
+}
+
+func TestCompute(t *testing.T) {
+	estate := heights(map[Point]int{
+		{X: 3, Y: 1}: 10,
+		{X: 3, Y: 2}: 30,
+		{X: 4, Y: 2}: 14,
+		{X: 6, Y: 2}: 24,
+		{X: 5, Y: 3}: 6,
+	})
+	distance := func(d int) *int { return &d }
+
+	testcases := []struct {
+		name        string
+		width       int
+		length      int
+		heightAt    func(x, y int) int
+		maxDistance *int
+		expected    Result
+	}{
+		{
+			name:     "single row",
+			width:    5,
+			length:   1,
+			heightAt: heights(map[Point]int{{X: 2, Y: 1}: 5, {X: 3, Y: 1}: 3, {X: 4, Y: 1}: 4}),
+			expected: Result{Distance: 54, PlotsEvaluated: 5, Area: 5},
+		},
+		{
+			name:     "several rows",
+			width:    6,
+			length:   3,
+			heightAt: estate,
+			expected: Result{Distance: 312, PlotsEvaluated: 18, Area: 18},
+		},
+		{
+			name:        "max distance not reached",
+			width:       6,
+			length:      3,
+			heightAt:    estate,
+			maxDistance: distance(1000),
+			expected:    Result{Distance: 312, Rest: &Point{X: 6, Y: 3}, PlotsEvaluated: 18, Area: 18},
+		},
+		{
+			name:        "max distance reached between plots",
+			width:       6,
+			length:      3,
+			heightAt:    estate,
+			maxDistance: distance(15),
+			expected:    Result{Distance: 21, Rest: &Point{X: 2, Y: 1}, PlotsEvaluated: 2, Area: 18},
+		},
+		{
+			name:        "max distance reached climbing a tree",
+			width:       6,
+			length:      3,
+			heightAt:    estate,
+			maxDistance: distance(25),
+			expected:    Result{Distance: 31, Rest: &Point{X: 3, Y: 1}, PlotsEvaluated: 3, Area: 18},
+		},
+		{
+			name:        "max distance reached between rows",
+			width:       1,
+			length:      2,
+			heightAt:    heights(nil),
+			maxDistance: distance(5),
+			expected:    Result{Distance: 11, Rest: &Point{X: 1, Y: 2}, PlotsEvaluated: 1, Area: 2},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Compute(tc.width, tc.length, tc.heightAt, tc.maxDistance))
+		})
+	}
+}
+
+type observerFunc func(result Result, duration time.Duration)
+
+func (f observerFunc) ObservePlan(result Result, duration time.Duration) {
+	f(result, duration)
+}
+
+func TestPlanner_Plan(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 5, Length: 1, Version: 2}
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), &repository.FilterEstate{ID: "estate-1", OrganizationID: "org-1"}).
+			Return(estate, nil)
+		mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), &repository.FilterEstateTree{
+			Filter:         repository.Filter{Page: 1, ShowAll: true},
+			OrganizationID: "org-1",
+			EstateID:       "estate-1",
+		}).Return(map[repository.CoordinatePoint]repository.EstateTree{
+			{X: 2, Y: 1}: {X: 2, Y: 1, Height: 5},
+			{X: 3, Y: 1}: {X: 3, Y: 1, Height: 3},
+			{X: 4, Y: 1}: {X: 4, Y: 1, Height: 4},
+		}, nil)
+
+		var observed []Result
+		p := New(Options{
+			Repository: mockRepo,
+			Observer:   observerFunc(func(result Result, _ time.Duration) { observed = append(observed, result) }),
+		})
+
+		result, err := p.Plan(context.Background(), "org-1", "estate-1", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, Result{Distance: 54, PlotsEvaluated: 5, Area: 5, Fingerprint: Fingerprint(estate, nil)}, result)
+		assert.Equal(t, []Result{result}, observed)
+	})
+
+	t.Run("Success: default max distance", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{Width: 5, Length: 1}, nil).Times(2)
+		mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).Return(map[repository.CoordinatePoint]repository.EstateTree{}, nil).Times(2)
+
+		p := New(Options{Repository: mockRepo, DefaultMaxDistance: 15})
+
+		result, err := p.Plan(context.Background(), "org-1", "estate-1", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, &Point{X: 2, Y: 1}, result.Rest)
+
+		maxDistance := 100
+		result, err = p.Plan(context.Background(), "org-1", "estate-1", &maxDistance)
+		assert.NoError(t, err)
+		assert.Equal(t, &Point{X: 5, Y: 1}, result.Rest, "an explicit max distance wins")
+	})
+
+	t.Run("Failed: estate not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, repository.ErrNotFound)
+
+		_, err := New(Options{Repository: mockRepo}).Plan(context.Background(), "org-1", "estate-1", nil)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+}
+
+func TestPlanner_Plan_Cache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 5, Length: 1, Version: 1}
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, *repository.FilterEstate) (repository.Estate, error) { return estate, nil }).
+		Times(4)
+	mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).
+		Return(map[repository.CoordinatePoint]repository.EstateTree{}, nil).
+		Times(3)
+
+	computed := 0
+	p := New(Options{
+		Repository: mockRepo,
+		Observer:   observerFunc(func(Result, time.Duration) { computed++ }),
+		Cache:      cache.NewMemory(0),
+	})
+
+	first, err := p.Plan(context.Background(), "org-1", "estate-1", nil)
+	require.NoError(t, err)
+	cached, err := p.Plan(context.Background(), "org-1", "estate-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, cached)
+	assert.Equal(t, 1, computed, "an unchanged estate is not recomputed")
+
+	maxDistance := 15
+	limited, err := p.Plan(context.Background(), "org-1", "estate-1", &maxDistance)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Fingerprint, limited.Fingerprint)
+	assert.Equal(t, 2, computed, "parameters are part of the key")
+
+	estate.Version++
+	changed, err := p.Plan(context.Background(), "org-1", "estate-1", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Fingerprint, changed.Fingerprint)
+	assert.Equal(t, 3, computed, "a new version is recomputed")
+}
+
+func TestPlanner_PlanWithProgress(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{Width: 2, Length: 4}, nil)
+		mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).Return(map[repository.CoordinatePoint]repository.EstateTree{}, nil)
+
+		// A planner without free slots still serves jobs.
+		p := New(Options{Repository: mockRepo, MaxConcurrent: 1})
+		p.slots <- struct{}{}
+
+		var reported []float64
+		result, err := p.PlanWithProgress(context.Background(), "org-1", "estate-1", nil, func(progress float64) {
+			reported = append(reported, progress)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 8, result.PlotsEvaluated)
+		assert.Equal(t, []float64{0.25, 0.5, 0.75}, reported)
+	})
+
+	t.Run("Failed: cancelled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{Width: 2, Length: 4}, nil)
+		mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).Return(map[repository.CoordinatePoint]repository.EstateTree{}, nil)
+
+		computed := false
+		p := New(Options{Repository: mockRepo, Observer: observerFunc(func(Result, time.Duration) { computed = true })})
+		_, err := p.PlanWithProgress(ctx, "org-1", "estate-1", nil, func(progress float64) {
+			if progress >= 0.5 {
+				cancel()
+			}
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, computed)
+	})
+}
+
+func TestPlanner_Waypoints(t *testing.T) {
+	estate := repository.Estate{BaseModel: repository.BaseModel{ID: "estate-1"}, OrganizationID: "org-1", Width: 3, Length: 2, Version: 1}
+	trees := map[repository.CoordinatePoint]repository.EstateTree{{X: 2, Y: 1}: {X: 2, Y: 1, Height: 5}}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil).Times(2)
+		mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).Return(trees, nil).Times(2)
+
+		p := New(Options{Repository: mockRepo, Cache: cache.NewMemory(0)})
+		planned, err := p.Plan(context.Background(), "org-1", "estate-1", nil)
+		require.NoError(t, err)
+
+		var waypoints []Waypoint
+		result, err := p.Waypoints(context.Background(), "org-1", "estate-1", nil, func(waypoint Waypoint) error {
+			waypoints = append(waypoints, waypoint)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, planned, result, "waypoints are computed even when the plan is cached")
+		assert.Equal(t, []Waypoint{
+			{X: 1, Y: 1, Altitude: 1, Distance: 1},
+			{X: 2, Y: 1, Altitude: 6, Distance: 16},
+			{X: 3, Y: 1, Altitude: 1, Distance: 31},
+			{X: 3, Y: 2, Altitude: 1, Distance: 41},
+			{X: 2, Y: 2, Altitude: 1, Distance: 51},
+			{X: 1, Y: 2, Altitude: 1, Distance: 61},
+			{X: 1, Y: 2, Altitude: 0, Distance: 62},
+		}, waypoints)
+	})
+
+	t.Run("Success: max distance", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil)
+		mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).Return(trees, nil)
+
+		var waypoints []Waypoint
+		maxDistance := 30
+		result, err := New(Options{Repository: mockRepo}).Waypoints(context.Background(), "org-1", "estate-1", &maxDistance, func(waypoint Waypoint) error {
+			waypoints = append(waypoints, waypoint)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &Point{X: 3, Y: 1}, result.Rest)
+		assert.Equal(t, []Waypoint{
+			{X: 1, Y: 1, Altitude: 1, Distance: 1},
+			{X: 2, Y: 1, Altitude: 6, Distance: 16},
+			{X: 3, Y: 1, Altitude: 0, Distance: 31},
+		}, waypoints, "the drone lands where it rests")
+	})
+
+	t.Run("Failed: visit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil)
+		mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).Return(trees, nil)
+
+		visited := 0
+		_, err := New(Options{Repository: mockRepo}).Waypoints(context.Background(), "org-1", "estate-1", nil, func(Waypoint) error {
+			visited++
+			return assert.AnError
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, 1, visited)
+	})
+
+	t.Run("Slot released before visiting", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+		mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(estate, nil).Times(2)
+		mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).Return(trees, nil).Times(2)
+
+		p := New(Options{Repository: mockRepo, MaxConcurrent: 1})
+		planned := false
+		_, err := p.Waypoints(context.Background(), "org-1", "estate-1", nil, func(Waypoint) error {
+			if !planned {
+				planned = true
+				_, err := p.Plan(context.Background(), "org-1", "estate-1", nil)
+				return err
+			}
+			return nil
+		})
+		assert.NoError(t, err, "a slow visit leaves the slot to other plans")
+	})
+}
+
+func TestPlanner_Plan_Concurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{Width: 1, Length: 1}, nil).Times(4)
+	mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, *repository.FilterEstateTree) (map[repository.CoordinatePoint]repository.EstateTree, error) {
+			entered <- struct{}{}
+			<-unblock
+			return map[repository.CoordinatePoint]repository.EstateTree{}, nil
+		}).Times(2)
+
+	p := New(Options{Repository: mockRepo, MaxConcurrent: 1})
+
+	done := make(chan error)
+	go func() {
+		_, err := p.Plan(context.Background(), "org-1", "estate-1", nil)
+		done <- err
+	}()
+	<-entered
+
+	_, err := p.Plan(context.Background(), "org-1", "estate-1", nil)
+	assert.ErrorIs(t, err, ErrBusy, "no slot left and no queue")
+
+	p.QueueTimeout = time.Second
+	go func() {
+		_, err := p.Plan(context.Background(), "org-1", "estate-1", nil)
+		done <- err
+	}()
+	unblock <- struct{}{}
+	assert.NoError(t, <-done)
+	<-entered
+	unblock <- struct{}{}
+	assert.NoError(t, <-done, "a queued plan runs once a slot is released")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.slots <- struct{}{}
+	_, err = p.Plan(ctx, "org-1", "estate-1", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPlanner_Plan_Span(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mockrepo.NewMockRepositoryInterface(ctrl)
+	mockRepo.EXPECT().FindEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{Width: 6, Length: 3}, nil)
+	mockRepo.EXPECT().FindAllMapEstateTree(gomock.Any(), gomock.Any()).Return(map[repository.CoordinatePoint]repository.EstateTree{}, nil)
+
+	_, err := New(Options{Repository: mockRepo}).Plan(context.Background(), "org-1", "estate-1", nil)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "planner.Compute", spans[0].Name())
+
+	attributes := map[string]int64{}
+	for _, kv := range spans[0].Attributes() {
+		attributes[string(kv.Key)] = kv.Value.AsInt64()
+	}
+	assert.Equal(t, map[string]int64{
+		"estate.width":         6,
+		"estate.length":        3,
+		"estate.trees":         0,
+		"plan.plots_evaluated": 18,
+		"plan.distance":        172,
+	}, attributes)
+}
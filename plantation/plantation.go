@@ -0,0 +1,248 @@
+// Package plantation generates synthetic estates, to seed databases and to
+// test the planner at scale. The trees only depend on the Options: the same
+// seed gives the same trees.
+package plantation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/repository"
+)
+
+// Layouts of the trees.
+const (
+	// LayoutGrid plants rows of evenly spaced trees, as on a real
+	// plantation.
+	LayoutGrid = "grid"
+	// LayoutRandom plants trees on plots picked at random.
+	LayoutRandom = "random"
+	// LayoutClustered plants trees around a few centres picked at random.
+	LayoutClustered = "clustered"
+)
+
+// Distributions of the heights of the trees.
+const (
+	// HeightsUniform draws every height between the minimum and the maximum
+	// equally often.
+	HeightsUniform = "uniform"
+	// HeightsNormal draws heights around the middle of the range, with a
+	// standard deviation of a sixth of the range.
+	HeightsNormal = "normal"
+)
+
+// Defaults of Options.
+const (
+	DefaultDensity = 0.3
+	// DefaultClusterArea is the number of plots per cluster of the
+	// clustered layout.
+	DefaultClusterArea = 2500
+)
+
+// maxAttemptsPerTree bounds the plots drawn for a tree of the clustered
+// layout before Generate gives up.
+const maxAttemptsPerTree = 100
+
+// Options describe a plantation. Zero values take the defaults.
+type Options struct {
+	// Width and Length are the size of the estate, in plots. They are
+	// required.
+	Width  int
+	Length int
+	// Density is the share of the plots with a tree, above 0 and up to 1.
+	// It defaults to DefaultDensity. The grid layout only approaches it.
+	Density float64
+	// Layout is LayoutGrid (the default), LayoutRandom or LayoutClustered.
+	Layout string
+	// RowSpacing is the distance between the rows of the grid layout, in
+	// plots. It defaults to the square root of 1/Density, the spacing of
+	// the trees within rows then being derived from the density.
+	RowSpacing int
+	// Clusters is the number of clusters of the clustered layout. It
+	// defaults to one per DefaultClusterArea plots.
+	Clusters int
+	// ClusterRadius is the standard deviation of the distance of the trees
+	// from the centre of their cluster, in plots. It defaults to the radius
+	// of a disc just holding the trees of a cluster.
+	ClusterRadius float64
+	// Heights is HeightsUniform (the default) or HeightsNormal.
+	Heights string
+	// MinHeight and MaxHeight bound the heights of the trees. They default
+	// to 1 and estates.MaxTreeHeight.
+	MinHeight int
+	MaxHeight int
+	// Seed seeds the random choices.
+	Seed int64
+}
+
+func (o *Options) setDefaults() {
+	if o.Density == 0 {
+		o.Density = DefaultDensity
+	}
+	if o.Layout == "" {
+		o.Layout = LayoutGrid
+	}
+	if o.Heights == "" {
+		o.Heights = HeightsUniform
+	}
+	if o.MinHeight == 0 {
+		o.MinHeight = 1
+	}
+	if o.MaxHeight == 0 {
+		o.MaxHeight = estates.MaxTreeHeight
+	}
+}
+
+func (o *Options) validate() error {
+	var errs []error
+	if o.Width < 1 || o.Width > estates.MaxSize || o.Length < 1 || o.Length > estates.MaxSize {
+		errs = append(errs, fmt.Errorf("width and length must be between 1 and %d", estates.MaxSize))
+	}
+	if o.Density <= 0 || o.Density > 1 {
+		errs = append(errs, errors.New("density must be above 0 and at most 1"))
+	}
+	if o.Layout != LayoutGrid && o.Layout != LayoutRandom && o.Layout != LayoutClustered {
+		errs = append(errs, fmt.Errorf("unknown layout %q", o.Layout))
+	}
+	if o.RowSpacing < 0 || o.Clusters < 0 || o.ClusterRadius < 0 {
+		errs = append(errs, errors.New("row spacing, clusters and cluster radius must not be negative"))
+	}
+	if o.Heights != HeightsUniform && o.Heights != HeightsNormal {
+		errs = append(errs, fmt.Errorf("unknown height distribution %q", o.Heights))
+	}
+	if o.MinHeight < 1 || o.MaxHeight > estates.MaxTreeHeight || o.MinHeight > o.MaxHeight {
+		errs = append(errs, fmt.Errorf("heights must be between 1 and %d, the minimum not above the maximum", estates.MaxTreeHeight))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("plantation: %w", err)
+	}
+	return nil
+}
+
+// generator is a plantation being generated.
+type generator struct {
+	Options
+	rand  *rand.Rand
+	visit func(estates.TreeRecord) error
+}
+
+// Generate calls visit with the trees of the plantation described by opts,
+// until visit returns an error. The trees fit the estate as
+// estates.Service checks it: x from 1 to Length and y from 1 to Width. The
+// clustered layout keeps the plots it planted in memory.
+func Generate(opts Options, visit func(estates.TreeRecord) error) error {
+	opts.setDefaults()
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	g := &generator{Options: opts, rand: rand.New(rand.NewSource(opts.Seed)), visit: visit}
+	switch opts.Layout {
+	case LayoutRandom:
+		return g.random()
+	case LayoutClustered:
+		return g.clustered()
+	}
+	return g.grid()
+}
+
+// trees returns the number of trees of the random and clustered layouts.
+func (g *generator) trees() int {
+	return int(math.Round(g.Density * float64(g.Width) * float64(g.Length)))
+}
+
+func (g *generator) plant(x, y int) error {
+	return g.visit(estates.TreeRecord{X: x, Y: y, Height: g.height()})
+}
+
+func (g *generator) height() int {
+	if g.Heights == HeightsNormal {
+		mean := float64(g.MinHeight+g.MaxHeight) / 2
+		deviation := float64(g.MaxHeight-g.MinHeight) / 6
+		height := int(math.Round(mean + g.rand.NormFloat64()*deviation))
+		return min(max(height, g.MinHeight), g.MaxHeight)
+	}
+	return g.MinHeight + g.rand.Intn(g.MaxHeight-g.MinHeight+1)
+}
+
+// grid plants rows along x, RowSpacing plots apart, of trees spaced so that
+// the plantation approaches the density.
+func (g *generator) grid() error {
+	rowSpacing := g.RowSpacing
+	if rowSpacing == 0 {
+		rowSpacing = max(1, int(math.Round(math.Sqrt(1/g.Density))))
+	}
+	treeSpacing := max(1, int(math.Round(1/(g.Density*float64(rowSpacing)))))
+	for y := 1; y <= g.Width; y += rowSpacing {
+		for x := 1; x <= g.Length; x += treeSpacing {
+			if err := g.plant(x, y); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// random picks exactly the number of trees of the density, each plot being
+// as likely as any other, by selection sampling over the plots in order.
+func (g *generator) random() error {
+	remaining := g.trees()
+	plots := g.Width * g.Length
+	for y := 1; y <= g.Width && remaining > 0; y++ {
+		for x := 1; x <= g.Length && remaining > 0; x++ {
+			if g.rand.Intn(plots) < remaining {
+				if err := g.plant(x, y); err != nil {
+					return err
+				}
+				remaining--
+			}
+			plots--
+		}
+	}
+	return nil
+}
+
+// clustered spreads the trees around cluster centres, at normally
+// distributed distances, skipping plots outside the estate or planted
+// already.
+func (g *generator) clustered() error {
+	trees := g.trees()
+	if trees == 0 {
+		return nil
+	}
+	clusters := g.Clusters
+	if clusters == 0 {
+		clusters = max(1, g.Width*g.Length/DefaultClusterArea)
+	}
+	radius := g.ClusterRadius
+	if radius == 0 {
+		radius = max(1, math.Sqrt(float64(trees)/float64(clusters)/math.Pi))
+	}
+	centres := make([]repository.CoordinatePoint, clusters)
+	for i := range centres {
+		centres[i] = repository.CoordinatePoint{X: 1 + g.rand.Intn(g.Length), Y: 1 + g.rand.Intn(g.Width)}
+	}
+
+	planted := make(map[repository.CoordinatePoint]bool, trees)
+	for attempts := maxAttemptsPerTree * trees; len(planted) < trees; attempts-- {
+		if attempts == 0 {
+			return fmt.Errorf("plantation: only %d of %d trees fit in %d clusters, lower the density or widen the clusters",
+				len(planted), trees, clusters)
+		}
+		centre := centres[g.rand.Intn(clusters)]
+		plot := repository.CoordinatePoint{
+			X: centre.X + int(math.Round(g.rand.NormFloat64()*radius)),
+			Y: centre.Y + int(math.Round(g.rand.NormFloat64()*radius)),
+		}
+		if plot.X < 1 || plot.X > g.Length || plot.Y < 1 || plot.Y > g.Width || planted[plot] {
+			continue
+		}
+		planted[plot] = true
+		if err := g.plant(plot.X, plot.Y); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package plantation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/estates"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generate(t *testing.T, opts Options) []estates.TreeRecord {
+	var trees []estates.TreeRecord
+	require.NoError(t, Generate(opts, func(tree estates.TreeRecord) error {
+		trees = append(trees, tree)
+		return nil
+	}))
+	return trees
+}
+
+func assertPlanted(t *testing.T, opts Options, trees []estates.TreeRecord) {
+	plots := map[repository.CoordinatePoint]bool{}
+	for _, tree := range trees {
+		plot := repository.CoordinatePoint{X: tree.X, Y: tree.Y}
+		assert.False(t, plots[plot], "plot %v planted twice", plot)
+		plots[plot] = true
+		assert.True(t, tree.X >= 1 && tree.X <= opts.Length && tree.Y >= 1 && tree.Y <= opts.Width, "tree %v out of bounds", tree)
+		assert.True(t, tree.Height >= opts.MinHeight && tree.Height <= opts.MaxHeight, "tree %v too short or too tall", tree)
+	}
+}
+
+func TestGenerate_Layouts(t *testing.T) {
+	tests := []struct {
+		layout string
+		trees  int
+	}{
+		{layout: LayoutGrid, trees: 100 * 20},
+		{layout: LayoutRandom, trees: 2000},
+		{layout: LayoutClustered, trees: 2000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			opts := Options{Width: 100, Length: 40, Density: 0.5, Layout: tt.layout, MinHeight: 5, MaxHeight: 20, Seed: 7}
+			trees := generate(t, opts)
+			assert.Equal(t, tt.trees, len(trees))
+			assertPlanted(t, opts, trees)
+		})
+	}
+}
+
+func TestGenerate_Grid(t *testing.T) {
+	trees := generate(t, Options{Width: 5, Length: 7, Density: 0.25, RowSpacing: 2, MaxHeight: 1})
+	assert.Equal(t, []estates.TreeRecord{
+		{X: 1, Y: 1, Height: 1}, {X: 3, Y: 1, Height: 1}, {X: 5, Y: 1, Height: 1}, {X: 7, Y: 1, Height: 1},
+		{X: 1, Y: 3, Height: 1}, {X: 3, Y: 3, Height: 1}, {X: 5, Y: 3, Height: 1}, {X: 7, Y: 3, Height: 1},
+		{X: 1, Y: 5, Height: 1}, {X: 3, Y: 5, Height: 1}, {X: 5, Y: 5, Height: 1}, {X: 7, Y: 5, Height: 1},
+	}, trees)
+}
+
+func TestGenerate_Seed(t *testing.T) {
+	opts := Options{Width: 60, Length: 60, Layout: LayoutClustered, Heights: HeightsNormal, Seed: 42}
+	first := generate(t, opts)
+	assert.Equal(t, first, generate(t, opts), "the same seed gives the same trees")
+
+	opts.Seed++
+	assert.NotEqual(t, first, generate(t, opts))
+}
+
+func TestGenerate_NormalHeights(t *testing.T) {
+	opts := Options{Width: 100, Length: 100, Density: 1, Heights: HeightsNormal, MinHeight: 10, MaxHeight: 20, Seed: 1}
+	trees := generate(t, opts)
+	assertPlanted(t, opts, trees)
+
+	var sum, middle int
+	for _, tree := range trees {
+		sum += tree.Height
+		if tree.Height >= 14 && tree.Height <= 16 {
+			middle++
+		}
+	}
+	assert.InDelta(t, 15, float64(sum)/float64(len(trees)), 0.2)
+	assert.Greater(t, middle, len(trees)/2, "heights gather around the middle")
+}
+
+func TestGenerate_ClustersFull(t *testing.T) {
+	err := Generate(Options{Width: 100, Length: 100, Density: 0.9, Layout: LayoutClustered, Clusters: 1, ClusterRadius: 1},
+		func(estates.TreeRecord) error { return nil })
+	assert.ErrorContains(t, err, "lower the density or widen the clusters")
+}
+
+func TestGenerate_VisitError(t *testing.T) {
+	var visited int
+	err := Generate(Options{Width: 10, Length: 10}, func(estates.TreeRecord) error {
+		visited++
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 1, visited)
+}
+
+func TestGenerate_InvalidOptions(t *testing.T) {
+	err := Generate(Options{Width: 0, Length: 10, Density: 2, Layout: "spiral", Heights: "bimodal", MinHeight: 20, MaxHeight: 10},
+		func(estates.TreeRecord) error { return errors.New("visited") })
+	require.Error(t, err)
+	for _, want := range []string{
+		"width and length must be between 1 and 50000",
+		"density must be above 0 and at most 1",
+		`unknown layout "spiral"`,
+		`unknown height distribution "bimodal"`,
+		"heights must be between 1 and 30",
+	} {
+		assert.ErrorContains(t, err, want)
+	}
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// BodyLimitConfig configures BodyLimit.
+type BodyLimitConfig struct {
+	Skipper middleware.Skipper
+	// Limit is the largest body accepted, in bytes.
+	Limit int64
+}
+
+// BodyLimit rejects requests whose body is larger than the limit with
+// echo.ErrStatusRequestEntityTooLarge, which the API renders as 429
+// PAYLOAD_TOO_LARGE. The body is read up front, so a body sent without a
+// Content-Length is caught here too rather than half way through decoding.
+func BodyLimit(config BodyLimitConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if config.Skipper(c) || req.Body == nil || req.Body == http.NoBody {
+				return next(c)
+			}
+			if req.ContentLength > config.Limit {
+				return echo.ErrStatusRequestEntityTooLarge
+			}
+
+			body, err := io.ReadAll(io.LimitReader(req.Body, config.Limit+1))
+			if err != nil {
+				return err
+			}
+			if int64(len(body)) > config.Limit {
+				return echo.ErrStatusRequestEntityTooLarge
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyLimit(t *testing.T) {
+	e := echo.New()
+	e.Use(BodyLimit(BodyLimitConfig{Limit: 8}))
+	e.POST("/estate", func(c echo.Context) error {
+		body, err := io.ReadAll(c.Request().Body)
+		require.NoError(t, err)
+		return c.String(http.StatusOK, string(body))
+	})
+	post := func(body io.Reader, contentLength int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/estate", body)
+		req.ContentLength = contentLength
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Within the limit", func(t *testing.T) {
+		rec := post(strings.NewReader("12345678"), 8)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "12345678", rec.Body.String())
+	})
+
+	t.Run("Content-Length above the limit", func(t *testing.T) {
+		rec := post(strings.NewReader("123456789"), 9)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("Body without Content-Length above the limit", func(t *testing.T) {
+		rec := post(io.MultiReader(strings.NewReader("12345"), strings.NewReader("6789")), -1)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
@@ -0,0 +1,190 @@
+// Package ratelimit throttles API clients with a token bucket per client
+// and rule.
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// DefaultIdleTimeout is how long the bucket of an inactive client is kept
+// when Config.IdleTimeout is zero.
+const DefaultIdleTimeout = 10 * time.Minute
+
+// DefaultRule names the buckets of the requests no rule matches.
+const DefaultRule = "default"
+
+// Limit is a sustained rate, in requests per second, and the burst allowed
+// above it. A zero Rate disables the limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Rule applies its own Limit, with its own buckets, to the requests it
+// matches.
+type Rule struct {
+	Name  string
+	Match func(c echo.Context) bool
+	Limit Limit
+}
+
+// Config configures Middleware.
+type Config struct {
+	Skipper middleware.Skipper
+	// Default limits the requests no rule matches.
+	Default Limit
+	// Rules are tried in order; the first match wins.
+	Rules []Rule
+	// KeyFunc identifies the client. It defaults to ClientKey.
+	KeyFunc func(c echo.Context) string
+	// Buckets holds the buckets of the clients. It defaults to buckets of
+	// their own, created with IdleTimeout and Now; sharing them with the
+	// gRPC API gives each client one budget over both.
+	Buckets *Buckets
+	// IdleTimeout is how long the bucket of an inactive client is kept.
+	IdleTimeout time.Duration
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// ClientKey identifies the client by its authenticated subject, e.g. its
+// API key, or by its IP address when the request is anonymous.
+func ClientKey(c echo.Context) string {
+	if identity, ok := auth.IdentityFromContext(c); ok && identity.Subject != "" {
+		return identity.Subject
+	}
+	return IPKey(c)
+}
+
+// IPKey identifies the client by its IP address alone, for limits applied
+// before authentication.
+func IPKey(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Buckets holds a token bucket per client and rule. It is safe for
+// concurrent use.
+type Buckets struct {
+	idleTimeout time.Duration
+	now         func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewBuckets returns empty buckets, which forget clients inactive for
+// idleTimeout, or DefaultIdleTimeout when zero. now returns the current
+// time; it defaults to time.Now.
+func NewBuckets(idleTimeout time.Duration, now func() time.Time) *Buckets {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &Buckets{idleTimeout: idleTimeout, now: now, buckets: map[string]*bucket{}}
+}
+
+// Middleware rejects requests exceeding the limit of their client with 429
+// Too Many Requests and a Retry-After header. With the default KeyFunc, it
+// must run after the authentication middleware to key requests by caller.
+func Middleware(config Config) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = ClientKey
+	}
+	if config.Buckets == nil {
+		config.Buckets = NewBuckets(config.IdleTimeout, config.Now)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			name, limit := match(config, c)
+			if wait := config.Buckets.Reserve(name, config.KeyFunc(c), limit); wait > 0 {
+				c.Response().Header().Set("Retry-After", RetryAfter(wait))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
+
+func match(config Config, c echo.Context) (string, Limit) {
+	for _, rule := range config.Rules {
+		if rule.Match(c) {
+			return rule.Name, rule.Limit
+		}
+	}
+	return DefaultRule, config.Default
+}
+
+// Reserve takes a token from the bucket of key under rule, created with
+// limit, and returns how long the client must wait when there is none. A
+// zero Rate never waits.
+func (b *Buckets) Reserve(rule, key string, limit Limit) time.Duration {
+	if limit.Rate <= 0 {
+		return 0
+	}
+	now := b.now()
+	key = rule + "|" + key
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep(now)
+	bk, ok := b.buckets[key]
+	if !ok {
+		bk = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.Rate), max(limit.Burst, 1))}
+		b.buckets[key] = bk
+	}
+	bk.lastSeen = now
+
+	reservation := bk.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+// sweep drops the buckets of clients idle for longer than idleTimeout. A
+// bucket idle that long has normally refilled, so forgetting it changes
+// nothing for the client.
+func (b *Buckets) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < b.idleTimeout {
+		return
+	}
+	b.lastSweep = now
+	for key, bk := range b.buckets {
+		if now.Sub(bk.lastSeen) >= b.idleTimeout {
+			delete(b.buckets, key)
+		}
+	}
+}
+
+// RetryAfter formats wait as a Retry-After value, in whole seconds rounded
+// up.
+func RetryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds()))))
+}
@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newServer(config Config) *echo.Echo {
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if key := c.Request().Header.Get("X-API-Key"); key != "" {
+				auth.SetIdentity(c, auth.Identity{Subject: "apikey:" + key})
+			}
+			return next(c)
+		}
+	})
+	e.Use(Middleware(config))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/estate/:id/stats", ok)
+	e.GET("/estate/:id/drone-plan", ok)
+	e.GET("/metrics", ok)
+	return e
+}
+
+func get(e *echo.Echo, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	e := newServer(Config{
+		Skipper: func(c echo.Context) bool { return c.Path() == "/metrics" },
+		Default: Limit{Rate: 1, Burst: 2},
+		Rules: []Rule{{
+			Name:  "plan",
+			Match: func(c echo.Context) bool { return strings.HasSuffix(c.Path(), "/drone-plan") },
+			Limit: Limit{Rate: 0.1, Burst: 1},
+		}},
+		Now: clk.Now,
+	})
+
+	t.Run("burst then throttled", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(e, "/estate/a/stats", "k1").Code)
+		assert.Equal(t, http.StatusOK, get(e, "/estate/a/stats", "k1").Code)
+
+		rec := get(e, "/estate/a/stats", "k1")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("buckets are per client", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(e, "/estate/a/stats", "k2").Code)
+		assert.Equal(t, http.StatusOK, get(e, "/estate/a/stats", "").Code, "anonymous clients are keyed by IP")
+	})
+
+	t.Run("rules have a stricter budget of their own", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(e, "/estate/a/drone-plan", "k1").Code, "default bucket is separate")
+
+		rec := get(e, "/estate/a/drone-plan", "k1")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "10", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("refills over time", func(t *testing.T) {
+		clk.Advance(time.Second)
+		assert.Equal(t, http.StatusOK, get(e, "/estate/a/stats", "k1").Code)
+		assert.Equal(t, http.StatusTooManyRequests, get(e, "/estate/a/drone-plan", "k1").Code)
+
+		clk.Advance(9 * time.Second)
+		assert.Equal(t, http.StatusOK, get(e, "/estate/a/drone-plan", "k1").Code)
+	})
+
+	t.Run("skipped routes are not limited", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusOK, get(e, "/metrics", "k1").Code)
+		}
+	})
+}
+
+func TestMiddleware_Disabled(t *testing.T) {
+	e := newServer(Config{})
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, get(e, "/estate/a/stats", "k1").Code)
+	}
+}
+
+func TestMiddleware_IPKey(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	e := newServer(Config{Default: Limit{Rate: 1, Burst: 2}, KeyFunc: IPKey, Now: clk.Now})
+
+	assert.Equal(t, http.StatusOK, get(e, "/estate/a/stats", "k1").Code)
+	assert.Equal(t, http.StatusOK, get(e, "/estate/a/stats", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get(e, "/estate/a/stats", "k2").Code, "callers share the bucket of their IP")
+}
+
+func TestBuckets_Sweep(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	b := NewBuckets(time.Minute, clk.Now)
+
+	b.Reserve(DefaultRule, "a", Limit{Rate: 1, Burst: 1})
+	clk.Advance(30 * time.Second)
+	b.Reserve(DefaultRule, "b", Limit{Rate: 1, Burst: 1})
+	clk.Advance(45 * time.Second)
+	b.Reserve(DefaultRule, "c", Limit{Rate: 1, Burst: 1})
+
+	assert.ElementsMatch(t, []string{"default|b", "default|c"}, keys(b.buckets))
+}
+
+func TestMiddleware_SharedBuckets(t *testing.T) {
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	buckets := NewBuckets(0, clk.Now)
+	e := newServer(Config{Default: Limit{Rate: 1, Burst: 2}, Buckets: buckets})
+
+	assert.Zero(t, buckets.Reserve(DefaultRule, "apikey:k1", Limit{Rate: 1, Burst: 2}), "e.g. an RPC of the same caller")
+	assert.Equal(t, http.StatusOK, get(e, "/estate/a/stats", "k1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get(e, "/estate/a/stats", "k1").Code)
+	assert.Equal(t, time.Second, buckets.Reserve(DefaultRule, "apikey:k1", Limit{Rate: 1, Burst: 2}))
+	assert.Zero(t, buckets.Reserve("drone-plan", "apikey:k1", Limit{Rate: 1, Burst: 2}), "rules have buckets of their own")
+	assert.Zero(t, buckets.Reserve(DefaultRule, "apikey:k1", Limit{}), "a zero rate is not limited")
+}
+
+func keys(m map[string]*bucket) []string {
+	var result []string
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, "1", RetryAfter(10*time.Millisecond))
+	assert.Equal(t, "2", RetryAfter(1500*time.Millisecond))
+	assert.Equal(t, "10", RetryAfter(10*time.Second))
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	InsertAPIKeyQuery = `INSERT INTO api_keys (id, organization_id, name, key_hash, scopes) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	GetAPIKeyQuery    = `SELECT id, organization_id, name, key_hash, scopes, created_at, updated_at, revoked_at FROM api_keys`
+	RevokeAPIKeyQuery = `UPDATE api_keys SET revoked_at = NOW(), updated_at = NOW() WHERE id = $1 AND revoked_at IS NULL RETURNING organization_id, name, scopes, revoked_at`
+)
+
+func (r *Repository) CreateAPIKey(ctx context.Context, data *APIKey) (err error) {
+	ctx, end := r.startQuery(ctx, "CreateAPIKey", "InsertAPIKeyQuery")
+	defer func() { end(err) }()
+
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			InsertAPIKeyQuery,
+			data.ID,
+			data.OrganizationID,
+			data.Name,
+			data.KeyHash,
+			pq.Array(data.Scopes),
+		)
+		if err != nil {
+			return err
+		}
+		return insertAuditEntry(ctx, tx, auditRecord{
+			organizationID: data.OrganizationID,
+			action:         AuditActionCreate,
+			entityType:     AuditEntityAPIKey,
+			entityID:       data.ID,
+			after:          apiKeyData{ID: data.ID, Name: data.Name, Scopes: data.Scopes},
+		})
+	})
+	return wrapError("CreateAPIKey", err)
+}
+
+func (r *Repository) FindAPIKey(ctx context.Context, filter *FilterAPIKey) (_ APIKey, err error) {
+	ctx, end := r.startQuery(ctx, "FindAPIKey", "GetAPIKeyQuery")
+	defer func() { end(err) }()
+
+	finalQuery, paramValue := r.setFilterAPIKey(GetAPIKeyQuery, filter)
+	var apiKey APIKey
+	err = r.Db.QueryRowContext(ctx, finalQuery, paramValue...).
+		Scan(&apiKey.ID, &apiKey.OrganizationID, &apiKey.Name, &apiKey.KeyHash,
+			pq.Array(&apiKey.Scopes), &apiKey.CreatedAt, &apiKey.UpdatedAt, &apiKey.RevokedAt)
+	if err != nil {
+		return APIKey{}, wrapError("FindAPIKey", err)
+	}
+
+	return apiKey, nil
+}
+
+func (r *Repository) FindAllAPIKey(ctx context.Context, filter *FilterAPIKey) (_ []APIKey, err error) {
+	ctx, end := r.startQuery(ctx, "FindAllAPIKey", "GetAPIKeyQuery")
+	defer func() { end(err) }()
+
+	finalQuery, paramValue := r.setFilterAPIKey(GetAPIKeyQuery, filter)
+	finalQuery += " ORDER BY created_at DESC"
+
+	rows, err := r.Db.QueryContext(ctx, finalQuery, paramValue...)
+	if err != nil {
+		return nil, wrapError("FindAllAPIKey", err)
+	}
+	defer closeRows(ctx, "FindAllAPIKey", rows)
+
+	var result []APIKey
+	for rows.Next() {
+		var apiKey APIKey
+		if err = rows.Scan(&apiKey.ID, &apiKey.OrganizationID, &apiKey.Name, &apiKey.KeyHash,
+			pq.Array(&apiKey.Scopes), &apiKey.CreatedAt, &apiKey.UpdatedAt, &apiKey.RevokedAt); err != nil {
+			return nil, wrapError("FindAllAPIKey", err)
+		}
+		result = append(result, apiKey)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapError("FindAllAPIKey", err)
+	}
+
+	return result, nil
+}
+
+func (r *Repository) RevokeAPIKey(ctx context.Context, id string) (err error) {
+	ctx, end := r.startQuery(ctx, "RevokeAPIKey", "RevokeAPIKeyQuery")
+	defer func() { end(err) }()
+
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		var (
+			organizationID string
+			revoked        = apiKeyData{ID: id}
+		)
+		err := tx.QueryRowContext(ctx, RevokeAPIKeyQuery, id).
+			Scan(&organizationID, &revoked.Name, pq.Array(&revoked.Scopes), &revoked.RevokedAt)
+		if err != nil {
+			return err
+		}
+		active := revoked
+		active.RevokedAt = nil
+		return insertAuditEntry(ctx, tx, auditRecord{
+			organizationID: organizationID,
+			action:         AuditActionRevoke,
+			entityType:     AuditEntityAPIKey,
+			entityID:       id,
+			before:         active,
+			after:          revoked,
+		})
+	})
+	return wrapError("RevokeAPIKey", err)
+}
+
+func (r *Repository) setFilterAPIKey(baseQuery string, filter *FilterAPIKey) (string, []interface{}) {
+	var (
+		where      []string
+		paramValue []interface{}
+	)
+	if filter.ID != "" {
+		where = append(where, "id = $"+strconv.Itoa(len(paramValue)+1))
+		paramValue = append(paramValue, filter.ID)
+	}
+	if filter.KeyHash != "" {
+		where = append(where, "key_hash = $"+strconv.Itoa(len(paramValue)+1))
+		paramValue = append(paramValue, filter.KeyHash)
+	}
+	if filter.OrganizationID != "" {
+		where = append(where, "organization_id = $"+strconv.Itoa(len(paramValue)+1))
+		paramValue = append(paramValue, filter.OrganizationID)
+	}
+	if !filter.ShowRevoked {
+		where = append(where, "revoked_at IS NULL")
+	}
+
+	clauseWhere := strings.Join(where, " AND ")
+	if clauseWhere != "" {
+		baseQuery += " WHERE " + clauseWhere
+	}
+
+	return baseQuery, paramValue
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var apiKeyColumns = []string{"id", "organization_id", "name", "key_hash", "scopes", "created_at", "updated_at", "revoked_at"}
+
+func TestRepository_CreateAPIKey(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		id := uuid.NewString()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO api_keys").
+			WithArgs(id, "org-1", "ci", "hash", pq.Array([]string{"read"})).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectAuditEntry(mock, "org-1", AuditActionCreate, AuditEntityAPIKey, id, "",
+			nil, `{"id":"`+id+`","name":"ci","scopes":["read"],"revoked_at":null}`)
+		mock.ExpectCommit()
+
+		err = repo.CreateAPIKey(context.Background(), &APIKey{
+			BaseModel:      BaseModel{ID: id},
+			OrganizationID: "org-1",
+			Name:           "ci",
+			KeyHash:        "hash",
+			Scopes:         []string{"read"},
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed: Duplicate hash", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO api_keys").WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		err = repo.CreateAPIKey(context.Background(), &APIKey{BaseModel: BaseModel{ID: uuid.NewString()}})
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_FindAPIKey(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		now := time.Now()
+		expected := APIKey{
+			BaseModel:      BaseModel{ID: uuid.NewString(), CreatedAt: now, UpdatedAt: now},
+			OrganizationID: "org-1",
+			Name:           "ci",
+			KeyHash:        "hash",
+			Scopes:         []string{"read", "plan"},
+		}
+
+		mock.ExpectQuery("SELECT .* FROM api_keys WHERE key_hash = \\$1 AND revoked_at IS NULL").
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+				AddRow(expected.ID, expected.OrganizationID, expected.Name, expected.KeyHash, "{read,plan}", now, now, nil))
+
+		result, err := repo.FindAPIKey(context.Background(), &FilterAPIKey{KeyHash: "hash"})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed: No rows found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectQuery("SELECT .* FROM api_keys").WithArgs("hash").WillReturnError(sql.ErrNoRows)
+
+		result, err := repo.FindAPIKey(context.Background(), &FilterAPIKey{KeyHash: "hash"})
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, APIKey{}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_FindAllAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT .* FROM api_keys WHERE organization_id = \\$1 ORDER BY created_at DESC").
+		WithArgs("org-1").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+			AddRow("key-2", "org-1", "ci", "hash-2", "{read}", now, now, nil).
+			AddRow("key-1", "org-1", "old", "hash-1", "{write}", now, now, now))
+
+	result, err := repo.FindAllAPIKey(context.Background(), &FilterAPIKey{OrganizationID: "org-1", ShowRevoked: true})
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "key-2", result[0].ID)
+	assert.Nil(t, result[0].RevokedAt)
+	assert.NotNil(t, result[1].RevokedAt)
+	assert.Equal(t, []string{"write"}, result[1].Scopes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_RevokeAPIKey(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE api_keys SET revoked_at").WithArgs("key-1").
+			WillReturnRows(sqlmock.NewRows([]string{"organization_id", "name", "scopes", "revoked_at"}).AddRow("org-1", "ci", "{read}", time.Now()))
+		expectAuditEntry(mock, "org-1", AuditActionRevoke, AuditEntityAPIKey, "key-1", "",
+			`{"id":"key-1","name":"ci","scopes":["read"],"revoked_at":null}`, sqlmock.AnyArg())
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.RevokeAPIKey(context.Background(), "key-1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed: Unknown or already revoked", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE api_keys SET revoked_at").WithArgs("key-1").
+			WillReturnRows(sqlmock.NewRows([]string{"organization_id", "name", "scopes", "revoked_at"}))
+		mock.ExpectRollback()
+
+		err = repo.RevokeAPIKey(context.Background(), "key-1")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/logging"
+)
+
+// SystemActor is the actor of writes made without one in their context.
+const SystemActor = "system"
+
+const (
+	InsertAuditEntryQuery = `INSERT INTO audit_log (organization_id, actor, action, entity_type, entity_id, estate_id, before, after, request_id)
+VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7::jsonb, $8::jsonb, NULLIF($9, ''))`
+	GetAuditEntryQuery = `SELECT id, organization_id, actor, action, entity_type, entity_id, estate_id, before, after, request_id, created_at FROM audit_log`
+)
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx whose writes are audited as made by
+// actor, e.g. the subject of the authenticated caller.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored by WithActor, or SystemActor.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+// organizationData, estateData, treeData, apiKeyData, planJobData and
+// webhookSubscriptionData are the JSON of entities in audit entries and
+// webhook events. They leave out secrets.
+type organizationData struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type estateData struct {
+	ID     string `json:"id"`
+	Width  int    `json:"width"`
+	Length int    `json:"length"`
+}
+
+type treeData struct {
+	ID       string `json:"id"`
+	EstateID string `json:"estate_id"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Height   int    `json:"height"`
+}
+
+// importData is a chunk of the trees of an import, as audited and sent in
+// a trees.imported event.
+type importData struct {
+	EstateID string     `json:"estate_id"`
+	Trees    []treeData `json:"trees"`
+}
+
+type apiKeyData struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+type planJobData struct {
+	ID              string        `json:"id"`
+	EstateID        string        `json:"estate_id"`
+	MaxDistance     *int          `json:"max_distance"`
+	Status          PlanJobStatus `json:"status"`
+	CancelRequested bool          `json:"cancel_requested"`
+}
+
+func newPlanJobData(job PlanJob) planJobData {
+	return planJobData{
+		ID:              job.ID,
+		EstateID:        job.EstateID,
+		MaxDistance:     job.MaxDistance,
+		Status:          job.Status,
+		CancelRequested: job.CancelRequested,
+	}
+}
+
+type webhookSubscriptionData struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// auditRecord is a write to audit; before and after are marshalled to JSON
+// and left NULL when nil.
+type auditRecord struct {
+	organizationID string
+	action         string
+	entityType     string
+	entityID       string
+	estateID       string
+	before         interface{}
+	after          interface{}
+}
+
+// insertAuditEntry appends record to the audit log in tx, with the actor and
+// request ID of ctx.
+func insertAuditEntry(ctx context.Context, tx *sql.Tx, record auditRecord) error {
+	before, err := auditJSON(record.before)
+	if err != nil {
+		return err
+	}
+	after, err := auditJSON(record.after)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		InsertAuditEntryQuery,
+		record.organizationID,
+		ActorFromContext(ctx),
+		record.action,
+		record.entityType,
+		record.entityID,
+		record.estateID,
+		before,
+		after,
+		logging.RequestIDFromContext(ctx),
+	)
+	return err
+}
+
+func auditJSON(v interface{}) (sql.NullString, error) {
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func (r *Repository) FindAllAuditEntry(ctx context.Context, filter *FilterAuditEntry) (_ []AuditEntry, err error) {
+	ctx, end := r.startQuery(ctx, "FindAllAuditEntry", "GetAuditEntryQuery")
+	defer func() { end(err) }()
+
+	if filter.OrganizationID == "" {
+		return nil, invalidFilter("FindAllAuditEntry", "organization is required")
+	}
+	where := []string{"organization_id = $1"}
+	paramValue := []interface{}{filter.OrganizationID}
+	if filter.EstateID != "" {
+		where = append(where, "estate_id = $"+strconv.Itoa(len(paramValue)+1))
+		paramValue = append(paramValue, filter.EstateID)
+	}
+	if filter.Actor != "" {
+		where = append(where, "actor = $"+strconv.Itoa(len(paramValue)+1))
+		paramValue = append(paramValue, filter.Actor)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "created_at >= $"+strconv.Itoa(len(paramValue)+1))
+		paramValue = append(paramValue, filter.From)
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "created_at < $"+strconv.Itoa(len(paramValue)+1))
+		paramValue = append(paramValue, filter.To)
+	}
+	finalQuery := GetAuditEntryQuery + " WHERE " + strings.Join(where, " AND ") + " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		finalQuery += " LIMIT $" + strconv.Itoa(len(paramValue)+1)
+		paramValue = append(paramValue, filter.Limit)
+	}
+
+	rows, err := r.Db.QueryContext(ctx, finalQuery, paramValue...)
+	if err != nil {
+		return nil, wrapError("FindAllAuditEntry", err)
+	}
+	defer closeRows(ctx, "FindAllAuditEntry", rows)
+
+	var result []AuditEntry
+	for rows.Next() {
+		var (
+			entry     AuditEntry
+			estateID  sql.NullString
+			requestID sql.NullString
+		)
+		if err = rows.Scan(&entry.ID, &entry.OrganizationID, &entry.Actor, &entry.Action, &entry.EntityType, &entry.EntityID,
+			&estateID, &entry.Before, &entry.After, &requestID, &entry.CreatedAt); err != nil {
+			return nil, wrapError("FindAllAuditEntry", err)
+		}
+		entry.EstateID = estateID.String
+		entry.RequestID = requestID.String
+		result = append(result, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapError("FindAllAuditEntry", err)
+	}
+	return result, nil
+}
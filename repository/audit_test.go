@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var auditEntryColumnNames = []string{"id", "organization_id", "actor", "action", "entity_type", "entity_id", "estate_id", "before", "after", "request_id", "created_at"}
+
+// expectAuditEntry expects the audit entry of a write made by SystemActor
+// outside a request. before and after are JSON strings, nil or
+// sqlmock.AnyArg().
+func expectAuditEntry(mock sqlmock.Sqlmock, organizationID, action, entityType, entityID, estateID string, before, after interface{}) {
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(organizationID, SystemActor, action, entityType, entityID, estateID, before, after, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func TestRepository_AuditActor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO organizations").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs("org-1", "apikey:key-1", AuditActionCreate, AuditEntityOrganization, "org-1", "", nil, sqlmock.AnyArg(), "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	ctx := WithActor(context.Background(), "apikey:key-1")
+	assert.NoError(t, repo.CreateOrganization(ctx, &Organization{BaseModel: BaseModel{ID: "org-1"}, Name: "Sawit Jaya"}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, SystemActor, ActorFromContext(context.Background()))
+}
+
+func TestRepository_AuditFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO estate_trees").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO audit_log").WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	err = repo.CreateEstateTree(context.Background(), &EstateTree{BaseModel: BaseModel{ID: "tree-1"}, OrganizationID: testOrganizationID, EstateID: "estate-1"})
+	assert.ErrorIs(t, err, assert.AnError, "the tree is not created without its audit entry")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_FindAllAuditEntry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	mock.ExpectQuery("SELECT .* FROM audit_log WHERE organization_id = \\$1 AND estate_id = \\$2 AND actor = \\$3 AND created_at >= \\$4 AND created_at < \\$5 ORDER BY id DESC LIMIT \\$6").
+		WithArgs("org-1", "estate-1", "apikey:key-1", from, to, 50).
+		WillReturnRows(sqlmock.NewRows(auditEntryColumnNames).
+			AddRow(2, "org-1", "apikey:key-1", AuditActionCreate, AuditEntityTree, "tree-1", "estate-1", nil, []byte(`{"id":"tree-1"}`), "req-1", from).
+			AddRow(1, "org-1", "apikey:key-1", AuditActionCreate, AuditEntityEstate, "estate-1", "estate-1", nil, []byte(`{"id":"estate-1"}`), nil, from))
+
+	entries, err := repo.FindAllAuditEntry(context.Background(), &FilterAuditEntry{
+		Filter:         Filter{Limit: 50},
+		OrganizationID: "org-1",
+		EstateID:       "estate-1",
+		Actor:          "apikey:key-1",
+		From:           from,
+		To:             to,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, int64(2), entries[0].ID)
+	assert.Equal(t, AuditEntityTree, entries[0].EntityType)
+	assert.Nil(t, entries[0].Before)
+	assert.JSONEq(t, `{"id":"tree-1"}`, string(entries[0].After))
+	assert.Equal(t, "req-1", entries[0].RequestID)
+	assert.Empty(t, entries[1].RequestID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = repo.FindAllAuditEntry(context.Background(), &FilterAuditEntry{EstateID: "estate-1"})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Kinds of BatchOperation.
+const (
+	BatchCreateEstate = "create_estate"
+	BatchCreateTree   = "create_tree"
+	BatchUpdateTree   = "update_tree"
+	BatchDeleteTree   = "delete_tree"
+)
+
+// Savepoints around the operations of a batch that is not atomic, so that a
+// failed one leaves the others in the transaction.
+const (
+	SavepointBatchOperationQuery = `SAVEPOINT batch_operation`
+	RollbackBatchOperationQuery  = `ROLLBACK TO SAVEPOINT batch_operation`
+	ReleaseBatchOperationQuery   = `RELEASE SAVEPOINT batch_operation`
+)
+
+// BatchOperation is a write of ApplyBatch. BatchCreateEstate creates
+// Estate; the other kinds write Tree as CreateEstateTree, UpdateEstateTree
+// and DeleteEstateTree do, the last only reading its ID and OrganizationID.
+type BatchOperation struct {
+	Kind   string
+	Estate Estate
+	Tree   EstateTree
+	// DependsOn are the indexes of the earlier operations this one was
+	// prepared against, e.g. the creation of the tree it updates. It is not
+	// run when one of them failed or was not run.
+	DependsOn []int
+}
+
+var (
+	// ErrBatchDependencyFailed is the error of the operations of a batch
+	// that were not run because an operation they depend on failed.
+	ErrBatchDependencyFailed = errors.New("repository: batch operation depends on a failed operation")
+
+	// errBatchFailed rolls back an atomic batch once an operation failed.
+	errBatchFailed = errors.New("batch operation failed")
+)
+
+// ApplyBatch writes ops in order in one transaction, each audited and
+// followed by the webhook and estate events of the method it stands for. It
+// returns the error of every operation run, nil for those that succeeded,
+// wrapped as that method would wrap it.
+//
+// When atomic, the first operation to fail rolls the whole batch back and
+// is the last one run. Otherwise every operation runs within a savepoint,
+// so a failed one is undone alone and the others are committed, but for
+// those depending on it: their error is ErrBatchDependencyFailed.
+func (r *Repository) ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool) (_ []error, err error) {
+	ctx, end := r.startQuery(ctx, "ApplyBatch", "SavepointBatchOperationQuery")
+	defer func() { end(err) }()
+
+	var results []error
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		results = make([]error, 0, len(ops))
+		for _, op := range ops {
+			if atomic {
+				opErr := applyBatchOperation(ctx, tx, op)
+				results = append(results, opErr)
+				if opErr != nil {
+					return errBatchFailed
+				}
+				continue
+			}
+
+			if dependencyFailed(op, results) {
+				results = append(results, ErrBatchDependencyFailed)
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, SavepointBatchOperationQuery); err != nil {
+				return err
+			}
+			opErr := applyBatchOperation(ctx, tx, op)
+			results = append(results, opErr)
+			query := ReleaseBatchOperationQuery
+			if opErr != nil {
+				query = RollbackBatchOperationQuery
+			}
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchFailed) {
+		return nil, wrapError("ApplyBatch", err)
+	}
+	return results, nil
+}
+
+// dependencyFailed reports whether an operation op depends on has an error
+// in results.
+func dependencyFailed(op BatchOperation, results []error) bool {
+	for _, i := range op.DependsOn {
+		if i < len(results) && results[i] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBatchOperation writes op in tx, wrapping its error for the method op
+// stands for.
+func applyBatchOperation(ctx context.Context, tx *sql.Tx, op BatchOperation) error {
+	switch op.Kind {
+	case BatchCreateEstate:
+		return wrapError("CreateEstate", createEstate(ctx, tx, &op.Estate))
+	case BatchCreateTree:
+		return wrapError("CreateEstateTree", createEstateTree(ctx, tx, &op.Tree))
+	case BatchUpdateTree:
+		return wrapError("UpdateEstateTree", updateEstateTree(ctx, tx, &op.Tree))
+	case BatchDeleteTree:
+		return wrapError("DeleteEstateTree", deleteEstateTree(ctx, tx, op.Tree.OrganizationID, op.Tree.ID))
+	}
+	return invalidFilter("ApplyBatch", "unknown operation "+op.Kind)
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_ApplyBatch(t *testing.T) {
+	estateID := uuid.NewString()
+	treeID := uuid.NewString()
+	ops := []BatchOperation{
+		{Kind: BatchCreateEstate, Estate: Estate{BaseModel: BaseModel{ID: estateID}, OrganizationID: testOrganizationID, Width: 10, Length: 20}},
+		{Kind: BatchCreateTree, Tree: EstateTree{BaseModel: BaseModel{ID: treeID}, OrganizationID: testOrganizationID, EstateID: estateID, X: 1, Y: 2, Height: 10}},
+		{Kind: BatchDeleteTree, Tree: EstateTree{BaseModel: BaseModel{ID: treeID}, OrganizationID: testOrganizationID}},
+	}
+	treeJSON := `{"id":"` + treeID + `","estate_id":"` + estateID + `","x":1,"y":2,"height":10}`
+	expectCreateEstate := func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec("INSERT INTO estates").WithArgs(estateID, testOrganizationID, 10, 20).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectAuditEntry(mock, testOrganizationID, AuditActionCreate, AuditEntityEstate, estateID, estateID,
+			nil, `{"id":"`+estateID+`","width":10,"length":20}`)
+		mock.ExpectExec("INSERT INTO webhook_events").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	t.Run("Atomic", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectBegin()
+		expectCreateEstate(mock)
+		mock.ExpectExec("INSERT INTO estate_trees").WithArgs(treeID, testOrganizationID, estateID, 1, 2, 10).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectAuditEntry(mock, testOrganizationID, AuditActionCreate, AuditEntityTree, treeID, estateID, nil, treeJSON)
+		mock.ExpectExec("INSERT INTO webhook_events").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("UPDATE estate_trees SET deleted_at").WithArgs(testOrganizationID, treeID).
+			WillReturnRows(sqlmock.NewRows([]string{"estate_id", "x", "y", "height"}).AddRow(estateID, 1, 2, 10))
+		expectAuditEntry(mock, testOrganizationID, AuditActionDelete, AuditEntityTree, treeID, estateID, treeJSON, nil)
+		mock.ExpectExec("INSERT INTO webhook_events").
+			WithArgs(sqlmock.AnyArg(), testOrganizationID, WebhookEventTreeDeleted, treeJSON).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		results, err := repo.ApplyBatch(context.Background(), ops, true)
+		require.NoError(t, err)
+		assert.Equal(t, []error{nil, nil, nil}, results)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Atomic failure rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectBegin()
+		expectCreateEstate(mock)
+		mock.ExpectExec("INSERT INTO estate_trees").WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		results, err := repo.ApplyBatch(context.Background(), ops, true)
+		require.NoError(t, err)
+		require.Len(t, results, 2, "the operations after the failed one are not run")
+		assert.NoError(t, results[0])
+		assert.ErrorIs(t, results[1], ErrConflict)
+		var repoErr *Error
+		require.ErrorAs(t, results[1], &repoErr)
+		assert.Equal(t, "CreateEstateTree", repoErr.Op)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failures are undone alone", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+		expectCreateEstate(mock)
+		mock.ExpectExec("RELEASE SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO estate_trees").WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("UPDATE estate_trees SET deleted_at").WithArgs(testOrganizationID, treeID).
+			WillReturnRows(sqlmock.NewRows([]string{"estate_id", "x", "y", "height"}))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		results, err := repo.ApplyBatch(context.Background(), ops, false)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.NoError(t, results[0])
+		assert.ErrorIs(t, results[1], ErrConflict)
+		assert.ErrorIs(t, results[2], ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Dependencies of failures are not run", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		dependent := append([]BatchOperation{}, ops...)
+		dependent[1].DependsOn = []int{0}
+		dependent[2].DependsOn = []int{1}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO estates").WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT batch_operation").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		results, err := repo.ApplyBatch(context.Background(), dependent, false)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.ErrorIs(t, results[0], ErrConflict)
+		assert.Equal(t, ErrBatchDependencyFailed, results[1])
+		assert.Equal(t, ErrBatchDependencyFailed, results[2], "dependencies are followed through skipped operations")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Transaction failure", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		var statements []string
+		repo := &Repository{Db: db, Hooks: []QueryHook{func(ctx context.Context, query QueryInfo) (context.Context, func(error)) {
+			statements = append(statements, query.Statement)
+			return ctx, func(error) {}
+		}}}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT batch_operation").WillReturnError(&pq.Error{Code: "08006"})
+		mock.ExpectRollback()
+
+		results, err := repo.ApplyBatch(context.Background(), ops, false)
+		assert.ErrorIs(t, err, ErrUnavailable)
+		assert.Nil(t, results)
+		assert.Equal(t, []string{"SavepointBatchOperationQuery"}, statements, "failures are reported under a statement")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
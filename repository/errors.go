@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/lib/pq"
+)
+
+// Sentinel errors reported by the repository. Every method wraps the
+// underlying database error in an *Error whose Kind is one of these, so
+// callers can use errors.Is without knowing about the SQL driver.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrConflict      = errors.New("conflict")
+	ErrUnavailable   = errors.New("unavailable")
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+// Error is returned by repository methods when a query fails.
+type Error struct {
+	// Op is the repository method that failed, e.g. "FindEstate".
+	Op string
+	// Kind is one of the sentinel errors, or nil when the failure could
+	// not be classified.
+	Kind error
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	msg := "repository: " + e.Op
+	if e.Kind != nil {
+		msg += ": " + e.Kind.Error()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap exposes both the sentinel kind and the underlying error to
+// errors.Is and errors.As.
+func (e *Error) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// wrapError classifies err and wraps it for the operation op. Errors that are
+// already wrapped are returned as is.
+func wrapError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var repoErr *Error
+	if errors.As(err, &repoErr) {
+		return err
+	}
+	return &Error{Op: op, Kind: classifyError(err), Err: err}
+}
+
+// invalidFilter reports a filter that cannot be turned into a query.
+func invalidFilter(op, reason string) error {
+	return &Error{Op: op, Kind: ErrInvalidFilter, Err: errors.New(reason)}
+}
+
+func classifyError(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, context.DeadlineExceeded):
+		return ErrUnavailable
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "53", "57": // connection exception, insufficient resources, operator intervention
+			return ErrUnavailable
+		case "23": // integrity constraint violation
+			if pqErr.Code.Name() == "foreign_key_violation" {
+				return ErrNotFound
+			}
+			return ErrConflict
+		}
+		return nil
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrUnavailable
+	}
+	return nil
+}
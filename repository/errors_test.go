@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapError(t *testing.T) {
+	testcases := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{name: "no rows", err: sql.ErrNoRows, kind: ErrNotFound},
+		{name: "bad connection", err: driver.ErrBadConn, kind: ErrUnavailable},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, kind: ErrUnavailable},
+		{name: "connection failure", err: &pq.Error{Code: "08006"}, kind: ErrUnavailable},
+		{name: "too many connections", err: &pq.Error{Code: "53300"}, kind: ErrUnavailable},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, kind: ErrConflict},
+		{name: "check violation", err: &pq.Error{Code: "23514"}, kind: ErrConflict},
+		{name: "foreign key violation", err: &pq.Error{Code: "23503"}, kind: ErrNotFound},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := wrapError("FindEstate", tc.err)
+			assert.ErrorIs(t, err, tc.kind)
+			assert.ErrorIs(t, err, tc.err)
+
+			var repoErr *Error
+			assert.True(t, errors.As(err, &repoErr))
+			assert.Equal(t, "FindEstate", repoErr.Op)
+		})
+	}
+
+	t.Run("Unclassified", func(t *testing.T) {
+		err := wrapError("FindEstate", errors.New("syntax error"))
+		for _, kind := range []error{ErrNotFound, ErrConflict, ErrUnavailable, ErrInvalidFilter} {
+			assert.NotErrorIs(t, err, kind)
+		}
+		assert.Equal(t, "repository: FindEstate: syntax error", err.Error())
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		assert.NoError(t, wrapError("FindEstate", nil))
+	})
+
+	t.Run("Already wrapped", func(t *testing.T) {
+		wrapped := wrapError("FindEstate", sql.ErrNoRows)
+		assert.Same(t, wrapped, wrapError("CreateEstate", wrapped))
+	})
+}
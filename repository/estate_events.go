@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+)
+
+// EstateEventsChannel is the channel notified with "<estate id>:<version>"
+// when an estate event is committed.
+const EstateEventsChannel = "estate_events"
+
+const (
+	GetEstateEventQuery = `SELECT estate_id, version, organization_id, type, data, created_at FROM estate_events`
+)
+
+func (r *Repository) FindAllEstateEvent(ctx context.Context, filter *FilterEstateEvent) (_ []EstateEvent, err error) {
+	ctx, end := r.startQuery(ctx, "FindAllEstateEvent", "GetEstateEventQuery")
+	defer func() { end(err) }()
+
+	if filter.OrganizationID == "" {
+		return nil, invalidFilter("FindAllEstateEvent", "organization is required")
+	}
+	if filter.EstateID == "" {
+		return nil, invalidFilter("FindAllEstateEvent", "estate is required")
+	}
+	finalQuery := GetEstateEventQuery + " WHERE organization_id = $1 AND estate_id = $2 AND version > $3 ORDER BY version ASC"
+	paramValue := []interface{}{filter.OrganizationID, filter.EstateID, filter.AfterVersion}
+	if filter.Limit > 0 {
+		finalQuery += " LIMIT $" + strconv.Itoa(len(paramValue)+1)
+		paramValue = append(paramValue, filter.Limit)
+	}
+
+	rows, err := r.Db.QueryContext(ctx, finalQuery, paramValue...)
+	if err != nil {
+		return nil, wrapError("FindAllEstateEvent", err)
+	}
+	defer closeRows(ctx, "FindAllEstateEvent", rows)
+
+	var result []EstateEvent
+	for rows.Next() {
+		var event EstateEvent
+		if err = rows.Scan(&event.EstateID, &event.Version, &event.OrganizationID, &event.Type, &event.Data, &event.CreatedAt); err != nil {
+			return nil, wrapError("FindAllEstateEvent", err)
+		}
+		result = append(result, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapError("FindAllEstateEvent", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_FindAllEstateEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT .* FROM estate_events WHERE organization_id = \\$1 AND estate_id = \\$2 AND version > \\$3 ORDER BY version ASC LIMIT \\$4").
+		WithArgs("org-1", "estate-1", int64(3), 100).
+		WillReturnRows(sqlmock.NewRows([]string{"estate_id", "version", "organization_id", "type", "data", "created_at"}).
+			AddRow("estate-1", 4, "org-1", EstateEventTreeCreated, []byte(`{"id":"tree-1"}`), createdAt).
+			AddRow("estate-1", 5, "org-1", EstateEventTreeDeleted, []byte(`{"id":"tree-1"}`), createdAt))
+
+	events, err := repo.FindAllEstateEvent(context.Background(), &FilterEstateEvent{
+		Filter:         Filter{Limit: 100},
+		OrganizationID: "org-1",
+		EstateID:       "estate-1",
+		AfterVersion:   3,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, int64(4), events[0].Version)
+	assert.Equal(t, EstateEventTreeCreated, events[0].Type)
+	assert.JSONEq(t, `{"id":"tree-1"}`, string(events[0].Data))
+	assert.Equal(t, EstateEventTreeDeleted, events[1].Type)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = repo.FindAllEstateEvent(context.Background(), &FilterEstateEvent{OrganizationID: "org-1"})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+	_, err = repo.FindAllEstateEvent(context.Background(), &FilterEstateEvent{EstateID: "estate-1"})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
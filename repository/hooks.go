@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/dimassantoso/drone-sawit/logging"
+)
+
+// QueryInfo describes the repository method a QueryHook observes.
+type QueryInfo struct {
+	// Method is the repository method, e.g. "FindEstate".
+	Method string
+	// Statement names the SQL statement the method runs, e.g.
+	// "GetEstateQuery".
+	Statement string
+}
+
+// QueryHook observes a repository method, e.g. to time it or trace it. It is
+// called when the method starts and returns the context the method runs
+// with and a function called with its error, nil on success, when it
+// returns.
+type QueryHook func(ctx context.Context, query QueryInfo) (context.Context, func(err error))
+
+// startQuery runs the hooks of r for method, which runs statement. The
+// returned function must be called exactly once when the method returns;
+// it also logs the failure of the method with the logger of ctx.
+func (r *Repository) startQuery(ctx context.Context, method, statement string) (context.Context, func(err error)) {
+	query := QueryInfo{Method: method, Statement: statement}
+	ends := make([]func(error), len(r.Hooks))
+	for i, hook := range r.Hooks {
+		ctx, ends[i] = hook(ctx, query)
+	}
+	return ctx, func(err error) {
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](err)
+		}
+		logQueryError(ctx, query, err)
+	}
+}
+
+// logQueryError logs a failed repository method. Errors the caller is
+// expected to handle, such as not found and conflicts, are only logged at
+// debug level.
+func logQueryError(ctx context.Context, query QueryInfo, err error) {
+	if err == nil {
+		return
+	}
+
+	level := slog.LevelError
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrConflict):
+		level = slog.LevelDebug
+	case errors.Is(err, ErrInvalidFilter):
+		level = slog.LevelWarn
+	}
+	logging.FromContext(ctx).LogAttrs(ctx, level, "repository query failed",
+		slog.String("method", query.Method),
+		slog.String("statement", query.Statement),
+		slog.Any("error", err),
+	)
+}
+
+// closeRows closes rows, logging the error the deferred Close would
+// otherwise discard.
+func closeRows(ctx context.Context, method string, rows io.Closer) {
+	if err := rows.Close(); err != nil {
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "repository rows close failed",
+			slog.String("method", method),
+			slog.Any("error", err),
+		)
+	}
+}
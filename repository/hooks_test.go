@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_Hooks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var calls []string
+	var ended []error
+	hook := func(name string) QueryHook {
+		return func(ctx context.Context, query QueryInfo) (context.Context, func(error)) {
+			calls = append(calls, name+" start "+query.Method+" "+query.Statement)
+			return ctx, func(err error) {
+				calls = append(calls, name+" end "+query.Method)
+				ended = append(ended, err)
+			}
+		}
+	}
+	repo := &Repository{Db: db, Hooks: []QueryHook{hook("outer"), hook("inner")}}
+
+	id := uuid.NewString()
+	mock.ExpectQuery("SELECT .* FROM estates").WithArgs(testOrganizationID, id).WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.FindEstate(context.Background(), &FilterEstate{ID: id, OrganizationID: testOrganizationID})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, []string{
+		"outer start FindEstate GetEstateQuery",
+		"inner start FindEstate GetEstateQuery",
+		"inner end FindEstate",
+		"outer end FindEstate",
+	}, calls)
+	assert.Len(t, ended, 2)
+	for _, e := range ended {
+		assert.ErrorIs(t, e, ErrNotFound)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_LogsFailedQueries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	ctx := logging.NewContext(context.Background(), logger)
+	repo := &Repository{Db: db}
+
+	id := uuid.NewString()
+	mock.ExpectQuery("SELECT .* FROM estates").WithArgs(testOrganizationID, id).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT COUNT").WillReturnError(errors.New("boom"))
+
+	_, err = repo.FindEstate(ctx, &FilterEstate{ID: id, OrganizationID: testOrganizationID})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Empty(t, buf.String(), "not found is only logged at debug level")
+
+	_, err = repo.CountEstateTree(ctx, &FilterEstateTree{OrganizationID: testOrganizationID, EstateID: id})
+	assert.Error(t, err)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "ERROR", entry["level"])
+	assert.Equal(t, "repository query failed", entry["msg"])
+	assert.Equal(t, "CountEstateTree", entry["method"])
+	assert.Equal(t, "EstateTreeCountQuery", entry["statement"])
+	assert.Contains(t, entry["error"], "boom")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
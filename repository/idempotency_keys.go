@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	// ClaimIdempotencyKeyQuery inserts the key, or takes over one that
+	// expired or whose request was abandoned before completing, i.e. claimed
+	// before $7. No row is returned when the key is held.
+	ClaimIdempotencyKeyQuery = `INSERT INTO idempotency_keys (organization_id, key, method, path, request_hash, expires_at) VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (organization_id, key) DO UPDATE SET method = EXCLUDED.method, path = EXCLUDED.path, request_hash = EXCLUDED.request_hash,
+status_code = NULL, content_type = NULL, response_body = NULL, created_at = NOW(), completed_at = NULL, expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= NOW() OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < $7) RETURNING created_at`
+	GetIdempotencyKeyQuery            = `SELECT organization_id, key, method, path, request_hash, status_code, content_type, response_body, created_at, completed_at, expires_at FROM idempotency_keys WHERE organization_id = $1 AND key = $2 AND expires_at > NOW()`
+	CompleteIdempotencyKeyQuery       = `UPDATE idempotency_keys SET status_code = $3, content_type = $4, response_body = $5, completed_at = NOW() WHERE organization_id = $1 AND key = $2 RETURNING completed_at`
+	DeleteIdempotencyKeyQuery         = `DELETE FROM idempotency_keys WHERE organization_id = $1 AND key = $2`
+	DeleteExpiredIdempotencyKeysQuery = `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`
+)
+
+// ClaimIdempotencyKey records data as in progress. It reports false when
+// the key is already held: completed and not expired, or in progress since
+// staleBefore or later.
+func (r *Repository) ClaimIdempotencyKey(ctx context.Context, data *IdempotencyKey, staleBefore time.Time) (_ bool, err error) {
+	ctx, end := r.startQuery(ctx, "ClaimIdempotencyKey", "ClaimIdempotencyKeyQuery")
+	defer func() { end(err) }()
+
+	if data.OrganizationID == "" {
+		return false, invalidFilter("ClaimIdempotencyKey", "organization is required")
+	}
+	err = r.Db.QueryRowContext(
+		ctx,
+		ClaimIdempotencyKeyQuery,
+		data.OrganizationID,
+		data.Key,
+		data.Method,
+		data.Path,
+		data.RequestHash,
+		data.ExpiresAt,
+		staleBefore,
+	).Scan(&data.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, wrapError("ClaimIdempotencyKey", err)
+	}
+	return true, nil
+}
+
+// FindIdempotencyKey returns the unexpired key of filter, with its response
+// once the request completed.
+func (r *Repository) FindIdempotencyKey(ctx context.Context, filter *FilterIdempotencyKey) (_ IdempotencyKey, err error) {
+	ctx, end := r.startQuery(ctx, "FindIdempotencyKey", "GetIdempotencyKeyQuery")
+	defer func() { end(err) }()
+
+	if filter.OrganizationID == "" {
+		return IdempotencyKey{}, invalidFilter("FindIdempotencyKey", "organization is required")
+	}
+	var (
+		key         IdempotencyKey
+		statusCode  sql.NullInt64
+		contentType sql.NullString
+	)
+	err = r.Db.QueryRowContext(ctx, GetIdempotencyKeyQuery, filter.OrganizationID, filter.Key).
+		Scan(&key.OrganizationID, &key.Key, &key.Method, &key.Path, &key.RequestHash, &statusCode,
+			&contentType, &key.ResponseBody, &key.CreatedAt, &key.CompletedAt, &key.ExpiresAt)
+	if err != nil {
+		return IdempotencyKey{}, wrapError("FindIdempotencyKey", err)
+	}
+	key.StatusCode = int(statusCode.Int64)
+	key.ContentType = contentType.String
+	return key, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request data guards.
+func (r *Repository) CompleteIdempotencyKey(ctx context.Context, data *IdempotencyKey) (err error) {
+	ctx, end := r.startQuery(ctx, "CompleteIdempotencyKey", "CompleteIdempotencyKeyQuery")
+	defer func() { end(err) }()
+
+	if data.OrganizationID == "" {
+		return invalidFilter("CompleteIdempotencyKey", "organization is required")
+	}
+	err = r.Db.QueryRowContext(
+		ctx,
+		CompleteIdempotencyKeyQuery,
+		data.OrganizationID,
+		data.Key,
+		data.StatusCode,
+		data.ContentType,
+		data.ResponseBody,
+	).Scan(&data.CompletedAt)
+	return wrapError("CompleteIdempotencyKey", err)
+}
+
+// DeleteIdempotencyKey releases a key, e.g. when the request it guards
+// failed and may be retried.
+func (r *Repository) DeleteIdempotencyKey(ctx context.Context, filter *FilterIdempotencyKey) (err error) {
+	ctx, end := r.startQuery(ctx, "DeleteIdempotencyKey", "DeleteIdempotencyKeyQuery")
+	defer func() { end(err) }()
+
+	if filter.OrganizationID == "" {
+		return invalidFilter("DeleteIdempotencyKey", "organization is required")
+	}
+	_, err = r.Db.ExecContext(ctx, DeleteIdempotencyKeyQuery, filter.OrganizationID, filter.Key)
+	return wrapError("DeleteIdempotencyKey", err)
+}
+
+// DeleteExpiredIdempotencyKeys purges expired keys and returns how many
+// were deleted.
+func (r *Repository) DeleteExpiredIdempotencyKeys(ctx context.Context) (_ int64, err error) {
+	ctx, end := r.startQuery(ctx, "DeleteExpiredIdempotencyKeys", "DeleteExpiredIdempotencyKeysQuery")
+	defer func() { end(err) }()
+
+	result, err := r.Db.ExecContext(ctx, DeleteExpiredIdempotencyKeysQuery)
+	if err != nil {
+		return 0, wrapError("DeleteExpiredIdempotencyKeys", err)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted, wrapError("DeleteExpiredIdempotencyKeys", err)
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var idempotencyKeyColumns = []string{"organization_id", "key", "method", "path", "request_hash", "status_code", "content_type", "response_body", "created_at", "completed_at", "expires_at"}
+
+func TestRepository_ClaimIdempotencyKey(t *testing.T) {
+	now := time.Now()
+	data := func() *IdempotencyKey {
+		return &IdempotencyKey{
+			OrganizationID: "org-1",
+			Key:            "key-1",
+			Method:         "POST",
+			Path:           "/estate",
+			RequestHash:    "hash",
+			ExpiresAt:      now.Add(24 * time.Hour),
+		}
+	}
+	staleBefore := now.Add(-time.Minute)
+
+	t.Run("Success: Claimed", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectQuery("INSERT INTO idempotency_keys .* ON CONFLICT").
+			WithArgs("org-1", "key-1", "POST", "/estate", "hash", now.Add(24*time.Hour), staleBefore).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(now))
+
+		key := data()
+		claimed, err := repo.ClaimIdempotencyKey(context.Background(), key, staleBefore)
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+		assert.Equal(t, now, key.CreatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success: Held", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectQuery("INSERT INTO idempotency_keys").
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}))
+
+		claimed, err := repo.ClaimIdempotencyKey(context.Background(), data(), staleBefore)
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed: Missing organization", func(t *testing.T) {
+		repo := &Repository{}
+
+		_, err := repo.ClaimIdempotencyKey(context.Background(), &IdempotencyKey{Key: "key-1"}, staleBefore)
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+	})
+}
+
+func TestRepository_FindIdempotencyKey(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Success: Completed", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectQuery("SELECT .* FROM idempotency_keys WHERE organization_id = \\$1 AND key = \\$2 AND expires_at > NOW\\(\\)").
+			WithArgs("org-1", "key-1").
+			WillReturnRows(sqlmock.NewRows(idempotencyKeyColumns).
+				AddRow("org-1", "key-1", "POST", "/estate", "hash", 201, "application/json", []byte(`{"id":"e-1"}`), now, now, now.Add(time.Hour)))
+
+		result, err := repo.FindIdempotencyKey(context.Background(), &FilterIdempotencyKey{OrganizationID: "org-1", Key: "key-1"})
+		assert.NoError(t, err)
+		assert.Equal(t, IdempotencyKey{
+			OrganizationID: "org-1",
+			Key:            "key-1",
+			Method:         "POST",
+			Path:           "/estate",
+			RequestHash:    "hash",
+			StatusCode:     201,
+			ContentType:    "application/json",
+			ResponseBody:   []byte(`{"id":"e-1"}`),
+			CreatedAt:      now,
+			CompletedAt:    &now,
+			ExpiresAt:      now.Add(time.Hour),
+		}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success: In progress", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectQuery("SELECT .* FROM idempotency_keys").
+			WillReturnRows(sqlmock.NewRows(idempotencyKeyColumns).
+				AddRow("org-1", "key-1", "POST", "/estate", "hash", nil, nil, nil, now, nil, now.Add(time.Hour)))
+
+		result, err := repo.FindIdempotencyKey(context.Background(), &FilterIdempotencyKey{OrganizationID: "org-1", Key: "key-1"})
+		assert.NoError(t, err)
+		assert.Zero(t, result.StatusCode)
+		assert.Nil(t, result.CompletedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed: Not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectQuery("SELECT .* FROM idempotency_keys").
+			WillReturnRows(sqlmock.NewRows(idempotencyKeyColumns))
+
+		_, err = repo.FindIdempotencyKey(context.Background(), &FilterIdempotencyKey{OrganizationID: "org-1", Key: "key-1"})
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_CompleteIdempotencyKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	now := time.Now()
+	mock.ExpectQuery("UPDATE idempotency_keys SET status_code = \\$3").
+		WithArgs("org-1", "key-1", 201, "application/json", []byte(`{}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"completed_at"}).AddRow(now))
+
+	key := &IdempotencyKey{OrganizationID: "org-1", Key: "key-1", StatusCode: 201, ContentType: "application/json", ResponseBody: []byte(`{}`)}
+	assert.NoError(t, repo.CompleteIdempotencyKey(context.Background(), key))
+	assert.Equal(t, &now, key.CompletedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_DeleteIdempotencyKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE organization_id = \\$1 AND key = \\$2").
+		WithArgs("org-1", "key-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.DeleteIdempotencyKey(context.Background(), &FilterIdempotencyKey{OrganizationID: "org-1", Key: "key-1"}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_DeleteExpiredIdempotencyKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE expires_at <= NOW\\(\\)").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	deleted, err := repo.DeleteExpiredIdempotencyKeys(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
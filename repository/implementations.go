@@ -2,141 +2,436 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strconv"
 	"strings"
 )
 
 const (
-	InsertEstateQuery     = `INSERT INTO estates (id, width, length) VALUES ($1, $2, $3) RETURNING id`
-	GetEstateQuery        = `SELECT id, created_at, updated_at, deleted_at, width, length FROM estates`
-	InsertEstateTreeQuery = `INSERT INTO estate_trees (id, estate_id, x, y, height) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	GetEstateTreeQuery    = `SELECT id, estate_id, created_at, updated_at, deleted_at, x, y, height FROM estate_trees`
+	InsertEstateQuery     = `INSERT INTO estates (id, organization_id, width, length) VALUES ($1, $2, $3, $4) RETURNING id`
+	GetEstateQuery        = `SELECT id, organization_id, created_at, updated_at, deleted_at, width, length, version FROM estates`
+	InsertEstateTreeQuery = `INSERT INTO estate_trees (id, organization_id, estate_id, x, y, height) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	// InsertEstateTreesQuery is followed by one ($n, ...) tuple per tree.
+	InsertEstateTreesQuery = `INSERT INTO estate_trees (id, organization_id, estate_id, x, y, height) VALUES `
+	// UpdateEstateTreeQuery returns the estate of the tree and where it
+	// stood and how tall it was before.
+	UpdateEstateTreeQuery = `UPDATE estate_trees t SET x = $3, y = $4, height = $5, updated_at = NOW()
+FROM (SELECT id, x, y, height FROM estate_trees WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL FOR UPDATE) old
+WHERE t.id = old.id RETURNING t.estate_id, old.x, old.y, old.height`
+	DeleteEstateTreeQuery = `UPDATE estate_trees SET deleted_at = NOW(), updated_at = NOW() WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL RETURNING estate_id, x, y, height`
+	GetEstateTreeQuery    = `SELECT id, organization_id, estate_id, created_at, updated_at, deleted_at, x, y, height FROM estate_trees`
 	EstateTreeCountQuery  = `SELECT COUNT(1) FROM estate_trees`
 	EstateTreeStatsQuery  = `SELECT MAX(height) as max, MIN(height) as min, PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY COALESCE(height, 0)) AS median FROM estate_trees`
 )
 
-func (r *Repository) CreateEstate(ctx context.Context, data *Estate) error {
-	_, err := r.Db.ExecContext(
+// estateTreeOrderColumns lists the columns FilterEstateTree.OrderBy may refer to.
+var estateTreeOrderColumns = map[string]bool{
+	"id":         true,
+	"x":          true,
+	"y":          true,
+	"height":     true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+func (r *Repository) CreateEstate(ctx context.Context, data *Estate) (err error) {
+	ctx, end := r.startQuery(ctx, "CreateEstate", "InsertEstateQuery")
+	defer func() { end(err) }()
+
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		return createEstate(ctx, tx, data)
+	})
+	return wrapError("CreateEstate", err)
+}
+
+// createEstate inserts data in tx. The audit entry and webhook event are
+// written with the estate, so auditors and subscribers hear of every estate
+// and of nothing else.
+func createEstate(ctx context.Context, tx *sql.Tx, data *Estate) error {
+	_, err := tx.ExecContext(
 		ctx,
 		InsertEstateQuery,
 		data.ID,
+		data.OrganizationID,
 		data.Width,
 		data.Length,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	estate := estateData{ID: data.ID, Width: data.Width, Length: data.Length}
+	err = insertAuditEntry(ctx, tx, auditRecord{
+		organizationID: data.OrganizationID,
+		action:         AuditActionCreate,
+		entityType:     AuditEntityEstate,
+		entityID:       data.ID,
+		estateID:       data.ID,
+		after:          estate,
+	})
+	if err != nil {
+		return err
+	}
+	return insertWebhookEvent(ctx, tx, data.OrganizationID, WebhookEventEstateCreated, estate)
 }
 
-func (r *Repository) FindEstate(ctx context.Context, filter *FilterEstate) (Estate, error) {
-	finalQuery, paramValue := r.setFilterEstate(GetEstateQuery, filter)
-	var estate Estate
-	err := r.Db.QueryRowContext(ctx, finalQuery, paramValue...).Scan(&estate.ID, &estate.CreatedAt, &estate.UpdatedAt, &estate.DeletedAt, &estate.Width, &estate.Length)
+func (r *Repository) FindEstate(ctx context.Context, filter *FilterEstate) (_ Estate, err error) {
+	ctx, end := r.startQuery(ctx, "FindEstate", "GetEstateQuery")
+	defer func() { end(err) }()
+
+	finalQuery, paramValue, err := r.setFilterEstate("FindEstate", GetEstateQuery, filter)
 	if err != nil {
 		return Estate{}, err
 	}
+	var estate Estate
+	err = r.Db.QueryRowContext(ctx, finalQuery, paramValue...).Scan(&estate.ID, &estate.OrganizationID, &estate.CreatedAt, &estate.UpdatedAt, &estate.DeletedAt, &estate.Width, &estate.Length, &estate.Version)
+	if err != nil {
+		return Estate{}, wrapError("FindEstate", err)
+	}
 
 	return estate, nil
 }
 
-func (r *Repository) setFilterEstate(baseQuery string, filter *FilterEstate) (string, []interface{}) {
-	var (
-		where      []string
-		paramValue []interface{}
-	)
+// FindAllEstate lists the estates of an organization in the order they were
+// created, up to filter.Limit when set, starting after filter.After. An
+// After that is not an estate of the organization lists nothing, so that
+// cursors cannot reveal when the estates of other tenants were created.
+func (r *Repository) FindAllEstate(ctx context.Context, filter *FilterEstate) (_ []Estate, err error) {
+	ctx, end := r.startQuery(ctx, "FindAllEstate", "GetEstateQuery")
+	defer func() { end(err) }()
+
+	finalQuery, paramValue, err := r.setFilterEstate("FindAllEstate", GetEstateQuery, filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter.After != "" {
+		paramValue = append(paramValue, filter.After)
+		finalQuery += " AND (created_at, id) > (SELECT created_at, id FROM estates WHERE id = $" + strconv.Itoa(len(paramValue)) +
+			" AND organization_id = $1)"
+	}
+	finalQuery += " ORDER BY created_at ASC, id ASC"
+	if filter.Limit > 0 {
+		paramValue = append(paramValue, filter.Limit)
+		finalQuery += " LIMIT $" + strconv.Itoa(len(paramValue))
+	}
+
+	rows, err := r.Db.QueryContext(ctx, finalQuery, paramValue...)
+	if err != nil {
+		return nil, wrapError("FindAllEstate", err)
+	}
+	defer closeRows(ctx, "FindAllEstate", rows)
+
+	var result []Estate
+	for rows.Next() {
+		var estate Estate
+		if err = rows.Scan(&estate.ID, &estate.OrganizationID, &estate.CreatedAt, &estate.UpdatedAt, &estate.DeletedAt, &estate.Width, &estate.Length, &estate.Version); err != nil {
+			return nil, wrapError("FindAllEstate", err)
+		}
+		result = append(result, estate)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapError("FindAllEstate", err)
+	}
+	return result, nil
+}
+
+// setFilterEstate builds the WHERE clause for filter. It refuses filters
+// without an organization so no query can ever span tenants.
+func (r *Repository) setFilterEstate(op, baseQuery string, filter *FilterEstate) (string, []interface{}, error) {
+	if filter.OrganizationID == "" {
+		return "", nil, invalidFilter(op, "organization is required")
+	}
+
+	where := []string{"organization_id = $1"}
+	paramValue := []interface{}{filter.OrganizationID}
 	if filter.ID != "" {
 		where = append(where, "id = $"+strconv.Itoa(len(paramValue)+1))
 		paramValue = append(paramValue, filter.ID)
 	}
 
 	where = append(where, "deleted_at IS NULL")
-	clauseWhere := strings.Join(where, " AND ")
-	if clauseWhere != "" {
-		baseQuery += " WHERE " + clauseWhere
-	}
+	baseQuery += " WHERE " + strings.Join(where, " AND ")
+
+	return baseQuery, paramValue, nil
+}
 
-	return baseQuery, paramValue
+func (r *Repository) CreateEstateTree(ctx context.Context, data *EstateTree) (err error) {
+	ctx, end := r.startQuery(ctx, "CreateEstateTree", "InsertEstateTreeQuery")
+	defer func() { end(err) }()
+
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		return createEstateTree(ctx, tx, data)
+	})
+	return wrapError("CreateEstateTree", err)
 }
 
-func (r *Repository) CreateEstateTree(ctx context.Context, data *EstateTree) error {
-	_, err := r.Db.ExecContext(
+func createEstateTree(ctx context.Context, tx *sql.Tx, data *EstateTree) error {
+	_, err := tx.ExecContext(
 		ctx,
 		InsertEstateTreeQuery,
 		data.ID,
+		data.OrganizationID,
 		data.EstateID,
 		data.X,
 		data.Y,
 		data.Height,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	tree := treeData{ID: data.ID, EstateID: data.EstateID, X: data.X, Y: data.Y, Height: data.Height}
+	err = insertAuditEntry(ctx, tx, auditRecord{
+		organizationID: data.OrganizationID,
+		action:         AuditActionCreate,
+		entityType:     AuditEntityTree,
+		entityID:       data.ID,
+		estateID:       data.EstateID,
+		after:          tree,
+	})
+	if err != nil {
+		return err
+	}
+	return insertWebhookEvent(ctx, tx, data.OrganizationID, WebhookEventTreeCreated, tree)
+}
+
+// UpdateEstateTree moves the tree data.ID of data.OrganizationID to data.X
+// and data.Y and sets its height to data.Height, and sets data.EstateID. It
+// is audited with the tree before and after, and sends a tree.updated
+// webhook event with the tree after; the database records the estate
+// event. A plot taken by another tree fails with ErrConflict and an
+// unknown or deleted tree with ErrNotFound.
+func (r *Repository) UpdateEstateTree(ctx context.Context, data *EstateTree) (err error) {
+	ctx, end := r.startQuery(ctx, "UpdateEstateTree", "UpdateEstateTreeQuery")
+	defer func() { end(err) }()
+
+	if data.OrganizationID == "" {
+		return invalidFilter("UpdateEstateTree", "organization is required")
+	}
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		return updateEstateTree(ctx, tx, data)
+	})
+	return wrapError("UpdateEstateTree", err)
 }
 
-func (r *Repository) FindAllMapEstateTree(ctx context.Context, filter *FilterEstateTree) (map[CoordinatePoint]EstateTree, error) {
+func updateEstateTree(ctx context.Context, tx *sql.Tx, data *EstateTree) error {
+	before := treeData{ID: data.ID}
+	err := tx.QueryRowContext(ctx, UpdateEstateTreeQuery, data.OrganizationID, data.ID, data.X, data.Y, data.Height).
+		Scan(&data.EstateID, &before.X, &before.Y, &before.Height)
+	if err != nil {
+		return err
+	}
+	before.EstateID = data.EstateID
+	after := treeData{ID: data.ID, EstateID: data.EstateID, X: data.X, Y: data.Y, Height: data.Height}
+	err = insertAuditEntry(ctx, tx, auditRecord{
+		organizationID: data.OrganizationID,
+		action:         AuditActionUpdate,
+		entityType:     AuditEntityTree,
+		entityID:       data.ID,
+		estateID:       data.EstateID,
+		before:         before,
+		after:          after,
+	})
+	if err != nil {
+		return err
+	}
+	return insertWebhookEvent(ctx, tx, data.OrganizationID, WebhookEventTreeUpdated, after)
+}
+
+// DeleteEstateTree deletes the tree filter.ID of filter.OrganizationID,
+// freeing its plot. It is audited with the tree before, and sends a
+// tree.deleted webhook event with it; the database records the estate
+// event. An unknown or deleted tree fails with
+// ErrNotFound.
+func (r *Repository) DeleteEstateTree(ctx context.Context, filter *FilterEstateTree) (err error) {
+	ctx, end := r.startQuery(ctx, "DeleteEstateTree", "DeleteEstateTreeQuery")
+	defer func() { end(err) }()
+
+	if filter.OrganizationID == "" {
+		return invalidFilter("DeleteEstateTree", "organization is required")
+	}
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		return deleteEstateTree(ctx, tx, filter.OrganizationID, filter.ID)
+	})
+	return wrapError("DeleteEstateTree", err)
+}
+
+func deleteEstateTree(ctx context.Context, tx *sql.Tx, organizationID, id string) error {
+	deleted := treeData{ID: id}
+	err := tx.QueryRowContext(ctx, DeleteEstateTreeQuery, organizationID, id).
+		Scan(&deleted.EstateID, &deleted.X, &deleted.Y, &deleted.Height)
+	if err != nil {
+		return err
+	}
+	err = insertAuditEntry(ctx, tx, auditRecord{
+		organizationID: organizationID,
+		action:         AuditActionDelete,
+		entityType:     AuditEntityTree,
+		entityID:       id,
+		estateID:       deleted.EstateID,
+		before:         deleted,
+	})
+	if err != nil {
+		return err
+	}
+	return insertWebhookEvent(ctx, tx, organizationID, WebhookEventTreeDeleted, deleted)
+}
+
+// estateTreesPerInsert is the number of trees inserted by a statement of
+// CreateEstateTrees, well under the 65535 parameters Postgres allows.
+const estateTreesPerInsert = 1000
+
+// CreateEstateTrees inserts the trees of an estate in one transaction, a
+// multi-row statement per estateTreesPerInsert trees. Unlike
+// CreateEstateTree, it records an audit entry, an import of the estate, and
+// a trees.imported webhook event per statement, each listing its trees,
+// rather than an entry and a tree.created event per tree; the estate events
+// are still recorded. A tree on an occupied plot fails the whole import
+// with ErrConflict.
+func (r *Repository) CreateEstateTrees(ctx context.Context, data []EstateTree) (err error) {
+	ctx, end := r.startQuery(ctx, "CreateEstateTrees", "InsertEstateTreesQuery")
+	defer func() { end(err) }()
+
+	if len(data) == 0 {
+		return nil
+	}
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		for start := 0; start < len(data); start += estateTreesPerInsert {
+			chunk := data[start:min(start+estateTreesPerInsert, len(data))]
+			var query strings.Builder
+			query.WriteString(InsertEstateTreesQuery)
+			paramValue := make([]interface{}, 0, 6*len(chunk))
+			imported := importData{EstateID: chunk[0].EstateID, Trees: make([]treeData, len(chunk))}
+			for i, tree := range chunk {
+				if i > 0 {
+					query.WriteString(", ")
+				}
+				n := len(paramValue)
+				fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6)
+				paramValue = append(paramValue, tree.ID, tree.OrganizationID, tree.EstateID, tree.X, tree.Y, tree.Height)
+				imported.Trees[i] = treeData{ID: tree.ID, EstateID: tree.EstateID, X: tree.X, Y: tree.Y, Height: tree.Height}
+			}
+			if _, err := tx.ExecContext(ctx, query.String(), paramValue...); err != nil {
+				return err
+			}
+			err := insertAuditEntry(ctx, tx, auditRecord{
+				organizationID: chunk[0].OrganizationID,
+				action:         AuditActionImport,
+				entityType:     AuditEntityEstate,
+				entityID:       chunk[0].EstateID,
+				estateID:       chunk[0].EstateID,
+				after:          imported,
+			})
+			if err != nil {
+				return err
+			}
+			if err = insertWebhookEvent(ctx, tx, chunk[0].OrganizationID, WebhookEventTreesImported, imported); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return wrapError("CreateEstateTrees", err)
+}
+
+func (r *Repository) FindAllMapEstateTree(ctx context.Context, filter *FilterEstateTree) (_ map[CoordinatePoint]EstateTree, err error) {
+	ctx, end := r.startQuery(ctx, "FindAllMapEstateTree", "GetEstateTreeQuery")
+	defer func() { end(err) }()
+
 	result := make(map[CoordinatePoint]EstateTree)
 
-	finalQuery, paramValue := r.setFilterEstateTree(GetEstateTreeQuery, filter)
+	finalQuery, paramValue, err := r.setFilterEstateTree("FindAllMapEstateTree", GetEstateTreeQuery, filter)
+	if err != nil {
+		return nil, err
+	}
 	if filter.OrderBy != "" {
-		finalQuery += " ORDER BY " + filter.OrderBy + " " + filter.Sort
+		if !estateTreeOrderColumns[filter.OrderBy] {
+			return nil, invalidFilter("FindAllMapEstateTree", "unsupported order by "+filter.OrderBy)
+		}
+		sort := strings.ToUpper(filter.Sort)
+		if sort != "ASC" && sort != "DESC" {
+			return nil, invalidFilter("FindAllMapEstateTree", "unsupported sort "+filter.Sort)
+		}
+		finalQuery += " ORDER BY " + filter.OrderBy + " " + sort
 	}
 	if filter.Limit > 0 || !filter.ShowAll {
+		if filter.Page < 1 || filter.Limit < 0 {
+			return nil, invalidFilter("FindAllMapEstateTree", "page and limit must be positive")
+		}
 		limit := "LIMIT $" + strconv.Itoa(len(paramValue)+1) + " OFFSET $" + strconv.Itoa(len(paramValue)+2)
 		finalQuery = fmt.Sprintf("%s %s", finalQuery, limit)
 		paramValue = append(paramValue, filter.Limit, filter.CalculateOffset())
 	}
 	rows, err := r.Db.QueryContext(ctx, finalQuery, paramValue...)
 	if err != nil {
-		return nil, err
+		return nil, wrapError("FindAllMapEstateTree", err)
 	}
-	defer rows.Close()
+	defer closeRows(ctx, "FindAllMapEstateTree", rows)
 
 	for rows.Next() {
 		var estateTree EstateTree
-		if err = rows.Scan(&estateTree.ID, &estateTree.EstateID, &estateTree.CreatedAt,
+		if err = rows.Scan(&estateTree.ID, &estateTree.OrganizationID, &estateTree.EstateID, &estateTree.CreatedAt,
 			&estateTree.UpdatedAt, &estateTree.DeletedAt,
 			&estateTree.X, &estateTree.Y,
 			&estateTree.Height); err != nil {
-			return nil, err
+			return nil, wrapError("FindAllMapEstateTree", err)
 		}
 		result[CoordinatePoint{X: estateTree.X, Y: estateTree.Y}] = estateTree
 	}
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, wrapError("FindAllMapEstateTree", err)
 	}
 
 	return result, nil
 }
 
-func (r *Repository) FindEstateTree(ctx context.Context, filter *FilterEstateTree) (EstateTree, error) {
-	finalQuery, paramValue := r.setFilterEstateTree(GetEstateTreeQuery, filter)
+func (r *Repository) FindEstateTree(ctx context.Context, filter *FilterEstateTree) (_ EstateTree, err error) {
+	ctx, end := r.startQuery(ctx, "FindEstateTree", "GetEstateTreeQuery")
+	defer func() { end(err) }()
+
+	finalQuery, paramValue, err := r.setFilterEstateTree("FindEstateTree", GetEstateTreeQuery, filter)
+	if err != nil {
+		return EstateTree{}, err
+	}
 	var estateTree EstateTree
-	err := r.Db.QueryRowContext(ctx, finalQuery, paramValue...).
-		Scan(&estateTree.ID, &estateTree.EstateID, &estateTree.CreatedAt,
+	err = r.Db.QueryRowContext(ctx, finalQuery, paramValue...).
+		Scan(&estateTree.ID, &estateTree.OrganizationID, &estateTree.EstateID, &estateTree.CreatedAt,
 			&estateTree.UpdatedAt, &estateTree.DeletedAt,
 			&estateTree.X, &estateTree.Y,
 			&estateTree.Height)
 	if err != nil {
-		return EstateTree{}, err
+		return EstateTree{}, wrapError("FindEstateTree", err)
 	}
 
 	return estateTree, nil
 }
 
-func (r *Repository) CountEstateTree(ctx context.Context, filter *FilterEstateTree) int {
-	finalQuery, paramValue := r.setFilterEstateTree(EstateTreeCountQuery, filter)
+func (r *Repository) CountEstateTree(ctx context.Context, filter *FilterEstateTree) (_ int, err error) {
+	ctx, end := r.startQuery(ctx, "CountEstateTree", "EstateTreeCountQuery")
+	defer func() { end(err) }()
+
+	finalQuery, paramValue, err := r.setFilterEstateTree("CountEstateTree", EstateTreeCountQuery, filter)
+	if err != nil {
+		return 0, err
+	}
 
 	var count int64
-	err := r.Db.QueryRowContext(ctx, finalQuery, paramValue...).Scan(&count)
+	err = r.Db.QueryRowContext(ctx, finalQuery, paramValue...).Scan(&count)
 	if err != nil {
-		return 0
+		return 0, wrapError("CountEstateTree", err)
 	}
 
-	return int(count)
+	return int(count), nil
 }
 
-func (r *Repository) setFilterEstateTree(baseQuery string, filter *FilterEstateTree) (string, []interface{}) {
-	var (
-		where      []string
-		paramValue []interface{}
-	)
+// setFilterEstateTree builds the WHERE clause for filter. Like
+// setFilterEstate it refuses filters without an organization.
+func (r *Repository) setFilterEstateTree(op, baseQuery string, filter *FilterEstateTree) (string, []interface{}, error) {
+	if filter.OrganizationID == "" {
+		return "", nil, invalidFilter(op, "organization is required")
+	}
+
+	where := []string{"organization_id = $1"}
+	paramValue := []interface{}{filter.OrganizationID}
 	if filter.ID != "" {
 		where = append(where, "id = $"+strconv.Itoa(len(paramValue)+1))
 		paramValue = append(paramValue, filter.ID)
@@ -155,21 +450,24 @@ func (r *Repository) setFilterEstateTree(baseQuery string, filter *FilterEstateT
 	}
 
 	where = append(where, "deleted_at IS NULL")
-	clauseWhere := strings.Join(where, " AND ")
-	if clauseWhere != "" {
-		baseQuery += " WHERE " + clauseWhere
-	}
+	baseQuery += " WHERE " + strings.Join(where, " AND ")
 
-	return baseQuery, paramValue
+	return baseQuery, paramValue, nil
 }
 
-func (r *Repository) GetEstateTreeStats(ctx context.Context, filter *FilterEstateTree) (EstateTreeStats, error) {
-	finalQuery, paramValue := r.setFilterEstateTree(EstateTreeStatsQuery, filter)
+func (r *Repository) GetEstateTreeStats(ctx context.Context, filter *FilterEstateTree) (_ EstateTreeStats, err error) {
+	ctx, end := r.startQuery(ctx, "GetEstateTreeStats", "EstateTreeStatsQuery")
+	defer func() { end(err) }()
+
+	finalQuery, paramValue, err := r.setFilterEstateTree("GetEstateTreeStats", EstateTreeStatsQuery, filter)
+	if err != nil {
+		return EstateTreeStats{}, err
+	}
 	var estateTreeStats EstateTreeStats
-	err := r.Db.QueryRowContext(ctx, finalQuery, paramValue...).
+	err = r.Db.QueryRowContext(ctx, finalQuery, paramValue...).
 		Scan(&estateTreeStats.Max, &estateTreeStats.Min, &estateTreeStats.Median)
 	if err != nil {
-		return EstateTreeStats{}, err
+		return EstateTreeStats{}, wrapError("GetEstateTreeStats", err)
 	}
 
 	return estateTreeStats, nil
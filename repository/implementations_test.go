@@ -12,6 +12,8 @@ import (
 	"time"
 )
 
+const testOrganizationID = "org-1"
+
 func TestRepository_CreateEstate(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
@@ -21,15 +23,23 @@ func TestRepository_CreateEstate(t *testing.T) {
 		repo := &Repository{Db: db}
 
 		id := uuid.NewString()
-		mock.ExpectExec("INSERT INTO estates").WithArgs(id, 100, 200).
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO estates").WithArgs(id, testOrganizationID, 100, 200).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectAuditEntry(mock, testOrganizationID, AuditActionCreate, AuditEntityEstate, id, id,
+			nil, `{"id":"`+id+`","width":100,"length":200}`)
+		mock.ExpectExec("INSERT INTO webhook_events").
+			WithArgs(sqlmock.AnyArg(), testOrganizationID, WebhookEventEstateCreated, `{"id":"`+id+`","width":100,"length":200}`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 
 		err = repo.CreateEstate(context.Background(), &Estate{
 			BaseModel: BaseModel{
 				ID: id,
 			},
-			Width:  100,
-			Length: 200,
+			OrganizationID: testOrganizationID,
+			Width:          100,
+			Length:         200,
 		})
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -42,9 +52,11 @@ func TestRepository_CreateEstate(t *testing.T) {
 
 		repo := &Repository{Db: db}
 
+		mock.ExpectBegin()
 		mock.ExpectExec("INSERT INTO estates").
-			WithArgs(sqlmock.AnyArg(), 100, 200).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 100, 200).
 			WillReturnError(assert.AnError)
+		mock.ExpectRollback()
 
 		err = repo.CreateEstate(context.Background(), &Estate{
 			BaseModel: BaseModel{
@@ -55,7 +67,7 @@ func TestRepository_CreateEstate(t *testing.T) {
 		})
 
 		assert.Error(t, err)
-		assert.Equal(t, assert.AnError, err)
+		assert.ErrorIs(t, err, assert.AnError)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -75,15 +87,17 @@ func TestRepository_FindEstate(t *testing.T) {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			},
-			Width:  100,
-			Length: 200,
+			OrganizationID: testOrganizationID,
+			Width:          100,
+			Length:         200,
+			Version:        3,
 		}
 
-		mock.ExpectQuery("SELECT .* FROM estates").WithArgs(id).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "deleted_at", "width", "length"}).
-				AddRow(expectedEstate.ID, expectedEstate.CreatedAt, expectedEstate.UpdatedAt, nil, expectedEstate.Width, expectedEstate.Length))
+		mock.ExpectQuery("SELECT .* FROM estates WHERE organization_id = \\$1 AND id = \\$2 AND deleted_at IS NULL").WithArgs(testOrganizationID, id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "created_at", "updated_at", "deleted_at", "width", "length", "version"}).
+				AddRow(expectedEstate.ID, expectedEstate.OrganizationID, expectedEstate.CreatedAt, expectedEstate.UpdatedAt, nil, expectedEstate.Width, expectedEstate.Length, expectedEstate.Version))
 
-		result, err := repo.FindEstate(context.Background(), &FilterEstate{ID: id})
+		result, err := repo.FindEstate(context.Background(), &FilterEstate{ID: id, OrganizationID: testOrganizationID})
 		assert.NoError(t, err)
 		assert.Equal(t, expectedEstate, result)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -97,11 +111,12 @@ func TestRepository_FindEstate(t *testing.T) {
 		repo := &Repository{Db: db}
 
 		id := uuid.NewString()
-		mock.ExpectQuery("SELECT .* FROM estates").WithArgs(id).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT .* FROM estates").WithArgs(testOrganizationID, id).WillReturnError(sql.ErrNoRows)
 
-		result, err := repo.FindEstate(context.Background(), &FilterEstate{ID: id})
+		result, err := repo.FindEstate(context.Background(), &FilterEstate{ID: id, OrganizationID: testOrganizationID})
 		assert.Error(t, err)
-		assert.Equal(t, sql.ErrNoRows, err)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.ErrorIs(t, err, ErrNotFound)
 		assert.Equal(t, Estate{}, result)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -115,17 +130,77 @@ func TestRepository_FindEstate(t *testing.T) {
 
 		id := "#######"
 		mock.ExpectQuery("SELECT .* FROM estates").
-			WithArgs(id).
+			WithArgs(testOrganizationID, id).
 			WillReturnError(errors.New("database error"))
 
-		result, err := repo.FindEstate(context.Background(), &FilterEstate{ID: id})
+		result, err := repo.FindEstate(context.Background(), &FilterEstate{ID: id, OrganizationID: testOrganizationID})
 		assert.Error(t, err)
-		assert.Equal(t, "database error", err.Error())
+		assert.Contains(t, err.Error(), "database error")
 		assert.Equal(t, Estate{}, result)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
+func TestRepository_FindAllEstate(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		createdAt := time.Now()
+		mock.ExpectQuery("SELECT .* FROM estates WHERE organization_id = \\$1 AND deleted_at IS NULL "+
+			"AND \\(created_at, id\\) > \\(SELECT created_at, id FROM estates WHERE id = \\$2 AND organization_id = \\$1\\) "+
+			"ORDER BY created_at ASC, id ASC LIMIT \\$3").
+			WithArgs(testOrganizationID, "estate-1", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "created_at", "updated_at", "deleted_at", "width", "length", "version"}).
+				AddRow("estate-2", testOrganizationID, createdAt, createdAt, nil, 10, 20, 1).
+				AddRow("estate-3", testOrganizationID, createdAt, createdAt, nil, 5, 5, 4))
+
+		result, err := repo.FindAllEstate(context.Background(), &FilterEstate{
+			Filter:         Filter{Limit: 2},
+			OrganizationID: testOrganizationID,
+			After:          "estate-1",
+		})
+		assert.NoError(t, err)
+		assert.Len(t, result, 2)
+		assert.Equal(t, "estate-2", result[0].ID)
+		assert.Equal(t, 20, result[0].Length)
+		assert.Equal(t, int64(4), result[1].Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Cursor of another organization", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		// The cursor is looked up within the organization, so the estate of
+		// another one compares to NULL and matches nothing.
+		mock.ExpectQuery("SELECT created_at, id FROM estates WHERE id = \\$2 AND organization_id = \\$1").
+			WithArgs(testOrganizationID, "estate-of-another-organization").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "created_at", "updated_at", "deleted_at", "width", "length", "version"}))
+
+		result, err := repo.FindAllEstate(context.Background(), &FilterEstate{
+			OrganizationID: testOrganizationID,
+			After:          "estate-of-another-organization",
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed: Organization required", func(t *testing.T) {
+		repo := &Repository{}
+
+		_, err := repo.FindAllEstate(context.Background(), &FilterEstate{})
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+	})
+}
+
 func TestRepository_CreateEstateTree(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
@@ -136,17 +211,26 @@ func TestRepository_CreateEstateTree(t *testing.T) {
 
 		id := uuid.NewString()
 		estateID := uuid.NewString()
-		mock.ExpectExec("INSERT INTO estate_trees").WithArgs(id, estateID, 1, 2, 30).
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO estate_trees").WithArgs(id, testOrganizationID, estateID, 1, 2, 30).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectAuditEntry(mock, testOrganizationID, AuditActionCreate, AuditEntityTree, id, estateID,
+			nil, `{"id":"`+id+`","estate_id":"`+estateID+`","x":1,"y":2,"height":30}`)
+		mock.ExpectExec("INSERT INTO webhook_events").
+			WithArgs(sqlmock.AnyArg(), testOrganizationID, WebhookEventTreeCreated,
+				`{"id":"`+id+`","estate_id":"`+estateID+`","x":1,"y":2,"height":30}`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
 
 		err = repo.CreateEstateTree(context.Background(), &EstateTree{
 			BaseModel: BaseModel{
 				ID: id,
 			},
-			EstateID: estateID,
-			X:        1,
-			Y:        2,
-			Height:   30,
+			OrganizationID: testOrganizationID,
+			EstateID:       estateID,
+			X:              1,
+			Y:              2,
+			Height:         30,
 		})
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -159,21 +243,160 @@ func TestRepository_CreateEstateTree(t *testing.T) {
 
 		repo := &Repository{Db: db}
 
+		mock.ExpectBegin()
 		mock.ExpectExec("INSERT INTO estate_trees").
-			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1, 2, 30).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, 2, 30).
 			WillReturnError(assert.AnError)
+		mock.ExpectRollback()
 
 		err = repo.CreateEstateTree(context.Background(), &EstateTree{
 			BaseModel: BaseModel{
 				ID: uuid.NewString(),
 			},
-			EstateID: uuid.NewString(),
-			X:        1,
-			Y:        2,
-			Height:   30,
+			OrganizationID: testOrganizationID,
+			EstateID:       uuid.NewString(),
+			X:              1,
+			Y:              2,
+			Height:         30,
 		})
 		assert.Error(t, err)
-		assert.Equal(t, assert.AnError, err)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_CreateEstateTrees(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	estateID := uuid.NewString()
+	trees := make([]EstateTree, estateTreesPerInsert+1)
+	for i := range trees {
+		trees[i] = EstateTree{
+			BaseModel:      BaseModel{ID: uuid.NewString()},
+			OrganizationID: testOrganizationID,
+			EstateID:       estateID,
+			X:              i + 1,
+			Y:              1,
+			Height:         10,
+		}
+	}
+	last := trees[estateTreesPerInsert]
+	imported := `{"estate_id":"` + estateID + `","trees":[{"id":"` + last.ID + `","estate_id":"` + estateID + `","x":1001,"y":1,"height":10}]}`
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO estate_trees .* VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\), \(\$7,`).
+		WillReturnResult(sqlmock.NewResult(0, estateTreesPerInsert))
+	expectAuditEntry(mock, testOrganizationID, AuditActionImport, AuditEntityEstate, estateID, estateID,
+		nil, sqlmock.AnyArg())
+	mock.ExpectExec("INSERT INTO webhook_events").
+		WithArgs(sqlmock.AnyArg(), testOrganizationID, WebhookEventTreesImported, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO estate_trees .* VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\)$`).
+		WithArgs(last.ID, testOrganizationID, estateID, estateTreesPerInsert+1, 1, 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAuditEntry(mock, testOrganizationID, AuditActionImport, AuditEntityEstate, estateID, estateID,
+		nil, imported)
+	mock.ExpectExec("INSERT INTO webhook_events").
+		WithArgs(sqlmock.AnyArg(), testOrganizationID, WebhookEventTreesImported, imported).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.CreateEstateTrees(context.Background(), trees))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_UpdateEstateTree(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		id := uuid.NewString()
+		estateID := uuid.NewString()
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE estate_trees t SET").WithArgs(testOrganizationID, id, 3, 4, 20).
+			WillReturnRows(sqlmock.NewRows([]string{"estate_id", "x", "y", "height"}).AddRow(estateID, 1, 2, 10))
+		expectAuditEntry(mock, testOrganizationID, AuditActionUpdate, AuditEntityTree, id, estateID,
+			`{"id":"`+id+`","estate_id":"`+estateID+`","x":1,"y":2,"height":10}`,
+			`{"id":"`+id+`","estate_id":"`+estateID+`","x":3,"y":4,"height":20}`)
+		mock.ExpectExec("INSERT INTO webhook_events").
+			WithArgs(sqlmock.AnyArg(), testOrganizationID, WebhookEventTreeUpdated,
+				`{"id":"`+id+`","estate_id":"`+estateID+`","x":3,"y":4,"height":20}`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		tree := EstateTree{BaseModel: BaseModel{ID: id}, OrganizationID: testOrganizationID, X: 3, Y: 4, Height: 20}
+		assert.NoError(t, repo.UpdateEstateTree(context.Background(), &tree))
+		assert.Equal(t, estateID, tree.EstateID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE estate_trees t SET").WillReturnRows(sqlmock.NewRows([]string{"estate_id", "x", "y", "height"}))
+		mock.ExpectRollback()
+
+		tree := EstateTree{BaseModel: BaseModel{ID: uuid.NewString()}, OrganizationID: testOrganizationID, X: 3, Y: 4, Height: 20}
+		assert.ErrorIs(t, repo.UpdateEstateTree(context.Background(), &tree), ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Organization is required", func(t *testing.T) {
+		repo := &Repository{}
+		err := repo.UpdateEstateTree(context.Background(), &EstateTree{BaseModel: BaseModel{ID: uuid.NewString()}})
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+	})
+}
+
+func TestRepository_DeleteEstateTree(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		id := uuid.NewString()
+		estateID := uuid.NewString()
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE estate_trees SET deleted_at").WithArgs(testOrganizationID, id).
+			WillReturnRows(sqlmock.NewRows([]string{"estate_id", "x", "y", "height"}).AddRow(estateID, 1, 2, 10))
+		expectAuditEntry(mock, testOrganizationID, AuditActionDelete, AuditEntityTree, id, estateID,
+			`{"id":"`+id+`","estate_id":"`+estateID+`","x":1,"y":2,"height":10}`, nil)
+		mock.ExpectExec("INSERT INTO webhook_events").
+			WithArgs(sqlmock.AnyArg(), testOrganizationID, WebhookEventTreeDeleted,
+				`{"id":"`+id+`","estate_id":"`+estateID+`","x":1,"y":2,"height":10}`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.DeleteEstateTree(context.Background(), &FilterEstateTree{OrganizationID: testOrganizationID, ID: id}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE estate_trees SET deleted_at").WillReturnRows(sqlmock.NewRows([]string{"estate_id", "x", "y", "height"}))
+		mock.ExpectRollback()
+
+		err = repo.DeleteEstateTree(context.Background(), &FilterEstateTree{OrganizationID: testOrganizationID, ID: uuid.NewString()})
+		assert.ErrorIs(t, err, ErrNotFound)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -194,14 +417,15 @@ func TestRepository_FindAllMapEstateTree(t *testing.T) {
 				OrderBy: "id",
 				Sort:    "ASC",
 			},
-			EstateID: estateID,
+			OrganizationID: testOrganizationID,
+			EstateID:       estateID,
 		}
 
-		mock.ExpectQuery("SELECT .* FROM estate_trees WHERE estate_id = \\$1 AND deleted_at IS NULL ORDER BY id ASC LIMIT \\$2 OFFSET \\$3").
-			WithArgs(estateID, 10, 0).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "estate_id", "created_at", "updated_at", "deleted_at", "x", "y", "height"}).
-				AddRow(uuid.NewString(), estateID, time.Now(), time.Now(), nil, 1, 2, 30).
-				AddRow(uuid.NewString(), estateID, time.Now(), time.Now(), nil, 3, 4, 25))
+		mock.ExpectQuery("SELECT .* FROM estate_trees WHERE organization_id = \\$1 AND estate_id = \\$2 AND deleted_at IS NULL ORDER BY id ASC LIMIT \\$3 OFFSET \\$4").
+			WithArgs(testOrganizationID, estateID, 10, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "estate_id", "created_at", "updated_at", "deleted_at", "x", "y", "height"}).
+				AddRow(uuid.NewString(), testOrganizationID, estateID, time.Now(), time.Now(), nil, 1, 2, 30).
+				AddRow(uuid.NewString(), testOrganizationID, estateID, time.Now(), time.Now(), nil, 3, 4, 25))
 
 		result, err := repo.FindAllMapEstateTree(context.Background(), filter)
 		assert.NoError(t, err)
@@ -227,16 +451,17 @@ func TestRepository_FindAllMapEstateTree(t *testing.T) {
 				OrderBy: "id",
 				Sort:    "ASC",
 			},
-			EstateID: estateID,
+			OrganizationID: testOrganizationID,
+			EstateID:       estateID,
 		}
 
-		mock.ExpectQuery("SELECT .* FROM estate_trees WHERE estate_id = \\$1 AND deleted_at IS NULL ORDER BY id ASC LIMIT \\$2 OFFSET \\$3").
-			WithArgs(estateID, 10, 0).WillReturnError(errors.New("query failed"))
+		mock.ExpectQuery("SELECT .* FROM estate_trees WHERE organization_id = \\$1 AND estate_id = \\$2 AND deleted_at IS NULL ORDER BY id ASC LIMIT \\$3 OFFSET \\$4").
+			WithArgs(testOrganizationID, estateID, 10, 0).WillReturnError(errors.New("query failed"))
 
 		result, err := repo.FindAllMapEstateTree(context.Background(), filter)
 		assert.Error(t, err)
 		assert.Nil(t, result)
-		assert.Equal(t, "query failed", err.Error())
+		assert.Contains(t, err.Error(), "query failed")
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
@@ -255,13 +480,14 @@ func TestRepository_FindAllMapEstateTree(t *testing.T) {
 				OrderBy: "id",
 				Sort:    "ASC",
 			},
-			EstateID: estateID,
+			OrganizationID: testOrganizationID,
+			EstateID:       estateID,
 		}
 
-		mock.ExpectQuery("SELECT .* FROM estate_trees WHERE estate_id = \\$1 AND deleted_at IS NULL ORDER BY id ASC LIMIT \\$2 OFFSET \\$3").
-			WithArgs(estateID, 10, 0).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "estate_id", "created_at", "updated_at", "deleted_at", "x", "y", "height"}).
-				AddRow("", estateID, time.Now(), time.Now(), nil, 1, "xxxxxxxx", 30))
+		mock.ExpectQuery("SELECT .* FROM estate_trees WHERE organization_id = \\$1 AND estate_id = \\$2 AND deleted_at IS NULL ORDER BY id ASC LIMIT \\$3 OFFSET \\$4").
+			WithArgs(testOrganizationID, estateID, 10, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "estate_id", "created_at", "updated_at", "deleted_at", "x", "y", "height"}).
+				AddRow("", testOrganizationID, estateID, time.Now(), time.Now(), nil, 1, "xxxxxxxx", 30))
 
 		result, err := repo.FindAllMapEstateTree(context.Background(), filter)
 		assert.Error(t, err)
@@ -283,29 +509,60 @@ func TestRepository_FindAllMapEstateTree(t *testing.T) {
 				Page:  1,
 				Limit: 10,
 			},
-			EstateID: estateID,
+			OrganizationID: testOrganizationID,
+			EstateID:       estateID,
 		}
 
 		rows := sqlmock.NewRows([]string{
-			"id", "estate_id", "created_at", "updated_at", "deleted_at", "x", "y", "height",
+			"id", "organization_id", "estate_id", "created_at", "updated_at", "deleted_at", "x", "y", "height",
 		}).
-			AddRow(uuid.NewString(), estateID, time.Now(), time.Now(), nil, 1, 2, 30).
-			AddRow(uuid.NewString(), estateID, time.Now(), time.Now(), nil, 3, 4, 21).
+			AddRow(uuid.NewString(), testOrganizationID, estateID, time.Now(), time.Now(), nil, 1, 2, 30).
+			AddRow(uuid.NewString(), testOrganizationID, estateID, time.Now(), time.Now(), nil, 3, 4, 21).
 			RowError(1, fmt.Errorf("iteration error"))
 
-		mock.ExpectQuery("SELECT .* FROM estate_trees WHERE estate_id = \\$1 AND deleted_at IS NULL LIMIT \\$2 OFFSET \\$3").
-			WithArgs(estateID, 10, 0).
+		mock.ExpectQuery("SELECT .* FROM estate_trees WHERE organization_id = \\$1 AND estate_id = \\$2 AND deleted_at IS NULL LIMIT \\$3 OFFSET \\$4").
+			WithArgs(testOrganizationID, estateID, 10, 0).
 			WillReturnRows(rows)
 
 		result, err := repo.FindAllMapEstateTree(context.Background(), filter)
 
 		assert.Error(t, err)
-		assert.EqualError(t, err, "iteration error")
+		assert.Contains(t, err.Error(), "iteration error")
 		assert.Nil(t, result)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
+func TestRepository_FindAllMapEstateTree_InvalidFilter(t *testing.T) {
+	testcases := []struct {
+		name   string
+		filter Filter
+	}{
+		{name: "unknown order column", filter: Filter{Page: 1, Limit: 10, OrderBy: "height; DROP TABLE estates", Sort: "ASC"}},
+		{name: "unknown sort", filter: Filter{Page: 1, Limit: 10, OrderBy: "id", Sort: "sideways"}},
+		{name: "page below one", filter: Filter{Page: 0, Limit: 10}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			repo := &Repository{Db: db}
+
+			result, err := repo.FindAllMapEstateTree(context.Background(), &FilterEstateTree{
+				Filter:         tc.filter,
+				OrganizationID: testOrganizationID,
+				EstateID:       uuid.NewString(),
+			})
+			assert.ErrorIs(t, err, ErrInvalidFilter)
+			assert.Nil(t, result)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestRepository_FindEstateTree(t *testing.T) {
 	t.Run("Success : filter by id", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
@@ -322,17 +579,18 @@ func TestRepository_FindEstateTree(t *testing.T) {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			},
-			EstateID: estateID,
-			X:        1,
-			Y:        2,
-			Height:   20,
+			OrganizationID: testOrganizationID,
+			EstateID:       estateID,
+			X:              1,
+			Y:              2,
+			Height:         20,
 		}
 
-		mock.ExpectQuery("SELECT .* FROM estate_trees").WithArgs(id).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "estate_id", "created_at", "updated_at", "deleted_at", "x", "y", "height"}).
-				AddRow(expectedEstateTree.ID, expectedEstateTree.EstateID, expectedEstateTree.CreatedAt, expectedEstateTree.UpdatedAt, nil, expectedEstateTree.X, expectedEstateTree.Y, expectedEstateTree.Height))
+		mock.ExpectQuery("SELECT .* FROM estate_trees").WithArgs(testOrganizationID, id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "estate_id", "created_at", "updated_at", "deleted_at", "x", "y", "height"}).
+				AddRow(expectedEstateTree.ID, expectedEstateTree.OrganizationID, expectedEstateTree.EstateID, expectedEstateTree.CreatedAt, expectedEstateTree.UpdatedAt, nil, expectedEstateTree.X, expectedEstateTree.Y, expectedEstateTree.Height))
 
-		result, err := repo.FindEstateTree(context.Background(), &FilterEstateTree{ID: id})
+		result, err := repo.FindEstateTree(context.Background(), &FilterEstateTree{OrganizationID: testOrganizationID, ID: id})
 		assert.NoError(t, err)
 		assert.Equal(t, expectedEstateTree, result)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -353,18 +611,19 @@ func TestRepository_FindEstateTree(t *testing.T) {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			},
-			EstateID: estateID,
-			X:        3,
-			Y:        4,
-			Height:   10,
+			OrganizationID: testOrganizationID,
+			EstateID:       estateID,
+			X:              3,
+			Y:              4,
+			Height:         10,
 		}
 
-		mock.ExpectQuery("SELECT .* FROM estate_trees WHERE estate_id = \\$1 AND x = \\$2 AND y = \\$3 AND deleted_at IS NULL").
-			WithArgs(estateID, 3, 4).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "estate_id", "created_at", "updated_at", "deleted_at", "x", "y", "height"}).
-				AddRow(expectedEstateTree.ID, expectedEstateTree.EstateID, expectedEstateTree.CreatedAt, expectedEstateTree.UpdatedAt, nil, expectedEstateTree.X, expectedEstateTree.Y, expectedEstateTree.Height))
+		mock.ExpectQuery("SELECT .* FROM estate_trees WHERE organization_id = \\$1 AND estate_id = \\$2 AND x = \\$3 AND y = \\$4 AND deleted_at IS NULL").
+			WithArgs(testOrganizationID, estateID, 3, 4).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "estate_id", "created_at", "updated_at", "deleted_at", "x", "y", "height"}).
+				AddRow(expectedEstateTree.ID, expectedEstateTree.OrganizationID, expectedEstateTree.EstateID, expectedEstateTree.CreatedAt, expectedEstateTree.UpdatedAt, nil, expectedEstateTree.X, expectedEstateTree.Y, expectedEstateTree.Height))
 
-		result, err := repo.FindEstateTree(context.Background(), &FilterEstateTree{X: 3, Y: 4, EstateID: estateID})
+		result, err := repo.FindEstateTree(context.Background(), &FilterEstateTree{OrganizationID: testOrganizationID, X: 3, Y: 4, EstateID: estateID})
 		assert.NoError(t, err)
 		assert.Equal(t, expectedEstateTree, result)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -378,11 +637,12 @@ func TestRepository_FindEstateTree(t *testing.T) {
 		repo := &Repository{Db: db}
 
 		id := uuid.NewString()
-		mock.ExpectQuery("SELECT .* FROM estate_trees").WithArgs(id).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT .* FROM estate_trees").WithArgs(testOrganizationID, id).WillReturnError(sql.ErrNoRows)
 
-		result, err := repo.FindEstateTree(context.Background(), &FilterEstateTree{ID: id})
+		result, err := repo.FindEstateTree(context.Background(), &FilterEstateTree{OrganizationID: testOrganizationID, ID: id})
 		assert.Error(t, err)
-		assert.Equal(t, sql.ErrNoRows, err)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.ErrorIs(t, err, ErrNotFound)
 		assert.Equal(t, EstateTree{}, result)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -395,12 +655,12 @@ func TestRepository_FindEstateTree(t *testing.T) {
 		repo := &Repository{Db: db}
 
 		id := "#######"
-		mock.ExpectQuery("SELECT .* FROM estate_trees").WithArgs(id).
+		mock.ExpectQuery("SELECT .* FROM estate_trees").WithArgs(testOrganizationID, id).
 			WillReturnError(errors.New("database error"))
 
-		result, err := repo.FindEstateTree(context.Background(), &FilterEstateTree{ID: id})
+		result, err := repo.FindEstateTree(context.Background(), &FilterEstateTree{OrganizationID: testOrganizationID, ID: id})
 		assert.Error(t, err)
-		assert.Equal(t, "database error", err.Error())
+		assert.Contains(t, err.Error(), "database error")
 		assert.Equal(t, EstateTree{}, result)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -415,14 +675,16 @@ func TestRepository_CountEstateTree(t *testing.T) {
 		repo := &Repository{Db: db}
 
 		filter := &FilterEstateTree{
-			EstateID: uuid.NewString(),
+			OrganizationID: testOrganizationID,
+			EstateID:       uuid.NewString(),
 		}
 
-		expectedQuery := "SELECT COUNT\\(1\\) FROM estate_trees WHERE estate_id = \\$1 AND deleted_at IS NULL"
-		mock.ExpectQuery(expectedQuery).WithArgs(filter.EstateID).
+		expectedQuery := "SELECT COUNT\\(1\\) FROM estate_trees WHERE organization_id = \\$1 AND estate_id = \\$2 AND deleted_at IS NULL"
+		mock.ExpectQuery(expectedQuery).WithArgs(testOrganizationID, filter.EstateID).
 			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
 
-		count := repo.CountEstateTree(context.Background(), filter)
+		count, err := repo.CountEstateTree(context.Background(), filter)
+		assert.NoError(t, err)
 		assert.Equal(t, 5, count)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -434,13 +696,16 @@ func TestRepository_CountEstateTree(t *testing.T) {
 
 		repo := &Repository{Db: db}
 		filter := &FilterEstateTree{
-			EstateID: uuid.NewString(),
+			OrganizationID: testOrganizationID,
+			EstateID:       uuid.NewString(),
 		}
 
-		expectedQuery := "SELECT COUNT\\(1\\) FROM estate_trees WHERE estate_id = \\$1 AND deleted_at IS NULL"
-		mock.ExpectQuery(expectedQuery).WithArgs(filter.EstateID).WillReturnError(fmt.Errorf("query error"))
+		expectedQuery := "SELECT COUNT\\(1\\) FROM estate_trees WHERE organization_id = \\$1 AND estate_id = \\$2 AND deleted_at IS NULL"
+		mock.ExpectQuery(expectedQuery).WithArgs(testOrganizationID, filter.EstateID).WillReturnError(fmt.Errorf("query error"))
 
-		count := repo.CountEstateTree(context.Background(), filter)
+		count, err := repo.CountEstateTree(context.Background(), filter)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "query error")
 		assert.Equal(t, 0, count)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -454,12 +719,13 @@ func TestMockRepositoryInterface_GetEstateTreeStats(t *testing.T) {
 
 		repo := &Repository{Db: db}
 		filter := &FilterEstateTree{
-			EstateID: uuid.NewString(),
+			OrganizationID: testOrganizationID,
+			EstateID:       uuid.NewString(),
 		}
 
 		expectedQuery := `SELECT MAX\(height\) as max, MIN\(height\) as min, PERCENTILE_CONT\(0.5\) WITHIN GROUP \(ORDER BY COALESCE\(height, 0\)\) AS median FROM estate_trees`
 		mock.ExpectQuery(expectedQuery).
-			WithArgs(filter.EstateID).WillReturnRows(sqlmock.NewRows([]string{"max", "min", "median"}).
+			WithArgs(testOrganizationID, filter.EstateID).WillReturnRows(sqlmock.NewRows([]string{"max", "min", "median"}).
 			AddRow(30, 3, 15))
 
 		stats, err := repo.GetEstateTreeStats(context.Background(), filter)
@@ -482,11 +748,12 @@ func TestMockRepositoryInterface_GetEstateTreeStats(t *testing.T) {
 				Page:  1,
 				Limit: 10,
 			},
-			EstateID: uuid.NewString(),
+			OrganizationID: testOrganizationID,
+			EstateID:       uuid.NewString(),
 		}
 
 		expectedQuery := `SELECT MAX\(height\) as max, MIN\(height\) as min, PERCENTILE_CONT\(0.5\) WITHIN GROUP \(ORDER BY COALESCE\(height, 0\)\) AS median FROM estate_trees`
-		mock.ExpectQuery(expectedQuery).WithArgs(filter.EstateID).WillReturnError(fmt.Errorf("query error"))
+		mock.ExpectQuery(expectedQuery).WithArgs(testOrganizationID, filter.EstateID).WillReturnError(fmt.Errorf("query error"))
 
 		stats, err := repo.GetEstateTreeStats(context.Background(), filter)
 		assert.Error(t, err)
@@ -2,14 +2,48 @@ package repository
 
 import (
 	"context"
+	"time"
 )
 
 type RepositoryInterface interface {
+	CreateOrganization(ctx context.Context, data *Organization) error
+	FindOrganization(ctx context.Context, filter *FilterOrganization) (Organization, error)
+	FindAllOrganization(ctx context.Context, filter *FilterOrganization) ([]Organization, error)
 	CreateEstate(ctx context.Context, data *Estate) error
 	FindEstate(ctx context.Context, filter *FilterEstate) (Estate, error)
+	FindAllEstate(ctx context.Context, filter *FilterEstate) ([]Estate, error)
 	CreateEstateTree(ctx context.Context, data *EstateTree) error
+	CreateEstateTrees(ctx context.Context, data []EstateTree) error
+	UpdateEstateTree(ctx context.Context, data *EstateTree) error
+	DeleteEstateTree(ctx context.Context, filter *FilterEstateTree) error
+	ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]error, error)
 	FindAllMapEstateTree(ctx context.Context, filter *FilterEstateTree) (map[CoordinatePoint]EstateTree, error)
 	FindEstateTree(ctx context.Context, filter *FilterEstateTree) (EstateTree, error)
-	CountEstateTree(ctx context.Context, filter *FilterEstateTree) int
+	CountEstateTree(ctx context.Context, filter *FilterEstateTree) (int, error)
 	GetEstateTreeStats(ctx context.Context, filter *FilterEstateTree) (EstateTreeStats, error)
+	CreateAPIKey(ctx context.Context, data *APIKey) error
+	FindAPIKey(ctx context.Context, filter *FilterAPIKey) (APIKey, error)
+	FindAllAPIKey(ctx context.Context, filter *FilterAPIKey) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	ClaimIdempotencyKey(ctx context.Context, data *IdempotencyKey, staleBefore time.Time) (bool, error)
+	FindIdempotencyKey(ctx context.Context, filter *FilterIdempotencyKey) (IdempotencyKey, error)
+	CompleteIdempotencyKey(ctx context.Context, data *IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, filter *FilterIdempotencyKey) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+	CreatePlanJob(ctx context.Context, data *PlanJob) error
+	FindPlanJob(ctx context.Context, filter *FilterPlanJob) (PlanJob, error)
+	ClaimPlanJob(ctx context.Context, staleBefore time.Time) (PlanJob, error)
+	UpdatePlanJobProgress(ctx context.Context, data *PlanJob) (bool, error)
+	FinishPlanJob(ctx context.Context, data *PlanJob) error
+	CancelPlanJob(ctx context.Context, filter *FilterPlanJob) (PlanJob, error)
+	CreateWebhookSubscription(ctx context.Context, data *WebhookSubscription) error
+	FindWebhookSubscription(ctx context.Context, filter *FilterWebhookSubscription) (WebhookSubscription, error)
+	FindAllWebhookSubscription(ctx context.Context, filter *FilterWebhookSubscription) ([]WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, filter *FilterWebhookSubscription) error
+	FindAllWebhookDelivery(ctx context.Context, filter *FilterWebhookDelivery) ([]WebhookDelivery, error)
+	ClaimWebhookDelivery(ctx context.Context, lease time.Duration) (WebhookDelivery, error)
+	FinishWebhookDelivery(ctx context.Context, data *WebhookDelivery, retryAfter time.Duration) error
+	ReplayWebhookDeliveries(ctx context.Context, filter *FilterWebhookDelivery) (int64, error)
+	FindAllAuditEntry(ctx context.Context, filter *FilterAuditEntry) ([]AuditEntry, error)
+	FindAllEstateEvent(ctx context.Context, filter *FilterEstateEvent) ([]EstateEvent, error)
 }
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/lib/pq"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID serializes concurrent Migrate calls, e.g. from several
+// replicas starting at once.
+const migrationLockID = 4_730_121
+
+const (
+	CreateMigrationTableQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (version INT PRIMARY KEY, name varchar(255) NOT NULL, applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW())`
+	LockMigrationQuery        = `SELECT pg_advisory_xact_lock($1)`
+	MigrationAppliedQuery     = `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`
+	InsertMigrationQuery      = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+	AppliedMigrationsQuery    = `SELECT version FROM schema_migrations`
+)
+
+// Migration is a schema change embedded from migrations/<version>_<name>.sql.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		version, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.sql", entry.Name())
+		}
+		number, err := strconv.Atoi(version)
+		if err != nil || number < 1 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", entry.Name(), version)
+		}
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: number, Name: name, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migration version %d is used twice", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// Migrate applies the pending migrations in order, each in its own
+// transaction, and returns the ones it applied.
+func (r *Repository) Migrate(ctx context.Context) (_ []Migration, err error) {
+	ctx, end := r.startQuery(ctx, "Migrate", "CreateMigrationTableQuery")
+	defer func() { end(err) }()
+
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.Db.ExecContext(ctx, CreateMigrationTableQuery); err != nil {
+		return nil, wrapError("Migrate", err)
+	}
+
+	var applied []Migration
+	for _, migration := range migrations {
+		ok, err := r.applyMigration(ctx, migration)
+		if err != nil {
+			return applied, wrapError("Migrate", fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err))
+		}
+		if ok {
+			logging.FromContext(ctx).LogAttrs(ctx, slog.LevelInfo, "migration applied",
+				slog.Int("version", migration.Version),
+				slog.String("name", migration.Name),
+			)
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// applyMigration runs migration unless another process already did.
+func (r *Repository) applyMigration(ctx context.Context, migration Migration) (bool, error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, LockMigrationQuery, migrationLockID); err != nil {
+		return false, err
+	}
+	var done bool
+	if err = tx.QueryRowContext(ctx, MigrationAppliedQuery, migration.Version).Scan(&done); err != nil {
+		return false, err
+	}
+	if done {
+		return false, nil
+	}
+	if _, err = tx.ExecContext(ctx, migration.SQL); err != nil {
+		return false, err
+	}
+	if _, err = tx.ExecContext(ctx, InsertMigrationQuery, migration.Version, migration.Name); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// PendingMigrations returns the embedded migrations the database has not
+// applied yet. Every migration is pending on a database never migrated.
+func (r *Repository) PendingMigrations(ctx context.Context) (_ []Migration, err error) {
+	ctx, end := r.startQuery(ctx, "PendingMigrations", "AppliedMigrationsQuery")
+	defer func() { end(err) }()
+
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Db.QueryContext(ctx, AppliedMigrationsQuery)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "42P01" { // undefined_table
+		return migrations, nil
+	}
+	if err != nil {
+		return nil, wrapError("PendingMigrations", err)
+	}
+	defer closeRows(ctx, "PendingMigrations", rows)
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var version int
+		if err = rows.Scan(&version); err != nil {
+			return nil, wrapError("PendingMigrations", err)
+		}
+		applied[version] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapError("PendingMigrations", err)
+	}
+
+	var pending []Migration
+	for _, migration := range migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,103 @@
+-- Initial schema. Every statement is idempotent. Databases created from the
+-- former database.sql, whose tables the CREATE TABLE statements leave as they
+-- are, are upgraded by the block before the indexes.
+
+-- organizations table
+CREATE TABLE IF NOT EXISTS organizations
+(
+    id         varchar(36) PRIMARY KEY,
+    name       varchar(255) NOT NULL,
+    deleted_at TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- estates table
+CREATE TABLE IF NOT EXISTS estates (
+    "id"     varchar(36) PRIMARY KEY,
+    organization_id varchar(36) NOT NULL REFERENCES organizations (id),
+    "width"  INT NOT NULL CHECK (width > 0 AND width <= 50000),
+    "length" INT NOT NULL CHECK (length > 0 AND length <= 50000),
+    deleted_at TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (id, organization_id)
+    );
+
+-- estate_tress table
+-- organization_id is denormalized so every tree query can be scoped by
+-- tenant; the composite key keeps it equal to the estate's organization.
+CREATE TABLE IF NOT EXISTS estate_trees
+(
+    id        varchar(36) PRIMARY KEY,
+    estate_id varchar(36) NOT NULL,
+    organization_id varchar(36) NOT NULL,
+    x         INT NOT NULL CHECK (x > 0),
+    y         INT NOT NULL CHECK (y > 0),
+    height    INT NOT NULL CHECK (height > 0 AND height <= 30),
+    deleted_at TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    FOREIGN KEY (estate_id, organization_id) REFERENCES estates (id, organization_id) ON DELETE CASCADE
+);
+
+-- Upgrade of the tables of database.sql, which have no tenants, a plot index
+-- that is not unique and a height check rejecting 30. Their estates and trees
+-- are given to the default organization; of the live trees sharing a plot,
+-- all but the oldest are deleted. Fresh databases skip it.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'estates' AND column_name = 'organization_id') THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO organizations (id, name) VALUES ('00000000-0000-0000-0000-000000000000', 'Default')
+    ON CONFLICT (id) DO NOTHING;
+
+    ALTER TABLE estates ADD COLUMN organization_id varchar(36) REFERENCES organizations (id);
+    UPDATE estates SET organization_id = '00000000-0000-0000-0000-000000000000';
+    ALTER TABLE estates ALTER COLUMN organization_id SET NOT NULL;
+    ALTER TABLE estates ADD UNIQUE (id, organization_id);
+
+    -- Trees without an estate cannot be reached by any query.
+    DELETE FROM estate_trees WHERE estate_id IS NULL;
+    ALTER TABLE estate_trees ADD COLUMN organization_id varchar(36);
+    UPDATE estate_trees SET organization_id = '00000000-0000-0000-0000-000000000000';
+    ALTER TABLE estate_trees ALTER COLUMN organization_id SET NOT NULL;
+    ALTER TABLE estate_trees ALTER COLUMN estate_id SET NOT NULL;
+    ALTER TABLE estate_trees DROP CONSTRAINT IF EXISTS estate_trees_estate_id_fkey;
+    ALTER TABLE estate_trees ADD FOREIGN KEY (estate_id, organization_id)
+        REFERENCES estates (id, organization_id) ON DELETE CASCADE;
+    ALTER TABLE estate_trees DROP CONSTRAINT IF EXISTS estate_trees_height_check;
+    ALTER TABLE estate_trees ADD CHECK (height > 0 AND height <= 30);
+
+    UPDATE estate_trees t SET deleted_at = NOW()
+    WHERE t.deleted_at IS NULL AND EXISTS (
+        SELECT 1 FROM estate_trees o
+        WHERE o.estate_id = t.estate_id AND o.x = t.x AND o.y = t.y AND o.deleted_at IS NULL
+          AND (COALESCE(o.created_at, '-infinity'), o.id) < (COALESCE(t.created_at, '-infinity'), t.id)
+    );
+    DROP INDEX IF EXISTS idx_tree_coords;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_estates_organization_id ON estates USING btree (organization_id);
+CREATE INDEX IF NOT EXISTS idx_estate_id ON estate_trees USING btree (estate_id);
+CREATE INDEX IF NOT EXISTS idx_estate_trees_organization_id ON estate_trees USING btree (organization_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tree_coords ON estate_trees USING btree (estate_id, x, y) WHERE deleted_at IS NULL;
+
+-- api_keys table
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    id              varchar(36) PRIMARY KEY,
+    organization_id varchar(36) NOT NULL REFERENCES organizations (id),
+    name            varchar(255) NOT NULL,
+    key_hash        varchar(64) NOT NULL UNIQUE,
+    scopes          TEXT[] NOT NULL DEFAULT '{}',
+    revoked_at      TIMESTAMPTZ DEFAULT NULL,
+    created_at      TIMESTAMPTZ DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_organization_id ON api_keys USING btree (organization_id);
@@ -0,0 +1,20 @@
+-- idempotency_keys table
+-- A key is claimed with a NULL status_code before the request runs and
+-- completed with the response once it is answered.
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    organization_id varchar(36) NOT NULL REFERENCES organizations (id),
+    key             varchar(255) NOT NULL,
+    method          varchar(16) NOT NULL,
+    path            TEXT NOT NULL,
+    request_hash    varchar(64) NOT NULL,
+    status_code     INT DEFAULT NULL,
+    content_type    varchar(255) DEFAULT NULL,
+    response_body   BYTEA DEFAULT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at    TIMESTAMPTZ DEFAULT NULL,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (organization_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys USING btree (expires_at);
@@ -0,0 +1,38 @@
+-- estates.version counts the changes to an estate and its trees. Cached
+-- plans and stats are keyed by it, so bumping it is what invalidates them.
+ALTER TABLE estates ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- Any update of an estate bumps its version, unless the statement sets the
+-- version itself.
+CREATE OR REPLACE FUNCTION bump_estate_version() RETURNS trigger AS $$
+BEGIN
+    IF NEW.version = OLD.version THEN
+        NEW.version := OLD.version + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS estates_bump_version ON estates;
+CREATE TRIGGER estates_bump_version
+    BEFORE UPDATE ON estates
+    FOR EACH ROW EXECUTE FUNCTION bump_estate_version();
+
+-- Any insert, update or delete of a tree bumps the version of its estate,
+-- including both estates when a tree moves.
+CREATE OR REPLACE FUNCTION bump_tree_estate_version() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        UPDATE estates SET version = version + 1 WHERE id = OLD.estate_id;
+    END IF;
+    IF TG_OP <> 'DELETE' AND (TG_OP = 'INSERT' OR NEW.estate_id <> OLD.estate_id) THEN
+        UPDATE estates SET version = version + 1 WHERE id = NEW.estate_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS estate_trees_bump_estate_version ON estate_trees;
+CREATE TRIGGER estate_trees_bump_estate_version
+    AFTER INSERT OR UPDATE OR DELETE ON estate_trees
+    FOR EACH ROW EXECUTE FUNCTION bump_tree_estate_version();
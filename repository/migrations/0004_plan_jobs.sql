@@ -0,0 +1,29 @@
+-- plan_jobs table
+-- Drone plans computed in the background. Workers claim queued jobs, and
+-- running jobs whose worker stopped heartbeating, with FOR UPDATE SKIP
+-- LOCKED, so jobs survive restarts and are never run twice at once.
+CREATE TABLE IF NOT EXISTS plan_jobs
+(
+    id               varchar(36) PRIMARY KEY,
+    organization_id  varchar(36) NOT NULL,
+    estate_id        varchar(36) NOT NULL,
+    max_distance     INT DEFAULT NULL CHECK (max_distance >= 0),
+    status           varchar(16) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')),
+    progress         DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (progress >= 0 AND progress <= 1),
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    attempts         INT NOT NULL DEFAULT 0,
+    distance         INT DEFAULT NULL,
+    rest_x           INT DEFAULT NULL,
+    rest_y           INT DEFAULT NULL,
+    error            TEXT DEFAULT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at       TIMESTAMPTZ DEFAULT NULL,
+    heartbeat_at     TIMESTAMPTZ DEFAULT NULL,
+    finished_at      TIMESTAMPTZ DEFAULT NULL,
+    FOREIGN KEY (estate_id, organization_id) REFERENCES estates (id, organization_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_plan_jobs_organization_id ON plan_jobs USING btree (organization_id);
+CREATE INDEX IF NOT EXISTS idx_plan_jobs_pending ON plan_jobs USING btree (created_at) WHERE status IN ('queued', 'running');
@@ -0,0 +1,53 @@
+-- webhook_subscriptions table
+-- Endpoints an organization wants notified of its events. The secret signs
+-- every delivery, so it is kept in clear.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    id              varchar(36) PRIMARY KEY,
+    organization_id varchar(36) NOT NULL REFERENCES organizations (id),
+    url             TEXT NOT NULL,
+    secret          varchar(255) NOT NULL,
+    events          TEXT[] NOT NULL,
+    deleted_at      TIMESTAMPTZ DEFAULT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_organization_id ON webhook_subscriptions USING btree (organization_id) WHERE deleted_at IS NULL;
+
+-- webhook_events table
+-- The outbox: events are written in the transaction of the change they
+-- describe, and only when a subscription wants them.
+CREATE TABLE IF NOT EXISTS webhook_events
+(
+    id              varchar(36) PRIMARY KEY,
+    organization_id varchar(36) NOT NULL REFERENCES organizations (id),
+    type            varchar(64) NOT NULL,
+    payload         JSONB NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- webhook_deliveries table
+-- One row per event and subscription, created with the event. Workers
+-- claim pending deliveries whose next_attempt_at passed with FOR UPDATE
+-- SKIP LOCKED, and push next_attempt_at forward while they send, so a
+-- delivery interrupted by a crash is retried.
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id               varchar(36) PRIMARY KEY,
+    organization_id  varchar(36) NOT NULL,
+    event_id         varchar(36) NOT NULL REFERENCES webhook_events (id) ON DELETE CASCADE,
+    subscription_id  varchar(36) NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    status           varchar(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts         INT NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INT DEFAULT NULL,
+    last_error       TEXT DEFAULT NULL,
+    delivered_at     TIMESTAMPTZ DEFAULT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries USING btree (subscription_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries USING btree (next_attempt_at) WHERE status = 'pending';
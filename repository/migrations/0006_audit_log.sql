@@ -0,0 +1,37 @@
+-- audit_log table
+-- One entry per write made on behalf of a caller, inserted in the same
+-- transaction as the write. Entries have no foreign keys so they outlive
+-- what they describe, and a trigger rejects updates and deletes.
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id              BIGSERIAL PRIMARY KEY,
+    organization_id varchar(36)  NOT NULL,
+    actor           varchar(255) NOT NULL,
+    action          varchar(32)  NOT NULL,
+    entity_type     varchar(32)  NOT NULL,
+    entity_id       varchar(36)  NOT NULL,
+    estate_id       varchar(36) DEFAULT NULL,
+    before          JSONB       DEFAULT NULL,
+    after           JSONB       DEFAULT NULL,
+    request_id      varchar(128) DEFAULT NULL,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_organization_id ON audit_log USING btree (organization_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_estate_id ON audit_log USING btree (organization_id, estate_id, id) WHERE estate_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log USING btree (organization_id, actor, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log USING btree (organization_id, created_at);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE
+    ON audit_log
+    FOR EACH ROW
+EXECUTE FUNCTION audit_log_append_only();
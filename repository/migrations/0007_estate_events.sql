@@ -0,0 +1,59 @@
+-- estate_events table
+-- The changes to the trees of each estate, numbered by the estate version
+-- they produced. Versions are bumped under the lock of the estate row, so
+-- they commit in order: a client that saw version N misses nothing by
+-- reading the events after N.
+CREATE TABLE IF NOT EXISTS estate_events
+(
+    estate_id       varchar(36) NOT NULL,
+    version         BIGINT      NOT NULL,
+    organization_id varchar(36) NOT NULL,
+    type            varchar(32) NOT NULL,
+    data            JSONB       NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (estate_id, version),
+    FOREIGN KEY (estate_id, organization_id) REFERENCES estates (id, organization_id) ON DELETE CASCADE
+);
+
+-- record_estate_event bumps the version of the estate of tree and, unless
+-- event_type is NULL, records the event at that version and notifies the
+-- estate_events channel with "<estate id>:<version>" on commit. Nothing is
+-- recorded for an estate being deleted.
+CREATE OR REPLACE FUNCTION record_estate_event(tree estate_trees, event_type text) RETURNS void AS $$
+DECLARE
+    estate_version BIGINT;
+BEGIN
+    UPDATE estates SET version = version + 1 WHERE id = tree.estate_id RETURNING version INTO estate_version;
+    IF estate_version IS NULL OR event_type IS NULL THEN
+        RETURN;
+    END IF;
+    INSERT INTO estate_events (estate_id, version, organization_id, type, data)
+    VALUES (tree.estate_id, estate_version, tree.organization_id, event_type,
+            jsonb_build_object('id', tree.id, 'estate_id', tree.estate_id, 'x', tree.x, 'y', tree.y, 'height', tree.height));
+    PERFORM pg_notify('estate_events', tree.estate_id || ':' || estate_version);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Any insert, update or delete of a tree still bumps the version of its
+-- estate, including both estates when a tree moves, and now records what
+-- happened. Soft deletes and restores are recorded as deletions and
+-- creations; changes to deleted trees are not recorded.
+CREATE OR REPLACE FUNCTION bump_tree_estate_version() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM record_estate_event(NEW, CASE WHEN NEW.deleted_at IS NULL THEN 'tree.created' END);
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM record_estate_event(OLD, CASE WHEN OLD.deleted_at IS NULL THEN 'tree.deleted' END);
+    ELSIF NEW.estate_id <> OLD.estate_id THEN
+        PERFORM record_estate_event(OLD, CASE WHEN OLD.deleted_at IS NULL THEN 'tree.deleted' END);
+        PERFORM record_estate_event(NEW, CASE WHEN NEW.deleted_at IS NULL THEN 'tree.created' END);
+    ELSE
+        PERFORM record_estate_event(NEW, CASE
+            WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'tree.deleted'
+            WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'tree.created'
+            WHEN NEW.deleted_at IS NULL THEN 'tree.updated'
+        END);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
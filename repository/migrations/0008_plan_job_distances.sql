@@ -0,0 +1,5 @@
+-- Drones fly the largest estates for longer than an INT holds: a 50000 by
+-- 50000 estate is flown for about 2.5e10 meters.
+ALTER TABLE plan_jobs
+    ALTER COLUMN max_distance TYPE BIGINT,
+    ALTER COLUMN distance TYPE BIGINT;
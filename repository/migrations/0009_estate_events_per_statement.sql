@@ -0,0 +1,102 @@
+-- Trees change in bulk: imports insert them a thousand at a time and
+-- deleting an estate deletes all of its trees. Estate events are now
+-- recorded once per statement rather than once per tree: each estate
+-- changed is updated once, its version bumped by the number of its events,
+-- and notified once.
+DROP TRIGGER IF EXISTS estate_trees_bump_estate_version ON estate_trees;
+DROP FUNCTION IF EXISTS bump_tree_estate_version();
+DROP FUNCTION IF EXISTS record_estate_event(estate_trees, text);
+
+-- tree_change is a change to the tree id of estate_id, as listed to
+-- record_estate_events. event_type is NULL for changes that bump the version
+-- without an event, i.e. changes to deleted trees.
+CREATE OR REPLACE FUNCTION tree_change(id text, estate_id text, organization_id text, x int, y int, height int,
+                                       event_type text) RETURNS jsonb AS $$
+    SELECT jsonb_build_object('estate_id', estate_id, 'organization_id', organization_id, 'type', event_type,
+                              'data', jsonb_build_object('id', id, 'estate_id', estate_id, 'x', x, 'y', y, 'height', height));
+$$ LANGUAGE sql IMMUTABLE;
+
+-- record_estate_events bumps the version of every estate in changes, a JSON
+-- array of tree_change, by the number of its events, or by one when it has
+-- none. It records the events at the versions in between, in the order of
+-- changes, and notifies the estate_events channel with "<estate id>:<version>"
+-- of the last one on commit. Nothing is recorded for an estate being deleted.
+CREATE OR REPLACE FUNCTION record_estate_events(changes jsonb) RETURNS void AS $$
+DECLARE
+    estate RECORD;
+BEGIN
+    FOR estate IN
+        UPDATE estates SET version = estates.version + GREATEST(counts.events, 1)
+        FROM (SELECT change ->> 'estate_id' AS estate_id, count(change ->> 'type') AS events
+              FROM jsonb_array_elements(changes) AS change
+              GROUP BY 1) AS counts
+        WHERE estates.id = counts.estate_id
+        RETURNING estates.id, estates.version, counts.events
+    LOOP
+        CONTINUE WHEN estate.events = 0;
+        INSERT INTO estate_events (estate_id, version, organization_id, type, data)
+        SELECT estate.id, estate.version - estate.events + row_number() OVER (ORDER BY n),
+               change ->> 'organization_id', change ->> 'type', change -> 'data'
+        FROM jsonb_array_elements(changes) WITH ORDINALITY AS e(change, n)
+        WHERE change ->> 'estate_id' = estate.id AND change ->> 'type' IS NOT NULL;
+        PERFORM pg_notify('estate_events', estate.id || ':' || estate.version);
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- The events of the trees inserted, updated or deleted by a statement, as
+-- bump_tree_estate_version recorded them: both estates are changed when a
+-- tree moves, and soft deletes and restores are recorded as deletions and
+-- creations.
+CREATE OR REPLACE FUNCTION record_tree_estate_events() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM record_estate_events((
+            SELECT jsonb_agg(tree_change(id, estate_id, organization_id, x, y, height,
+                                         CASE WHEN deleted_at IS NULL THEN 'tree.created' END))
+            FROM new_trees));
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM record_estate_events((
+            SELECT jsonb_agg(tree_change(id, estate_id, organization_id, x, y, height,
+                                         CASE WHEN deleted_at IS NULL THEN 'tree.deleted' END))
+            FROM old_trees));
+    ELSE
+        PERFORM record_estate_events((
+            SELECT jsonb_agg(change)
+            FROM old_trees AS o
+                JOIN new_trees AS n ON n.id = o.id
+                CROSS JOIN LATERAL (
+                    SELECT tree_change(o.id, o.estate_id, o.organization_id, o.x, o.y, o.height,
+                                       CASE WHEN o.deleted_at IS NULL THEN 'tree.deleted' END)
+                    WHERE n.estate_id <> o.estate_id
+                    UNION ALL
+                    SELECT tree_change(n.id, n.estate_id, n.organization_id, n.x, n.y, n.height, CASE
+                        WHEN n.estate_id <> o.estate_id THEN CASE WHEN n.deleted_at IS NULL THEN 'tree.created' END
+                        WHEN o.deleted_at IS NULL AND n.deleted_at IS NOT NULL THEN 'tree.deleted'
+                        WHEN o.deleted_at IS NOT NULL AND n.deleted_at IS NULL THEN 'tree.created'
+                        WHEN n.deleted_at IS NULL THEN 'tree.updated'
+                    END)
+                ) AS changes(change)));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Transition tables are only allowed on triggers of a single event.
+DROP TRIGGER IF EXISTS estate_trees_insert_estate_events ON estate_trees;
+CREATE TRIGGER estate_trees_insert_estate_events
+    AFTER INSERT ON estate_trees
+    REFERENCING NEW TABLE AS new_trees
+    FOR EACH STATEMENT EXECUTE FUNCTION record_tree_estate_events();
+
+DROP TRIGGER IF EXISTS estate_trees_update_estate_events ON estate_trees;
+CREATE TRIGGER estate_trees_update_estate_events
+    AFTER UPDATE ON estate_trees
+    REFERENCING OLD TABLE AS old_trees NEW TABLE AS new_trees
+    FOR EACH STATEMENT EXECUTE FUNCTION record_tree_estate_events();
+
+DROP TRIGGER IF EXISTS estate_trees_delete_estate_events ON estate_trees;
+CREATE TRIGGER estate_trees_delete_estate_events
+    AFTER DELETE ON estate_trees
+    REFERENCING OLD TABLE AS old_trees
+    FOR EACH STATEMENT EXECUTE FUNCTION record_tree_estate_events();
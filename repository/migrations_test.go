@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "initial", migrations[0].Name)
+	assert.Contains(t, migrations[0].SQL, "CREATE TABLE IF NOT EXISTS estates")
+	// The plot index of database.sql, which is not unique, is dropped before
+	// the unique one is created under its name.
+	dropIndex := strings.Index(migrations[0].SQL, "DROP INDEX IF EXISTS idx_tree_coords")
+	require.NotEqual(t, -1, dropIndex)
+	assert.Less(t, dropIndex, strings.Index(migrations[0].SQL, "CREATE UNIQUE INDEX IF NOT EXISTS idx_tree_coords"))
+	for i := 1; i < len(migrations); i++ {
+		assert.Greater(t, migrations[i].Version, migrations[i-1].Version)
+	}
+	// Distances of the largest estates overflow an INT.
+	assert.Contains(t, migrations[7].SQL, "ALTER COLUMN max_distance TYPE BIGINT")
+	assert.Contains(t, migrations[7].SQL, "ALTER COLUMN distance TYPE BIGINT")
+	// Estate events are recorded per statement, not per tree, for bulk
+	// imports and cascading deletes.
+	assert.Contains(t, migrations[8].SQL, "DROP TRIGGER IF EXISTS estate_trees_bump_estate_version")
+	assert.Equal(t, 3, strings.Count(migrations[8].SQL, "FOR EACH STATEMENT EXECUTE FUNCTION record_tree_estate_events()"))
+}
+
+func TestRepository_Migrate(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+
+	t.Run("applies pending migrations", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := &Repository{Db: db}
+
+		mock.ExpectExec(regexp.QuoteMeta(CreateMigrationTableQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+		for i, migration := range migrations {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(LockMigrationQuery)).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(MigrationAppliedQuery)).WithArgs(migration.Version).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(i == 0))
+			if i == 0 {
+				mock.ExpectRollback()
+				continue
+			}
+			mock.ExpectExec(regexp.QuoteMeta(migration.SQL)).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta(InsertMigrationQuery)).WithArgs(migration.Version, migration.Name).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		}
+
+		applied, err := repo.Migrate(context.Background())
+		require.NoError(t, err)
+		assert.ElementsMatch(t, migrations[1:], applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stops at the first failure", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := &Repository{Db: db}
+
+		mock.ExpectExec(regexp.QuoteMeta(CreateMigrationTableQuery)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(LockMigrationQuery)).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(MigrationAppliedQuery)).WithArgs(migrations[0].Version).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(regexp.QuoteMeta(migrations[0].SQL)).WillReturnError(errors.New("syntax error"))
+		mock.ExpectRollback()
+
+		applied, err := repo.Migrate(context.Background())
+		assert.ErrorContains(t, err, "migration 1_initial: syntax error")
+		assert.Empty(t, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_PendingMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+
+	t.Run("never migrated", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := &Repository{Db: db}
+
+		mock.ExpectQuery(regexp.QuoteMeta(AppliedMigrationsQuery)).WillReturnError(&pq.Error{Code: "42P01"})
+
+		pending, err := repo.PendingMigrations(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, migrations, pending)
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := &Repository{Db: db}
+
+		rows := sqlmock.NewRows([]string{"version"})
+		for _, migration := range migrations {
+			rows.AddRow(migration.Version)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(AppliedMigrationsQuery)).WillReturnRows(rows)
+
+		pending, err := repo.PendingMigrations(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		repo := &Repository{Db: db}
+
+		mock.ExpectQuery(regexp.QuoteMeta(AppliedMigrationsQuery)).WillReturnError(errors.New("boom"))
+
+		_, err = repo.PendingMigrations(context.Background())
+		assert.Error(t, err)
+	})
+}
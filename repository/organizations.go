@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+const (
+	InsertOrganizationQuery = `INSERT INTO organizations (id, name) VALUES ($1, $2) RETURNING id`
+	GetOrganizationQuery    = `SELECT id, name, created_at, updated_at, deleted_at FROM organizations`
+)
+
+func (r *Repository) CreateOrganization(ctx context.Context, data *Organization) (err error) {
+	ctx, end := r.startQuery(ctx, "CreateOrganization", "InsertOrganizationQuery")
+	defer func() { end(err) }()
+
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, InsertOrganizationQuery, data.ID, data.Name); err != nil {
+			return err
+		}
+		return insertAuditEntry(ctx, tx, auditRecord{
+			organizationID: data.ID,
+			action:         AuditActionCreate,
+			entityType:     AuditEntityOrganization,
+			entityID:       data.ID,
+			after:          organizationData{ID: data.ID, Name: data.Name},
+		})
+	})
+	return wrapError("CreateOrganization", err)
+}
+
+func (r *Repository) FindOrganization(ctx context.Context, filter *FilterOrganization) (_ Organization, err error) {
+	ctx, end := r.startQuery(ctx, "FindOrganization", "GetOrganizationQuery")
+	defer func() { end(err) }()
+
+	finalQuery, paramValue := r.setFilterOrganization(GetOrganizationQuery, filter)
+	var organization Organization
+	err = r.Db.QueryRowContext(ctx, finalQuery, paramValue...).
+		Scan(&organization.ID, &organization.Name, &organization.CreatedAt, &organization.UpdatedAt, &organization.DeletedAt)
+	if err != nil {
+		return Organization{}, wrapError("FindOrganization", err)
+	}
+
+	return organization, nil
+}
+
+func (r *Repository) FindAllOrganization(ctx context.Context, filter *FilterOrganization) (_ []Organization, err error) {
+	ctx, end := r.startQuery(ctx, "FindAllOrganization", "GetOrganizationQuery")
+	defer func() { end(err) }()
+
+	finalQuery, paramValue := r.setFilterOrganization(GetOrganizationQuery, filter)
+	finalQuery += " ORDER BY name ASC"
+
+	rows, err := r.Db.QueryContext(ctx, finalQuery, paramValue...)
+	if err != nil {
+		return nil, wrapError("FindAllOrganization", err)
+	}
+	defer closeRows(ctx, "FindAllOrganization", rows)
+
+	var result []Organization
+	for rows.Next() {
+		var organization Organization
+		if err = rows.Scan(&organization.ID, &organization.Name, &organization.CreatedAt,
+			&organization.UpdatedAt, &organization.DeletedAt); err != nil {
+			return nil, wrapError("FindAllOrganization", err)
+		}
+		result = append(result, organization)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapError("FindAllOrganization", err)
+	}
+
+	return result, nil
+}
+
+func (r *Repository) setFilterOrganization(baseQuery string, filter *FilterOrganization) (string, []interface{}) {
+	var (
+		where      []string
+		paramValue []interface{}
+	)
+	if filter.ID != "" {
+		where = append(where, "id = $"+strconv.Itoa(len(paramValue)+1))
+		paramValue = append(paramValue, filter.ID)
+	}
+
+	where = append(where, "deleted_at IS NULL")
+	baseQuery += " WHERE " + strings.Join(where, " AND ")
+
+	return baseQuery, paramValue
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_CreateOrganization(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		id := uuid.NewString()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO organizations").WithArgs(id, "Sawit Jaya").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectAuditEntry(mock, id, AuditActionCreate, AuditEntityOrganization, id, "", nil, `{"id":"`+id+`","name":"Sawit Jaya"}`)
+		mock.ExpectCommit()
+
+		err = repo.CreateOrganization(context.Background(), &Organization{BaseModel: BaseModel{ID: id}, Name: "Sawit Jaya"})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed: Duplicate", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO organizations").WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		err = repo.CreateOrganization(context.Background(), &Organization{BaseModel: BaseModel{ID: uuid.NewString()}, Name: "Sawit Jaya"})
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_FindOrganization(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		now := time.Now()
+		expected := Organization{BaseModel: BaseModel{ID: uuid.NewString(), CreatedAt: now, UpdatedAt: now}, Name: "Sawit Jaya"}
+		mock.ExpectQuery("SELECT .* FROM organizations WHERE id = \\$1 AND deleted_at IS NULL").WithArgs(expected.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at", "deleted_at"}).
+				AddRow(expected.ID, expected.Name, now, now, nil))
+
+		result, err := repo.FindOrganization(context.Background(), &FilterOrganization{ID: expected.ID})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed: No rows found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectQuery("SELECT .* FROM organizations").WillReturnError(sql.ErrNoRows)
+
+		result, err := repo.FindOrganization(context.Background(), &FilterOrganization{ID: "missing"})
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, Organization{}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_FindAllOrganization(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT .* FROM organizations WHERE deleted_at IS NULL ORDER BY name ASC").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "updated_at", "deleted_at"}).
+			AddRow("org-1", "Agro Lestari", now, now, nil).
+			AddRow("org-2", "Sawit Jaya", now, now, nil))
+
+	result, err := repo.FindAllOrganization(context.Background(), &FilterOrganization{})
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "Agro Lestari", result[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
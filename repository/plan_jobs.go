@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// planJobColumns lists the columns scanned by scanPlanJob.
+const planJobColumns = `id, organization_id, estate_id, max_distance, status, progress, cancel_requested, attempts, distance, rest_x, rest_y, error, created_at, updated_at, started_at, heartbeat_at, finished_at`
+
+const (
+	InsertPlanJobQuery = `INSERT INTO plan_jobs (id, organization_id, estate_id, max_distance) VALUES ($1, $2, $3, $4) RETURNING ` + planJobColumns
+	GetPlanJobQuery    = `SELECT ` + planJobColumns + ` FROM plan_jobs`
+	// ClaimPlanJobQuery takes the oldest queued job, or a running one whose
+	// worker stopped heartbeating before $1. SKIP LOCKED keeps concurrent
+	// workers from claiming the same job.
+	ClaimPlanJobQuery = `UPDATE plan_jobs SET status = 'running', attempts = attempts + 1, started_at = NOW(), heartbeat_at = NOW(), updated_at = NOW()
+WHERE id = (SELECT id FROM plan_jobs WHERE status = 'queued' OR (status = 'running' AND heartbeat_at < $1) ORDER BY created_at FOR UPDATE SKIP LOCKED LIMIT 1)
+RETURNING ` + planJobColumns
+	// UpdatePlanJobProgressQuery and FinishPlanJobQuery only match the
+	// attempt that claimed the job, so a worker whose job was taken over
+	// cannot overwrite it.
+	UpdatePlanJobProgressQuery = `UPDATE plan_jobs SET progress = $3, heartbeat_at = NOW(), updated_at = NOW() WHERE id = $1 AND attempts = $2 AND status = 'running' RETURNING cancel_requested`
+	FinishPlanJobQuery         = `UPDATE plan_jobs SET status = $3, progress = $4, distance = $5, rest_x = $6, rest_y = $7, error = $8, heartbeat_at = NULL, finished_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND attempts = $2 AND status = 'running' RETURNING finished_at, updated_at`
+	LockPlanJobQuery = GetPlanJobQuery + ` WHERE organization_id = $1 AND id = $2 FOR UPDATE`
+	// CancelPlanJobQuery cancels a queued job at once and flags a running
+	// one for its worker.
+	CancelPlanJobQuery = `UPDATE plan_jobs SET cancel_requested = TRUE, status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+finished_at = CASE WHEN status = 'queued' THEN NOW() ELSE finished_at END, updated_at = NOW()
+WHERE organization_id = $1 AND id = $2 AND status IN ('queued', 'running') RETURNING ` + planJobColumns
+)
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPlanJob(row rowScanner) (PlanJob, error) {
+	var (
+		job         PlanJob
+		maxDistance sql.NullInt64
+		distance    sql.NullInt64
+		restX       sql.NullInt64
+		restY       sql.NullInt64
+		jobError    sql.NullString
+	)
+	err := row.Scan(&job.ID, &job.OrganizationID, &job.EstateID, &maxDistance, &job.Status, &job.Progress,
+		&job.CancelRequested, &job.Attempts, &distance, &restX, &restY, &jobError,
+		&job.CreatedAt, &job.UpdatedAt, &job.StartedAt, &job.HeartbeatAt, &job.FinishedAt)
+	if err != nil {
+		return PlanJob{}, err
+	}
+	job.MaxDistance = nullInt(maxDistance)
+	job.Distance = nullInt(distance)
+	job.RestX = nullInt(restX)
+	job.RestY = nullInt(restY)
+	job.Error = jobError.String
+	return job, nil
+}
+
+func nullInt(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+// CreatePlanJob queues data and fills in the columns set by the database.
+// An unknown estate returns ErrNotFound.
+func (r *Repository) CreatePlanJob(ctx context.Context, data *PlanJob) (err error) {
+	ctx, end := r.startQuery(ctx, "CreatePlanJob", "InsertPlanJobQuery")
+	defer func() { end(err) }()
+
+	if data.OrganizationID == "" {
+		return invalidFilter("CreatePlanJob", "organization is required")
+	}
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		job, err := scanPlanJob(tx.QueryRowContext(ctx, InsertPlanJobQuery, data.ID, data.OrganizationID, data.EstateID, data.MaxDistance))
+		if err != nil {
+			return err
+		}
+		*data = job
+		return insertAuditEntry(ctx, tx, auditRecord{
+			organizationID: job.OrganizationID,
+			action:         AuditActionCreate,
+			entityType:     AuditEntityPlanJob,
+			entityID:       job.ID,
+			estateID:       job.EstateID,
+			after:          newPlanJobData(job),
+		})
+	})
+	return wrapError("CreatePlanJob", err)
+}
+
+func (r *Repository) FindPlanJob(ctx context.Context, filter *FilterPlanJob) (_ PlanJob, err error) {
+	ctx, end := r.startQuery(ctx, "FindPlanJob", "GetPlanJobQuery")
+	defer func() { end(err) }()
+
+	if filter.OrganizationID == "" {
+		return PlanJob{}, invalidFilter("FindPlanJob", "organization is required")
+	}
+	query := GetPlanJobQuery + " WHERE organization_id = $1 AND id = $2"
+	args := []interface{}{filter.OrganizationID, filter.ID}
+	if filter.EstateID != "" {
+		query += " AND estate_id = $3"
+		args = append(args, filter.EstateID)
+	}
+	job, err := scanPlanJob(r.Db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return PlanJob{}, wrapError("FindPlanJob", err)
+	}
+	return job, nil
+}
+
+// ClaimPlanJob marks the next job to run as running and returns it, or
+// returns ErrNotFound when there is none. Running jobs last heartbeating
+// before staleBefore are claimed again. It spans organizations, as workers
+// serve every tenant.
+func (r *Repository) ClaimPlanJob(ctx context.Context, staleBefore time.Time) (_ PlanJob, err error) {
+	ctx, end := r.startQuery(ctx, "ClaimPlanJob", "ClaimPlanJobQuery")
+	defer func() { end(err) }()
+
+	job, err := scanPlanJob(r.Db.QueryRowContext(ctx, ClaimPlanJobQuery, staleBefore))
+	if err != nil {
+		return PlanJob{}, wrapError("ClaimPlanJob", err)
+	}
+	return job, nil
+}
+
+// UpdatePlanJobProgress records the progress of a running job and reports
+// whether it was asked to cancel. It returns ErrNotFound when the job is no
+// longer running under data.Attempts, e.g. because it was taken over.
+func (r *Repository) UpdatePlanJobProgress(ctx context.Context, data *PlanJob) (_ bool, err error) {
+	ctx, end := r.startQuery(ctx, "UpdatePlanJobProgress", "UpdatePlanJobProgressQuery")
+	defer func() { end(err) }()
+
+	var cancelRequested bool
+	err = r.Db.QueryRowContext(ctx, UpdatePlanJobProgressQuery, data.ID, data.Attempts, data.Progress).Scan(&cancelRequested)
+	if err != nil {
+		return false, wrapError("UpdatePlanJobProgress", err)
+	}
+	return cancelRequested, nil
+}
+
+// FinishPlanJob stores the final status, progress, result and error of a
+// running job. Like UpdatePlanJobProgress it returns ErrNotFound when the
+// attempt lost the job.
+func (r *Repository) FinishPlanJob(ctx context.Context, data *PlanJob) (err error) {
+	ctx, end := r.startQuery(ctx, "FinishPlanJob", "FinishPlanJobQuery")
+	defer func() { end(err) }()
+
+	var jobError sql.NullString
+	if data.Error != "" {
+		jobError = sql.NullString{String: data.Error, Valid: true}
+	}
+	err = r.Db.QueryRowContext(
+		ctx,
+		FinishPlanJobQuery,
+		data.ID,
+		data.Attempts,
+		data.Status,
+		data.Progress,
+		data.Distance,
+		data.RestX,
+		data.RestY,
+		jobError,
+	).Scan(&data.FinishedAt, &data.UpdatedAt)
+	return wrapError("FinishPlanJob", err)
+}
+
+// CancelPlanJob cancels a queued job or asks the worker of a running one to
+// stop. It returns ErrNotFound when no unfinished job matches filter.
+func (r *Repository) CancelPlanJob(ctx context.Context, filter *FilterPlanJob) (_ PlanJob, err error) {
+	ctx, end := r.startQuery(ctx, "CancelPlanJob", "CancelPlanJobQuery")
+	defer func() { end(err) }()
+
+	if filter.OrganizationID == "" {
+		return PlanJob{}, invalidFilter("CancelPlanJob", "organization is required")
+	}
+	var job PlanJob
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		// The job is locked first so the audit entry has its state before
+		// the cancellation.
+		before, err := scanPlanJob(tx.QueryRowContext(ctx, LockPlanJobQuery, filter.OrganizationID, filter.ID))
+		if err != nil {
+			return err
+		}
+		job, err = scanPlanJob(tx.QueryRowContext(ctx, CancelPlanJobQuery, filter.OrganizationID, filter.ID))
+		if err != nil {
+			return err
+		}
+		return insertAuditEntry(ctx, tx, auditRecord{
+			organizationID: job.OrganizationID,
+			action:         AuditActionCancel,
+			entityType:     AuditEntityPlanJob,
+			entityID:       job.ID,
+			estateID:       job.EstateID,
+			before:         newPlanJobData(before),
+			after:          newPlanJobData(job),
+		})
+	})
+	if err != nil {
+		return PlanJob{}, wrapError("CancelPlanJob", err)
+	}
+	return job, nil
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var planJobColumnNames = []string{"id", "organization_id", "estate_id", "max_distance", "status", "progress", "cancel_requested", "attempts", "distance", "rest_x", "rest_y", "error", "created_at", "updated_at", "started_at", "heartbeat_at", "finished_at"}
+
+func TestRepository_CreatePlanJob(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		now := time.Now()
+		maxDistance := 100
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO plan_jobs").
+			WithArgs("job-1", "org-1", "estate-1", &maxDistance).
+			WillReturnRows(sqlmock.NewRows(planJobColumnNames).
+				AddRow("job-1", "org-1", "estate-1", 100, "queued", 0.0, false, 0, nil, nil, nil, nil, now, now, nil, nil, nil))
+		expectAuditEntry(mock, "org-1", AuditActionCreate, AuditEntityPlanJob, "job-1", "estate-1",
+			nil, `{"id":"job-1","estate_id":"estate-1","max_distance":100,"status":"queued","cancel_requested":false}`)
+		mock.ExpectCommit()
+
+		job := &PlanJob{BaseModel: BaseModel{ID: "job-1"}, OrganizationID: "org-1", EstateID: "estate-1", MaxDistance: &maxDistance}
+		assert.NoError(t, repo.CreatePlanJob(context.Background(), job))
+		assert.Equal(t, PlanJob{
+			BaseModel:      BaseModel{ID: "job-1", CreatedAt: now, UpdatedAt: now},
+			OrganizationID: "org-1",
+			EstateID:       "estate-1",
+			MaxDistance:    &maxDistance,
+			Status:         PlanJobQueued,
+		}, *job)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed: Unknown estate", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO plan_jobs").WillReturnError(&pq.Error{Code: "23503"})
+		mock.ExpectRollback()
+
+		err = repo.CreatePlanJob(context.Background(), &PlanJob{BaseModel: BaseModel{ID: "job-1"}, OrganizationID: "org-1", EstateID: "missing"})
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_FindPlanJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT .* FROM plan_jobs WHERE organization_id = \\$1 AND id = \\$2 AND estate_id = \\$3").
+		WithArgs("org-1", "job-1", "estate-1").
+		WillReturnRows(sqlmock.NewRows(planJobColumnNames).
+			AddRow("job-1", "org-1", "estate-1", nil, "succeeded", 1.0, false, 1, 54, nil, nil, nil, now, now, now, nil, now))
+
+	job, err := repo.FindPlanJob(context.Background(), &FilterPlanJob{ID: "job-1", OrganizationID: "org-1", EstateID: "estate-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, PlanJobSucceeded, job.Status)
+	assert.Equal(t, 1.0, job.Progress)
+	assert.Equal(t, 54, *job.Distance)
+	assert.Nil(t, job.MaxDistance)
+	assert.Nil(t, job.RestX)
+	assert.Equal(t, &now, job.FinishedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = repo.FindPlanJob(context.Background(), &FilterPlanJob{ID: "job-1"})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func TestRepository_ClaimPlanJob(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		now := time.Now()
+		staleBefore := now.Add(-time.Minute)
+		mock.ExpectQuery("UPDATE plan_jobs SET status = 'running'.*FOR UPDATE SKIP LOCKED").
+			WithArgs(staleBefore).
+			WillReturnRows(sqlmock.NewRows(planJobColumnNames).
+				AddRow("job-1", "org-1", "estate-1", nil, "running", 0.0, false, 1, nil, nil, nil, nil, now, now, now, now, nil))
+
+		job, err := repo.ClaimPlanJob(context.Background(), staleBefore)
+		assert.NoError(t, err)
+		assert.Equal(t, PlanJobRunning, job.Status)
+		assert.Equal(t, 1, job.Attempts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed: Nothing to claim", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		repo := &Repository{Db: db}
+
+		mock.ExpectQuery("UPDATE plan_jobs").WillReturnRows(sqlmock.NewRows(planJobColumnNames))
+
+		_, err = repo.ClaimPlanJob(context.Background(), time.Now())
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_UpdatePlanJobProgress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	mock.ExpectQuery("UPDATE plan_jobs SET progress = \\$3.* WHERE id = \\$1 AND attempts = \\$2").
+		WithArgs("job-1", 2, 0.5).
+		WillReturnRows(sqlmock.NewRows([]string{"cancel_requested"}).AddRow(true))
+
+	cancelRequested, err := repo.UpdatePlanJobProgress(context.Background(), &PlanJob{BaseModel: BaseModel{ID: "job-1"}, Attempts: 2, Progress: 0.5})
+	assert.NoError(t, err)
+	assert.True(t, cancelRequested)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_FinishPlanJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	now := time.Now()
+	distance, x, y := 21, 2, 1
+	mock.ExpectQuery("UPDATE plan_jobs SET status = \\$3").
+		WithArgs("job-1", 1, PlanJobSucceeded, 1.0, &distance, &x, &y, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"finished_at", "updated_at"}).AddRow(now, now))
+
+	job := &PlanJob{BaseModel: BaseModel{ID: "job-1"}, Attempts: 1, Status: PlanJobSucceeded, Progress: 1, Distance: &distance, RestX: &x, RestY: &y}
+	assert.NoError(t, repo.FinishPlanJob(context.Background(), job))
+	assert.Equal(t, &now, job.FinishedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_CancelPlanJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM plan_jobs WHERE organization_id = \\$1 AND id = \\$2 FOR UPDATE").
+		WithArgs("org-1", "job-1").
+		WillReturnRows(sqlmock.NewRows(planJobColumnNames).
+			AddRow("job-1", "org-1", "estate-1", nil, "queued", 0.0, false, 0, nil, nil, nil, nil, now, now, nil, nil, nil))
+	mock.ExpectQuery("UPDATE plan_jobs SET cancel_requested = TRUE").
+		WithArgs("org-1", "job-1").
+		WillReturnRows(sqlmock.NewRows(planJobColumnNames).
+			AddRow("job-1", "org-1", "estate-1", nil, "cancelled", 0.0, true, 0, nil, nil, nil, nil, now, now, nil, nil, now))
+	expectAuditEntry(mock, "org-1", AuditActionCancel, AuditEntityPlanJob, "job-1", "estate-1",
+		`{"id":"job-1","estate_id":"estate-1","max_distance":null,"status":"queued","cancel_requested":false}`,
+		`{"id":"job-1","estate_id":"estate-1","max_distance":null,"status":"cancelled","cancel_requested":true}`)
+	mock.ExpectCommit()
+
+	job, err := repo.CancelPlanJob(context.Background(), &FilterPlanJob{ID: "job-1", OrganizationID: "org-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, PlanJobCancelled, job.Status)
+	assert.True(t, job.CancelRequested)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
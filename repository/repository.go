@@ -1,29 +1,125 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/logging"
 	_ "github.com/lib/pq"
 )
 
+// Backoff between connection attempts in Open.
+var (
+	connectInitialBackoff = 250 * time.Millisecond
+	connectMaxBackoff     = 10 * time.Second
+)
+
 type Repository struct {
-	Db *sql.DB
+	Db    *sql.DB
+	Hooks []QueryHook
 }
 
 type NewRepositoryOptions struct {
-	Dsn string
+	Dsn   string
+	Hooks []QueryHook
+	// ConnectTimeout is how long Open keeps retrying to reach the database.
+	// Zero means a single attempt.
+	ConnectTimeout time.Duration
+	// Connection pool limits, see sql.DB. Zero keeps the sql.DB default.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
-func NewRepository(opts NewRepositoryOptions) *Repository {
+// Open connects to the database, retrying with exponential backoff until
+// it answers, ConnectTimeout elapses or ctx is done.
+func Open(ctx context.Context, opts NewRepositoryOptions) (*Repository, error) {
 	db, err := sql.Open("postgres", opts.Dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	if err = connect(ctx, db, opts.ConnectTimeout); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Repository{
+		Db:    db,
+		Hooks: opts.Hooks,
+	}, nil
+}
+
+// NewRepository is Open with a background context. It panics when the
+// database cannot be reached.
+func NewRepository(opts NewRepositoryOptions) *Repository {
+	repo, err := Open(context.Background(), opts)
 	if err != nil {
 		panic(err)
 	}
-	err = db.Ping()
+	return repo
+}
+
+// connect pings db until it answers or timeout elapses.
+func connect(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	backoff := connectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("repository: connect after %d attempts: %w", attempt, err)
+		}
+
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "database not ready, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.Any("error", err),
+		)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("repository: connect: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, connectMaxBackoff)
+	}
+}
+
+// Ping checks that the database answers.
+func (r *Repository) Ping(ctx context.Context) (err error) {
+	ctx, end := r.startQuery(ctx, "Ping", "")
+	defer func() { end(err) }()
+
+	return wrapError("Ping", r.Db.PingContext(ctx))
+}
+
+// inTx runs fn in a transaction, committed when fn succeeds and rolled
+// back otherwise.
+func (r *Repository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.Db.BeginTx(ctx, nil)
 	if err != nil {
-		panic("failed: " + err.Error())
+		return err
 	}
+	defer tx.Rollback()
 
-	return &Repository{
-		Db: db,
+	if err = fn(tx); err != nil {
+		return err
 	}
+	return tx.Commit()
+}
+
+// Close closes the database connections.
+func (r *Repository) Close() error {
+	return r.Db.Close()
 }
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnect(t *testing.T) {
+	initial, max := connectInitialBackoff, connectMaxBackoff
+	connectInitialBackoff, connectMaxBackoff = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { connectInitialBackoff, connectMaxBackoff = initial, max })
+
+	t.Run("retries until the database answers", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		mock.ExpectPing()
+
+		assert.NoError(t, connect(context.Background(), db, time.Second))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("gives up after the timeout", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		defer db.Close()
+
+		for i := 0; i < 100; i++ {
+			mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		}
+
+		err = connect(context.Background(), db, 20*time.Millisecond)
+		assert.ErrorContains(t, err, "connection refused")
+	})
+
+	t.Run("single attempt without timeout", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+		err = connect(context.Background(), db, 0)
+		assert.ErrorContains(t, err, "after 1 attempts")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = connect(ctx, db, time.Minute)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRepository_TenantScoping proves no estate or tree query can run
+// without an organization: the filter builders reject it before anything
+// reaches the database.
+func TestRepository_TenantScoping(t *testing.T) {
+	estateID := uuid.NewString()
+
+	testcases := []struct {
+		name string
+		call func(repo *Repository) error
+	}{
+		{name: "FindEstate", call: func(repo *Repository) error {
+			_, err := repo.FindEstate(context.Background(), &FilterEstate{ID: estateID})
+			return err
+		}},
+		{name: "FindAllMapEstateTree", call: func(repo *Repository) error {
+			_, err := repo.FindAllMapEstateTree(context.Background(), &FilterEstateTree{Filter: Filter{Page: 1, ShowAll: true}, EstateID: estateID})
+			return err
+		}},
+		{name: "FindEstateTree", call: func(repo *Repository) error {
+			_, err := repo.FindEstateTree(context.Background(), &FilterEstateTree{EstateID: estateID, X: 1, Y: 1})
+			return err
+		}},
+		{name: "CountEstateTree", call: func(repo *Repository) error {
+			_, err := repo.CountEstateTree(context.Background(), &FilterEstateTree{EstateID: estateID})
+			return err
+		}},
+		{name: "GetEstateTreeStats", call: func(repo *Repository) error {
+			_, err := repo.GetEstateTreeStats(context.Background(), &FilterEstateTree{EstateID: estateID})
+			return err
+		}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			err = tc.call(&Repository{Db: db})
+			assert.ErrorIs(t, err, ErrInvalidFilter)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepository_setFilterEstateTree(t *testing.T) {
+	repo := &Repository{}
+
+	query, params, err := repo.setFilterEstateTree("Test", GetEstateTreeQuery, &FilterEstateTree{
+		OrganizationID: "org-2",
+		ID:             "tree-1",
+		EstateID:       "estate-1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, GetEstateTreeQuery+" WHERE organization_id = $1 AND id = $2 AND estate_id = $3 AND deleted_at IS NULL", query)
+	assert.Equal(t, []interface{}{"org-2", "tree-1", "estate-1"}, params)
+}
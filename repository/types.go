@@ -22,19 +22,31 @@ func (f *Filter) CalculateOffset() int {
 	return f.Offset
 }
 
-// FilterEstate model
-type FilterEstate struct {
+// FilterOrganization model
+type FilterOrganization struct {
 	Filter
 	ID string
 }
 
-// FilterEstateTree model
+// FilterEstate model. OrganizationID is required: estates are always looked
+// up within a single tenant.
+type FilterEstate struct {
+	Filter
+	ID             string
+	OrganizationID string
+	// After lists the estates created after this one, for FindAllEstate.
+	After string
+}
+
+// FilterEstateTree model. OrganizationID is required: trees are always
+// looked up within a single tenant.
 type FilterEstateTree struct {
 	Filter
-	ID       string
-	EstateID string
-	X        int
-	Y        int
+	ID             string
+	OrganizationID string
+	EstateID       string
+	X              int
+	Y              int
 }
 
 type BaseModel struct {
@@ -44,20 +56,32 @@ type BaseModel struct {
 	DeletedAt *time.Time
 }
 
+// Organization model. Every estate, tree and API key belongs to exactly one
+// organization.
+type Organization struct {
+	BaseModel
+	Name string
+}
+
 // Estate model
 type Estate struct {
 	BaseModel
-	Width  int
-	Length int
+	OrganizationID string
+	Width          int
+	Length         int
+	// Version is bumped by the database on every change to the estate or
+	// its trees.
+	Version int64
 }
 
 // EstateTree model
 type EstateTree struct {
 	BaseModel
-	EstateID string
-	X        int
-	Y        int
-	Height   int
+	OrganizationID string
+	EstateID       string
+	X              int
+	Y              int
+	Height         int
 }
 
 type EstateTreeStats struct {
@@ -70,3 +94,240 @@ type CoordinatePoint struct {
 	X int
 	Y int
 }
+
+// FilterAPIKey model
+type FilterAPIKey struct {
+	Filter
+	ID             string
+	KeyHash        string
+	OrganizationID string
+	ShowRevoked    bool
+}
+
+// APIKey model. Only the SHA-256 hash of the key is stored.
+type APIKey struct {
+	BaseModel
+	OrganizationID string
+	Name           string
+	KeyHash        string
+	Scopes         []string
+	RevokedAt      *time.Time
+}
+
+// FilterIdempotencyKey model. Keys are unique within an organization.
+type FilterIdempotencyKey struct {
+	OrganizationID string
+	Key            string
+}
+
+// IdempotencyKey model. StatusCode is zero while the request it guards is
+// still running.
+type IdempotencyKey struct {
+	OrganizationID string
+	Key            string
+	Method         string
+	Path           string
+	RequestHash    string
+	StatusCode     int
+	ContentType    string
+	ResponseBody   []byte
+	CreatedAt      time.Time
+	CompletedAt    *time.Time
+	ExpiresAt      time.Time
+}
+
+// PlanJobStatus is the lifecycle state of a PlanJob.
+type PlanJobStatus string
+
+const (
+	PlanJobQueued    PlanJobStatus = "queued"
+	PlanJobRunning   PlanJobStatus = "running"
+	PlanJobSucceeded PlanJobStatus = "succeeded"
+	PlanJobFailed    PlanJobStatus = "failed"
+	PlanJobCancelled PlanJobStatus = "cancelled"
+)
+
+// FilterPlanJob model. OrganizationID is required; EstateID, when set,
+// must also match.
+type FilterPlanJob struct {
+	ID             string
+	OrganizationID string
+	EstateID       string
+}
+
+// PlanJob model. A drone plan computed in the background. Attempts counts
+// the claims by workers; the result columns are set once it succeeded.
+type PlanJob struct {
+	BaseModel
+	OrganizationID  string
+	EstateID        string
+	MaxDistance     *int
+	Status          PlanJobStatus
+	Progress        float64
+	CancelRequested bool
+	Attempts        int
+	Distance        *int
+	RestX           *int
+	RestY           *int
+	Error           string
+	StartedAt       *time.Time
+	HeartbeatAt     *time.Time
+	FinishedAt      *time.Time
+}
+
+// Webhook event types.
+const (
+	WebhookEventEstateCreated = "estate.created"
+	WebhookEventTreeCreated   = "tree.created"
+	WebhookEventTreeUpdated   = "tree.updated"
+	WebhookEventTreeDeleted   = "tree.deleted"
+	// WebhookEventTreesImported lists the trees of an import, up to
+	// estateTreesPerInsert per event, instead of a tree.created event each.
+	WebhookEventTreesImported = "trees.imported"
+)
+
+// WebhookEventTypes lists every event a subscription may ask for.
+var WebhookEventTypes = []string{
+	WebhookEventEstateCreated,
+	WebhookEventTreeCreated,
+	WebhookEventTreeUpdated,
+	WebhookEventTreeDeleted,
+	WebhookEventTreesImported,
+}
+
+// FilterWebhookSubscription model. OrganizationID is required.
+type FilterWebhookSubscription struct {
+	ID             string
+	OrganizationID string
+}
+
+// WebhookSubscription model. Secret signs the deliveries to URL of the
+// Events it asks for.
+type WebhookSubscription struct {
+	BaseModel
+	OrganizationID string
+	URL            string
+	Secret         string
+	Events         []string
+}
+
+// WebhookDeliveryStatus is the lifecycle state of a WebhookDelivery.
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryDead deliveries ran out of attempts; they are only
+	// retried when replayed.
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// FilterWebhookDelivery model. OrganizationID and SubscriptionID are
+// required; Status and IDs, when set, must also match.
+type FilterWebhookDelivery struct {
+	Filter
+	OrganizationID string
+	SubscriptionID string
+	Status         WebhookDeliveryStatus
+	IDs            []string
+}
+
+// WebhookDelivery model. The event of a subscription, sent until it is
+// delivered or dead.
+type WebhookDelivery struct {
+	BaseModel
+	OrganizationID string
+	EventID        string
+	SubscriptionID string
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode *int
+	LastError      string
+	DeliveredAt    *time.Time
+	// EventType, Payload and EventCreatedAt describe the event; URL and
+	// Secret come from the subscription. Only EventType is set by
+	// FindAllWebhookDelivery; ClaimWebhookDelivery sets them all.
+	EventType      string
+	Payload        []byte
+	EventCreatedAt time.Time
+	URL            string
+	Secret         string
+}
+
+// Audit actions.
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionRevoke = "revoke"
+	AuditActionCancel = "cancel"
+	AuditActionDelete = "delete"
+	AuditActionReplay = "replay"
+	AuditActionImport = "import"
+)
+
+// Audited entity types.
+const (
+	AuditEntityOrganization        = "organization"
+	AuditEntityEstate              = "estate"
+	AuditEntityTree                = "tree"
+	AuditEntityAPIKey              = "api_key"
+	AuditEntityPlanJob             = "plan_job"
+	AuditEntityWebhookSubscription = "webhook_subscription"
+)
+
+// FilterAuditEntry model. OrganizationID is required; entries are listed
+// newest first, created at or after From and before To when they are set.
+type FilterAuditEntry struct {
+	Filter
+	OrganizationID string
+	EstateID       string
+	Actor          string
+	From           time.Time
+	To             time.Time
+}
+
+// AuditEntry model. A write made by Actor, with the JSON of the entity
+// before and after it; Before is nil for creations and After for deletions.
+type AuditEntry struct {
+	ID             int64
+	OrganizationID string
+	Actor          string
+	Action         string
+	EntityType     string
+	EntityID       string
+	// EstateID is the estate the entity belongs to, if any.
+	EstateID  string
+	Before    []byte
+	After     []byte
+	RequestID string
+	CreatedAt time.Time
+}
+
+// Estate event types.
+const (
+	EstateEventTreeCreated = "tree.created"
+	EstateEventTreeUpdated = "tree.updated"
+	EstateEventTreeDeleted = "tree.deleted"
+)
+
+// FilterEstateEvent model. OrganizationID and EstateID are required; events
+// are listed oldest first, after the AfterVersion of the estate.
+type FilterEstateEvent struct {
+	Filter
+	OrganizationID string
+	EstateID       string
+	AfterVersion   int64
+}
+
+// EstateEvent model. A change to a tree of an estate, recorded by the
+// database as it happens; Version is the version of the estate it produced
+// and Data the JSON of the tree.
+type EstateEvent struct {
+	EstateID       string
+	Version        int64
+	OrganizationID string
+	Type           string
+	Data           []byte
+	CreatedAt      time.Time
+}
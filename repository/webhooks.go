@@ -0,0 +1,343 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// webhookDeliveryColumns lists the columns scanned by scanWebhookDelivery.
+const webhookDeliveryColumns = `id, organization_id, event_id, subscription_id, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at, created_at, updated_at`
+
+const (
+	InsertWebhookSubscriptionQuery = `INSERT INTO webhook_subscriptions (id, organization_id, url, secret, events) VALUES ($1, $2, $3, $4, $5) RETURNING created_at, updated_at`
+	GetWebhookSubscriptionQuery    = `SELECT id, organization_id, url, secret, events, created_at, updated_at FROM webhook_subscriptions`
+	DeleteWebhookSubscriptionQuery = `UPDATE webhook_subscriptions SET deleted_at = NOW(), updated_at = NOW() WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL RETURNING url, events`
+	// InsertWebhookEventQuery writes event $1 of organization $2, of type
+	// $3 with payload $4, and a pending delivery for every subscription
+	// asking for it. Nothing is written when no subscription does.
+	InsertWebhookEventQuery = `WITH subscriptions AS (
+	SELECT id FROM webhook_subscriptions WHERE organization_id = $2::text AND $3::text = ANY (events) AND deleted_at IS NULL
+), event AS (
+	INSERT INTO webhook_events (id, organization_id, type, payload)
+	SELECT $1::text, $2::text, $3::text, $4::jsonb WHERE EXISTS (SELECT 1 FROM subscriptions) RETURNING id
+)
+INSERT INTO webhook_deliveries (id, organization_id, event_id, subscription_id)
+SELECT gen_random_uuid()::text, $2::text, event.id, subscriptions.id FROM event, subscriptions`
+	GetWebhookDeliveryQuery = `SELECT ` + webhookDeliveryColumns + `, (SELECT e.type FROM webhook_events e WHERE e.id = webhook_deliveries.event_id) FROM webhook_deliveries`
+	// ClaimWebhookDeliveryQuery takes the pending delivery due first and
+	// pushes it $1 seconds ahead, so it is retried if its worker dies while
+	// sending. SKIP LOCKED keeps concurrent workers from claiming the same
+	// delivery.
+	ClaimWebhookDeliveryQuery = `UPDATE webhook_deliveries d SET attempts = d.attempts + 1, next_attempt_at = NOW() + make_interval(secs => $1), updated_at = NOW()
+FROM webhook_events e, webhook_subscriptions s
+WHERE d.id = (
+	SELECT pending.id FROM webhook_deliveries pending JOIN webhook_subscriptions subscription ON subscription.id = pending.subscription_id
+	WHERE pending.status = 'pending' AND pending.next_attempt_at <= NOW() AND subscription.deleted_at IS NULL
+	ORDER BY pending.next_attempt_at FOR UPDATE OF pending SKIP LOCKED LIMIT 1
+) AND e.id = d.event_id AND s.id = d.subscription_id
+RETURNING d.id, d.organization_id, d.event_id, d.subscription_id, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at, d.updated_at,
+	e.type, e.payload, e.created_at, s.url, s.secret`
+	// FinishWebhookDeliveryQuery only matches the attempt that claimed the
+	// delivery, so a worker whose lease expired cannot overwrite a retry.
+	FinishWebhookDeliveryQuery = `UPDATE webhook_deliveries SET status = $3::varchar, last_status_code = $4, last_error = $5, next_attempt_at = NOW() + make_interval(secs => $6),
+delivered_at = CASE WHEN $3::varchar = 'delivered' THEN NOW() ELSE NULL END, updated_at = NOW()
+WHERE id = $1 AND attempts = $2 AND status = 'pending'`
+	// ReplayWebhookDeliveriesQuery gives dead deliveries a fresh set of
+	// attempts.
+	ReplayWebhookDeliveriesQuery = `UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()`
+)
+
+// insertWebhookEvent writes an event of eventType with data in tx, for the
+// subscriptions of organizationID asking for it.
+func insertWebhookEvent(ctx context.Context, tx *sql.Tx, organizationID, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, InsertWebhookEventQuery, uuid.NewString(), organizationID, eventType, string(payload))
+	return err
+}
+
+func (r *Repository) CreateWebhookSubscription(ctx context.Context, data *WebhookSubscription) (err error) {
+	ctx, end := r.startQuery(ctx, "CreateWebhookSubscription", "InsertWebhookSubscriptionQuery")
+	defer func() { end(err) }()
+
+	if data.OrganizationID == "" {
+		return invalidFilter("CreateWebhookSubscription", "organization is required")
+	}
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, InsertWebhookSubscriptionQuery, data.ID, data.OrganizationID, data.URL, data.Secret, pq.Array(data.Events)).
+			Scan(&data.CreatedAt, &data.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return insertAuditEntry(ctx, tx, auditRecord{
+			organizationID: data.OrganizationID,
+			action:         AuditActionCreate,
+			entityType:     AuditEntityWebhookSubscription,
+			entityID:       data.ID,
+			after:          webhookSubscriptionData{ID: data.ID, URL: data.URL, Events: data.Events},
+		})
+	})
+	return wrapError("CreateWebhookSubscription", err)
+}
+
+func (r *Repository) FindWebhookSubscription(ctx context.Context, filter *FilterWebhookSubscription) (_ WebhookSubscription, err error) {
+	ctx, end := r.startQuery(ctx, "FindWebhookSubscription", "GetWebhookSubscriptionQuery")
+	defer func() { end(err) }()
+
+	if filter.ID == "" {
+		return WebhookSubscription{}, invalidFilter("FindWebhookSubscription", "id is required")
+	}
+	subscriptions, err := r.findWebhookSubscriptions(ctx, "FindWebhookSubscription", filter)
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+	if len(subscriptions) == 0 {
+		return WebhookSubscription{}, wrapError("FindWebhookSubscription", sql.ErrNoRows)
+	}
+	return subscriptions[0], nil
+}
+
+func (r *Repository) FindAllWebhookSubscription(ctx context.Context, filter *FilterWebhookSubscription) (_ []WebhookSubscription, err error) {
+	ctx, end := r.startQuery(ctx, "FindAllWebhookSubscription", "GetWebhookSubscriptionQuery")
+	defer func() { end(err) }()
+
+	return r.findWebhookSubscriptions(ctx, "FindAllWebhookSubscription", filter)
+}
+
+func (r *Repository) findWebhookSubscriptions(ctx context.Context, op string, filter *FilterWebhookSubscription) ([]WebhookSubscription, error) {
+	if filter.OrganizationID == "" {
+		return nil, invalidFilter(op, "organization is required")
+	}
+	where := []string{"organization_id = $1", "deleted_at IS NULL"}
+	paramValue := []interface{}{filter.OrganizationID}
+	if filter.ID != "" {
+		where = append(where, "id = $"+strconv.Itoa(len(paramValue)+1))
+		paramValue = append(paramValue, filter.ID)
+	}
+	finalQuery := GetWebhookSubscriptionQuery + " WHERE " + strings.Join(where, " AND ") + " ORDER BY created_at ASC"
+
+	rows, err := r.Db.QueryContext(ctx, finalQuery, paramValue...)
+	if err != nil {
+		return nil, wrapError(op, err)
+	}
+	defer closeRows(ctx, op, rows)
+
+	var result []WebhookSubscription
+	for rows.Next() {
+		var subscription WebhookSubscription
+		if err = rows.Scan(&subscription.ID, &subscription.OrganizationID, &subscription.URL, &subscription.Secret,
+			pq.Array(&subscription.Events), &subscription.CreatedAt, &subscription.UpdatedAt); err != nil {
+			return nil, wrapError(op, err)
+		}
+		result = append(result, subscription)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapError(op, err)
+	}
+	return result, nil
+}
+
+// DeleteWebhookSubscription stops the deliveries of a subscription,
+// including the pending ones.
+func (r *Repository) DeleteWebhookSubscription(ctx context.Context, filter *FilterWebhookSubscription) (err error) {
+	ctx, end := r.startQuery(ctx, "DeleteWebhookSubscription", "DeleteWebhookSubscriptionQuery")
+	defer func() { end(err) }()
+
+	if filter.OrganizationID == "" {
+		return invalidFilter("DeleteWebhookSubscription", "organization is required")
+	}
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		deleted := webhookSubscriptionData{ID: filter.ID}
+		err := tx.QueryRowContext(ctx, DeleteWebhookSubscriptionQuery, filter.OrganizationID, filter.ID).
+			Scan(&deleted.URL, pq.Array(&deleted.Events))
+		if err != nil {
+			return err
+		}
+		return insertAuditEntry(ctx, tx, auditRecord{
+			organizationID: filter.OrganizationID,
+			action:         AuditActionDelete,
+			entityType:     AuditEntityWebhookSubscription,
+			entityID:       filter.ID,
+			before:         deleted,
+		})
+	})
+	return wrapError("DeleteWebhookSubscription", err)
+}
+
+func scanWebhookDelivery(row rowScanner, extra ...interface{}) (WebhookDelivery, error) {
+	var (
+		delivery   WebhookDelivery
+		statusCode sql.NullInt64
+		lastError  sql.NullString
+	)
+	dest := append([]interface{}{&delivery.ID, &delivery.OrganizationID, &delivery.EventID, &delivery.SubscriptionID,
+		&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &statusCode, &lastError, &delivery.DeliveredAt,
+		&delivery.CreatedAt, &delivery.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return WebhookDelivery{}, err
+	}
+	delivery.LastStatusCode = nullInt(statusCode)
+	delivery.LastError = lastError.String
+	return delivery, nil
+}
+
+// FindAllWebhookDelivery returns the deliveries of a subscription, newest
+// first.
+func (r *Repository) FindAllWebhookDelivery(ctx context.Context, filter *FilterWebhookDelivery) (_ []WebhookDelivery, err error) {
+	ctx, end := r.startQuery(ctx, "FindAllWebhookDelivery", "GetWebhookDeliveryQuery")
+	defer func() { end(err) }()
+
+	where, paramValue, err := setFilterWebhookDelivery("FindAllWebhookDelivery", filter)
+	if err != nil {
+		return nil, err
+	}
+	finalQuery := GetWebhookDeliveryQuery + where + " ORDER BY created_at DESC"
+	if filter.Limit > 0 {
+		finalQuery += " LIMIT $" + strconv.Itoa(len(paramValue)+1)
+		paramValue = append(paramValue, filter.Limit)
+	}
+
+	rows, err := r.Db.QueryContext(ctx, finalQuery, paramValue...)
+	if err != nil {
+		return nil, wrapError("FindAllWebhookDelivery", err)
+	}
+	defer closeRows(ctx, "FindAllWebhookDelivery", rows)
+
+	var result []WebhookDelivery
+	for rows.Next() {
+		var eventType string
+		delivery, err := scanWebhookDelivery(rows, &eventType)
+		if err != nil {
+			return nil, wrapError("FindAllWebhookDelivery", err)
+		}
+		delivery.EventType = eventType
+		result = append(result, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapError("FindAllWebhookDelivery", err)
+	}
+	return result, nil
+}
+
+// setFilterWebhookDelivery builds the WHERE clause for filter, refusing
+// filters without an organization and a subscription.
+func setFilterWebhookDelivery(op string, filter *FilterWebhookDelivery) (string, []interface{}, error) {
+	if filter.OrganizationID == "" {
+		return "", nil, invalidFilter(op, "organization is required")
+	}
+	if filter.SubscriptionID == "" {
+		return "", nil, invalidFilter(op, "subscription is required")
+	}
+
+	where := []string{"organization_id = $1", "subscription_id = $2"}
+	paramValue := []interface{}{filter.OrganizationID, filter.SubscriptionID}
+	if filter.Status != "" {
+		where = append(where, "status = $"+strconv.Itoa(len(paramValue)+1))
+		paramValue = append(paramValue, filter.Status)
+	}
+	if len(filter.IDs) > 0 {
+		where = append(where, "id = ANY ($"+strconv.Itoa(len(paramValue)+1)+")")
+		paramValue = append(paramValue, pq.Array(filter.IDs))
+	}
+	return " WHERE " + strings.Join(where, " AND "), paramValue, nil
+}
+
+// ClaimWebhookDelivery takes the pending delivery due first, with its
+// event and subscription, and counts the attempt. It is retried after
+// lease unless FinishWebhookDelivery is called first. ErrNotFound means
+// nothing is due.
+func (r *Repository) ClaimWebhookDelivery(ctx context.Context, lease time.Duration) (_ WebhookDelivery, err error) {
+	ctx, end := r.startQuery(ctx, "ClaimWebhookDelivery", "ClaimWebhookDeliveryQuery")
+	defer func() { end(err) }()
+
+	var delivery WebhookDelivery
+	var payload []byte
+	var eventType, url, secret string
+	var eventCreatedAt time.Time
+	delivery, err = scanWebhookDelivery(r.Db.QueryRowContext(ctx, ClaimWebhookDeliveryQuery, lease.Seconds()),
+		&eventType, &payload, &eventCreatedAt, &url, &secret)
+	if err != nil {
+		return WebhookDelivery{}, wrapError("ClaimWebhookDelivery", err)
+	}
+	delivery.EventType, delivery.Payload, delivery.EventCreatedAt = eventType, payload, eventCreatedAt
+	delivery.URL, delivery.Secret = url, secret
+	return delivery, nil
+}
+
+// FinishWebhookDelivery records the outcome of the attempt that claimed
+// data: its Status, LastStatusCode and LastError. A pending delivery is
+// retried after retryAfter. ErrNotFound means the attempt was taken over.
+func (r *Repository) FinishWebhookDelivery(ctx context.Context, data *WebhookDelivery, retryAfter time.Duration) (err error) {
+	ctx, end := r.startQuery(ctx, "FinishWebhookDelivery", "FinishWebhookDeliveryQuery")
+	defer func() { end(err) }()
+
+	lastError := sql.NullString{String: data.LastError, Valid: data.LastError != ""}
+	result, err := r.Db.ExecContext(ctx, FinishWebhookDeliveryQuery, data.ID, data.Attempts, data.Status,
+		data.LastStatusCode, lastError, retryAfter.Seconds())
+	if err != nil {
+		return wrapError("FinishWebhookDelivery", err)
+	}
+	updated, err := result.RowsAffected()
+	if err == nil && updated == 0 {
+		err = sql.ErrNoRows
+	}
+	return wrapError("FinishWebhookDelivery", err)
+}
+
+// ReplayWebhookDeliveries sends the dead deliveries matching filter again,
+// with a fresh set of attempts, and returns how many there were.
+func (r *Repository) ReplayWebhookDeliveries(ctx context.Context, filter *FilterWebhookDelivery) (_ int64, err error) {
+	ctx, end := r.startQuery(ctx, "ReplayWebhookDeliveries", "ReplayWebhookDeliveriesQuery")
+	defer func() { end(err) }()
+
+	where, paramValue, err := setFilterWebhookDelivery("ReplayWebhookDeliveries", &FilterWebhookDelivery{
+		OrganizationID: filter.OrganizationID,
+		SubscriptionID: filter.SubscriptionID,
+		Status:         WebhookDeliveryDead,
+		IDs:            filter.IDs,
+	})
+	if err != nil {
+		return 0, err
+	}
+	var replayed []string
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, ReplayWebhookDeliveriesQuery+where+" RETURNING id", paramValue...)
+		if err != nil {
+			return err
+		}
+		defer closeRows(ctx, "ReplayWebhookDeliveries", rows)
+		for rows.Next() {
+			var id string
+			if err = rows.Scan(&id); err != nil {
+				return err
+			}
+			replayed = append(replayed, id)
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		if len(replayed) == 0 {
+			return nil
+		}
+		return insertAuditEntry(ctx, tx, auditRecord{
+			organizationID: filter.OrganizationID,
+			action:         AuditActionReplay,
+			entityType:     AuditEntityWebhookSubscription,
+			entityID:       filter.SubscriptionID,
+			after:          map[string][]string{"delivery_ids": replayed},
+		})
+	})
+	if err != nil {
+		return 0, wrapError("ReplayWebhookDeliveries", err)
+	}
+	return int64(len(replayed)), nil
+}
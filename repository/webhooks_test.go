@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var webhookDeliveryColumnNames = []string{"id", "organization_id", "event_id", "subscription_id", "status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at", "created_at", "updated_at"}
+
+func TestRepository_CreateEstate_WebhookEventFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO estates").WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAuditEntry(mock, testOrganizationID, AuditActionCreate, AuditEntityEstate, "estate-1", "estate-1", nil, sqlmock.AnyArg())
+	mock.ExpectExec("INSERT INTO webhook_events").WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	err = repo.CreateEstate(context.Background(), &Estate{BaseModel: BaseModel{ID: "estate-1"}, OrganizationID: testOrganizationID, Width: 1, Length: 1})
+	assert.ErrorIs(t, err, assert.AnError, "the estate is not created without its event")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_CreateWebhookSubscription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO webhook_subscriptions").
+		WithArgs("sub-1", "org-1", "https://erp.example/hook", "secret", pq.Array([]string{WebhookEventTreeCreated})).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now))
+	expectAuditEntry(mock, "org-1", AuditActionCreate, AuditEntityWebhookSubscription, "sub-1", "",
+		nil, `{"id":"sub-1","url":"https://erp.example/hook","events":["tree.created"]}`)
+	mock.ExpectCommit()
+
+	subscription := &WebhookSubscription{
+		BaseModel:      BaseModel{ID: "sub-1"},
+		OrganizationID: "org-1",
+		URL:            "https://erp.example/hook",
+		Secret:         "secret",
+		Events:         []string{WebhookEventTreeCreated},
+	}
+	assert.NoError(t, repo.CreateWebhookSubscription(context.Background(), subscription))
+	assert.Equal(t, now, subscription.CreatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	err = repo.CreateWebhookSubscription(context.Background(), &WebhookSubscription{BaseModel: BaseModel{ID: "sub-2"}})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func TestRepository_FindWebhookSubscription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	now := time.Now()
+	columns := []string{"id", "organization_id", "url", "secret", "events", "created_at", "updated_at"}
+	mock.ExpectQuery("SELECT .* FROM webhook_subscriptions WHERE organization_id = \\$1 AND deleted_at IS NULL AND id = \\$2").
+		WithArgs("org-1", "sub-1").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("sub-1", "org-1", "https://erp.example/hook", "secret", "{estate.created,tree.created}", now, now))
+	mock.ExpectQuery("SELECT .* FROM webhook_subscriptions").
+		WithArgs("org-1", "missing").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	subscription, err := repo.FindWebhookSubscription(context.Background(), &FilterWebhookSubscription{ID: "sub-1", OrganizationID: "org-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{WebhookEventEstateCreated, WebhookEventTreeCreated}, subscription.Events)
+
+	_, err = repo.FindWebhookSubscription(context.Background(), &FilterWebhookSubscription{ID: "missing", OrganizationID: "org-1"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = repo.FindAllWebhookSubscription(context.Background(), &FilterWebhookSubscription{})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func TestRepository_DeleteWebhookSubscription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	columns := []string{"url", "events"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE webhook_subscriptions SET deleted_at").WithArgs("org-1", "sub-1").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("https://erp.example/hook", "{tree.created}"))
+	expectAuditEntry(mock, "org-1", AuditActionDelete, AuditEntityWebhookSubscription, "sub-1", "",
+		`{"id":"sub-1","url":"https://erp.example/hook","events":["tree.created"]}`, nil)
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE webhook_subscriptions SET deleted_at").WithArgs("org-1", "sub-1").WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectRollback()
+
+	filter := &FilterWebhookSubscription{ID: "sub-1", OrganizationID: "org-1"}
+	assert.NoError(t, repo.DeleteWebhookSubscription(context.Background(), filter))
+	assert.ErrorIs(t, repo.DeleteWebhookSubscription(context.Background(), filter), ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_FindAllWebhookDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT .* FROM webhook_deliveries WHERE organization_id = \\$1 AND subscription_id = \\$2 AND status = \\$3 ORDER BY created_at DESC LIMIT \\$4").
+		WithArgs("org-1", "sub-1", WebhookDeliveryDead, 10).
+		WillReturnRows(sqlmock.NewRows(append(append([]string{}, webhookDeliveryColumnNames...), "type")).
+			AddRow("delivery-1", "org-1", "event-1", "sub-1", "dead", 8, now, 500, "500 Internal Server Error", nil, now, now, WebhookEventEstateCreated))
+
+	deliveries, err := repo.FindAllWebhookDelivery(context.Background(), &FilterWebhookDelivery{
+		Filter:         Filter{Limit: 10},
+		OrganizationID: "org-1",
+		SubscriptionID: "sub-1",
+		Status:         WebhookDeliveryDead,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, 500, *deliveries[0].LastStatusCode)
+	assert.Equal(t, "500 Internal Server Error", deliveries[0].LastError)
+	assert.Equal(t, WebhookEventEstateCreated, deliveries[0].EventType)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = repo.FindAllWebhookDelivery(context.Background(), &FilterWebhookDelivery{OrganizationID: "org-1"})
+	assert.ErrorIs(t, err, ErrInvalidFilter, "the subscription is required")
+}
+
+func TestRepository_ClaimWebhookDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	now := time.Now()
+	columns := append(append([]string{}, webhookDeliveryColumnNames...), "type", "payload", "created_at", "url", "secret")
+	mock.ExpectQuery("UPDATE webhook_deliveries d SET attempts = d.attempts \\+ 1").
+		WithArgs(30.0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("delivery-1", "org-1", "event-1", "sub-1", "pending", 1, now, nil, nil, nil, now, now,
+				WebhookEventTreeCreated, []byte(`{"id":"tree-1"}`), now, "https://erp.example/hook", "secret"))
+	mock.ExpectQuery("UPDATE webhook_deliveries d").WillReturnRows(sqlmock.NewRows(columns))
+
+	delivery, err := repo.ClaimWebhookDelivery(context.Background(), 30*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, WebhookEventTreeCreated, delivery.EventType)
+	assert.JSONEq(t, `{"id":"tree-1"}`, string(delivery.Payload))
+	assert.Equal(t, "https://erp.example/hook", delivery.URL)
+	assert.Equal(t, "secret", delivery.Secret)
+	assert.Nil(t, delivery.LastStatusCode)
+
+	_, err = repo.ClaimWebhookDelivery(context.Background(), 30*time.Second)
+	assert.ErrorIs(t, err, ErrNotFound, "nothing is due")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_FinishWebhookDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	statusCode := 503
+	delivery := &WebhookDelivery{
+		BaseModel:      BaseModel{ID: "delivery-1"},
+		Status:         WebhookDeliveryPending,
+		Attempts:       2,
+		LastStatusCode: &statusCode,
+		LastError:      "503 Service Unavailable",
+	}
+	mock.ExpectExec("UPDATE webhook_deliveries SET status").
+		WithArgs("delivery-1", 2, WebhookDeliveryPending, &statusCode, "503 Service Unavailable", 40.0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE webhook_deliveries SET status").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.FinishWebhookDelivery(context.Background(), delivery, 40*time.Second))
+	assert.ErrorIs(t, repo.FinishWebhookDelivery(context.Background(), delivery, 40*time.Second), ErrNotFound,
+		"the attempt was taken over")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_ReplayWebhookDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := &Repository{Db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE webhook_deliveries SET status = 'pending', attempts = 0, .* WHERE organization_id = \\$1 AND subscription_id = \\$2 AND status = \\$3 AND id = ANY \\(\\$4\\) RETURNING id").
+		WithArgs("org-1", "sub-1", WebhookDeliveryDead, pq.Array([]string{"delivery-1", "delivery-2"})).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("delivery-1"))
+	expectAuditEntry(mock, "org-1", AuditActionReplay, AuditEntityWebhookSubscription, "sub-1", "", nil, `{"delivery_ids":["delivery-1"]}`)
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE webhook_deliveries SET status = 'pending'").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	replayed, err := repo.ReplayWebhookDeliveries(context.Background(), &FilterWebhookDelivery{
+		OrganizationID: "org-1",
+		SubscriptionID: "sub-1",
+		Status:         WebhookDeliveryPending,
+		IDs:            []string{"delivery-1", "delivery-2"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), replayed, "delivery-2 is not dead")
+
+	replayed, err = repo.ReplayWebhookDeliveries(context.Background(), &FilterWebhookDelivery{OrganizationID: "org-1", SubscriptionID: "sub-1"})
+	assert.NoError(t, err)
+	assert.Zero(t, replayed, "nothing to replay, nothing audited")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
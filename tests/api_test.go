@@ -1,50 +1,89 @@
 package tests
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
-
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/client"
+	"github.com/dimassantoso/drone-sawit/docs"
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/dimassantoso/drone-sawit/handler"
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/repository"
 	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/require"
 )
 
-const ApiUrl = "http://localhost:8080"
+const testAPIKey = "dsk_test"
 
-func TestApi(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skip API tests")
-	}
+// Deprecation of the unprefixed routes served by newTestEcho.
+var (
+	testDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	testSunset       = time.Date(2027, time.April, 18, 0, 0, 0, 0, time.UTC)
+)
 
-	testcases := getTestCases()
-	ctx := context.Background()
-	client := &http.Client{}
+// newTestEcho routes the API with the handlers, docs, error handler,
+// authentication and request validation of the server, backed by repo, with
+// the deprecated unprefixed routes.
+func newTestEcho(t *testing.T, repo repository.RepositoryInterface) *echo.Echo {
+	swagger, err := generated.GetSwagger()
+	require.NoError(t, err)
+	apiDocs, err := docs.New(swagger)
+	require.NoError(t, err)
 
-	for _, tc := range testcases {
-		t.Run(tc.Name, func(t *testing.T) {
-			for idx := range tc.Steps {
-				step := &tc.Steps[idx]
-				request, err := step.Request(t, ctx, &tc)
-				request.Header.Set("Content-Type", "application/json")
-				request.Header.Set("Accept", "application/json")
-				require.NoError(t, err)
+	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	require.NoError(t, handler.RegisterRoutes(e, handler.NewServer(handler.NewServerOptions{Repository: repo}), handler.RouteOptions{
+		Unprefixed: &handler.Deprecation{Date: testDeprecatedAt, Sunset: testSunset},
+	}))
+	apiDocs.Register(e)
+	e.Use(logging.RequestID())
+	e.Use(auth.Middleware(auth.Config{
+		Skipper:        docs.IsDocs,
+		Authenticators: []auth.Authenticator{auth.NewAPIKeyAuthenticator(repo)},
+	}))
+	return e
+}
 
-				// Send request
-				response, err := client.Do(request)
+// newTestServer serves newTestEcho over HTTP.
+func newTestServer(t *testing.T, repo repository.RepositoryInterface) *httptest.Server {
+	server := httptest.NewServer(newTestEcho(t, repo))
+	t.Cleanup(server.Close)
+	return server
+}
 
-				require.NoError(t, err)
-				defer response.Body.Close()
+func TestApi(t *testing.T) {
+	repo := newMemoryRepository(repository.APIKey{
+		BaseModel:      repository.BaseModel{ID: "key-1"},
+		OrganizationID: "org-1",
+		KeyHash:        auth.HashAPIKey(testAPIKey),
+		Scopes:         []string{string(auth.ScopeRead), string(auth.ScopeWrite), string(auth.ScopePlan)},
+	})
+	server := newTestServer(t, repo)
+	c, err := client.New(client.Options{BaseURL: server.URL, APIKey: testAPIKey, MaxAttempts: 1})
+	require.NoError(t, err)
 
-				// Check response
-				ReadJsonResult(t, response, step)
-				step.Expect(t, ctx, &tc, response, step.Result)
+	ctx := context.Background()
+	for _, tc := range getTestCases() {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Client = c
+			for _, step := range tc.Steps {
+				require.NoError(t, step(t, ctx, &tc))
 			}
 		})
 	}
+
+	t.Run("Test Error: Missing Credentials", func(t *testing.T) {
+		anonymous, err := client.New(client.Options{BaseURL: server.URL, MaxAttempts: 1})
+		require.NoError(t, err)
+		_, err = anonymous.CreateEstate(ctx, generated.EstateRequest{Length: 10, Width: 10})
+		require.ErrorIs(t, err, client.ErrUnauthorized)
+	})
 }
 
 func getTestCases() []TestCase {
@@ -53,34 +92,25 @@ func getTestCases() []TestCase {
 		{
 			Name: "Test Error 1",
 			Steps: []TestCaseStep{
-				{
-					Request: func(t *testing.T, ctx context.Context, tc *TestCase) (*http.Request, error) {
-						return http.NewRequest("POST", ApiUrl+"/estate", nil)
-					},
-					Expect: ExpectBadRequest(),
+				func(t *testing.T, ctx context.Context, tc *TestCase) error {
+					res, err := tc.Client.API().PostEstateWithBodyWithResponse(ctx, "application/json", nil)
+					require.NoError(t, err)
+					require.Equal(t, http.StatusBadRequest, res.StatusCode())
+					return nil
 				},
 			},
 		},
 		{
 			Name: "Test Error 2: Invalid Format",
 			Steps: []TestCaseStep{
-				{
-					Request: SendRequestNewEstate(-1, -5),
-					Expect:  ExpectBadRequest(),
-				},
+				ExpectBadRequest(SendRequestNewEstate(-1, -5)),
 			},
 		},
 		{
 			Name: "Test Error: Create Tree Out of Bound",
 			Steps: []TestCaseStep{
-				{
-					Request: SendRequestNewEstate(10, 20),
-					Expect:  ExpectNewEstateOk(),
-				},
-				{
-					Request: SendRequestNewTree(5, 0, 0),
-					Expect:  ExpectBadRequest(),
-				},
+				SendRequestNewEstate(10, 20),
+				ExpectBadRequest(SendRequestNewTree(5, 0, 0)),
 			},
 		},
 		CreateNormalTestCase("Normal 1", []any{
@@ -100,32 +130,17 @@ func getTestCases() []TestCase {
 }
 
 type TestCase struct {
-	Name  string
-	Steps []TestCaseStep
-}
-
-type RequestFunc func(*testing.T, context.Context, *TestCase) (*http.Request, error)
-type ExpectFunc func(*testing.T, context.Context, *TestCase, *http.Response, map[string]any)
-
-type TestCaseStep struct {
-	Request RequestFunc
-	Expect  ExpectFunc
-	Result  map[string]any
-}
-
-func ResponseContains(t *testing.T, resp *http.Response, text string) {
-	body, err := io.ReadAll(resp.Body)
-	bodyStr := string(body)
-	require.NoError(t, err)
-	require.Contains(t, bodyStr, text)
+	Name   string
+	Steps  []TestCaseStep
+	Client *client.Client
+	// EstateID is the estate created by the first step.
+	EstateID string
 }
 
-func ReadJsonResult(t *testing.T, resp *http.Response, step *TestCaseStep) {
-	var result map[string]any
-	err := json.NewDecoder(resp.Body).Decode(&result)
-	step.Result = result
-	require.NoError(t, err)
-}
+// TestCaseStep sends a request and checks its response. It returns the
+// error of a failed request, which fails the test case unless the step is
+// wrapped with ExpectBadRequest.
+type TestCaseStep func(*testing.T, context.Context, *TestCase) error
 
 func RequireIsUUID(t *testing.T, value string) {
 	_, err := uuid.Parse(value)
@@ -146,122 +161,82 @@ func CreateNormalTestCase(name string, a []any) TestCase {
 	for _, step := range a {
 		switch step.([]any)[0].(int) {
 		case CreateEstate:
-			tc.Steps = append(tc.Steps, TestCaseStep{
-				Request: SendRequestNewEstate(step.([]any)[1].(int), step.([]any)[2].(int)),
-				Expect:  ExpectNewEstateOk(),
-			})
+			tc.Steps = append(tc.Steps, SendRequestNewEstate(step.([]any)[1].(int), step.([]any)[2].(int)))
 		case CreateTree:
-			tc.Steps = append(tc.Steps, TestCaseStep{
-				Request: SendRequestNewTree(step.([]any)[1].(int), step.([]any)[2].(int), step.([]any)[3].(int)),
-				Expect:  ExpectNewTreeOk(),
-			})
+			tc.Steps = append(tc.Steps, SendRequestNewTree(step.([]any)[1].(int), step.([]any)[2].(int), step.([]any)[3].(int)))
 		case GetStats:
-			tc.Steps = append(tc.Steps, TestCaseStep{
-				Request: SendRequestGetStats(),
-				Expect:  ExpectGetStatsOk(step.([]any)[1].(int), step.([]any)[2].(int), step.([]any)[3].(int), step.([]any)[4].(int)),
-			})
+			tc.Steps = append(tc.Steps, ExpectGetStatsOk(step.([]any)[1].(int), step.([]any)[2].(int), step.([]any)[3].(int), step.([]any)[4].(int)))
 		case GetDronePlan:
-			tc.Steps = append(tc.Steps, TestCaseStep{
-				Request: SendRequestGetDronePlan(step.([]any)[1].(int)),
-				Expect:  ExpectGetDronePlanOk(step.([]any)[2].(int)),
-			})
+			tc.Steps = append(tc.Steps, ExpectGetDronePlanOk(step.([]any)[1].(int), step.([]any)[2].(int)))
 		}
 
 	}
 	return tc
 }
 
-func SendRequestNewEstate(length, width int) RequestFunc {
-	return func(t *testing.T, ctx context.Context, tc *TestCase) (*http.Request, error) {
-		req := map[string]int{
-			"length": length,
-			"width":  width,
+// SendRequestNewEstate creates an estate, which later steps use.
+func SendRequestNewEstate(length, width int) TestCaseStep {
+	return func(t *testing.T, ctx context.Context, tc *TestCase) error {
+		estate, err := tc.Client.CreateEstate(ctx, generated.EstateRequest{Length: length, Width: width})
+		if err != nil {
+			return err
 		}
-		body, err := json.Marshal(req)
-		require.NoError(t, err)
-		return http.NewRequest("POST", ApiUrl+"/estate", bytes.NewReader(body))
+		RequireIsUUID(t, estate.Id)
+		tc.EstateID = estate.Id
+		return nil
 	}
 }
 
-func ExpectNewEstateOk() ExpectFunc {
-	return func(t *testing.T, ctx context.Context, tc *TestCase, resp *http.Response, data map[string]any) {
-		RequireReturnIsUUID(t, resp, data)
-	}
-}
-
-func SendRequestNewTree(height, x, y int) RequestFunc {
-	return func(t *testing.T, ctx context.Context, tc *TestCase) (*http.Request, error) {
-		req := map[string]int{
-			"height": height,
-			"x":      x,
-			"y":      y,
+func SendRequestNewTree(height, x, y int) TestCaseStep {
+	return func(t *testing.T, ctx context.Context, tc *TestCase) error {
+		tree, err := tc.Client.CreateTree(ctx, tc.EstateID, generated.EstateTreeRequest{Height: height, X: x, Y: y})
+		if err != nil {
+			return err
 		}
-		id := tc.Steps[0].Result["id"].(string)
-		body, err := json.Marshal(req)
-		require.NoError(t, err)
-		return http.NewRequest("POST", ApiUrl+"/estate/"+id+"/tree", bytes.NewReader(body))
-	}
-}
-
-func ExpectNewTreeOk() ExpectFunc {
-	return func(t *testing.T, ctx context.Context, tc *TestCase, resp *http.Response, data map[string]any) {
-		RequireReturnIsUUID(t, resp, data)
-	}
-}
-
-func SendRequestGetStats() RequestFunc {
-	return func(t *testing.T, ctx context.Context, tc *TestCase) (*http.Request, error) {
-		id := tc.Steps[0].Result["id"].(string)
-		return http.NewRequest("GET", ApiUrl+"/estate/"+id+"/stats", nil)
+		RequireIsUUID(t, tree.Id)
+		return nil
 	}
 }
 
-func ExpectGetStatsOk(count, min, max, median int) ExpectFunc {
-	return func(t *testing.T, ctx context.Context, tc *TestCase, resp *http.Response, data map[string]any) {
-		RequireStats(t, resp, data, count, min, max, median)
-	}
-}
-
-func SendRequestGetDronePlan(distance int) RequestFunc {
-	return func(t *testing.T, ctx context.Context, tc *TestCase) (*http.Request, error) {
-		id := tc.Steps[0].Result["id"].(string)
-		var url string
-
-		if distance == 0 {
-			url = fmt.Sprintf("%s/estate/%s/drone-plan", ApiUrl, id)
-		} else {
-			url = fmt.Sprintf("%s/estate/%s/drone-plan?distance=%d", ApiUrl, id, distance)
+func ExpectGetStatsOk(count, min, max, median int) TestCaseStep {
+	return func(t *testing.T, ctx context.Context, tc *TestCase) error {
+		stats, err := tc.Client.GetStats(ctx, tc.EstateID)
+		if err != nil {
+			return err
 		}
-		return http.NewRequest("GET", url, nil)
+		require.Equal(t, count, stats.Count)
+		require.Equal(t, min, stats.Min)
+		require.Equal(t, max, stats.Max)
+		require.Equal(t, median, int(stats.Median))
+		return nil
 	}
 }
 
-func ExpectGetDronePlanOk(distance int) ExpectFunc {
-	return func(t *testing.T, ctx context.Context, tc *TestCase, resp *http.Response, data map[string]any) {
-		RequireDistance(t, resp, data, distance)
+// ExpectGetDronePlanOk requests the drone plan, stopping after maxDistance
+// unless it is 0.
+func ExpectGetDronePlanOk(maxDistance, distance int) TestCaseStep {
+	return func(t *testing.T, ctx context.Context, tc *TestCase) error {
+		var limit *int
+		if maxDistance != 0 {
+			limit = &maxDistance
+		}
+		plan, err := tc.Client.GetDronePlan(ctx, tc.EstateID, limit)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, distance, plan.Distance)
+		return nil
 	}
 }
 
-func RequireReturnIsUUID(t *testing.T, resp *http.Response, data map[string]any) {
-	require.Equal(t, http.StatusCreated, resp.StatusCode)
-	RequireIsUUID(t, data["id"].(string))
-}
-
-func RequireStats(t *testing.T, resp *http.Response, data map[string]any, count, min, max, median int) {
-	require.Equal(t, http.StatusOK, resp.StatusCode)
-	require.Equal(t, count, int(data["count"].(float64)))
-	require.Equal(t, min, int(data["min"].(float64)))
-	require.Equal(t, max, int(data["max"].(float64)))
-	require.Equal(t, median, int(data["median"].(float64)))
-}
-
-func RequireDistance(t *testing.T, resp *http.Response, data map[string]any, distance int) {
-	require.Equal(t, http.StatusOK, resp.StatusCode)
-	require.Equal(t, distance, int(data["distance"].(float64)))
-}
-
-func ExpectBadRequest() ExpectFunc {
-	return func(t *testing.T, ctx context.Context, tc *TestCase, resp *http.Response, data map[string]any) {
-		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+// ExpectBadRequest runs step, which must fail with 400 Bad Request.
+func ExpectBadRequest(step TestCaseStep) TestCaseStep {
+	return func(t *testing.T, ctx context.Context, tc *TestCase) error {
+		err := step(t, ctx, tc)
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		require.ErrorIs(t, err, client.ErrInvalidRequest)
+		return nil
 	}
 }
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/dimassantoso/drone-sawit/repository"
+)
+
+// memoryRepository keeps the estates and trees of the API tests in memory.
+// Only what the tested endpoints use is implemented: other methods panic.
+type memoryRepository struct {
+	repository.RepositoryInterface
+
+	mu      sync.Mutex
+	apiKeys map[string]repository.APIKey
+	estates map[string]repository.Estate
+	trees   map[string][]repository.EstateTree
+}
+
+func newMemoryRepository(apiKeys ...repository.APIKey) *memoryRepository {
+	r := &memoryRepository{
+		apiKeys: map[string]repository.APIKey{},
+		estates: map[string]repository.Estate{},
+		trees:   map[string][]repository.EstateTree{},
+	}
+	for _, key := range apiKeys {
+		r.apiKeys[key.KeyHash] = key
+	}
+	return r
+}
+
+func (r *memoryRepository) FindAPIKey(_ context.Context, filter *repository.FilterAPIKey) (repository.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.apiKeys[filter.KeyHash]
+	if !ok {
+		return repository.APIKey{}, repository.ErrNotFound
+	}
+	return key, nil
+}
+
+func (r *memoryRepository) CreateEstate(_ context.Context, data *repository.Estate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.estates[data.ID] = *data
+	return nil
+}
+
+func (r *memoryRepository) FindEstate(_ context.Context, filter *repository.FilterEstate) (repository.Estate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	estate, ok := r.estates[filter.ID]
+	if !ok || estate.OrganizationID != filter.OrganizationID {
+		return repository.Estate{}, repository.ErrNotFound
+	}
+	return estate, nil
+}
+
+func (r *memoryRepository) FindAllEstate(_ context.Context, filter *repository.FilterEstate) ([]repository.Estate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var estates []repository.Estate
+	for _, estate := range r.estates {
+		if estate.OrganizationID == filter.OrganizationID {
+			estates = append(estates, estate)
+		}
+	}
+	sort.Slice(estates, func(i, j int) bool { return estates[i].ID < estates[j].ID })
+	return estates, nil
+}
+
+func (r *memoryRepository) CreateEstateTree(_ context.Context, data *repository.EstateTree) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tree := range r.trees[data.EstateID] {
+		if tree.X == data.X && tree.Y == data.Y {
+			return repository.ErrConflict
+		}
+	}
+	r.trees[data.EstateID] = append(r.trees[data.EstateID], *data)
+	estate := r.estates[data.EstateID]
+	estate.Version++
+	r.estates[data.EstateID] = estate
+	return nil
+}
+
+// estateTrees returns the trees matching filter.
+func (r *memoryRepository) estateTrees(filter *repository.FilterEstateTree) []repository.EstateTree {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var trees []repository.EstateTree
+	for _, tree := range r.trees[filter.EstateID] {
+		if tree.OrganizationID != filter.OrganizationID ||
+			(filter.X != 0 && tree.X != filter.X) || (filter.Y != 0 && tree.Y != filter.Y) {
+			continue
+		}
+		trees = append(trees, tree)
+	}
+	return trees
+}
+
+func (r *memoryRepository) FindEstateTree(_ context.Context, filter *repository.FilterEstateTree) (repository.EstateTree, error) {
+	trees := r.estateTrees(filter)
+	if len(trees) == 0 {
+		return repository.EstateTree{}, repository.ErrNotFound
+	}
+	return trees[0], nil
+}
+
+func (r *memoryRepository) FindAllMapEstateTree(_ context.Context, filter *repository.FilterEstateTree) (map[repository.CoordinatePoint]repository.EstateTree, error) {
+	trees := map[repository.CoordinatePoint]repository.EstateTree{}
+	for _, tree := range r.estateTrees(filter) {
+		trees[repository.CoordinatePoint{X: tree.X, Y: tree.Y}] = tree
+	}
+	return trees, nil
+}
+
+func (r *memoryRepository) CountEstateTree(_ context.Context, filter *repository.FilterEstateTree) (int, error) {
+	return len(r.estateTrees(filter)), nil
+}
+
+func (r *memoryRepository) GetEstateTreeStats(_ context.Context, filter *repository.FilterEstateTree) (repository.EstateTreeStats, error) {
+	var heights []int
+	for _, tree := range r.estateTrees(filter) {
+		heights = append(heights, tree.Height)
+	}
+	if len(heights) == 0 {
+		return repository.EstateTreeStats{}, nil
+	}
+	sort.Ints(heights)
+	n := len(heights)
+	median := float32(heights[n/2])
+	if n%2 == 0 {
+		median = float32(heights[n/2-1]+heights[n/2]) / 2
+	}
+	return repository.EstateTreeStats{Min: heights[0], Max: heights[n-1], Median: median}, nil
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/docs"
+	"github.com/dimassantoso/drone-sawit/handler"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// operations lists the operations of spec as "METHOD /path/:param", as echo
+// names its routes, with the paths under each of baseURLs.
+func operations(spec *openapi3.T, baseURLs ...string) []string {
+	if len(baseURLs) == 0 {
+		baseURLs = []string{""}
+	}
+	var ops []string
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			for _, baseURL := range baseURLs {
+				ops = append(ops, method+" "+baseURL+pathParam.ReplaceAllString(path, ":$1"))
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// TestRoutesMatchSpec fails when the routes served by the API and the spec
+// it serves, or api.yml, disagree: an operation without a handler, a route
+// missing from the spec, or generated code older than api.yml. Every
+// operation is served under /v1 and, deprecated, without the prefix.
+func TestRoutesMatchSpec(t *testing.T) {
+	e := newTestEcho(t, newMemoryRepository())
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, docs.PathJSON, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	served, err := openapi3.NewLoader().LoadFromData(rec.Body.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, served.Validate(openapi3.NewLoader().Context))
+
+	var routes []string
+	for _, route := range e.Routes() {
+		switch route.Path {
+		case docs.PathJSON, docs.PathYAML, docs.PathUI:
+			continue
+		}
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+	sort.Strings(routes)
+	assert.Equal(t, operations(served, "/"+handler.V1, ""), routes, "the routes are the operations of the served spec")
+	require.Len(t, served.Servers, 1)
+	serverURL, err := url.Parse(served.Servers[0].URL)
+	require.NoError(t, err)
+	assert.Equal(t, "/"+handler.V1, serverURL.Path, "the spec describes the routes under /v1")
+
+	file, err := openapi3.NewLoader().LoadFromFile("../api.yml")
+	require.NoError(t, err)
+	assert.Equal(t, operations(file), operations(served),
+		"the served spec is api.yml: run make generated")
+	// The generator names the operations api.yml leaves unnamed.
+	for path, item := range served.Paths.Map() {
+		for method, op := range item.Operations() {
+			if fileItem := file.Paths.Find(path); fileItem != nil && fileItem.GetOperation(method) != nil {
+				op.OperationID = fileItem.GetOperation(method).OperationID
+			}
+		}
+	}
+	// It also drops the schemas nothing refers to: compare the paths only.
+	filePaths, err := file.Paths.MarshalJSON()
+	require.NoError(t, err)
+	servedPaths, err := served.Paths.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(filePaths), string(servedPaths), "the served spec is api.yml: run make generated")
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/auth"
+	"github.com/dimassantoso/drone-sawit/client"
+	"github.com/dimassantoso/drone-sawit/generated"
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	const ownerKey, intruderKey = "dsk_owner", "dsk_intruder"
+	scopes := []string{string(auth.ScopeRead), string(auth.ScopeWrite), string(auth.ScopePlan)}
+	repo := newMemoryRepository(
+		repository.APIKey{BaseModel: repository.BaseModel{ID: "key-1"}, OrganizationID: "org-a", KeyHash: auth.HashAPIKey(ownerKey), Scopes: scopes},
+		repository.APIKey{BaseModel: repository.BaseModel{ID: "key-2"}, OrganizationID: "org-b", KeyHash: auth.HashAPIKey(intruderKey), Scopes: scopes},
+	)
+	server := newTestServer(t, repo)
+	owner, err := client.New(client.Options{BaseURL: server.URL, APIKey: ownerKey, MaxAttempts: 1})
+	require.NoError(t, err)
+	intruder, err := client.New(client.Options{BaseURL: server.URL, APIKey: intruderKey, MaxAttempts: 1})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	estate, err := owner.CreateEstate(ctx, generated.EstateRequest{Width: 5, Length: 5})
+	require.NoError(t, err)
+	_, err = owner.CreateTree(ctx, estate.Id, generated.EstateTreeRequest{X: 1, Y: 1, Height: 10})
+	require.NoError(t, err)
+
+	t.Run("Estate", func(t *testing.T) {
+		_, err := intruder.GetEstate(ctx, estate.Id)
+		assert.ErrorIs(t, err, client.ErrNotFound)
+	})
+
+	t.Run("Estate list", func(t *testing.T) {
+		list, err := intruder.ListEstates(ctx, generated.GetEstateParams{})
+		require.NoError(t, err)
+		assert.Empty(t, list.Estates)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		_, err := intruder.GetStats(ctx, estate.Id)
+		assert.ErrorIs(t, err, client.ErrNotFound)
+	})
+
+	t.Run("Drone plan", func(t *testing.T) {
+		_, err := intruder.GetDronePlan(ctx, estate.Id, nil)
+		assert.ErrorIs(t, err, client.ErrNotFound)
+	})
+
+	t.Run("Tree", func(t *testing.T) {
+		_, err := intruder.CreateTree(ctx, estate.Id, generated.EstateTreeRequest{X: 2, Y: 2, Height: 10})
+		assert.ErrorIs(t, err, client.ErrNotFound)
+	})
+
+	t.Run("Owner is unaffected", func(t *testing.T) {
+		stats, err := owner.GetStats(ctx, estate.Id)
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.Count)
+	})
+}
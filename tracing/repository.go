@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dimassantoso/drone-sawit/repository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AttributeStatementName names the repository SQL statement, e.g.
+// "GetEstateQuery", rather than its text.
+const AttributeStatementName = attribute.Key("db.statement.name")
+
+// QueryHook starts a client span for every repository method. It is meant
+// for repository.NewRepositoryOptions.Hooks.
+func QueryHook(ctx context.Context, query repository.QueryInfo) (context.Context, func(err error)) {
+	ctx, span := tracer().Start(ctx, "repository."+query.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(query.Method),
+			AttributeStatementName.String(query.Statement),
+		),
+	)
+	return ctx, func(err error) {
+		// Not found is an answer, not a failure of the query.
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useRecorder installs a tracer provider recording every span for the
+// duration of the test.
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[string]interface{} {
+	result := map[string]interface{}{}
+	for _, kv := range span.Attributes() {
+		result[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return result
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := useRecorder(t)
+
+	e := echo.New()
+	e.Use(Middleware(func(c echo.Context) bool { return c.Path() == "/metrics" }))
+	e.GET("/estate/:id/stats", func(c echo.Context) error {
+		_, end := QueryHook(c.Request().Context(), repository.QueryInfo{Method: "FindEstate", Statement: "GetEstateQuery"})
+		end(&repository.Error{Op: "FindEstate", Kind: repository.ErrUnavailable})
+		return echo.NewHTTPError(http.StatusServiceUnavailable)
+	})
+	e.GET("/metrics", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/estate/abc/stats", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2, "the skipped route is not traced")
+	query, server := spans[0], spans[1]
+
+	assert.Equal(t, "GET /estate/:id/stats", server.Name())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String(), "trace continues from traceparent")
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, server.Status().Code)
+	assert.Equal(t, "/estate/:id/stats", attributes(server)["http.route"])
+	assert.Equal(t, int64(http.StatusServiceUnavailable), attributes(server)["http.response.status_code"])
+
+	assert.Equal(t, "repository.FindEstate", query.Name())
+	assert.Equal(t, server.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Equal(t, "GetEstateQuery", attributes(query)["db.statement.name"])
+	assert.Equal(t, "postgresql", attributes(query)["db.system"])
+	assert.Equal(t, codes.Error, query.Status().Code)
+}
+
+func TestQueryHook_NotFoundIsNotAnError(t *testing.T) {
+	recorder := useRecorder(t)
+
+	_, end := QueryHook(context.Background(), repository.QueryInfo{Method: "FindEstate", Statement: "GetEstateQuery"})
+	end(&repository.Error{Op: "FindEstate", Kind: repository.ErrNotFound})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	t.Run("File exporter", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.json")
+		shutdown, err := Setup(context.Background(), Config{Exporter: ExporterFile, File: path})
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(context.Background(), "offline-span")
+		span.End()
+		require.NoError(t, shutdown(context.Background()))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"Name":"offline-span"`)
+		assert.Contains(t, string(data), ServiceName)
+	})
+
+	t.Run("None", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), Config{})
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := Setup(context.Background(), Config{Exporter: "jaeger"})
+		assert.Error(t, err)
+		_, err = Setup(context.Background(), Config{Exporter: ExporterFile})
+		assert.Error(t, err)
+	})
+}
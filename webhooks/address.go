@@ -0,0 +1,114 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for hosts, and by the default client for
+// connections, that are loopback, private, shared, NAT64, link-local or
+// unspecified addresses: subscriptions must not reach the services next to
+// the API.
+var ErrForbiddenAddress = errors.New("webhooks: forbidden address")
+
+// sharedNetworks are reached through the NAT of the network the API runs
+// in rather than the internet: shared address space (RFC 6598), used by
+// carrier-grade NAT and some cloud networks, and the NAT64 prefixes (RFC
+// 6052 and RFC 8215), which embed any IPv4 address, private ones included.
+var sharedNetworks = []*net.IPNet{
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("64:ff9b::/96"),
+	mustParseCIDR("64:ff9b:1::/48"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// Resolver looks up the addresses of a host. *net.Resolver is one.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// CheckIP returns ErrForbiddenAddress when ip is a loopback, private
+// (RFC 1918 or RFC 4193), shared (CGNAT), NAT64, link-local or unspecified
+// address, IPv4-mapped ones included.
+func CheckIP(ip net.IP) error {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		// 0.0.0.0/8 is "this network": connecting to it reaches the host.
+		if ip[0] == 0 {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	for _, network := range sharedNetworks {
+		if network.Contains(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+		}
+	}
+	return nil
+}
+
+// CheckHost resolves host with r and checks every address it resolves to
+// with CheckIP. IP literals are checked without a lookup.
+func CheckHost(ctx context.Context, r Resolver, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		return CheckIP(ip)
+	}
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	for _, addr := range addrs {
+		if err := CheckIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newClient returns the default client of the Dispatcher. It checks every
+// address it connects to with check, after the lookup, so a host resolving
+// to another address than at registration is refused too, and does not
+// follow redirects, so a redirect counts as a failure. Proxies are not
+// used, as they would connect on its behalf.
+func newClient(check func(net.IP) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return check(ip)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport:     transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
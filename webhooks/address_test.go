@@ -0,0 +1,85 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIP(t *testing.T) {
+	for _, tc := range []struct {
+		class string
+		ip    string
+	}{
+		{class: "loopback", ip: "127.0.0.1"},
+		{class: "loopback", ip: "127.1.2.3"},
+		{class: "loopback", ip: "::1"},
+		{class: "loopback", ip: "::ffff:127.0.0.1"},
+		{class: "private", ip: "10.0.0.1"},
+		{class: "private", ip: "172.16.0.1"},
+		{class: "private", ip: "172.31.255.255"},
+		{class: "private", ip: "192.168.1.1"},
+		{class: "private", ip: "fd00::1"},
+		{class: "shared", ip: "100.64.0.1"},
+		{class: "shared", ip: "100.127.255.254"},
+		{class: "shared", ip: "::ffff:100.64.0.1"},
+		{class: "NAT64", ip: "64:ff9b::a00:1"},
+		{class: "NAT64", ip: "64:ff9b::8.8.8.8"},
+		{class: "NAT64", ip: "64:ff9b:1::a00:1"},
+		{class: "link-local", ip: "169.254.169.254"},
+		{class: "link-local", ip: "fe80::1"},
+		{class: "link-local", ip: "ff02::1"},
+		{class: "unspecified", ip: "0.0.0.0"},
+		{class: "unspecified", ip: "0.1.2.3"},
+		{class: "unspecified", ip: "::"},
+	} {
+		t.Run(tc.class+" "+tc.ip, func(t *testing.T) {
+			assert.ErrorIs(t, CheckIP(net.ParseIP(tc.ip)), ErrForbiddenAddress)
+		})
+	}
+
+	for _, ip := range []string{"8.8.8.8", "172.32.0.1", "100.63.255.255", "100.128.0.1", "203.0.113.10", "2001:4860:4860::8888"} {
+		t.Run("public "+ip, func(t *testing.T) {
+			assert.NoError(t, CheckIP(net.ParseIP(ip)))
+		})
+	}
+}
+
+// fakeResolver resolves the hosts in it, and no others.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs, nil
+}
+
+func TestCheckHost(t *testing.T) {
+	ctx := context.Background()
+	resolver := fakeResolver{
+		"erp.example":      {"203.0.113.10"},
+		"internal.example": {"203.0.113.10", "10.0.0.5"},
+		"empty.example":    {},
+	}
+
+	assert.NoError(t, CheckHost(ctx, resolver, "erp.example"))
+	assert.NoError(t, CheckHost(ctx, resolver, "203.0.113.10"), "literals are not looked up")
+	assert.ErrorIs(t, CheckHost(ctx, resolver, "internal.example"), ErrForbiddenAddress, "every address is checked")
+	assert.ErrorIs(t, CheckHost(ctx, resolver, "::1"), ErrForbiddenAddress)
+
+	var dnsErr *net.DNSError
+	for _, host := range []string{"unknown.example", "empty.example"} {
+		err := CheckHost(ctx, resolver, host)
+		assert.True(t, errors.As(err, &dnsErr), host)
+		assert.NotErrorIs(t, err, ErrForbiddenAddress, host)
+	}
+}
@@ -0,0 +1,331 @@
+// Package webhooks delivers the events written to the outbox by the
+// repository to the subscriptions of each organization, as signed POSTs
+// retried with exponential backoff until they succeed or run out of
+// attempts.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/logging"
+	"github.com/dimassantoso/drone-sawit/repository"
+)
+
+// Headers of every delivery.
+const (
+	// HeaderSignature carries the signature computed by Sign.
+	HeaderSignature = "X-Webhook-Signature"
+	// HeaderEvent is the event type, e.g. "tree.created".
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderDelivery identifies the delivery; retries and replays reuse it.
+	HeaderDelivery = "X-Webhook-Delivery"
+)
+
+const (
+	// DefaultWorkers is the number of deliveries sent at once when
+	// Options.Workers is zero.
+	DefaultWorkers = 2
+	// DefaultPollInterval is how often idle workers look for due deliveries
+	// when Options.PollInterval is zero.
+	DefaultPollInterval = time.Second
+	// DefaultTimeout bounds a delivery when Options.Timeout is zero.
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxAttempts is how many times a delivery is sent before it is
+	// dead, when Options.MaxAttempts is zero.
+	DefaultMaxAttempts = 8
+	// DefaultInitialBackoff is the delay before the first retry when
+	// Options.InitialBackoff is zero; it doubles on every retry.
+	DefaultInitialBackoff = 10 * time.Second
+	// DefaultMaxBackoff caps the delay between retries when
+	// Options.MaxBackoff is zero.
+	DefaultMaxBackoff = time.Hour
+)
+
+// ErrInvalidSignature is returned by Verify.
+var ErrInvalidSignature = errors.New("webhooks: invalid signature")
+
+// maxErrorLength caps the error recorded for a failed delivery.
+const maxErrorLength = 255
+
+// Event is the body of every delivery.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the HeaderSignature of body sent at timestamp:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">".
+// Signing the timestamp lets receivers reject replayed requests.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + signature(secret, t, body)
+}
+
+func signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the HeaderSignature header of body, and that it was signed
+// within tolerance of now. Receivers written in Go can use it as is.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp, v1 string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			v1 = value
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || v1 == "" {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(v1), []byte(signature(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type Options struct {
+	Repository repository.RepositoryInterface
+	// Client sends the deliveries. It defaults to a client that refuses to
+	// connect to the addresses CheckIP forbids and does not follow
+	// redirects, so a redirect counts as a failure.
+	Client *http.Client
+	// Workers is the number of deliveries sent at once by this process.
+	Workers        int
+	PollInterval   time.Duration
+	Timeout        time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Now returns the time deliveries are signed at. It defaults to
+	// time.Now.
+	Now func() time.Time
+}
+
+// Dispatcher sends the due deliveries with Run.
+type Dispatcher struct {
+	repository     repository.RepositoryInterface
+	client         *http.Client
+	workers        int
+	pollInterval   time.Duration
+	timeout        time.Duration
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	now            func() time.Time
+}
+
+func New(opts Options) *Dispatcher {
+	d := &Dispatcher{
+		repository:     opts.Repository,
+		client:         opts.Client,
+		workers:        opts.Workers,
+		pollInterval:   opts.PollInterval,
+		timeout:        opts.Timeout,
+		maxAttempts:    opts.MaxAttempts,
+		initialBackoff: opts.InitialBackoff,
+		maxBackoff:     opts.MaxBackoff,
+		now:            opts.Now,
+	}
+	if d.client == nil {
+		d.client = newClient(CheckIP)
+	}
+	if d.workers <= 0 {
+		d.workers = DefaultWorkers
+	}
+	if d.pollInterval <= 0 {
+		d.pollInterval = DefaultPollInterval
+	}
+	if d.timeout <= 0 {
+		d.timeout = DefaultTimeout
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = DefaultMaxAttempts
+	}
+	if d.initialBackoff <= 0 {
+		d.initialBackoff = DefaultInitialBackoff
+	}
+	if d.maxBackoff <= 0 {
+		d.maxBackoff = DefaultMaxBackoff
+	}
+	if d.now == nil {
+		d.now = time.Now
+	}
+	return d
+}
+
+// Run sends deliveries until ctx is done. A delivery interrupted by ctx is
+// retried, here or by another replica, once its lease expires.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	for {
+		// Drain the due deliveries before waiting again.
+		for ctx.Err() == nil {
+			delivery, err := d.repository.ClaimWebhookDelivery(ctx, d.lease())
+			if err != nil {
+				// Nothing is due, or a failure the repository already logged.
+				break
+			}
+			d.deliver(ctx, delivery)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lease is how long a claimed delivery is left to its worker before it is
+// retried.
+func (d *Dispatcher) lease() time.Duration {
+	return 2 * d.timeout
+}
+
+// deliver sends a claimed delivery and records the outcome.
+func (d *Dispatcher) deliver(ctx context.Context, delivery repository.WebhookDelivery) {
+	logger := logging.FromContext(ctx).With(
+		slog.String("delivery_id", delivery.ID),
+		slog.String("subscription_id", delivery.SubscriptionID),
+		slog.Int("attempt", delivery.Attempts),
+	)
+
+	statusCode, err := d.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// Shutting down: the delivery is retried once its lease expires.
+		return
+	}
+
+	var retryAfter time.Duration
+	delivery.LastStatusCode = nil
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+	switch {
+	case err == nil:
+		delivery.Status = repository.WebhookDeliveryDelivered
+		delivery.LastError = ""
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = repository.WebhookDeliveryDead
+		delivery.LastError = truncate(err.Error())
+		logger.Warn("webhook delivery dead", slog.Any("error", err))
+	default:
+		delivery.Status = repository.WebhookDeliveryPending
+		delivery.LastError = truncate(err.Error())
+		retryAfter = d.backoff(delivery.Attempts)
+		logger.Info("webhook delivery failed", slog.Any("error", err), slog.Duration("retry_after", retryAfter))
+	}
+
+	// The outcome is recorded even when ctx is done meanwhile, so a sent
+	// delivery is not sent again.
+	err = d.repository.FinishWebhookDelivery(context.WithoutCancel(ctx), &delivery, retryAfter)
+	if errors.Is(err, repository.ErrNotFound) {
+		logger.Warn("webhook delivery lease expired while sending")
+	}
+}
+
+// backoff returns the delay after the failed attempt number attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.initialBackoff
+	for i := 1; i < attempts && backoff < d.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, d.maxBackoff)
+}
+
+// send POSTs delivery and returns the status code of the response, if
+// any. Responses other than 2xx are errors.
+func (d *Dispatcher) send(ctx context.Context, delivery repository.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(Event{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.EventCreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "drone-sawit-webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, d.now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func truncate(s string) string {
+	if len(s) <= maxErrorLength {
+		return s
+	}
+	return s[:maxErrorLength]
+}
@@ -0,0 +1,289 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dimassantoso/drone-sawit/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository keeps deliveries in memory, with the semantics of the
+// webhook delivery queries. Other methods are not implemented.
+type fakeRepository struct {
+	repository.RepositoryInterface
+
+	mu         sync.Mutex
+	deliveries map[string]repository.WebhookDelivery
+	retries    map[string]time.Duration
+}
+
+func newFakeRepository(deliveries ...repository.WebhookDelivery) *fakeRepository {
+	r := &fakeRepository{deliveries: map[string]repository.WebhookDelivery{}, retries: map[string]time.Duration{}}
+	for _, delivery := range deliveries {
+		delivery.Status = repository.WebhookDeliveryPending
+		r.deliveries[delivery.ID] = delivery
+	}
+	return r
+}
+
+func (r *fakeRepository) ClaimWebhookDelivery(_ context.Context, lease time.Duration) (repository.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for id, delivery := range r.deliveries {
+		if delivery.Status == repository.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) {
+			delivery.Attempts++
+			delivery.NextAttemptAt = now.Add(lease)
+			r.deliveries[id] = delivery
+			return delivery, nil
+		}
+	}
+	return repository.WebhookDelivery{}, repository.ErrNotFound
+}
+
+func (r *fakeRepository) FinishWebhookDelivery(_ context.Context, data *repository.WebhookDelivery, retryAfter time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery, ok := r.deliveries[data.ID]
+	if !ok || delivery.Attempts != data.Attempts || delivery.Status != repository.WebhookDeliveryPending {
+		return repository.ErrNotFound
+	}
+	delivery.Status, delivery.LastStatusCode, delivery.LastError = data.Status, data.LastStatusCode, data.LastError
+	delivery.NextAttemptAt = time.Now().Add(retryAfter)
+	r.deliveries[data.ID] = delivery
+	r.retries[data.ID] = retryAfter
+	return nil
+}
+
+func (r *fakeRepository) delivery(id string) repository.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveries[id]
+}
+
+// claim claims the due delivery, failing the test when there is none.
+func (r *fakeRepository) claim(t *testing.T) repository.WebhookDelivery {
+	t.Helper()
+	delivery, err := r.ClaimWebhookDelivery(context.Background(), time.Minute)
+	require.NoError(t, err)
+	return delivery
+}
+
+func testDelivery(url string) repository.WebhookDelivery {
+	return repository.WebhookDelivery{
+		BaseModel:      repository.BaseModel{ID: "delivery-1"},
+		OrganizationID: "org-1",
+		EventID:        "event-1",
+		SubscriptionID: "sub-1",
+		EventType:      repository.WebhookEventTreeCreated,
+		EventCreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Payload:        []byte(`{"id":"tree-1","estate_id":"estate-1","x":1,"y":2,"height":3}`),
+		URL:            url,
+		Secret:         "whsec_test",
+	}
+}
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver starts an httptest server answering with the statuses in turn,
+// then 200.
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan received) {
+	requests := make(chan received, 16)
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+		mu.Lock()
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+// newTestDispatcher returns a Dispatcher whose client may connect to the
+// loopback receivers of the tests.
+func newTestDispatcher(opts Options) *Dispatcher {
+	d := New(opts)
+	d.client = newClient(func(net.IP) error { return nil })
+	return d
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	server, requests := receiver(t)
+	repo := newFakeRepository(testDelivery(server.URL))
+	now := time.Now()
+	d := newTestDispatcher(Options{Repository: repo, Now: func() time.Time { return now }})
+
+	d.deliver(context.Background(), repo.claim(t))
+
+	req := <-requests
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+	assert.Equal(t, repository.WebhookEventTreeCreated, req.header.Get(HeaderEvent))
+	assert.Equal(t, "delivery-1", req.header.Get(HeaderDelivery))
+	assert.NoError(t, Verify("whsec_test", req.header.Get(HeaderSignature), req.body, time.Minute, now))
+
+	var event Event
+	require.NoError(t, json.Unmarshal(req.body, &event))
+	assert.Equal(t, "event-1", event.ID)
+	assert.Equal(t, repository.WebhookEventTreeCreated, event.Type)
+	assert.JSONEq(t, `{"id":"tree-1","estate_id":"estate-1","x":1,"y":2,"height":3}`, string(event.Data))
+
+	delivery := repo.delivery("delivery-1")
+	assert.Equal(t, repository.WebhookDeliveryDelivered, delivery.Status)
+	assert.Equal(t, http.StatusOK, *delivery.LastStatusCode)
+	assert.Empty(t, delivery.LastError)
+}
+
+func TestDispatcher_Deliver_Backoff(t *testing.T) {
+	server, _ := receiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable)
+	repo := newFakeRepository(testDelivery(server.URL))
+	d := newTestDispatcher(Options{Repository: repo, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second, MaxAttempts: 5})
+
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		d.deliver(context.Background(), repo.claim(t))
+		delivery := repo.delivery("delivery-1")
+		assert.Equal(t, repository.WebhookDeliveryPending, delivery.Status)
+		assert.Equal(t, want, repo.retries["delivery-1"])
+		makeDue(repo, "delivery-1")
+	}
+	assert.Equal(t, "unexpected status 503 Service Unavailable", repo.delivery("delivery-1").LastError)
+
+	d.deliver(context.Background(), repo.claim(t))
+	assert.Equal(t, repository.WebhookDeliveryDelivered, repo.delivery("delivery-1").Status)
+}
+
+func TestDispatcher_Deliver_DeadLetter(t *testing.T) {
+	server, _ := receiver(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	repo := newFakeRepository(testDelivery(server.URL))
+	d := newTestDispatcher(Options{Repository: repo, MaxAttempts: 2})
+
+	d.deliver(context.Background(), repo.claim(t))
+	makeDue(repo, "delivery-1")
+	d.deliver(context.Background(), repo.claim(t))
+
+	delivery := repo.delivery("delivery-1")
+	assert.Equal(t, repository.WebhookDeliveryDead, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, *delivery.LastStatusCode)
+	_, err := repo.ClaimWebhookDelivery(context.Background(), time.Minute)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "dead deliveries are not retried")
+}
+
+func TestDispatcher_Deliver_Unreachable(t *testing.T) {
+	server, _ := receiver(t)
+	server.Close()
+	repo := newFakeRepository(testDelivery(server.URL))
+	d := newTestDispatcher(Options{Repository: repo})
+
+	d.deliver(context.Background(), repo.claim(t))
+
+	delivery := repo.delivery("delivery-1")
+	assert.Equal(t, repository.WebhookDeliveryPending, delivery.Status)
+	assert.Nil(t, delivery.LastStatusCode)
+	assert.NotEmpty(t, delivery.LastError)
+}
+
+func TestDispatcher_Deliver_RedirectIsAFailure(t *testing.T) {
+	target, requests := receiver(t)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+	repo := newFakeRepository(testDelivery(redirect.URL))
+	d := newTestDispatcher(Options{Repository: repo})
+
+	d.deliver(context.Background(), repo.claim(t))
+
+	assert.Equal(t, http.StatusFound, *repo.delivery("delivery-1").LastStatusCode)
+	assert.Empty(t, requests)
+}
+
+func TestDispatcher_Deliver_ForbiddenAddress(t *testing.T) {
+	server, requests := receiver(t)
+	repo := newFakeRepository(testDelivery(server.URL))
+	d := New(Options{Repository: repo})
+
+	d.deliver(context.Background(), repo.claim(t))
+
+	delivery := repo.delivery("delivery-1")
+	assert.Equal(t, repository.WebhookDeliveryPending, delivery.Status)
+	assert.Nil(t, delivery.LastStatusCode)
+	assert.Contains(t, delivery.LastError, "forbidden address")
+	assert.Empty(t, requests, "the loopback receiver is not connected to")
+}
+
+func TestDispatcher_Run(t *testing.T) {
+	server, requests := receiver(t)
+	first, second := testDelivery(server.URL), testDelivery(server.URL)
+	second.ID = "delivery-2"
+	repo := newFakeRepository(first, second)
+	d := newTestDispatcher(Options{Repository: repo, PollInterval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-requests:
+		case <-time.After(5 * time.Second):
+			t.Fatal("delivery not sent")
+		}
+	}
+	require.Eventually(t, func() bool {
+		return repo.delivery("delivery-1").Status == repository.WebhookDeliveryDelivered &&
+			repo.delivery("delivery-2").Status == repository.WebhookDeliveryDelivered
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"id":"event-1"}`)
+	header := Sign("secret", now, body)
+
+	assert.NoError(t, Verify("secret", header, body, time.Minute, now.Add(30*time.Second)))
+	assert.ErrorIs(t, Verify("other", header, body, time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", header, []byte(`{"id":"event-2"}`), time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", header, body, time.Minute, now.Add(2*time.Minute)), ErrInvalidSignature, "too old")
+	assert.ErrorIs(t, Verify("secret", "v1=abc", body, time.Minute, now), ErrInvalidSignature)
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	require.NoError(t, err)
+	b, err := NewSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	assert.Len(t, a, len("whsec_")+64)
+}
+
+// makeDue makes delivery id due now, as if its backoff elapsed.
+func makeDue(r *fakeRepository, id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery := r.deliveries[id]
+	delivery.NextAttemptAt = time.Time{}
+	r.deliveries[id] = delivery
+}